@@ -109,7 +109,10 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- $ignored = required "backend.externalDatabase.host is required" .Values.backend.externalDatabase.host -}}
 {{- $ignored = required "backend.externalDatabase.existingSecret.name is required" .Values.backend.externalDatabase.existingSecret.name -}}
 {{- end -}}
-{{- if and (not .Values.backend.persistence.enabled) (not .Values.backend.persistence.existingClaim) }}{{ fail "enable backend.persistence or set backend.persistence.existingClaim" }}{{ end -}}
+{{- if eq .Values.backend.storage.backend "s3" -}}
+{{- $ignored = required "backend.storage.s3.bucket is required when backend.storage.backend=s3" .Values.backend.storage.s3.bucket -}}
+{{- $ignored = required "backend.storage.s3.existingSecret.name is required when backend.storage.backend=s3" .Values.backend.storage.s3.existingSecret.name -}}
+{{- else if and (not .Values.backend.persistence.enabled) (not .Values.backend.persistence.existingClaim) }}{{ fail "enable backend.persistence or set backend.persistence.existingClaim" }}{{ end -}}
 {{- if .Values.customCA.enabled -}}
 {{- if and .Values.customCA.existingConfigMap .Values.customCA.existingSecret }}{{ fail "set only one customCA source" }}{{ end -}}
 {{- if not (or .Values.customCA.existingConfigMap .Values.customCA.existingSecret) }}{{ fail "set a customCA ConfigMap or Secret when customCA.enabled=true" }}{{ end -}}
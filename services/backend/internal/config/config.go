@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -22,10 +23,44 @@ type Config struct {
 	OIDCEncryptionKey string
 	CORSOrigin        string
 	FileStorageRoot   string
-	MaxUploadBytes    int64
-	Production        bool
-	MigrationsMode    string
-	CustomCACertFile  string
+	StorageBackend    string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3ForcePathStyle  bool
+	// S3PresignedDownloads redirects downloads to short-lived bucket URLs.
+	S3PresignedDownloads bool
+	// StorageMode runs a one-shot maintenance command instead of the server:
+	// reconcile checks the store against the database, migrate copies every
+	// referenced blob from StorageMigrateFrom into StorageBackend.
+	StorageMode            string
+	StorageMigrateFrom     string
+	StorageDeleteOrphans   bool
+	StorageVerifyChecksums bool
+	MaxUploadBytes         int64
+	Production             bool
+	MigrationsMode         string
+	CustomCACertFile       string
+	PublicURL              string
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
+	SMTPFrom               string
+	SMTPTLSMode            string
+
+	// MaxResumableUploadBytes caps files uploaded through upload sessions,
+	// whose chunks are each capped by MaxUploadBytes.
+	MaxResumableUploadBytes int64
+	// ProjectStorageQuotaBytes caps the bytes stored per project; zero
+	// disables the quota.
+	ProjectStorageQuotaBytes int64
+	// BudgetAlertThresholds are the ascending percentages of a project's hour
+	// budget at which its owners and admins are notified; empty disables
+	// budget alerts.
+	BudgetAlertThresholds []int
 }
 
 func Load() *Config {
@@ -34,22 +69,76 @@ func Load() *Config {
 		port = getEnvInt("BACKEND_PORT", 8080)
 	}
 	cfg := &Config{
-		Port:              port,
-		DBHost:            getEnv("DB_HOST", "localhost"),
-		DBPort:            getEnvInt("DB_PORT", 5432),
-		DBUser:            getEnv("DB_USER", "justspace"),
-		DBPassword:        getEnv("DB_PASSWORD", "justspace"),
-		DBName:            getEnv("DB_NAME", "justspace"),
-		DBSSLMode:         getEnv("DB_SSLMODE", "disable"),
-		JWTSecret:         getEnv("JWT_SECRET", "change-me-in-production"),
-		OIDCEncryptionKey: getEnv("OIDC_ENCRYPTION_KEY", ""),
-		CORSOrigin:        getEnv("CORS_ORIGIN", "http://localhost:3000"),
-		FileStorageRoot:   getEnv("FILE_STORAGE_ROOT", "/data/uploads"),
-		MaxUploadBytes:    getEnvInt64("MAX_UPLOAD_BYTES", 50*1024*1024),
-		Production:        strings.EqualFold(getEnv("APP_ENV", "development"), "production"),
-		MigrationsMode:    strings.ToLower(getEnv("MIGRATIONS_MODE", "auto")),
-		CustomCACertFile:  getEnv("CUSTOM_CA_CERT_FILE", ""),
+		Port:                   port,
+		DBHost:                 getEnv("DB_HOST", "localhost"),
+		DBPort:                 getEnvInt("DB_PORT", 5432),
+		DBUser:                 getEnv("DB_USER", "justspace"),
+		DBPassword:             getEnv("DB_PASSWORD", "justspace"),
+		DBName:                 getEnv("DB_NAME", "justspace"),
+		DBSSLMode:              getEnv("DB_SSLMODE", "disable"),
+		JWTSecret:              getEnv("JWT_SECRET", "change-me-in-production"),
+		OIDCEncryptionKey:      getEnv("OIDC_ENCRYPTION_KEY", ""),
+		CORSOrigin:             getEnv("CORS_ORIGIN", "http://localhost:3000"),
+		FileStorageRoot:        getEnv("FILE_STORAGE_ROOT", "/data/uploads"),
+		StorageBackend:         strings.ToLower(getEnv("STORAGE_BACKEND", "disk")),
+		S3Endpoint:             getEnv("S3_ENDPOINT", ""),
+		S3Region:               getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:               getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:          getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:      getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3ForcePathStyle:       getEnvBool("S3_FORCE_PATH_STYLE", false),
+		S3PresignedDownloads:   getEnvBool("S3_PRESIGNED_DOWNLOADS", false),
+		StorageMode:            strings.ToLower(getEnv("STORAGE_MODE", "serve")),
+		StorageMigrateFrom:     strings.ToLower(getEnv("STORAGE_MIGRATE_FROM", "")),
+		StorageDeleteOrphans:   getEnvBool("STORAGE_DELETE_ORPHANS", false),
+		StorageVerifyChecksums: getEnvBool("STORAGE_VERIFY_CHECKSUMS", true),
+		MaxUploadBytes:         getEnvInt64("MAX_UPLOAD_BYTES", 50*1024*1024),
+		Production:             strings.EqualFold(getEnv("APP_ENV", "development"), "production"),
+		MigrationsMode:         strings.ToLower(getEnv("MIGRATIONS_MODE", "auto")),
+		CustomCACertFile:       getEnv("CUSTOM_CA_CERT_FILE", ""),
+		SMTPHost:               getEnv("SMTP_HOST", ""),
+		SMTPPort:               getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:           getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:               getEnv("SMTP_FROM", ""),
+		SMTPTLSMode:            strings.ToLower(getEnv("SMTP_TLS_MODE", "starttls")),
+
+		MaxResumableUploadBytes:  getEnvInt64("MAX_RESUMABLE_UPLOAD_BYTES", 10*1024*1024*1024),
+		ProjectStorageQuotaBytes: getEnvInt64("PROJECT_STORAGE_QUOTA_BYTES", 0),
+	}
+	// Links in emails point at the web app. The first CORS origin is the web
+	// app in the default deployment topology.
+	cfg.PublicURL = strings.TrimRight(getEnv("APP_PUBLIC_URL", strings.TrimSpace(strings.Split(cfg.CORSOrigin, ",")[0])), "/")
+	if cfg.SMTPTLSMode != "starttls" && cfg.SMTPTLSMode != "tls" && cfg.SMTPTLSMode != "none" {
+		panic("SMTP_TLS_MODE must be one of starttls, tls, or none")
+	}
+	if cfg.SMTPHost != "" && cfg.SMTPFrom == "" {
+		panic("SMTP_FROM must be configured when SMTP_HOST is set")
+	}
+	if cfg.StorageBackend != "disk" && cfg.StorageBackend != "s3" {
+		panic("STORAGE_BACKEND must be one of disk or s3")
+	}
+	if cfg.StorageMode != "serve" && cfg.StorageMode != "reconcile" && cfg.StorageMode != "migrate" {
+		panic("STORAGE_MODE must be one of serve, reconcile, or migrate")
+	}
+	if cfg.StorageMode == "migrate" && (cfg.StorageMigrateFrom != "disk" && cfg.StorageMigrateFrom != "s3" || cfg.StorageMigrateFrom == cfg.StorageBackend) {
+		panic("STORAGE_MIGRATE_FROM must name the other storage backend when STORAGE_MODE=migrate")
 	}
+	usesS3 := cfg.StorageBackend == "s3" || (cfg.StorageMode == "migrate" && cfg.StorageMigrateFrom == "s3")
+	if usesS3 && (cfg.S3Bucket == "" || cfg.S3AccessKeyID == "" || cfg.S3SecretAccessKey == "") {
+		panic("S3_BUCKET, S3_ACCESS_KEY_ID, and S3_SECRET_ACCESS_KEY must be configured when S3 storage is used")
+	}
+	if cfg.MaxUploadBytes <= 0 || cfg.MaxResumableUploadBytes <= 0 {
+		panic("MAX_UPLOAD_BYTES and MAX_RESUMABLE_UPLOAD_BYTES must be positive")
+	}
+	if cfg.ProjectStorageQuotaBytes < 0 {
+		panic("PROJECT_STORAGE_QUOTA_BYTES must not be negative")
+	}
+	thresholds, err := parsePercentages(getEnv("BUDGET_ALERT_THRESHOLDS", "50,80,100"))
+	if err != nil {
+		panic("BUDGET_ALERT_THRESHOLDS must be off or a comma-separated list of percentages between 1 and 1000")
+	}
+	cfg.BudgetAlertThresholds = thresholds
 	if cfg.MigrationsMode != "auto" && cfg.MigrationsMode != "only" && cfg.MigrationsMode != "skip" {
 		panic("MIGRATIONS_MODE must be one of auto, only, or skip")
 	}
@@ -112,6 +201,11 @@ func (c *Config) ConfigureDefaultHTTPClient() error {
 	return nil
 }
 
+// MailEnabled reports whether outgoing email is configured.
+func (c *Config) MailEnabled() bool {
+	return c.SMTPHost != ""
+}
+
 func (c *Config) DSN() string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
@@ -119,6 +213,26 @@ func (c *Config) DSN() string {
 	)
 }
 
+// parsePercentages parses a comma-separated list of whole percentages into
+// ascending order without duplicates; "off" yields none.
+func parsePercentages(value string) ([]int, error) {
+	if strings.EqualFold(strings.TrimSpace(value), "off") {
+		return []int{}, nil
+	}
+	percentages := []int{}
+	for _, part := range strings.Split(value, ",") {
+		percentage, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || percentage < 1 || percentage > 1000 {
+			return nil, fmt.Errorf("invalid percentage %q", part)
+		}
+		if !slices.Contains(percentages, percentage) {
+			percentages = append(percentages, percentage)
+		}
+	}
+	slices.Sort(percentages)
+	return percentages, nil
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -143,3 +257,12 @@ func getEnvInt64(key string, fallback int64) int64 {
 	}
 	return fallback
 }
+
+func getEnvBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
@@ -85,3 +86,89 @@ func TestLoadAllowsDisabledDatabaseTLSInProduction(t *testing.T) {
 		t.Fatalf("DBSSLMode = %q, want disable", got)
 	}
 }
+
+func TestLoadDefaultsPublicURLToFirstCORSOrigin(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("CORS_ORIGIN", "https://app.example.com/, https://other.example.com")
+	if got := Load().PublicURL; got != "https://app.example.com" {
+		t.Fatalf("PublicURL = %q, want https://app.example.com", got)
+	}
+}
+
+func TestLoadRequiresSMTPFromWithHost(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Load() did not panic without SMTP_FROM")
+		}
+	}()
+	Load()
+}
+
+func TestLoadRequiresS3Bucket(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("STORAGE_BACKEND", "s3")
+	t.Setenv("S3_ACCESS_KEY_ID", "access")
+	t.Setenv("S3_SECRET_ACCESS_KEY", "secret")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Load() did not panic without S3_BUCKET")
+		}
+	}()
+	Load()
+}
+
+func TestLoadRejectsMigrationToSameStorageBackend(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("STORAGE_MODE", "migrate")
+	t.Setenv("STORAGE_MIGRATE_FROM", "disk")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Load() did not panic when migrating disk storage to itself")
+		}
+	}()
+	Load()
+}
+
+func TestLoadRejectsNegativeProjectQuota(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("PROJECT_STORAGE_QUOTA_BYTES", "-1")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Load() did not panic with a negative PROJECT_STORAGE_QUOTA_BYTES")
+		}
+	}()
+	Load()
+}
+
+func TestLoadParsesBudgetAlertThresholds(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []int
+	}{
+		{value: "", want: []int{50, 80, 100}},
+		{value: "100, 75,75", want: []int{75, 100}},
+		{value: "off", want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("APP_ENV", "development")
+			t.Setenv("BUDGET_ALERT_THRESHOLDS", tt.value)
+			if got := Load().BudgetAlertThresholds; !slices.Equal(got, tt.want) {
+				t.Fatalf("BudgetAlertThresholds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidBudgetAlertThresholds(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("BUDGET_ALERT_THRESHOLDS", "50,0")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Load() did not panic with a zero budget alert threshold")
+		}
+	}()
+	Load()
+}
@@ -22,6 +22,22 @@ func (h *AuthHandler) requirePlatformAdmin(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusForbidden, "platform admin access required")
 		return false
 	}
+	required, err := h.adminMFARequired(r.Context(), user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return false
+	}
+	if required {
+		mfa, err := h.repo.GetUserMFA(r.Context(), user.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return false
+		}
+		if mfa == nil || mfa.EnabledAt == nil {
+			writeError(w, http.StatusForbidden, mfaRequiredMessage)
+			return false
+		}
+	}
 	return true
 }
 
@@ -62,6 +78,26 @@ func (h *AuthHandler) UpdateAdminSettings(w http.ResponseWriter, r *http.Request
 			return
 		}
 	}
+	if req.DefaultWorkspaceStorageQuotaBytes != nil && *req.DefaultWorkspaceStorageQuotaBytes < 0 {
+		writeError(w, http.StatusBadRequest, "defaultWorkspaceStorageQuotaBytes must not be negative")
+		return
+	}
+	if req.RequireVerifiedEmail != nil && *req.RequireVerifiedEmail && !h.mailer.Enabled() {
+		writeError(w, http.StatusConflict, "configure email delivery before requiring verified emails")
+		return
+	}
+	if req.RequireAdminMFA != nil && *req.RequireAdminMFA {
+		// Refuse settings that would lock the acting admin out immediately.
+		mfa, err := h.repo.GetUserMFA(r.Context(), middleware.GetUserID(r))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to validate authentication settings")
+			return
+		}
+		if mfa == nil || mfa.EnabledAt == nil {
+			writeError(w, http.StatusConflict, "enable two-factor authentication on your account before requiring it for admins")
+			return
+		}
+	}
 	settings, err := h.repo.UpdatePlatformSettings(r.Context(), req)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to update platform settings")
@@ -83,6 +119,41 @@ func (h *AuthHandler) AdminOverview(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, overview)
 }
 
+// AdminUpdateWorkspaceStorageQuota overrides the platform default quota for
+// one workspace. Zero lifts the limit; null restores the default.
+func (h *AuthHandler) AdminUpdateWorkspaceStorageQuota(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlatformAdmin(w, r) {
+		return
+	}
+	var req models.WorkspaceStorageQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.QuotaBytes != nil && *req.QuotaBytes < 0 {
+		writeError(w, http.StatusBadRequest, "quotaBytes must not be negative")
+		return
+	}
+	workspaceID := chi.URLParam(r, "workspaceId")
+	updated, err := h.repo.SetWorkspaceStorageQuota(r.Context(), workspaceID, req.QuotaBytes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update storage quota")
+		return
+	}
+	if !updated {
+		writeError(w, http.StatusNotFound, "workspace not found")
+		return
+	}
+	usage, err := h.repo.GetWorkspaceStorageUsage(r.Context(), workspaceID)
+	if err != nil || usage == nil {
+		writeError(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+	metadata, _ := json.Marshal(map[string]any{"quotaBytes": req.QuotaBytes})
+	h.recordAdminAudit(r, "workspace.storage_quota_updated", "workspace", usage.WorkspaceID, usage.Name, metadata)
+	writeJSON(w, http.StatusOK, usage)
+}
+
 func (h *AuthHandler) AdminAudit(w http.ResponseWriter, r *http.Request) {
 	if !h.requirePlatformAdmin(w, r) {
 		return
@@ -130,7 +201,13 @@ func (h *AuthHandler) UpdateAdminUser(w http.ResponseWriter, r *http.Request) {
 	if h.hub != nil {
 		h.hub.DisconnectUser(user.ID)
 	}
-	h.recordAdminAudit(r, "user.access_updated", "user", user.ID, user.Email, nil)
+	if req.IsPlatformAdmin != nil || req.IsActive != nil {
+		h.recordAdminAudit(r, "user.access_updated", "user", user.ID, user.Email, nil)
+	}
+	if req.RevokeAllSessions || len(req.RevokeSessionIDs) > 0 {
+		metadata, _ := json.Marshal(map[string]any{"all": req.RevokeAllSessions, "sessionIds": req.RevokeSessionIDs})
+		h.recordAdminAudit(r, "user.sessions_revoked", "user", user.ID, user.Email, metadata)
+	}
 	writeJSON(w, http.StatusOK, user)
 }
 
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+)
+
+type AnalyticsHandler struct {
+	repo *repository.Repo
+}
+
+func NewAnalyticsHandler(repo *repository.Repo) *AnalyticsHandler {
+	return &AnalyticsHandler{repo: repo}
+}
+
+// Burndown returns daily total, completed and remaining task counts. With a
+// sprintId only the sprint's tasks count and the range defaults to the
+// sprint's dates.
+func (h *AnalyticsHandler) Burndown(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.ensureAccess(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	var sprintID *string
+	defaultFrom, defaultTo := "", ""
+	if id := query.Get("sprintId"); id != "" {
+		sprint, err := h.repo.GetSprint(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load sprint")
+			return
+		}
+		if sprint == nil || sprint.ProjectID != projectID {
+			writeError(w, http.StatusNotFound, "sprint not found")
+			return
+		}
+		sprintID = &sprint.ID
+		defaultFrom, defaultTo = sprint.StartDate.Format(time.DateOnly), sprint.EndDate.Format(time.DateOnly)
+	}
+	if query.Get("from") != "" {
+		defaultFrom = query.Get("from")
+	}
+	if query.Get("to") != "" {
+		defaultTo = query.Get("to")
+	}
+	from, to, message := dayRange(defaultFrom, defaultTo, 13, time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	points, err := h.repo.ProjectBurndown(r.Context(), projectID, sprintID, from, to)
+	if err != nil {
+		log.Printf("ProjectBurndown error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load burndown")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"from":     from.Format(time.DateOnly),
+		"to":       to.Format(time.DateOnly),
+		"sprintId": sprintID,
+		"points":   points,
+	})
+}
+
+// Throughput returns the number of tasks completed per week.
+func (h *AnalyticsHandler) Throughput(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.ensureAccess(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := dayRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), 12*7-1, time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	weeks, err := h.repo.ProjectThroughput(r.Context(), projectID, from, to)
+	if err != nil {
+		log.Printf("ProjectThroughput error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load throughput")
+		return
+	}
+	total := 0
+	for _, week := range weeks {
+		total += week.Completed
+	}
+	average := 0.0
+	if len(weeks) > 0 {
+		average = float64(total) / float64(len(weeks))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"from":           from.Format(time.DateOnly),
+		"to":             to.Format(time.DateOnly),
+		"weeks":          weeks,
+		"averagePerWeek": average,
+	})
+}
+
+// CycleTime returns lead and cycle times for the tasks completed in the range.
+func (h *AnalyticsHandler) CycleTime(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.ensureAccess(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := dayRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), 29, time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	tasks, err := h.repo.ListTaskCycleTimes(r.Context(), projectID, from, to)
+	if err != nil {
+		log.Printf("ListTaskCycleTimes error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load cycle times")
+		return
+	}
+	leadTimes := make([]float64, 0, len(tasks))
+	cycleTimes := make([]float64, 0, len(tasks))
+	for _, task := range tasks {
+		leadTimes = append(leadTimes, task.LeadTimeHours)
+		if task.CycleTimeHours != nil {
+			cycleTimes = append(cycleTimes, *task.CycleTimeHours)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.CycleTimeReport{
+		LeadTime:  durationStats(leadTimes),
+		CycleTime: durationStats(cycleTimes),
+		Tasks:     tasks,
+	})
+}
+
+// TimeInStatus returns the time tasks spent in each workflow status.
+func (h *AnalyticsHandler) TimeInStatus(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.ensureAccess(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := dayRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), 29, time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	statuses, err := h.repo.ProjectTimeInStatus(r.Context(), projectID, from, to)
+	if err != nil {
+		log.Printf("ProjectTimeInStatus error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load time in status")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.StatusTime]{Total: len(statuses), Documents: statuses})
+}
+
+func (h *AnalyticsHandler) ensureAccess(w http.ResponseWriter, r *http.Request) (string, bool) {
+	projectID := chi.URLParam(r, "projectId")
+	return projectID, ensureProjectAccess(w, r, h.repo, projectID, middleware.GetUserID(r))
+}
+
+// durationStats summarizes durations in hours, or returns nil when there are
+// none. The 85th percentile uses the nearest-rank method.
+func durationStats(hours []float64) *models.DurationStats {
+	if len(hours) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(hours)
+	slices.Sort(sorted)
+	total := 0.0
+	for _, value := range sorted {
+		total += value
+	}
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return &models.DurationStats{
+		Count:        n,
+		AverageHours: total / float64(n),
+		MedianHours:  median,
+		P85Hours:     sorted[int(math.Ceil(0.85*float64(n)))-1],
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import "testing"
+
+func TestDurationStats(t *testing.T) {
+	if got := durationStats(nil); got != nil {
+		t.Fatalf("durationStats(nil) = %+v, want nil", got)
+	}
+	tests := []struct {
+		name                 string
+		hours                []float64
+		average, median, p85 float64
+	}{
+		{name: "single", hours: []float64{4}, average: 4, median: 4, p85: 4},
+		{name: "odd count", hours: []float64{9, 1, 5}, average: 5, median: 5, p85: 9},
+		{name: "even count", hours: []float64{1, 2, 3, 10}, average: 4, median: 2.5, p85: 10},
+		{name: "ten values", hours: []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, average: 5.5, median: 5.5, p85: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]float64(nil), tt.hours...)
+			got := durationStats(input)
+			if got.Count != len(tt.hours) || got.AverageHours != tt.average || got.MedianHours != tt.median || got.P85Hours != tt.p85 {
+				t.Fatalf("durationStats(%v) = %+v", tt.hours, got)
+			}
+			for i := range input {
+				if input[i] != tt.hours[i] {
+					t.Fatalf("durationStats reordered its input")
+				}
+			}
+		})
+	}
+}
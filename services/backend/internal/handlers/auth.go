@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/justlabv1/justspace/backend/internal/mailer"
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
 	"github.com/justlabv1/justspace/backend/internal/repository"
@@ -23,12 +24,13 @@ type AuthHandler struct {
 	oidcEncryptionKey string
 	frontendURL       string
 	hub               *websocket.Hub
-	fileStore         *storage.FileStore
+	fileStore         storage.FileStore
 	authLimiter       *authRateLimiter
+	mailer            *mailer.Mailer
 }
 
-func NewAuthHandler(repo *repository.Repo, jwtSecret, oidcEncryptionKey, frontendURL string, hub *websocket.Hub, fileStore *storage.FileStore) *AuthHandler {
-	return &AuthHandler{repo: repo, jwtSecret: jwtSecret, oidcEncryptionKey: oidcEncryptionKey, frontendURL: strings.TrimRight(strings.Split(frontendURL, ",")[0], "/"), hub: hub, fileStore: fileStore, authLimiter: newAuthRateLimiter()}
+func NewAuthHandler(repo *repository.Repo, jwtSecret, oidcEncryptionKey, frontendURL string, hub *websocket.Hub, fileStore storage.FileStore, m *mailer.Mailer) *AuthHandler {
+	return &AuthHandler{repo: repo, jwtSecret: jwtSecret, oidcEncryptionKey: oidcEncryptionKey, frontendURL: strings.TrimRight(strings.Split(frontendURL, ",")[0], "/"), hub: hub, fileStore: fileStore, authLimiter: newAuthRateLimiter(), mailer: m}
 }
 
 func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
@@ -74,7 +76,16 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to create user")
 		return
 	}
-	token, err := h.generateToken(user.ID)
+	if h.mailer.Enabled() {
+		if err := h.sendAccountEmail(r.Context(), user, emailVerificationPurpose); err != nil {
+			log.Printf("email verification error: %v", err)
+		}
+	}
+	if settings.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		writeJSON(w, http.StatusCreated, models.AuthResponse{User: *user, VerificationRequired: true})
+		return
+	}
+	token, err := h.generateToken(r, user.ID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
@@ -116,7 +127,26 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
-	token, err := h.generateToken(user.ID)
+	if settings.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		writeError(w, http.StatusForbidden, "email address is not verified")
+		return
+	}
+	mfa, err := h.repo.GetUserMFA(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if mfa != nil && mfa.EnabledAt != nil {
+		expiresAt := time.Now().Add(mfaTokenTTL)
+		mfaToken, err := h.generateMFAToken(user.ID, expiresAt)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		writeJSON(w, http.StatusOK, models.MFAChallengeResponse{MFARequired: true, MFAToken: mfaToken, ExpiresAt: expiresAt})
+		return
+	}
+	token, err := h.generateToken(r, user.ID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
@@ -126,10 +156,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name: "js_token", Value: "", Path: "/",
-		MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode,
-	})
+	if userID, sessionID := h.currentSession(r); sessionID != "" {
+		if revoked, err := h.repo.RevokeUserSession(r.Context(), userID, sessionID); err != nil {
+			log.Printf("RevokeUserSession error: %v", err)
+		} else if revoked && h.hub != nil {
+			h.hub.DisconnectUser(userID)
+		}
+	}
+	clearTokenCookie(w)
 	writeJSON(w, http.StatusOK, map[string]string{"message": "logged out"})
 }
 
@@ -159,12 +193,21 @@ func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, user)
 }
 
-func (h *AuthHandler) generateToken(userID string) (string, error) {
+// generateToken registers a session for the requesting device and returns a
+// JWT whose "jti" claim names it.
+func (h *AuthHandler) generateToken(r *http.Request, userID string) (string, error) {
+	expiresAt := time.Now().Add(sessionTTL)
+	userAgent := truncateRunes(r.UserAgent(), 512)
+	sessionID, version, err := h.repo.CreateUserSession(r.Context(), userID, deviceLabel(userAgent), userAgent, middleware.ClientIP(r), expiresAt)
+	if err != nil {
+		return "", err
+	}
 	claims := jwt.MapClaims{
 		"sub": userID,
-		"sv":  h.sessionVersion(context.Background(), userID),
+		"sv":  version,
+		"jti": sessionID,
 		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"exp": expiresAt.Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(h.jwtSecret))
@@ -178,9 +221,16 @@ func (h *AuthHandler) sessionVersion(ctx context.Context, userID string) int64 {
 	return version
 }
 
+func clearTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name: "js_token", Value: "", Path: "/",
+		MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode,
+	})
+}
+
 func (h *AuthHandler) setTokenCookie(w http.ResponseWriter, r *http.Request, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name: "js_token", Value: token, Path: "/",
-		MaxAge: 7 * 24 * 60 * 60, HttpOnly: true, Secure: requestIsSecure(r), SameSite: http.SameSiteLaxMode,
+		MaxAge: int(sessionTTL / time.Second), HttpOnly: true, Secure: requestIsSecure(r), SameSite: http.SameSiteLaxMode,
 	})
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -24,6 +25,9 @@ import (
 const maxBrandLogoBytes int64 = 2 * 1024 * 1024
 const maxBrandLogoRequestBytes int64 = maxBrandLogoBytes + 128*1024
 
+// brandingLogoSizes are rendered for every uploaded logo, largest first.
+var brandingLogoSizes = []int{512, 192, 180, 32}
+
 func (h *AuthHandler) PublicBranding(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-store")
 	settings, err := h.repo.GetPlatformSettings(r.Context())
@@ -42,7 +46,7 @@ func (h *AuthHandler) PublicBrandLogo(w http.ResponseWriter, r *http.Request) {
 	}
 	size := brandingLogoSize(chi.URLParam(r, "size"))
 	path := brandingLogoPath(*settings.BrandLogoKey, size)
-	file, err := h.fileStore.Open(path)
+	file, err := h.fileStore.Open(r.Context(), path)
 	if err != nil {
 		writeError(w, http.StatusNotFound, "brand logo not found")
 		return
@@ -139,13 +143,13 @@ func (h *AuthHandler) UploadBrandLogo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	savedPaths := make([]string, 0, 4)
-	for _, size := range []int{512, 192, 180, 32} {
+	for _, size := range brandingLogoSizes {
 		canvas := image.NewRGBA(image.Rect(0, 0, size, size))
 		draw.CatmullRom.Scale(canvas, canvas.Bounds(), source, source.Bounds(), draw.Over, nil)
 		var encoded bytes.Buffer
 		if err := png.Encode(&encoded, canvas); err != nil {
 			for _, saved := range savedPaths {
-				_ = h.fileStore.Delete(saved)
+				_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), saved)
 			}
 			writeError(w, http.StatusInternalServerError, "failed to encode logo")
 			return
@@ -153,7 +157,7 @@ func (h *AuthHandler) UploadBrandLogo(w http.ResponseWriter, r *http.Request) {
 		path := brandingLogoPath(key, size)
 		if err := h.fileStore.Save(r.Context(), path, bytes.NewReader(encoded.Bytes())); err != nil {
 			for _, saved := range savedPaths {
-				_ = h.fileStore.Delete(saved)
+				_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), saved)
 			}
 			writeError(w, http.StatusInternalServerError, "failed to store logo")
 			return
@@ -163,7 +167,7 @@ func (h *AuthHandler) UploadBrandLogo(w http.ResponseWriter, r *http.Request) {
 	previous, err := h.repo.GetPlatformSettings(r.Context())
 	if err != nil {
 		for _, saved := range savedPaths {
-			_ = h.fileStore.Delete(saved)
+			_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), saved)
 		}
 		writeError(w, http.StatusInternalServerError, "failed to load current branding")
 		return
@@ -172,14 +176,14 @@ func (h *AuthHandler) UploadBrandLogo(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("brand logo activation failed: %v", err)
 		for _, saved := range savedPaths {
-			_ = h.fileStore.Delete(saved)
+			_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), saved)
 		}
 		writeError(w, http.StatusInternalServerError, "failed to activate logo")
 		return
 	}
 	if previous.BrandLogoKey != nil {
-		for _, size := range []int{512, 192, 180, 32} {
-			_ = h.fileStore.Delete(brandingLogoPath(*previous.BrandLogoKey, size))
+		for _, size := range brandingLogoSizes {
+			_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), brandingLogoPath(*previous.BrandLogoKey, size))
 		}
 	}
 	h.recordAdminAudit(r, "branding.logo_updated", "platform", "", settings.BrandName, nil)
@@ -206,8 +210,8 @@ func (h *AuthHandler) DeleteBrandLogo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if h.fileStore != nil {
-		for _, size := range []int{512, 192, 180, 32} {
-			_ = h.fileStore.Delete(brandingLogoPath(oldKey, size))
+		for _, size := range brandingLogoSizes {
+			_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), brandingLogoPath(oldKey, size))
 		}
 	}
 	h.recordAdminAudit(r, "branding.logo_removed", "platform", "", settings.BrandName, nil)
@@ -237,6 +241,15 @@ func brandingLogoPath(key string, size int) string {
 	return fmt.Sprintf("branding/%s/logo-%d.png", key, size)
 }
 
+// BrandingLogoPaths returns the storage keys of every rendition of a logo.
+func BrandingLogoPaths(key string) []string {
+	paths := make([]string, 0, len(brandingLogoSizes))
+	for _, size := range brandingLogoSizes {
+		paths = append(paths, brandingLogoPath(key, size))
+	}
+	return paths
+}
+
 func brandingLogoSize(value string) int {
 	size, _ := strconv.Atoi(value)
 	switch size {
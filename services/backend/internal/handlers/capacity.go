@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+const (
+	// capacityDefaultWeeks is the range of capacity requests without dates.
+	capacityDefaultWeeks = 8
+	// capacityAlertWeeks is how far ahead allocation changes are checked for
+	// over-allocation.
+	capacityAlertWeeks   = 26
+	maxAbsenceNoteLength = 255
+)
+
+// Capacity compares each member's weekly capacity with their allocations on
+// all projects of a consulting workspace, by default for the next 8 weeks.
+func (h *CustomerHandler) Capacity(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !h.ensureConsulting(w, r, workspaceID, userID) {
+		return
+	}
+	from, to, message := capacityRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	members, err := h.repo.ListWorkspaceMembers(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list workspace members")
+		return
+	}
+	allocations, err := h.repo.ListWorkspaceAllocations(r.Context(), workspaceID, "", from, to)
+	if err != nil {
+		log.Printf("ListWorkspaceAllocations error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load capacity")
+		return
+	}
+	absences, err := h.repo.ListMemberAbsences(r.Context(), workspaceID, "", from, to)
+	if err != nil {
+		log.Printf("ListMemberAbsences error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load capacity")
+		return
+	}
+	report := models.WorkspaceCapacity{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly), Members: make([]models.MemberCapacity, 0, len(members))}
+	for _, member := range members {
+		report.Members = append(report.Members, memberCapacity(member, allocations, absences, from, to))
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *CustomerHandler) ListAbsences(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !h.ensureConsulting(w, r, workspaceID, userID) {
+		return
+	}
+	params := r.URL.Query()
+	from, to, message := capacityRange(params.Get("from"), params.Get("to"), time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	absences, err := h.repo.ListMemberAbsences(r.Context(), workspaceID, params.Get("userId"), from, to)
+	if err != nil {
+		log.Printf("ListMemberAbsences error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to list absences")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.MemberAbsence]{Total: len(absences), Documents: absences})
+}
+
+// CreateAbsence plans an absence. Members plan their own; owners and admins
+// plan anyone's.
+func (h *CustomerHandler) CreateAbsence(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !h.ensureConsulting(w, r, workspaceID, userID) {
+		return
+	}
+	var req models.CreateMemberAbsenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = userID
+	}
+	if req.UserID != userID && !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	startDate, endDate, message := absenceDates(req.StartDate, req.EndDate)
+	req.Note = strings.TrimSpace(req.Note)
+	if message == "" && utf8.RuneCountInString(req.Note) > maxAbsenceNoteLength {
+		message = "note is too long"
+	}
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	role, err := h.repo.GetWorkspaceRole(r.Context(), workspaceID, req.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate workspace member")
+		return
+	}
+	if role == "" {
+		writeError(w, http.StatusBadRequest, "user is not a workspace member")
+		return
+	}
+	absence, err := h.repo.CreateMemberAbsence(r.Context(), workspaceID, userID, req, startDate, endDate)
+	if err != nil {
+		log.Printf("CreateMemberAbsence error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to create absence")
+		return
+	}
+	h.broadcastWorkspace(workspaceID, models.WSEvent{Type: "create", Collection: "member_absences", Document: absence, UserID: userID})
+	writeJSON(w, http.StatusCreated, absence)
+}
+
+func (h *CustomerHandler) DeleteAbsence(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !h.ensureConsulting(w, r, workspaceID, userID) {
+		return
+	}
+	absence, err := h.repo.GetMemberAbsence(r.Context(), chi.URLParam(r, "absenceId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load absence")
+		return
+	}
+	if absence == nil || absence.WorkspaceID != workspaceID {
+		writeError(w, http.StatusNotFound, "absence not found")
+		return
+	}
+	if absence.UserID != userID && !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	if err := h.repo.DeleteMemberAbsence(r.Context(), absence.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete absence")
+		return
+	}
+	h.broadcastWorkspace(workspaceID, models.WSEvent{Type: "delete", Collection: "member_absences", Document: map[string]string{"id": absence.ID}, UserID: userID})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// overAllocatedWeeks returns the start days of the weeks between from and to
+// in which the member is over-allocated in the project's workspace.
+func (h *CustomerHandler) overAllocatedWeeks(ctx context.Context, projectID, memberID string, from, to time.Time) map[string]bool {
+	weeks := map[string]bool{}
+	if _, capacity := h.projectMemberCapacity(ctx, projectID, memberID, from, to); capacity != nil {
+		for _, week := range capacity.Weeks {
+			if week.OverAllocated {
+				weeks[week.WeekStart] = true
+			}
+		}
+	}
+	return weeks
+}
+
+// notifyOverAllocation tells the owners and admins of the project's workspace
+// when an allocation change left the member over-allocated in weeks that were
+// not over-allocated before. Failures are logged; the change stands.
+func (h *CustomerHandler) notifyOverAllocation(ctx context.Context, projectID, memberID, actorID string, before map[string]bool, from, to time.Time) {
+	workspaceID, capacity := h.projectMemberCapacity(ctx, projectID, memberID, from, to)
+	if capacity == nil {
+		return
+	}
+	weeks := []models.CapacityWeek{}
+	for _, week := range capacity.Weeks {
+		if week.OverAllocated && !before[week.WeekStart] {
+			weeks = append(weeks, week)
+		}
+	}
+	if len(weeks) == 0 {
+		return
+	}
+	members, err := h.repo.ListWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		log.Printf("list over-allocation recipients error: %v", err)
+		return
+	}
+	metadata := map[string]any{"userId": capacity.UserID, "userName": capacity.Name, "weeks": weeks}
+	for _, member := range members {
+		if member.Role != "owner" && member.Role != "admin" {
+			continue
+		}
+		notification, err := h.repo.CreateNotification(ctx, member.UserID, actorID, "over_allocation", projectID, "", nil, metadata)
+		if err != nil {
+			log.Printf("create over-allocation notification error: %v", err)
+			continue
+		}
+		if notification != nil {
+			h.hub.Broadcast(member.UserID, models.WSEvent{Type: "create", Collection: "notifications", Document: notification, UserID: actorID})
+		}
+	}
+}
+
+// projectMemberCapacity loads the member's capacity in the project's
+// workspace. The capacity is nil for workspaces that are not consulting
+// workspaces, for users who are not members, and when loading fails.
+func (h *CustomerHandler) projectMemberCapacity(ctx context.Context, projectID, memberID string, from, to time.Time) (string, *models.MemberCapacity) {
+	workspaceID, err := h.repo.GetProjectWorkspaceID(ctx, projectID)
+	if err != nil || workspaceID == "" {
+		return "", nil
+	}
+	if consulting, err := h.repo.IsConsultingWorkspace(ctx, workspaceID); err != nil || !consulting {
+		return workspaceID, nil
+	}
+	members, err := h.repo.ListWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		log.Printf("load member capacity error: %v", err)
+		return workspaceID, nil
+	}
+	for _, member := range members {
+		if member.UserID != memberID {
+			continue
+		}
+		allocations, err := h.repo.ListWorkspaceAllocations(ctx, workspaceID, memberID, from, to)
+		if err != nil {
+			log.Printf("load member capacity error: %v", err)
+			return workspaceID, nil
+		}
+		absences, err := h.repo.ListMemberAbsences(ctx, workspaceID, memberID, from, to)
+		if err != nil {
+			log.Printf("load member capacity error: %v", err)
+			return workspaceID, nil
+		}
+		capacity := memberCapacity(member, allocations, absences, from, to)
+		return workspaceID, &capacity
+	}
+	return workspaceID, nil
+}
+
+// broadcastWorkspace sends event to every member of the workspace.
+func (h *CustomerHandler) broadcastWorkspace(workspaceID string, event models.WSEvent) {
+	members, err := h.repo.ListWorkspaceMembers(context.Background(), workspaceID)
+	if err != nil {
+		return
+	}
+	userIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	h.hub.BroadcastUsers(userIDs, event)
+}
+
+// memberCapacity builds the report of member from the allocations and
+// absences of the workspace, which may include other members'.
+func memberCapacity(member models.WorkspaceMember, allocations []models.ProjectMemberAllocation, absences []models.MemberAbsence, from, to time.Time) models.MemberCapacity {
+	capacity := models.MemberCapacity{
+		UserID:             member.UserID,
+		Name:               member.Name,
+		WeeklyCapacityDays: member.WeeklyCapacityDays,
+		Allocations:        []models.ProjectMemberAllocation{},
+		Absences:           []models.MemberAbsence{},
+	}
+	for _, allocation := range allocations {
+		if allocation.UserID == member.UserID {
+			capacity.Allocations = append(capacity.Allocations, allocation)
+		}
+	}
+	for _, absence := range absences {
+		if absence.UserID == member.UserID {
+			capacity.Absences = append(capacity.Absences, absence)
+		}
+	}
+	capacity.Weeks = capacityWeeks(member.WeeklyCapacityDays, capacity.Allocations, capacity.Absences, from, to)
+	for _, week := range capacity.Weeks {
+		capacity.OverAllocated = capacity.OverAllocated || week.OverAllocated
+	}
+	return capacity
+}
+
+// capacityWeeks compares capacity with allocations for each week from the one
+// from falls in through the one to falls in. Only weekdays count: each adds a
+// fifth of the weekly capacity unless the member is absent, and a fifth of the
+// days per week of every allocation active on it.
+func capacityWeeks(weeklyCapacity float64, allocations []models.ProjectMemberAllocation, absences []models.MemberAbsence, from, to time.Time) []models.CapacityWeek {
+	weeks := []models.CapacityWeek{}
+	for start := weekStart(from); !start.After(to); start = start.AddDate(0, 0, 7) {
+		week := models.CapacityWeek{WeekStart: start.Format(time.DateOnly)}
+		for i := range 5 {
+			day := start.AddDate(0, 0, i)
+			if absentOn(absences, day) {
+				week.AbsenceDays++
+			} else {
+				week.CapacityDays += weeklyCapacity / 5
+			}
+			for _, allocation := range allocations {
+				if (allocation.StartDate == nil || !day.Before(*allocation.StartDate)) && (allocation.EndDate == nil || !day.After(*allocation.EndDate)) {
+					week.AllocatedDays += allocation.DaysPerWeek / 5
+				}
+			}
+		}
+		week.CapacityDays = math.Round(week.CapacityDays*100) / 100
+		week.AllocatedDays = math.Round(week.AllocatedDays*100) / 100
+		week.OverAllocated = week.AllocatedDays > week.CapacityDays
+		weeks = append(weeks, week)
+	}
+	return weeks
+}
+
+func absentOn(absences []models.MemberAbsence, day time.Time) bool {
+	for _, absence := range absences {
+		if !day.Before(absence.StartDate) && !day.After(absence.EndDate) {
+			return true
+		}
+	}
+	return false
+}
+
+// absenceDates parses the days of an absence, which spans at most
+// maxRangeDays days.
+func absenceDates(start, end string) (time.Time, time.Time, string) {
+	startDate, endDate, message := dateSpan(start, end)
+	if message == "" && endDate.Sub(startDate) >= maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, "absence must not exceed 366 days"
+	}
+	return startDate, endDate, message
+}
+
+// weekStart returns the Monday (UTC) of the week t falls in.
+func weekStart(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// capacityRange parses the from and to days of a capacity request and widens
+// them to whole weeks. from defaults to the current week and to to
+// capacityDefaultWeeks weeks after from.
+func capacityRange(fromValue, toValue string, now time.Time) (time.Time, time.Time, string) {
+	if fromValue == "" {
+		fromValue = weekStart(now).Format(time.DateOnly)
+	}
+	if toValue == "" {
+		if start, err := time.Parse(time.DateOnly, fromValue); err == nil {
+			toValue = weekStart(start).AddDate(0, 0, 7*capacityDefaultWeeks-1).Format(time.DateOnly)
+		}
+	}
+	from, to, message := dayRange(fromValue, toValue, 0, now)
+	if message != "" {
+		return time.Time{}, time.Time{}, message
+	}
+	return weekStart(from), weekStart(to).AddDate(0, 0, 6), ""
+}
+
+// allocationDates validates the optional dates of an allocation update. A
+// missing date keeps the stored one and an empty date clears it, so each
+// result is nil, empty, or a date. A reversed range is only caught here when
+// both dates are given; the table's check covers a stored counterpart.
+func allocationDates(start, end *string) (*string, *string, string) {
+	parse := func(value *string, field string) (*string, *time.Time, string) {
+		if value == nil {
+			return nil, nil, ""
+		}
+		trimmed := strings.TrimSpace(*value)
+		if trimmed == "" {
+			return &trimmed, nil, ""
+		}
+		parsed, err := time.Parse(time.DateOnly, trimmed)
+		if err != nil {
+			return nil, nil, field + " must be a date such as 2026-10-20"
+		}
+		return &trimmed, &parsed, ""
+	}
+	startValue, startDate, message := parse(start, "startDate")
+	if message != "" {
+		return nil, nil, message
+	}
+	endValue, endDate, message := parse(end, "endDate")
+	if message != "" {
+		return nil, nil, message
+	}
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, nil, "endDate must not be before startDate"
+	}
+	return startValue, endValue, ""
+}
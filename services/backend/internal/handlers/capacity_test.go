@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+func TestCapacityWeeks(t *testing.T) {
+	date := func(value string) *time.Time {
+		parsed, _ := time.Parse(time.DateOnly, value)
+		return &parsed
+	}
+	// 2026-10-19 and 2026-10-26 are Mondays.
+	from, to := *date("2026-10-21"), *date("2026-10-27")
+	tests := []struct {
+		name        string
+		capacity    float64
+		allocations []models.ProjectMemberAllocation
+		absences    []models.MemberAbsence
+		want        []models.CapacityWeek
+	}{
+		{
+			name:     "no allocations",
+			capacity: 5,
+			want: []models.CapacityWeek{
+				{WeekStart: "2026-10-19", CapacityDays: 5},
+				{WeekStart: "2026-10-26", CapacityDays: 5},
+			},
+		},
+		{
+			name:     "allocations across projects add up",
+			capacity: 4,
+			allocations: []models.ProjectMemberAllocation{
+				{ProjectID: "a", DaysPerWeek: 3},
+				{ProjectID: "b", DaysPerWeek: 1.5},
+			},
+			want: []models.CapacityWeek{
+				{WeekStart: "2026-10-19", CapacityDays: 4, AllocatedDays: 4.5, OverAllocated: true},
+				{WeekStart: "2026-10-26", CapacityDays: 4, AllocatedDays: 4.5, OverAllocated: true},
+			},
+		},
+		{
+			name:     "allocation bounded by dates",
+			capacity: 5,
+			allocations: []models.ProjectMemberAllocation{
+				{ProjectID: "a", DaysPerWeek: 5},
+				{ProjectID: "b", DaysPerWeek: 5, StartDate: date("2026-10-29"), EndDate: date("2026-10-30")},
+			},
+			want: []models.CapacityWeek{
+				{WeekStart: "2026-10-19", CapacityDays: 5, AllocatedDays: 5},
+				{WeekStart: "2026-10-26", CapacityDays: 5, AllocatedDays: 7, OverAllocated: true},
+			},
+		},
+		{
+			name:        "absence reduces capacity but not allocations",
+			capacity:    4,
+			allocations: []models.ProjectMemberAllocation{{ProjectID: "a", DaysPerWeek: 3.2}},
+			absences:    []models.MemberAbsence{{StartDate: *date("2026-10-23"), EndDate: *date("2026-10-26")}},
+			want: []models.CapacityWeek{
+				{WeekStart: "2026-10-19", CapacityDays: 3.2, AllocatedDays: 3.2, AbsenceDays: 1},
+				{WeekStart: "2026-10-26", CapacityDays: 3.2, AllocatedDays: 3.2, AbsenceDays: 1},
+			},
+		},
+		{
+			name:        "weekend absence is free",
+			capacity:    5,
+			allocations: []models.ProjectMemberAllocation{{ProjectID: "a", DaysPerWeek: 5}},
+			absences:    []models.MemberAbsence{{StartDate: *date("2026-10-24"), EndDate: *date("2026-10-25")}},
+			want: []models.CapacityWeek{
+				{WeekStart: "2026-10-19", CapacityDays: 5, AllocatedDays: 5},
+				{WeekStart: "2026-10-26", CapacityDays: 5, AllocatedDays: 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := capacityWeeks(tt.capacity, tt.allocations, tt.absences, from, to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("capacityWeeks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapacityRange(t *testing.T) {
+	// 2026-10-17 is a Saturday.
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		from, to         string
+		wantFrom, wantTo string
+		wantMessage      string
+	}{
+		{name: "defaults", wantFrom: "2026-10-12", wantTo: "2026-12-06"},
+		{name: "from only", from: "2026-11-04", wantFrom: "2026-11-02", wantTo: "2026-12-27"},
+		{name: "widened to weeks", from: "2026-10-21", to: "2026-10-28", wantFrom: "2026-10-19", wantTo: "2026-11-01"},
+		{name: "bad from", from: "soon", wantMessage: "from must be a date such as 2026-10-20"},
+		{name: "reversed", from: "2026-11-04", to: "2026-11-01", wantMessage: "to must not be before from"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, message := capacityRange(tt.from, tt.to, now)
+			if message != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", message, tt.wantMessage)
+			}
+			if message == "" && (from.Format(time.DateOnly) != tt.wantFrom || to.Format(time.DateOnly) != tt.wantTo) {
+				t.Fatalf("range = %s to %s, want %s to %s", from.Format(time.DateOnly), to.Format(time.DateOnly), tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestAllocationDates(t *testing.T) {
+	text := func(value string) *string { return &value }
+	tests := []struct {
+		name        string
+		start, end  *string
+		wantStart   string
+		wantEnd     string
+		wantMessage string
+	}{
+		{name: "omitted dates are kept", wantStart: "keep", wantEnd: "keep"},
+		{name: "empty strings clear", start: text(""), end: text(" "), wantStart: "", wantEnd: ""},
+		{name: "bounded", start: text("2026-11-02"), end: text("2026-12-18"), wantStart: "2026-11-02", wantEnd: "2026-12-18"},
+		{name: "end only", end: text("2026-12-18"), wantStart: "keep", wantEnd: "2026-12-18"},
+		{name: "bad end", end: text("next month"), wantMessage: "endDate must be a date such as 2026-10-20"},
+		{name: "reversed", start: text("2026-12-18"), end: text("2026-11-02"), wantMessage: "endDate must not be before startDate"},
+	}
+	format := func(value *string) string {
+		if value == nil {
+			return "keep"
+		}
+		return *value
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, message := allocationDates(tt.start, tt.end)
+			if message != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", message, tt.wantMessage)
+			}
+			if message == "" && (format(start) != tt.wantStart || format(end) != tt.wantEnd) {
+				t.Fatalf("dates = %q, %q, want %q, %q", format(start), format(end), tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestAbsenceDates(t *testing.T) {
+	tests := []struct {
+		name        string
+		start, end  string
+		wantMessage string
+	}{
+		{name: "single day", start: "2026-12-24", end: "2026-12-24"},
+		{name: "366 days", start: "2026-01-01", end: "2027-01-01"},
+		{name: "longer than 366 days", start: "2026-01-01", end: "2027-01-02", wantMessage: "absence must not exceed 366 days"},
+		{name: "ends before start", start: "2026-12-24", end: "2026-12-23", wantMessage: "endDate must not be before startDate"},
+		{name: "missing start", end: "2026-12-24", wantMessage: "startDate must be a date such as 2026-10-20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, message := absenceDates(tt.start, tt.end)
+			if message != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", message, tt.wantMessage)
+			}
+			if message == "" && (start.Format(time.DateOnly) != tt.start || end.Format(time.DateOnly) != tt.end) {
+				t.Fatalf("dates = %s, %s", start, end)
+			}
+		})
+	}
+}
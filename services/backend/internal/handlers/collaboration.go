@@ -9,29 +9,49 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/mailer"
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
 	"github.com/justlabv1/justspace/backend/internal/repository"
 	"github.com/justlabv1/justspace/backend/internal/storage"
+	"github.com/justlabv1/justspace/backend/internal/thumbnails"
+	"github.com/justlabv1/justspace/backend/internal/webhooks"
 	"github.com/justlabv1/justspace/backend/internal/websocket"
 )
 
 type CollaborationHandler struct {
-	repo           *repository.Repo
-	hub            *websocket.Hub
-	fileStore      *storage.FileStore
-	maxUploadBytes int64
+	repo      *repository.Repo
+	hub       *websocket.Hub
+	fileStore storage.FileStore
+	limits    UploadLimits
+	webhooks  *webhooks.Dispatcher
+	mailer    *mailer.Mailer
 }
 
-func NewCollaborationHandler(repo *repository.Repo, hub *websocket.Hub, fileStore *storage.FileStore, maxUploadBytes int64) *CollaborationHandler {
-	return &CollaborationHandler{repo: repo, hub: hub, fileStore: fileStore, maxUploadBytes: maxUploadBytes}
+// UploadLimits bounds file uploads. MaxUploadBytes applies to single-request
+// uploads and to each chunk of a resumable upload, MaxResumableUploadBytes to
+// the assembled file. A zero ProjectQuotaBytes disables the project quota.
+type UploadLimits struct {
+	MaxUploadBytes          int64
+	MaxResumableUploadBytes int64
+	ProjectQuotaBytes       int64
+}
+
+// presignedDownloadTTL bounds how long a redirect link to the object store
+// stays usable after the access check.
+const presignedDownloadTTL = 5 * time.Minute
+
+func NewCollaborationHandler(repo *repository.Repo, hub *websocket.Hub, fileStore storage.FileStore, limits UploadLimits, dispatcher *webhooks.Dispatcher, m *mailer.Mailer) *CollaborationHandler {
+	return &CollaborationHandler{repo: repo, hub: hub, fileStore: fileStore, limits: limits, webhooks: dispatcher, mailer: m}
 }
 
 func (h *CollaborationHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
@@ -250,6 +270,12 @@ func (h *CollaborationHandler) CreateInvitation(w http.ResponseWriter, r *http.R
 		}
 	}
 
+	// Encrypted project names are envelopes and stay out of the email.
+	projectName := project.Name
+	if project.IsEncrypted {
+		projectName = ""
+	}
+	deliverInvitationEmail(r.Context(), h.repo, h.mailer, invite.Email, userID, "project", projectName, invite.Role, token, invite.ExpiresAt)
 	h.broadcastProject(projectID, models.WSEvent{Type: "create", Collection: "team_invitations", Document: invite, UserID: userID})
 	writeJSON(w, http.StatusCreated, invite)
 }
@@ -364,8 +390,13 @@ func (h *CollaborationHandler) UploadProjectFile(w http.ResponseWriter, r *http.
 	if !ensureProjectRole(w, r, h.repo, projectID, userID, "owner", "admin", "editor") {
 		return
 	}
-	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
-	if err := r.ParseMultipartForm(h.maxUploadBytes); err != nil {
+	// Content-Length overstates the file by the multipart framing, but checking
+	// it avoids receiving a body that cannot be stored.
+	if !h.ensureStorageQuota(w, r, projectID, r.ContentLength) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxUploadBytes)
+	if err := r.ParseMultipartForm(h.limits.MaxUploadBytes); err != nil {
 		writeError(w, http.StatusBadRequest, "failed to parse upload")
 		return
 	}
@@ -376,6 +407,9 @@ func (h *CollaborationHandler) UploadProjectFile(w http.ResponseWriter, r *http.
 		return
 	}
 	defer file.Close()
+	if r.ContentLength < 0 && !h.ensureStorageQuota(w, r, projectID, header.Size) {
+		return
+	}
 
 	req := models.CreateProjectFileRequest{
 		ProjectID:     projectID,
@@ -393,28 +427,24 @@ func (h *CollaborationHandler) UploadProjectFile(w http.ResponseWriter, r *http.
 		req.ContentType = "application/octet-stream"
 	}
 
-	uploadID, err := randomToken()
+	storagePath, err := projectFileStoragePath(projectID, "")
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to allocate file id")
 		return
 	}
-	storagePath := fmt.Sprintf("%s/%s-%s.bin", projectID, time.Now().UTC().Format("20060102150405"), uploadID)
-	if err := h.fileStore.Save(r.Context(), storagePath, limitFileReader(file, header.Size)); err != nil {
+	checksum, size, err := storage.SaveWithChecksum(r.Context(), h.fileStore, storagePath, limitFileReader(file, header.Size))
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to store file")
 		return
 	}
 
-	projectFile, err := h.repo.CreateProjectFile(r.Context(), userID, req, header.Size, storagePath)
+	projectFile, err := h.recordProjectFile(r.Context(), userID, req, size, storagePath, checksum)
 	if err != nil {
-		_ = h.fileStore.Delete(storagePath)
 		writeError(w, http.StatusInternalServerError, "failed to save file metadata")
 		return
 	}
-
-	if _, err := h.repo.LogActivity(r.Context(), userID, "create", "File", req.EncryptedName, &projectID, nil, nil); err == nil {
-		h.broadcastProjectActivity(projectID, userID)
-	}
-	h.broadcastProject(projectID, models.WSEvent{Type: "create", Collection: "project_files", Document: projectFile, UserID: userID})
+	h.queueThumbnail(r.Context(), projectFile)
+	h.announceFileUpload(r.Context(), userID, projectFile)
 	writeJSON(w, http.StatusCreated, projectFile)
 }
 
@@ -430,6 +460,9 @@ func (h *CollaborationHandler) ListTaskFiles(w http.ResponseWriter, r *http.Requ
 		writeError(w, http.StatusNotFound, "task not found")
 		return
 	}
+	if !ensureTokenProject(w, r, h.repo, task.ProjectID) {
+		return
+	}
 	files, err := h.repo.ListTaskFiles(r.Context(), taskID, userID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to list task files")
@@ -453,8 +486,11 @@ func (h *CollaborationHandler) UploadTaskFile(w http.ResponseWriter, r *http.Req
 	if !ensureProjectRole(w, r, h.repo, task.ProjectID, userID, "owner", "admin", "editor") {
 		return
 	}
-	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
-	if err := r.ParseMultipartForm(h.maxUploadBytes); err != nil {
+	if !h.ensureStorageQuota(w, r, task.ProjectID, r.ContentLength) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxUploadBytes)
+	if err := r.ParseMultipartForm(h.limits.MaxUploadBytes); err != nil {
 		writeError(w, http.StatusBadRequest, "failed to parse upload")
 		return
 	}
@@ -465,6 +501,9 @@ func (h *CollaborationHandler) UploadTaskFile(w http.ResponseWriter, r *http.Req
 		return
 	}
 	defer file.Close()
+	if r.ContentLength < 0 && !h.ensureStorageQuota(w, r, task.ProjectID, header.Size) {
+		return
+	}
 
 	req := models.CreateProjectFileRequest{
 		ProjectID:     task.ProjectID,
@@ -482,28 +521,24 @@ func (h *CollaborationHandler) UploadTaskFile(w http.ResponseWriter, r *http.Req
 		req.ContentType = "application/octet-stream"
 	}
 
-	uploadID, err := randomToken()
+	storagePath, err := projectFileStoragePath(task.ProjectID, taskID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to allocate file id")
 		return
 	}
-	storagePath := fmt.Sprintf("%s/tasks/%s/%s-%s.bin", task.ProjectID, taskID, time.Now().UTC().Format("20060102150405"), uploadID)
-	if err := h.fileStore.Save(r.Context(), storagePath, limitFileReader(file, header.Size)); err != nil {
+	checksum, size, err := storage.SaveWithChecksum(r.Context(), h.fileStore, storagePath, limitFileReader(file, header.Size))
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to store file")
 		return
 	}
 
-	taskFile, err := h.repo.CreateProjectFile(r.Context(), userID, req, header.Size, storagePath)
+	taskFile, err := h.recordProjectFile(r.Context(), userID, req, size, storagePath, checksum)
 	if err != nil {
-		_ = h.fileStore.Delete(storagePath)
 		writeError(w, http.StatusInternalServerError, "failed to save file metadata")
 		return
 	}
-
-	if _, err := h.repo.LogActivity(r.Context(), userID, "create", "File", req.EncryptedName, &task.ProjectID, &task.ID, nil); err == nil {
-		h.broadcastProjectActivity(task.ProjectID, userID)
-	}
-	h.broadcastProject(task.ProjectID, models.WSEvent{Type: "create", Collection: "project_files", Document: taskFile, UserID: userID})
+	h.queueThumbnail(r.Context(), taskFile)
+	h.announceFileUpload(r.Context(), userID, taskFile)
 	writeJSON(w, http.StatusCreated, taskFile)
 }
 
@@ -562,7 +597,7 @@ func (h *CollaborationHandler) AddTaskAssignee(w http.ResponseWriter, r *http.Re
 	}
 	h.broadcastProject(task.ProjectID, models.WSEvent{Type: "create", Collection: "task_assignees", Document: assignee, UserID: userID})
 	if !alreadyAssigned {
-		if notification, err := h.repo.CreateNotification(r.Context(), req.UserID, userID, "task_assigned", task.ProjectID, task.ID, nil); err != nil {
+		if notification, err := h.repo.CreateNotification(r.Context(), req.UserID, userID, "task_assigned", task.ProjectID, task.ID, nil, nil); err != nil {
 			log.Printf("create assignment notification error: %v", err)
 		} else if notification != nil {
 			h.hub.Broadcast(req.UserID, models.WSEvent{Type: "create", Collection: "notifications", Document: notification, UserID: userID})
@@ -653,7 +688,7 @@ func (h *CollaborationHandler) CreateTaskComment(w http.ResponseWriter, r *http.
 	}
 	h.broadcastProject(task.ProjectID, models.WSEvent{Type: "create", Collection: "task_comments", Document: comment, UserID: userID})
 	for mentionedUserID := range seenMentions {
-		notification, err := h.repo.CreateNotification(r.Context(), mentionedUserID, userID, "mention", task.ProjectID, task.ID, &comment.ID)
+		notification, err := h.repo.CreateNotification(r.Context(), mentionedUserID, userID, "mention", task.ProjectID, task.ID, &comment.ID, nil)
 		if err != nil {
 			log.Printf("create mention notification error: %v", err)
 			continue
@@ -756,7 +791,32 @@ func (h *CollaborationHandler) DownloadProjectFile(w http.ResponseWriter, r *htt
 		writeError(w, http.StatusNotFound, "file not found")
 		return
 	}
-	reader, err := h.fileStore.Open(projectFile.StoragePath)
+	if !ensureTokenProject(w, r, h.repo, projectFile.ProjectID) {
+		return
+	}
+	// The checksum identifies the stored bytes, so it is a strong validator
+	// for every file recorded since checksums were introduced.
+	etag := ""
+	if projectFile.ChecksumSHA256 != nil {
+		etag = `"` + *projectFile.ChecksumSHA256 + `"`
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	disposition := contentDisposition(projectFile)
+	if presigner, ok := h.fileStore.(storage.Presigner); ok {
+		link, err := presigner.PresignGet(projectFile.StoragePath, projectFile.ContentType, disposition, presignedDownloadTTL)
+		if err != nil {
+			log.Printf("DownloadProjectFile presign error: %v", err)
+		} else if link != "" {
+			w.Header().Set("Cache-Control", "no-store")
+			http.Redirect(w, r, link, http.StatusFound)
+			return
+		}
+	}
+	reader, err := storage.OpenReadSeeker(r.Context(), h.fileStore, projectFile.StoragePath, projectFile.SizeBytes)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to open file")
 		return
@@ -764,8 +824,65 @@ func (h *CollaborationHandler) DownloadProjectFile(w http.ResponseWriter, r *htt
 	defer reader.Close()
 
 	w.Header().Set("Content-Type", projectFile.ContentType)
-	w.Header().Set("Content-Length", strconv.FormatInt(projectFile.SizeBytes, 10))
-	io.Copy(w, reader)
+	if disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	// ServeContent handles Range, If-Range, and the conditional headers. Files
+	// never change after upload, so the upload time is the modification time.
+	http.ServeContent(w, r, "", projectFile.CreatedAt, reader)
+}
+
+// ProjectFileThumbnail serves a rendered preview of an image file. ?size=
+// picks the smallest rendition covering that many pixels.
+func (h *CollaborationHandler) ProjectFileThumbnail(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	fileID := chi.URLParam(r, "fileId")
+	projectFile, err := h.repo.GetProjectFile(r.Context(), fileID, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load file")
+		return
+	}
+	if projectFile == nil {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	if !ensureTokenProject(w, r, h.repo, projectFile.ProjectID) {
+		return
+	}
+	if projectFile.ThumbnailContentType == nil {
+		writeError(w, http.StatusNotFound, "thumbnail not available")
+		return
+	}
+	requested, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	size := thumbnails.Size(requested)
+	etag := ""
+	if projectFile.ChecksumSHA256 != nil {
+		etag = fmt.Sprintf(`"%s-%d"`, *projectFile.ChecksumSHA256, size)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	reader, err := h.fileStore.Open(r.Context(), thumbnails.Path(projectFile.StoragePath, size))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to open thumbnail")
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", *projectFile.ThumbnailContentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		log.Printf("ProjectFileThumbnail write error: %v", err)
+	}
 }
 
 func (h *CollaborationHandler) DeleteProjectFile(w http.ResponseWriter, r *http.Request) {
@@ -788,9 +905,7 @@ func (h *CollaborationHandler) DeleteProjectFile(w http.ResponseWriter, r *http.
 		writeError(w, http.StatusInternalServerError, "failed to delete file metadata")
 		return
 	}
-	if err := h.fileStore.Delete(projectFile.StoragePath); err != nil {
-		log.Printf("DeleteProjectFile storage cleanup error: %v", err)
-	}
+	h.releaseBlob(context.WithoutCancel(r.Context()), projectFile.StoragePath)
 
 	if _, err := h.repo.LogActivity(r.Context(), userID, "delete", "File", projectFile.EncryptedName, &projectFile.ProjectID, projectFile.TaskID, nil); err == nil {
 		h.broadcastProjectActivity(projectFile.ProjectID, userID)
@@ -953,6 +1068,7 @@ func (h *CollaborationHandler) broadcastProject(projectID string, event models.W
 		return
 	}
 	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(projectID, event)
 }
 
 func (h *CollaborationHandler) broadcastProjectActivity(projectID, actorUserID string) {
@@ -991,6 +1107,194 @@ func randomToken() (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
+// projectFileStoragePath allocates a fresh store key for a project or task file.
+func projectFileStoragePath(projectID, taskID string) (string, error) {
+	uploadID, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s.bin", time.Now().UTC().Format("20060102150405"), uploadID)
+	if taskID != "" {
+		return fmt.Sprintf("%s/tasks/%s/%s", projectID, taskID, name), nil
+	}
+	return fmt.Sprintf("%s/%s", projectID, name), nil
+}
+
+// ensureStorageQuota rejects an upload of size bytes that would take the
+// project past PROJECT_STORAGE_QUOTA_BYTES or its workspace past the workspace
+// quota. Unknown sizes pass; they are still bounded by the upload limits.
+func (h *CollaborationHandler) ensureStorageQuota(w http.ResponseWriter, r *http.Request, projectID string, size int64) bool {
+	if size <= 0 {
+		return true
+	}
+	if h.limits.ProjectQuotaBytes > 0 {
+		used, err := h.repo.ProjectStorageUsage(r.Context(), projectID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to check storage quota")
+			return false
+		}
+		if used+size > h.limits.ProjectQuotaBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, "project storage quota exceeded")
+			return false
+		}
+	}
+	workspaceID, err := h.repo.GetProjectWorkspaceID(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check storage quota")
+		return false
+	}
+	if workspaceID == "" {
+		return true
+	}
+	usage, err := h.repo.GetWorkspaceStorageUsage(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check storage quota")
+		return false
+	}
+	if usage != nil && usage.QuotaBytes > 0 && usage.UsedBytes+usage.PendingUploadBytes+size > usage.QuotaBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "workspace storage quota exceeded")
+		return false
+	}
+	return true
+}
+
+// announceFileUpload records the activity for a new file and notifies project
+// members and webhooks.
+func (h *CollaborationHandler) announceFileUpload(ctx context.Context, userID string, file *models.ProjectFile) {
+	if _, err := h.repo.LogActivity(ctx, userID, "create", "File", file.EncryptedName, &file.ProjectID, file.TaskID, nil); err == nil {
+		h.broadcastProjectActivity(file.ProjectID, userID)
+	}
+	h.broadcastProject(file.ProjectID, models.WSEvent{Type: "create", Collection: "project_files", Document: file, UserID: userID})
+}
+
+// contentStoragePath is the store key of a plaintext blob with checksum.
+func contentStoragePath(checksum string) string {
+	return fmt.Sprintf("blobs/sha256/%s/%s", checksum[:2], checksum)
+}
+
+// recordProjectFile records an upload written to uploadPath. Plaintext content
+// is copied to its checksum key unless an identical blob already exists;
+// either way the upload at uploadPath is deleted afterwards. Encrypted uploads
+// stay where they are.
+func (h *CollaborationHandler) recordProjectFile(ctx context.Context, userID string, req models.CreateProjectFileRequest, size int64, uploadPath, checksum string) (*models.ProjectFile, error) {
+	storagePath := uploadPath
+	var place func() error
+	if !req.IsEncrypted {
+		storagePath = contentStoragePath(checksum)
+		place = func() error {
+			reader, err := h.fileStore.Open(ctx, uploadPath)
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+			return h.fileStore.Save(ctx, storagePath, reader)
+		}
+	}
+	file, err := h.repo.CreateProjectFile(ctx, userID, req, size, storagePath, checksum, place)
+	if err != nil || file.StoragePath != uploadPath {
+		if err := h.fileStore.Delete(context.WithoutCancel(ctx), uploadPath); err != nil {
+			log.Printf("upload cleanup error: %v", err)
+		}
+	}
+	return file, err
+}
+
+// releaseBlob deletes a stored blob once the last file referring to it is gone.
+func (h *CollaborationHandler) releaseBlob(ctx context.Context, storagePath string) {
+	err := h.repo.ReleaseFileBlob(ctx, storagePath, func(blob models.StoredFile) error {
+		if err := h.fileStore.Delete(ctx, blob.StoragePath); err != nil {
+			return err
+		}
+		if blob.HasThumbnails {
+			if err := thumbnails.Delete(ctx, h.fileStore, blob.StoragePath); err != nil {
+				log.Printf("file thumbnail cleanup error: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("release file blob error: %v", err)
+	}
+}
+
+// queueThumbnail asks for thumbnails of plaintext images. Failing to queue
+// only costs the preview, so the upload still succeeds.
+func (h *CollaborationHandler) queueThumbnail(ctx context.Context, file *models.ProjectFile) {
+	if file.IsEncrypted || !thumbnails.Supported(file.ContentType) || file.SizeBytes > thumbnails.MaxSourceBytes {
+		return
+	}
+	if err := h.repo.QueueFileThumbnail(ctx, file); err != nil {
+		log.Printf("queue file thumbnail error: %v", err)
+	}
+}
+
+// contentDisposition names non-encrypted downloads after the original file.
+// Types browsers render safely are served inline so media can be previewed and
+// seeked; everything else, including HTML and SVG, is downloaded. Encrypted
+// files keep no header because their stored name is ciphertext.
+func contentDisposition(file *models.ProjectFile) string {
+	if file.IsEncrypted {
+		return ""
+	}
+	disposition := "attachment"
+	if inlineContentType(file.ContentType) {
+		disposition = "inline"
+	}
+	name := downloadFilename(file.EncryptedName)
+	if name == "" {
+		return disposition
+	}
+	// FormatMediaType switches to RFC 2231 encoding for non-ASCII names.
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": name}); value != "" {
+		return value
+	}
+	return disposition
+}
+
+func inlineContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return true
+	}
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp", "image/avif", "application/pdf", "text/plain":
+		return true
+	}
+	return false
+}
+
+// downloadFilename strips directories and control characters from a
+// client-supplied file name.
+func downloadFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return truncateRunes(name, 255)
+}
+
+// etagMatches implements the weak comparison If-None-Match requires.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func limitFileReader(file multipart.File, size int64) io.Reader {
 	if size <= 0 {
 		return file
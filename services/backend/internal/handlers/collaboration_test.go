@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := `"3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: etag, want: true},
+		{header: "W/" + etag, want: true},
+		{header: `"other", ` + etag, want: true},
+		{header: "*", want: true},
+		{header: `"other"`, want: false},
+		{header: etag[:len(etag)-1], want: false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name string
+		file models.ProjectFile
+		want string
+	}{
+		{name: "encrypted", file: models.ProjectFile{IsEncrypted: true, EncryptedName: "b64ciphertext", ContentType: "video/mp4"}, want: ""},
+		{name: "video plays inline", file: models.ProjectFile{EncryptedName: "demo.mp4", ContentType: "video/mp4"}, want: `inline; filename=demo.mp4`},
+		{name: "html is downloaded", file: models.ProjectFile{EncryptedName: "page.html", ContentType: "text/html; charset=utf-8"}, want: `attachment; filename=page.html`},
+		{name: "svg is downloaded", file: models.ProjectFile{EncryptedName: "logo.svg", ContentType: "image/svg+xml"}, want: `attachment; filename=logo.svg`},
+		{name: "path and quotes", file: models.ProjectFile{EncryptedName: `..\\dir/My "report".pdf`, ContentType: "application/pdf"}, want: `inline; filename="My \"report\".pdf"`},
+		{name: "non-ASCII name", file: models.ProjectFile{EncryptedName: "Übersicht.iso", ContentType: "application/octet-stream"}, want: `attachment; filename*=utf-8''%C3%9Cbersicht.iso`},
+		{name: "control characters only", file: models.ProjectFile{EncryptedName: "\x00\n", ContentType: "text/plain"}, want: "inline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contentDisposition(&tt.file); got != tt.want {
+				t.Fatalf("contentDisposition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentStoragePath(t *testing.T) {
+	checksum := "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"
+	if got, want := contentStoragePath(checksum), "blobs/sha256/3a/"+checksum; got != want {
+		t.Fatalf("contentStoragePath() = %q, want %q", got, want)
+	}
+}
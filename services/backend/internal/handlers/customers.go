@@ -3,16 +3,23 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
 	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
 )
 
-type CustomerHandler struct{ repo *repository.Repo }
+type CustomerHandler struct {
+	repo *repository.Repo
+	hub  *websocket.Hub
+}
 
-func NewCustomerHandler(repo *repository.Repo) *CustomerHandler { return &CustomerHandler{repo: repo} }
+func NewCustomerHandler(repo *repository.Repo, hub *websocket.Hub) *CustomerHandler {
+	return &CustomerHandler{repo: repo, hub: hub}
+}
 
 func (h *CustomerHandler) ensureConsulting(w http.ResponseWriter, r *http.Request, workspaceID, userID string, roles ...string) bool {
 	if len(roles) > 0 && !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, roles...) {
@@ -101,10 +108,20 @@ func (h *CustomerHandler) UpsertAllocation(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusBadRequest, "a non-negative allocation is required")
 		return
 	}
-	allocation, err := h.repo.UpsertProjectAllocation(r.Context(), projectID, chi.URLParam(r, "userId"), req.DaysPerWeek)
+	startDate, endDate, message := allocationDates(req.StartDate, req.EndDate)
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	memberID := chi.URLParam(r, "userId")
+	from := weekStart(time.Now())
+	to := from.AddDate(0, 0, 7*capacityAlertWeeks-1)
+	before := h.overAllocatedWeeks(r.Context(), projectID, memberID, from, to)
+	allocation, err := h.repo.UpsertProjectAllocation(r.Context(), projectID, memberID, req.DaysPerWeek, startDate, endDate)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "failed to update allocation")
 		return
 	}
+	h.notifyOverAllocation(r.Context(), projectID, memberID, userID, before, from, to)
 	writeJSON(w, http.StatusOK, allocation)
 }
@@ -1,9 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/justlabv1/justspace/backend/internal/mailer"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
 	"github.com/justlabv1/justspace/backend/internal/repository"
 )
@@ -20,6 +30,136 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// reportFormat reads the format parameter of an exportable report: json
+// (default), csv, or excel, a CSV variant that spreadsheet apps open with the
+// right encoding.
+func reportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		return "json", true
+	case "json", "csv", "excel":
+		return format, true
+	}
+	writeError(w, http.StatusBadRequest, "format must be json, csv, or excel")
+	return "", false
+}
+
+// writeCSV sends records as a CSV download. The excel variant starts with a
+// byte order mark and ends lines with CRLF.
+func writeCSV(w http.ResponseWriter, filename string, excel bool, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+	if excel {
+		w.Write([]byte("\ufeff"))
+	}
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = excel
+	writer.WriteAll(records)
+}
+
+// csvText keeps a user-provided value from being read as a formula by
+// spreadsheet apps.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// pageRequest reads the cursor and limit parameters of a paginated list. A
+// list without either is unpaged, or gets unpagedLimit rows when that is not
+// zero.
+func pageRequest(w http.ResponseWriter, r *http.Request, unpagedLimit int) (models.PageRequest, bool) {
+	params := r.URL.Query()
+	page := models.PageRequest{Cursor: params.Get("cursor"), Limit: unpagedLimit}
+	if page.Cursor != "" && page.Limit == 0 {
+		page.Limit = defaultPageSize
+	}
+	limit, ok := pageLimit(w, r, page.Limit)
+	page.Limit = limit
+	return page, ok
+}
+
+// pageLimit reads the limit parameter, capped at maxPageSize.
+func pageLimit(w http.ResponseWriter, r *http.Request, fallback int) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return fallback, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "limit must be a positive number")
+		return 0, false
+	}
+	return min(limit, maxPageSize), true
+}
+
+// maxRangeDays bounds the date range of analytics and time entry requests.
+const maxRangeDays = 366
+
+// dayRange parses the from and to dates of a request; both days are
+// included. to defaults to today (UTC) and from to defaultDays days before to.
+func dayRange(fromValue, toValue string, defaultDays int, now time.Time) (time.Time, time.Time, string) {
+	to := now.UTC().Truncate(24 * time.Hour)
+	if toValue != "" {
+		parsed, err := time.Parse(time.DateOnly, toValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, "to must be a date such as 2026-10-20"
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -defaultDays)
+	if fromValue != "" {
+		parsed, err := time.Parse(time.DateOnly, fromValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, "from must be a date such as 2026-10-20"
+		}
+		from = parsed
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, "to must not be before from"
+	}
+	if to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, "range must not exceed 366 days"
+	}
+	return from, to, ""
+}
+
+// dateSpan parses a startDate and endDate pair such as a sprint's, returning
+// an error message when they are not dates or the span ends before it starts.
+func dateSpan(start, end string) (time.Time, time.Time, string) {
+	startDate, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return time.Time{}, time.Time{}, "startDate must be a date such as 2026-10-20"
+	}
+	endDate, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, "endDate must be a date such as 2026-10-20"
+	}
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, "endDate must not be before startDate"
+	}
+	return startDate, endDate, ""
+}
+
+// writeListError reports a failed list query. Cursors the list did not issue
+// are the client's fault.
+func writeListError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	log.Printf("%s: %v", message, err)
+	writeError(w, http.StatusInternalServerError, message)
+}
+
 func ensureProjectAccess(w http.ResponseWriter, r *http.Request, repo *repository.Repo, projectID, userID string) bool {
 	allowed, err := repo.CanAccessProject(r.Context(), projectID, userID)
 	if err != nil {
@@ -30,7 +170,7 @@ func ensureProjectAccess(w http.ResponseWriter, r *http.Request, repo *repositor
 		writeError(w, http.StatusForbidden, "project access denied")
 		return false
 	}
-	return true
+	return ensureTokenProject(w, r, repo, projectID)
 }
 
 func ensureProjectRole(w http.ResponseWriter, r *http.Request, repo *repository.Repo, projectID, userID string, roles ...string) bool {
@@ -43,7 +183,7 @@ func ensureProjectRole(w http.ResponseWriter, r *http.Request, repo *repository.
 		writeError(w, http.StatusForbidden, "insufficient project permissions")
 		return false
 	}
-	return true
+	return ensureTokenProject(w, r, repo, projectID)
 }
 
 func ensureWorkspaceAccess(w http.ResponseWriter, r *http.Request, repo *repository.Repo, workspaceID, userID string) bool {
@@ -56,7 +196,7 @@ func ensureWorkspaceAccess(w http.ResponseWriter, r *http.Request, repo *reposit
 		writeError(w, http.StatusForbidden, "workspace access denied")
 		return false
 	}
-	return true
+	return ensureTokenWorkspace(w, r, workspaceID)
 }
 
 func ensureWorkspaceRole(w http.ResponseWriter, r *http.Request, repo *repository.Repo, workspaceID, userID string, roles ...string) bool {
@@ -67,7 +207,7 @@ func ensureWorkspaceRole(w http.ResponseWriter, r *http.Request, repo *repositor
 	}
 	for _, allowedRole := range roles {
 		if role == allowedRole {
-			return true
+			return ensureTokenWorkspace(w, r, workspaceID)
 		}
 	}
 	writeError(w, http.StatusForbidden, "insufficient workspace permissions")
@@ -84,5 +224,79 @@ func ensureTaskAccess(w http.ResponseWriter, r *http.Request, repo *repository.R
 		writeError(w, http.StatusNotFound, "task not found")
 		return nil, false
 	}
+	if !ensureTokenProject(w, r, repo, task.ProjectID) {
+		return nil, false
+	}
 	return task, true
 }
+
+// ensureTokenWorkspace rejects personal access tokens that are not scoped to
+// workspaceID. Browser sessions and unscoped tokens always pass.
+func ensureTokenWorkspace(w http.ResponseWriter, r *http.Request, workspaceID string) bool {
+	if !middleware.GetTokenScope(r).AllowsWorkspace(workspaceID) {
+		writeError(w, http.StatusForbidden, "token is not scoped to this workspace")
+		return false
+	}
+	return true
+}
+
+func ensureTokenProject(w http.ResponseWriter, r *http.Request, repo *repository.Repo, projectID string) bool {
+	scope := middleware.GetTokenScope(r)
+	if scope == nil || len(scope.WorkspaceIDs) == 0 {
+		return true
+	}
+	workspaceID, err := repo.GetProjectWorkspaceID(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate token scope")
+		return false
+	}
+	return ensureTokenWorkspace(w, r, workspaceID)
+}
+
+// tokenWorkspaceFilter returns the workspaceId list filter, requiring one for
+// workspace-scoped tokens so cross-workspace listings stay within the scope.
+func tokenWorkspaceFilter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	workspaceID := r.URL.Query().Get("workspaceId")
+	scope := middleware.GetTokenScope(r)
+	if scope == nil || len(scope.WorkspaceIDs) == 0 {
+		return workspaceID, true
+	}
+	if workspaceID == "" {
+		if len(scope.WorkspaceIDs) == 1 {
+			return scope.WorkspaceIDs[0], true
+		}
+		writeError(w, http.StatusBadRequest, "workspaceId is required for workspace-scoped tokens")
+		return "", false
+	}
+	if !ensureTokenWorkspace(w, r, workspaceID) {
+		return "", false
+	}
+	return workspaceID, true
+}
+
+// platformBrandName returns the configured brand for outgoing emails.
+func platformBrandName(ctx context.Context, repo *repository.Repo) string {
+	if settings, err := repo.GetPlatformSettings(ctx); err == nil && settings.BrandName != "" {
+		return settings.BrandName
+	}
+	return "justspace"
+}
+
+// deliverInvitationEmail sends the one-time invitation link to the invitee.
+// Invitations are created even when email is not configured; the token is still
+// returned to the inviter for manual sharing.
+func deliverInvitationEmail(ctx context.Context, repo *repository.Repo, m *mailer.Mailer, email, inviterID, targetKind, targetName, role, token string, expiresAt time.Time) {
+	if !m.Enabled() {
+		return
+	}
+	data := mailer.InvitationData{BrandName: platformBrandName(ctx, repo), TargetKind: targetKind, TargetName: targetName, Role: role, URL: m.InvitationURL(token), ExpiresAt: expiresAt}
+	if inviter, err := repo.GetUserByID(ctx, inviterID); err == nil && inviter != nil {
+		data.InviterName = inviter.Name
+	}
+	msg, err := mailer.RenderInvitation(email, data)
+	if err != nil {
+		log.Printf("invitation email render error: %v", err)
+		return
+	}
+	m.Deliver(msg)
+}
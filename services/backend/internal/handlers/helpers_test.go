@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPageRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		unpagedLimit int
+		wantLimit    int
+		wantCursor   string
+		wantStatus   int
+	}{
+		{name: "unpaged list", query: "", wantLimit: 0},
+		{name: "bounded list", query: "", unpagedLimit: 50, wantLimit: 50},
+		{name: "limit pages", query: "limit=10", wantLimit: 10},
+		{name: "cursor pages", query: "cursor=abc", wantLimit: defaultPageSize, wantCursor: "abc"},
+		{name: "cursor with limit", query: "cursor=abc&limit=20", unpagedLimit: 50, wantLimit: 20, wantCursor: "abc"},
+		{name: "limit capped", query: "limit=5000", wantLimit: maxPageSize},
+		{name: "invalid limit", query: "limit=-1", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/notifications?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			page, ok := pageRequest(rec, req, tt.unpagedLimit)
+			if tt.wantStatus != 0 {
+				if ok || rec.Code != tt.wantStatus {
+					t.Fatalf("ok = %v, status = %d, want status %d", ok, rec.Code, tt.wantStatus)
+				}
+				return
+			}
+			if !ok || page.Limit != tt.wantLimit || page.Cursor != tt.wantCursor {
+				t.Fatalf("page, ok = %+v, %v, want limit %d, cursor %q", page, ok, tt.wantLimit, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	records := [][]string{{"Project", "Hours"}, {"Café, Berlin", "1.50"}}
+	tests := []struct {
+		name  string
+		excel bool
+		want  string
+	}{
+		{name: "csv", want: "Project,Hours\n\"Café, Berlin\",1.50\n"},
+		{name: "excel", excel: true, want: "\ufeffProject,Hours\r\n\"Café, Berlin\",1.50\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeCSV(rec, "timesheet 2026.csv", tt.excel, records)
+			if got := rec.Body.String(); got != tt.want {
+				t.Fatalf("body = %q, want %q", got, tt.want)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="timesheet 2026.csv"` {
+				t.Fatalf("Content-Disposition = %q", got)
+			}
+		})
+	}
+}
+
+func TestCSVText(t *testing.T) {
+	for value, want := range map[string]string{"": "", "Acme": "Acme", "=1+1": "'=1+1", "-5": "'-5", "@SUM": "'@SUM", "a=b": "a=b"} {
+		if got := csvText(value); got != want {
+			t.Errorf("csvText(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestDayRange(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		from, to         string
+		wantFrom, wantTo string
+		wantMessage      string
+	}{
+		{name: "defaults", wantFrom: "2026-10-04", wantTo: "2026-10-17"},
+		{name: "explicit range", from: "2026-09-01", to: "2026-09-30", wantFrom: "2026-09-01", wantTo: "2026-09-30"},
+		{name: "default from before to", to: "2026-09-30", wantFrom: "2026-09-17", wantTo: "2026-09-30"},
+		{name: "single day", from: "2026-10-17", to: "2026-10-17", wantFrom: "2026-10-17", wantTo: "2026-10-17"},
+		{name: "full year", from: "2025-10-17", to: "2026-10-17", wantFrom: "2025-10-17", wantTo: "2026-10-17"},
+		{name: "too long", from: "2025-10-16", to: "2026-10-17", wantMessage: "range must not exceed 366 days"},
+		{name: "reversed", from: "2026-10-18", wantMessage: "to must not be before from"},
+		{name: "bad from", from: "yesterday", wantMessage: "from must be a date such as 2026-10-20"},
+		{name: "bad to", to: "2026-13-01", wantMessage: "to must be a date such as 2026-10-20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, message := dayRange(tt.from, tt.to, 13, now)
+			if message != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", message, tt.wantMessage)
+			}
+			if message != "" {
+				return
+			}
+			if got := from.Format(time.DateOnly); got != tt.wantFrom {
+				t.Errorf("from = %s, want %s", got, tt.wantFrom)
+			}
+			if got := to.Format(time.DateOnly); got != tt.wantTo {
+				t.Errorf("to = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/totp"
+)
+
+const (
+	mfaTokenTTL        = 5 * time.Minute
+	recoveryCodeCount  = 10
+	mfaTokenPurpose    = "mfa"
+	mfaRequiredMessage = "two-factor authentication is required for platform admins"
+)
+
+// mfaSigningKey is derived from the session secret so a pending MFA token can
+// never pass middleware.Auth or the WebSocket handshake as a real session.
+func (h *AuthHandler) mfaSigningKey() []byte {
+	mac := hmac.New(sha256.New, []byte(h.jwtSecret))
+	mac.Write([]byte("mfa-pending"))
+	return mac.Sum(nil)
+}
+
+func (h *AuthHandler) generateMFAToken(userID string, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":     userID,
+		"sv":      h.sessionVersion(context.Background(), userID),
+		"purpose": mfaTokenPurpose,
+		"iat":     time.Now().Unix(),
+		"exp":     expiresAt.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.mfaSigningKey())
+}
+
+// parseMFAToken returns the user of a valid pending token whose session
+// version still matches, so a password reset invalidates open challenges.
+func (h *AuthHandler) parseMFAToken(ctx context.Context, tokenStr string) string {
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return h.mfaSigningKey(), nil
+	})
+	if err != nil || !token.Valid {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != mfaTokenPurpose {
+		return ""
+	}
+	userID, _ := claims["sub"].(string)
+	version, _ := claims["sv"].(float64)
+	if userID == "" || int64(version) != h.sessionVersion(ctx, userID) {
+		return ""
+	}
+	return userID
+}
+
+// newRecoveryCodes returns codes formatted as "xxxxx-xxxxx" together with the
+// hashes that are stored; the plain codes are shown to the user only once.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	return hashInvitationToken(normalized)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Accepted TOTP steps are recorded so a code works only once.
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, mfa *models.UserMFA, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(strings.ReplaceAll(code, " ", "")) == totp.Digits {
+		secret, err := h.decryptSecret(mfa.SecretEncrypted)
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), mfa.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		return h.repo.RecordUserMFAStep(ctx, mfa.UserID, step)
+	}
+	if code == "" {
+		return false, nil
+	}
+	return h.repo.UseUserMFARecoveryCode(ctx, mfa.UserID, hashRecoveryCode(code))
+}
+
+// enabledMFA loads the caller's active enrollment, writing an error response
+// when there is none.
+func (h *AuthHandler) enabledMFA(w http.ResponseWriter, r *http.Request) *models.UserMFA {
+	mfa, err := h.repo.GetUserMFA(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load two-factor authentication")
+		return nil
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		writeError(w, http.StatusConflict, "two-factor authentication is not enabled")
+		return nil
+	}
+	return mfa
+}
+
+// adminMFARequired reports whether user may only administer the platform once
+// two-factor authentication is enabled.
+func (h *AuthHandler) adminMFARequired(ctx context.Context, user *models.User) (bool, error) {
+	if !user.IsPlatformAdmin {
+		return false, nil
+	}
+	settings, err := h.repo.GetPlatformSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+	return settings.RequireAdminMFA, nil
+}
+
+func (h *AuthHandler) MFAStatus(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	user, err := h.repo.GetUserByID(r.Context(), userID)
+	if err != nil || user == nil {
+		writeError(w, http.StatusUnauthorized, "user not found")
+		return
+	}
+	mfa, err := h.repo.GetUserMFA(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load two-factor authentication")
+		return
+	}
+	required, err := h.adminMFARequired(r.Context(), user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	status := models.MFAStatus{Required: required}
+	if mfa != nil && mfa.EnabledAt != nil {
+		status.Enabled, status.EnabledAt = true, mfa.EnabledAt
+		if status.RecoveryCodesRemaining, err = h.repo.CountUserMFARecoveryCodes(r.Context(), userID); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load two-factor authentication")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// SetupMFA creates a pending secret. It stays inactive until EnableMFA
+// confirms a code generated from it.
+func (h *AuthHandler) SetupMFA(w http.ResponseWriter, r *http.Request) {
+	if len(h.encryptionKey()) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "two-factor authentication requires OIDC_ENCRYPTION_KEY")
+		return
+	}
+	user, err := h.repo.GetUserByID(r.Context(), middleware.GetUserID(r))
+	if err != nil || user == nil {
+		writeError(w, http.StatusUnauthorized, "user not found")
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	encrypted, err := h.encryptSecret(secret)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encrypt two-factor secret")
+		return
+	}
+	saved, err := h.repo.SaveUserMFASecret(r.Context(), user.ID, encrypted)
+	if err != nil {
+		log.Printf("SaveUserMFASecret error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to set up two-factor authentication")
+		return
+	}
+	if !saved {
+		writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.MFASetupResponse{
+		Secret:     secret,
+		OTPAuthURL: totp.ProvisioningURI(platformBrandName(r.Context(), h.repo), user.Email, secret),
+	})
+}
+
+func (h *AuthHandler) EnableMFA(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	mfa, err := h.repo.GetUserMFA(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load two-factor authentication")
+		return
+	}
+	if mfa == nil {
+		writeError(w, http.StatusConflict, "start two-factor setup first")
+		return
+	}
+	if mfa.EnabledAt != nil {
+		writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	secret, err := h.decryptSecret(mfa.SecretEncrypted)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read two-factor secret")
+		return
+	}
+	step, ok := totp.Validate(secret, req.Code, time.Now(), mfa.LastUsedStep)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid authentication code")
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	enabled, err := h.repo.EnableUserMFA(r.Context(), userID, step, hashes)
+	if err != nil {
+		log.Printf("EnableUserMFA error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to enable two-factor authentication")
+		return
+	}
+	if !enabled {
+		writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	mfa := h.enabledMFA(w, r)
+	if mfa == nil {
+		return
+	}
+	user, err := h.repo.GetUserByID(r.Context(), mfa.UserID)
+	if err != nil || user == nil {
+		writeError(w, http.StatusUnauthorized, "user not found")
+		return
+	}
+	required, err := h.adminMFARequired(r.Context(), user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if required {
+		writeError(w, http.StatusConflict, mfaRequiredMessage)
+		return
+	}
+	ok, err := h.checkSecondFactor(r.Context(), mfa, req.Code)
+	if err != nil {
+		log.Printf("DisableMFA error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to verify authentication code")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid authentication code")
+		return
+	}
+	if _, err := h.repo.DeleteUserMFA(r.Context(), mfa.UserID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	mfa := h.enabledMFA(w, r)
+	if mfa == nil {
+		return
+	}
+	ok, err := h.checkSecondFactor(r.Context(), mfa, req.Code)
+	if err != nil {
+		log.Printf("RegenerateRecoveryCodes error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to verify authentication code")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid authentication code")
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if err := h.repo.ReplaceUserMFARecoveryCodes(r.Context(), mfa.UserID, hashes); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to regenerate recovery codes")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// VerifyMFA completes a login that returned an MFA challenge and issues the
+// session cookie.
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	if !h.authLimiter.allow(r) {
+		writeError(w, http.StatusTooManyRequests, "too many authentication attempts")
+		return
+	}
+	var req models.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	userID := h.parseMFAToken(r.Context(), req.MFAToken)
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "two-factor challenge expired, sign in again")
+		return
+	}
+	user, err := h.repo.GetUserByID(r.Context(), userID)
+	if err != nil || user == nil || !user.IsActive {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	mfa, err := h.repo.GetUserMFA(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		writeError(w, http.StatusUnauthorized, "two-factor challenge expired, sign in again")
+		return
+	}
+	ok, err := h.checkSecondFactor(r.Context(), mfa, req.Code)
+	if err != nil {
+		log.Printf("VerifyMFA error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to verify authentication code")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid authentication code")
+		return
+	}
+	token, err := h.generateToken(r, user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	h.setTokenCookie(w, r, token)
+	writeJSON(w, http.StatusOK, models.AuthResponse{User: *user})
+}
+
+// AdminResetUserMFA removes a user's enrollment and recovery codes, e.g. after
+// a lost device. The user can sign in with the password alone and enroll again.
+func (h *AuthHandler) AdminResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlatformAdmin(w, r) {
+		return
+	}
+	user, err := h.repo.GetUserByID(r.Context(), chi.URLParam(r, "userId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if user == nil {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	deleted, err := h.repo.DeleteUserMFA(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to reset two-factor authentication")
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, "two-factor authentication is not enabled")
+		return
+	}
+	h.recordAdminAudit(r, "user.mfa_reset", "user", user.ID, user.Email, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[0-9a-f]{5}-[0-9a-f]{5}$`)
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Fatalf("code %q does not match xxxxx-xxxxx", code)
+		}
+		if hashRecoveryCode(code) != hashes[i] {
+			t.Fatalf("hash of %q does not match the stored hash", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCodeNormalizesInput(t *testing.T) {
+	want := hashRecoveryCode("abcde-01234")
+	for _, input := range []string{"abcde01234", " ABCDE-01234 ", "abcde 01234"} {
+		if got := hashRecoveryCode(input); got != want {
+			t.Fatalf("hashRecoveryCode(%q) differs from the canonical form", input)
+		}
+	}
+	if hashRecoveryCode("abcde-01235") == want {
+		t.Fatal("different codes share a hash")
+	}
+}
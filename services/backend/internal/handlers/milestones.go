@@ -38,6 +38,9 @@ func (h *MilestoneHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *MilestoneHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	projectID := chi.URLParam(r, "projectId")
+	if !ensureTokenProject(w, r, h.repo, projectID) {
+		return
+	}
 	var req models.CreateProjectMilestoneRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -54,6 +57,9 @@ func (h *MilestoneHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 func (h *MilestoneHandler) Update(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
+	if !h.ensureTokenMilestone(w, r) {
+		return
+	}
 	var req models.UpdateProjectMilestoneRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -70,6 +76,9 @@ func (h *MilestoneHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 func (h *MilestoneHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
+	if !h.ensureTokenMilestone(w, r) {
+		return
+	}
 	if err := h.repo.DeleteProjectMilestone(r.Context(), chi.URLParam(r, "id"), userID); err != nil {
 		writeError(w, http.StatusBadRequest, "failed to delete milestone")
 		return
@@ -77,6 +86,22 @@ func (h *MilestoneHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
 
+func (h *MilestoneHandler) ensureTokenMilestone(w http.ResponseWriter, r *http.Request) bool {
+	if middleware.GetTokenScope(r) == nil {
+		return true
+	}
+	projectID, err := h.repo.GetProjectMilestoneProjectID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load milestone")
+		return false
+	}
+	if projectID == "" {
+		writeError(w, http.StatusNotFound, "milestone not found")
+		return false
+	}
+	return ensureTokenProject(w, r, h.repo, projectID)
+}
+
 func (h *MilestoneHandler) broadcastProject(projectID string, event models.WSEvent) {
 	memberIDs, err := h.repo.ListProjectMemberUserIDs(context.Background(), projectID)
 	if err == nil {
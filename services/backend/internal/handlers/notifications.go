@@ -21,12 +21,16 @@ func NewNotificationHandler(repo *repository.Repo, hub *websocket.Hub) *Notifica
 
 func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
-	notifications, err := h.repo.ListNotifications(r.Context(), userID)
+	page, ok := pageRequest(w, r, defaultPageSize)
+	if !ok {
+		return
+	}
+	notifications, nextCursor, err := h.repo.ListNotifications(r.Context(), userID, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list notifications")
+		writeListError(w, err, "failed to list notifications")
 		return
 	}
-	writeJSON(w, http.StatusOK, models.ListResponse[models.Notification]{Total: len(notifications), Documents: notifications})
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Notification]{Total: len(notifications), Documents: notifications, NextCursor: nextCursor})
 }
 
 func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
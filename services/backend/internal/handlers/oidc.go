@@ -34,8 +34,10 @@ type oidcState struct {
 }
 
 type authConfigResponse struct {
-	LocalAuthEnabled bool                  `json:"localAuthEnabled"`
-	OIDCProviders    []models.OIDCProvider `json:"oidcProviders"`
+	LocalAuthEnabled     bool                  `json:"localAuthEnabled"`
+	PasswordResetEnabled bool                  `json:"passwordResetEnabled"`
+	RequireVerifiedEmail bool                  `json:"requireVerifiedEmail"`
+	OIDCProviders        []models.OIDCProvider `json:"oidcProviders"`
 }
 
 func (h *AuthHandler) AuthConfig(w http.ResponseWriter, r *http.Request) {
@@ -49,7 +51,12 @@ func (h *AuthHandler) AuthConfig(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to load authentication providers")
 		return
 	}
-	writeJSON(w, http.StatusOK, authConfigResponse{LocalAuthEnabled: settings.LocalAuthEnabled, OIDCProviders: providers})
+	writeJSON(w, http.StatusOK, authConfigResponse{
+		LocalAuthEnabled:     settings.LocalAuthEnabled,
+		PasswordResetEnabled: settings.LocalAuthEnabled && h.mailer.Enabled(),
+		RequireVerifiedEmail: settings.RequireVerifiedEmail,
+		OIDCProviders:        providers,
+	})
 }
 
 func (h *AuthHandler) OIDCStart(w http.ResponseWriter, r *http.Request) {
@@ -216,7 +223,7 @@ func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
 		h.oidcErrorRedirect(w, r, false, "oidc account is unavailable")
 		return
 	}
-	sessionToken, err := h.generateToken(user.ID)
+	sessionToken, err := h.generateToken(r, user.ID)
 	if err != nil {
 		h.oidcErrorRedirect(w, r, false, "failed to create session")
 		return
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/mailer"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetPurpose     = "password_reset"
+	emailVerificationPurpose = "email_verification"
+	passwordResetTTL         = time.Hour
+	emailVerificationTTL     = 72 * time.Hour
+)
+
+// The response never reveals whether an account exists for the address.
+const accountEmailAcceptedMessage = "if the address belongs to an account, an email is on its way"
+
+// newAccountToken returns "<random>.<signature>". The HMAC binds the token to
+// its purpose so a verification link can never be replayed as a reset link,
+// and malformed tokens are rejected before touching the database.
+func newAccountToken(secret, purpose string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(buf)
+	return nonce + "." + signAccountToken(secret, purpose, nonce), nil
+}
+
+func signAccountToken(secret, purpose, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + ":" + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validAccountToken(secret, purpose, token string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(signAccountToken(secret, purpose, nonce)))
+}
+
+// sendAccountEmail issues a token for user and emails the matching link.
+func (h *AuthHandler) sendAccountEmail(ctx context.Context, user *models.User, purpose string) error {
+	token, err := newAccountToken(h.jwtSecret, purpose)
+	if err != nil {
+		return err
+	}
+	ttl, link, render := passwordResetTTL, h.mailer.PasswordResetURL(token), mailer.RenderPasswordReset
+	if purpose == emailVerificationPurpose {
+		ttl, link, render = emailVerificationTTL, h.mailer.EmailVerificationURL(token), mailer.RenderEmailVerification
+	}
+	expiresAt := time.Now().Add(ttl)
+	if err := h.repo.CreateUserAuthToken(ctx, user.ID, purpose, hashInvitationToken(token), user.Email, expiresAt); err != nil {
+		return err
+	}
+	msg, err := render(user.Email, mailer.AccountLinkData{BrandName: platformBrandName(ctx, h.repo), Name: user.Name, URL: link, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	h.mailer.Deliver(msg)
+	return nil
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if !h.authLimiter.allow(r) {
+		writeError(w, http.StatusTooManyRequests, "too many authentication attempts")
+		return
+	}
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !h.mailer.Enabled() {
+		writeError(w, http.StatusServiceUnavailable, "password reset requires email delivery")
+		return
+	}
+	settings, err := h.repo.GetPlatformSettings(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !settings.LocalAuthEnabled {
+		writeError(w, http.StatusForbidden, "local authentication is disabled")
+		return
+	}
+	user, err := h.repo.GetUserByEmail(r.Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	// OIDC-only accounts have no password to reset.
+	if user != nil && user.IsActive && user.PasswordHash != "" {
+		if err := h.sendAccountEmail(r.Context(), user, passwordResetPurpose); err != nil {
+			log.Printf("password reset email error: %v", err)
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": accountEmailAcceptedMessage})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if !h.authLimiter.allow(r) {
+		writeError(w, http.StatusTooManyRequests, "too many authentication attempts")
+		return
+	}
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Password) < 12 {
+		writeError(w, http.StatusBadRequest, "a password of at least 12 characters is required")
+		return
+	}
+	if !validAccountToken(h.jwtSecret, passwordResetPurpose, req.Token) {
+		writeError(w, http.StatusBadRequest, "invalid or expired reset link")
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	reset, err := h.repo.ResetUserPassword(r.Context(), hashInvitationToken(req.Token), string(hash))
+	if err != nil {
+		log.Printf("ResetUserPassword error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+	if !reset {
+		writeError(w, http.StatusBadRequest, "invalid or expired reset link")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "password updated"})
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if !h.authLimiter.allow(r) {
+		writeError(w, http.StatusTooManyRequests, "too many authentication attempts")
+		return
+	}
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !validAccountToken(h.jwtSecret, emailVerificationPurpose, req.Token) {
+		writeError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return
+	}
+	user, err := h.repo.VerifyUserEmail(r.Context(), hashInvitationToken(req.Token))
+	if err != nil {
+		log.Printf("VerifyUserEmail error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to verify email")
+		return
+	}
+	if user == nil {
+		writeError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.AuthResponse{User: *user})
+}
+
+// ResendVerification is public because unverified accounts cannot sign in
+// while verified emails are required.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if !h.authLimiter.allow(r) {
+		writeError(w, http.StatusTooManyRequests, "too many authentication attempts")
+		return
+	}
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !h.mailer.Enabled() {
+		writeError(w, http.StatusServiceUnavailable, "email verification requires email delivery")
+		return
+	}
+	user, err := h.repo.GetUserByEmail(r.Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if user != nil && user.IsActive && user.EmailVerifiedAt == nil {
+		if err := h.sendAccountEmail(r.Context(), user, emailVerificationPurpose); err != nil {
+			log.Printf("email verification error: %v", err)
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": accountEmailAcceptedMessage})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAccountTokens(t *testing.T) {
+	const secret = "a-unique-jwt-secret-with-at-least-32-characters"
+	reset, err := newAccountToken(secret, passwordResetPurpose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, _, _ := strings.Cut(reset, ".")
+	tests := []struct {
+		name    string
+		secret  string
+		purpose string
+		token   string
+		want    bool
+	}{
+		{name: "valid reset token", secret: secret, purpose: passwordResetPurpose, token: reset, want: true},
+		{name: "reset token used for verification", secret: secret, purpose: emailVerificationPurpose, token: reset},
+		{name: "other secret", secret: "another-secret", purpose: passwordResetPurpose, token: reset},
+		{name: "tampered signature", secret: secret, purpose: passwordResetPurpose, token: nonce + ".00"},
+		{name: "missing signature", secret: secret, purpose: passwordResetPurpose, token: nonce},
+		{name: "empty token", secret: secret, purpose: passwordResetPurpose, token: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validAccountToken(tt.secret, tt.purpose, tt.token); got != tt.want {
+				t.Fatalf("validAccountToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,27 +9,36 @@ import (
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
 	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/webhooks"
 	"github.com/justlabv1/justspace/backend/internal/websocket"
 )
 
 type ProjectHandler struct {
-	repo *repository.Repo
-	hub  *websocket.Hub
+	repo     *repository.Repo
+	hub      *websocket.Hub
+	webhooks *webhooks.Dispatcher
 }
 
-func NewProjectHandler(repo *repository.Repo, hub *websocket.Hub) *ProjectHandler {
-	return &ProjectHandler{repo: repo, hub: hub}
+func NewProjectHandler(repo *repository.Repo, hub *websocket.Hub, dispatcher *webhooks.Dispatcher) *ProjectHandler {
+	return &ProjectHandler{repo: repo, hub: hub, webhooks: dispatcher}
 }
 
 func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
-	projects, err := h.repo.ListProjects(r.Context(), userID, r.URL.Query().Get("workspaceId"))
+	workspaceID, ok := tokenWorkspaceFilter(w, r)
+	if !ok {
+		return
+	}
+	page, ok := pageRequest(w, r, 0)
+	if !ok {
+		return
+	}
+	projects, nextCursor, err := h.repo.ListProjects(r.Context(), userID, workspaceID, page)
 	if err != nil {
-		log.Printf("ListProjects error: %v", err)
-		writeError(w, http.StatusInternalServerError, "failed to list projects")
+		writeListError(w, err, "failed to list projects")
 		return
 	}
-	writeJSON(w, http.StatusOK, models.ListResponse[models.Project]{Total: len(projects), Documents: projects})
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Project]{Total: len(projects), Documents: projects, NextCursor: nextCursor})
 }
 
 func (h *ProjectHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +66,15 @@ func (h *ProjectHandler) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	if scope := middleware.GetTokenScope(r); scope != nil && len(scope.WorkspaceIDs) > 0 {
+		if req.WorkspaceID == "" {
+			writeError(w, http.StatusBadRequest, "workspaceId is required for workspace-scoped tokens")
+			return
+		}
+		if !ensureTokenWorkspace(w, r, req.WorkspaceID) {
+			return
+		}
+	}
 	project, err := h.repo.CreateProject(r.Context(), userID, req)
 	if err != nil {
 		log.Printf("CreateProject error: %v", err)
@@ -65,7 +83,9 @@ func (h *ProjectHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	h.repo.LogActivity(r.Context(), userID, "create", "Project", project.Name, &project.ID, nil, nil)
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), project.ID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "create", Collection: "projects", Document: project, UserID: userID})
+	event := models.WSEvent{Type: "create", Collection: "projects", Document: project, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(project.ID, event)
 	if activity, err := h.repo.ListProjectActivity(r.Context(), project.ID, 25); err == nil {
 		h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_activity", Document: activity, UserID: userID})
 	}
@@ -100,7 +120,9 @@ func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	h.repo.LogActivity(r.Context(), userID, "update", "Project", project.Name, &project.ID, nil, nil)
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), project.ID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "projects", Document: project, UserID: userID})
+	event := models.WSEvent{Type: "update", Collection: "projects", Document: project, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(project.ID, event)
 	if activity, err := h.repo.ListProjectActivity(r.Context(), project.ID, 25); err == nil {
 		h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_activity", Document: activity, UserID: userID})
 	}
@@ -124,8 +146,10 @@ func (h *ProjectHandler) MigrateEncryption(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), projectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "projects", Document: project, UserID: userID})
+	event := models.WSEvent{Type: "update", Collection: "projects", Document: project, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
 	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "tasks", Document: map[string]string{"projectId": projectID}, UserID: userID})
+	h.webhooks.Emit(projectID, event)
 	writeJSON(w, http.StatusOK, project)
 }
 
@@ -155,12 +179,19 @@ func (h *ProjectHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	if !ensureProjectRole(w, r, h.repo, id, userID, "owner") {
 		return
 	}
+	// The workspace is resolved up front because the project row is gone once
+	// the delete event is emitted.
+	workspaceID, encrypted, scopeErr := h.repo.GetWebhookProjectScope(r.Context(), id)
 	if err := h.repo.DeleteProject(r.Context(), id, userID); err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to delete project")
 		return
 	}
 	h.repo.LogActivity(r.Context(), userID, "delete", "Project", "Project", nil, nil, nil)
-	h.hub.Broadcast(userID, models.WSEvent{Type: "delete", Collection: "projects", Document: map[string]string{"id": id}, UserID: userID})
+	event := models.WSEvent{Type: "delete", Collection: "projects", Document: map[string]string{"id": id}, UserID: userID}
+	h.hub.Broadcast(userID, event)
+	if scopeErr == nil && workspaceID != "" {
+		h.webhooks.EmitWorkspace(r.Context(), workspaceID, id, encrypted, event)
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
 
@@ -196,7 +227,9 @@ func (h *ProjectHandler) CreateTaskStatus(w http.ResponseWriter, r *http.Request
 		return
 	}
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), projectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "create", Collection: "project_task_statuses", Document: status, UserID: userID})
+	event := models.WSEvent{Type: "create", Collection: "project_task_statuses", Document: status, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(projectID, event)
 	writeJSON(w, http.StatusCreated, status)
 }
 
@@ -223,7 +256,9 @@ func (h *ProjectHandler) UpdateTaskStatus(w http.ResponseWriter, r *http.Request
 		return
 	}
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), projectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_task_statuses", Document: status, UserID: userID})
+	event := models.WSEvent{Type: "update", Collection: "project_task_statuses", Document: status, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(projectID, event)
 	writeJSON(w, http.StatusOK, status)
 }
 
@@ -253,7 +288,9 @@ func (h *ProjectHandler) DeleteTaskStatus(w http.ResponseWriter, r *http.Request
 		return
 	}
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), projectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "delete", Collection: "project_task_statuses", Document: map[string]string{"id": statusID}, UserID: userID})
+	event := models.WSEvent{Type: "delete", Collection: "project_task_statuses", Document: map[string]string{"id": statusID}, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(projectID, event)
 	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
 
@@ -275,6 +312,8 @@ func (h *ProjectHandler) ReorderTaskStatuses(w http.ResponseWriter, r *http.Requ
 	}
 	statuses, _ := h.repo.ListProjectTaskStatuses(r.Context(), projectID)
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), projectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_task_statuses", Document: statuses, UserID: userID})
+	event := models.WSEvent{Type: "update", Collection: "project_task_statuses", Document: statuses, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(projectID, event)
 	writeJSON(w, http.StatusOK, models.ListResponse[models.ProjectTaskStatus]{Total: len(statuses), Documents: statuses})
 }
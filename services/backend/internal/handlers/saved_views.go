@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/taskquery"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
+)
+
+const (
+	maxSavedViewNameLength    = 120
+	maxSavedViewSettingsBytes = 16 << 10
+)
+
+var (
+	savedViewModes    = map[string]bool{"list": true, "kanban": true, "timeline": true, "calendar": true}
+	savedViewGroupBys = map[string]bool{"": true, "status": true, "priority": true, "assignee": true, "tag": true, "due": true}
+)
+
+type SavedViewHandler struct {
+	repo *repository.Repo
+	hub  *websocket.Hub
+}
+
+func NewSavedViewHandler(repo *repository.Repo, hub *websocket.Hub) *SavedViewHandler {
+	return &SavedViewHandler{repo: repo, hub: hub}
+}
+
+func (h *SavedViewHandler) ListByProject(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	projectID := chi.URLParam(r, "projectId")
+	if !ensureProjectAccess(w, r, h.repo, projectID, userID) {
+		return
+	}
+	views, err := h.repo.ListProjectSavedViews(r.Context(), projectID, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list saved views")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.SavedView]{Total: len(views), Documents: views})
+}
+
+func (h *SavedViewHandler) ListByWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	workspaceID := chi.URLParam(r, "workspaceId")
+	if !ensureWorkspaceAccess(w, r, h.repo, workspaceID, userID) {
+		return
+	}
+	views, err := h.repo.ListWorkspaceSavedViews(r.Context(), workspaceID, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list saved views")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.SavedView]{Total: len(views), Documents: views})
+}
+
+func (h *SavedViewHandler) CreateForProject(w http.ResponseWriter, r *http.Request) {
+	h.create(w, r, chi.URLParam(r, "projectId"), "")
+}
+
+func (h *SavedViewHandler) CreateForWorkspace(w http.ResponseWriter, r *http.Request) {
+	h.create(w, r, "", chi.URLParam(r, "workspaceId"))
+}
+
+func (h *SavedViewHandler) create(w http.ResponseWriter, r *http.Request, projectID, workspaceID string) {
+	userID := middleware.GetUserID(r)
+	var req models.CreateSavedViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = "user"
+	}
+	if req.ViewMode == "" {
+		req.ViewMode = "list"
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if projectID != "" && req.Scope == "workspace" || workspaceID != "" && req.Scope == "project" {
+		writeError(w, http.StatusBadRequest, "scope does not match the view's project or workspace")
+		return
+	}
+	if message := validateSavedView(req.Scope, &req.Name, &req.ViewMode, &req.Filter, &req.Sort, &req.GroupBy, req.Settings); message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	view := &models.SavedView{OwnerID: userID, Scope: req.Scope}
+	if projectID != "" {
+		view.ProjectID = &projectID
+	} else {
+		view.WorkspaceID = &workspaceID
+	}
+	if !h.ensureCanWrite(w, r, view, userID) {
+		return
+	}
+	view, err := h.repo.CreateSavedView(r.Context(), userID, projectID, workspaceID, req)
+	if err != nil {
+		log.Printf("CreateSavedView error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to create saved view")
+		return
+	}
+	h.broadcast(view, models.WSEvent{Type: "create", Collection: "saved_views", Document: view, UserID: userID})
+	writeJSON(w, http.StatusCreated, view)
+}
+
+func (h *SavedViewHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	view, ok := h.loadView(w, r, userID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *SavedViewHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	view, ok := h.loadView(w, r, userID)
+	if !ok {
+		return
+	}
+	var req models.UpdateSavedViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	name, viewMode, filter, sort, groupBy := view.Name, view.ViewMode, view.Filter, view.Sort, view.GroupBy
+	for _, field := range []struct{ from, to *string }{
+		{req.Name, &name}, {req.ViewMode, &viewMode}, {req.Filter, &filter}, {req.Sort, &sort}, {req.GroupBy, &groupBy},
+	} {
+		if field.from != nil {
+			*field.to = *field.from
+		}
+	}
+	name = strings.TrimSpace(name)
+	if message := validateSavedView(view.Scope, &name, &viewMode, &filter, &sort, &groupBy, req.Settings); message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	if !h.ensureCanWrite(w, r, view, userID) {
+		return
+	}
+	req = models.UpdateSavedViewRequest{Name: &name, ViewMode: &viewMode, Filter: &filter, Sort: &sort, GroupBy: &groupBy, Settings: req.Settings}
+	updated, err := h.repo.UpdateSavedView(r.Context(), view.ID, req)
+	if err != nil {
+		log.Printf("UpdateSavedView error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to update saved view")
+		return
+	}
+	if updated == nil {
+		writeError(w, http.StatusNotFound, "saved view not found")
+		return
+	}
+	h.broadcast(updated, models.WSEvent{Type: "update", Collection: "saved_views", Document: updated, UserID: userID})
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *SavedViewHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	view, ok := h.loadView(w, r, userID)
+	if !ok {
+		return
+	}
+	if !h.ensureCanWrite(w, r, view, userID) {
+		return
+	}
+	if err := h.repo.DeleteSavedView(r.Context(), view.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete saved view")
+		return
+	}
+	h.broadcast(view, models.WSEvent{Type: "delete", Collection: "saved_views", Document: map[string]string{"id": view.ID}, UserID: userID})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// loadView loads the view of the request and checks that the user can see
+// it. Personal views of other users are reported as missing.
+func (h *SavedViewHandler) loadView(w http.ResponseWriter, r *http.Request, userID string) (*models.SavedView, bool) {
+	view, err := h.repo.GetSavedView(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load saved view")
+		return nil, false
+	}
+	if view == nil || view.Scope == "user" && view.OwnerID != userID {
+		writeError(w, http.StatusNotFound, "saved view not found")
+		return nil, false
+	}
+	if view.ProjectID != nil {
+		return view, ensureProjectAccess(w, r, h.repo, *view.ProjectID, userID)
+	}
+	return view, ensureWorkspaceAccess(w, r, h.repo, *view.WorkspaceID, userID)
+}
+
+// ensureCanWrite checks that the user may create, change, or delete view.
+// Anyone with access manages their own personal views; shared views need
+// editor rights on the project or admin rights on the workspace.
+func (h *SavedViewHandler) ensureCanWrite(w http.ResponseWriter, r *http.Request, view *models.SavedView, userID string) bool {
+	switch view.Scope {
+	case "project":
+		return ensureProjectRole(w, r, h.repo, *view.ProjectID, userID, "owner", "admin", "editor")
+	case "workspace":
+		return ensureWorkspaceRole(w, r, h.repo, *view.WorkspaceID, userID, "owner", "admin")
+	}
+	if view.OwnerID != userID {
+		writeError(w, http.StatusNotFound, "saved view not found")
+		return false
+	}
+	if view.ProjectID != nil {
+		return ensureProjectAccess(w, r, h.repo, *view.ProjectID, userID)
+	}
+	return ensureWorkspaceAccess(w, r, h.repo, *view.WorkspaceID, userID)
+}
+
+// broadcast sends event to everyone who can see view.
+func (h *SavedViewHandler) broadcast(view *models.SavedView, event models.WSEvent) {
+	ctx := context.Background()
+	switch view.Scope {
+	case "project":
+		memberIDs, err := h.repo.ListProjectMemberUserIDs(ctx, *view.ProjectID)
+		if err == nil {
+			h.hub.BroadcastUsers(memberIDs, event)
+		}
+	case "workspace":
+		members, err := h.repo.ListWorkspaceMembers(ctx, *view.WorkspaceID)
+		if err != nil {
+			return
+		}
+		userIDs := make([]string, 0, len(members))
+		for _, member := range members {
+			userIDs = append(userIDs, member.UserID)
+		}
+		h.hub.BroadcastUsers(userIDs, event)
+	default:
+		h.hub.Broadcast(view.OwnerID, event)
+	}
+}
+
+// validateSavedView checks a view definition, normalizing the filter, and
+// returns an error message, or "" when the definition is valid.
+func validateSavedView(scope string, name, viewMode, filter, sort, groupBy *string, settings json.RawMessage) string {
+	if scope != "user" && scope != "project" && scope != "workspace" {
+		return "scope must be user, project, or workspace"
+	}
+	if *name == "" {
+		return "name is required"
+	}
+	if utf8.RuneCountInString(*name) > maxSavedViewNameLength {
+		return "name is too long"
+	}
+	if !savedViewModes[*viewMode] {
+		return "viewMode must be list, kanban, timeline, or calendar"
+	}
+	*filter = strings.TrimSpace(*filter)
+	if _, err := taskquery.Parse(*filter, time.Now()); err != nil {
+		return "invalid filter: " + err.Error()
+	}
+	if *sort != "" && !repository.ValidTaskSort(*sort) {
+		return "invalid sort"
+	}
+	if !savedViewGroupBys[*groupBy] {
+		return "groupBy must be status, priority, assignee, tag, or due"
+	}
+	if len(settings) > maxSavedViewSettingsBytes {
+		return "settings are too large"
+	}
+	if len(settings) > 0 {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(settings, &object); err != nil || object == nil {
+			return "settings must be a JSON object"
+		}
+	}
+	return ""
+}
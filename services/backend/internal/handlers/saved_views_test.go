@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateSavedView(t *testing.T) {
+	for _, tc := range []struct {
+		name                                       string
+		scope, viewName, viewMode, filter, sortKey string
+		groupBy                                    string
+		settings                                   string
+		wantError                                  string
+	}{
+		{name: "valid", scope: "project", viewName: "Sprint board", viewMode: "kanban", filter: " status:todo,review -blocked ", sortKey: "order", groupBy: "assignee", settings: `{"hideCompleted":true}`},
+		{name: "defaults", scope: "user", viewName: "Mine", viewMode: "list"},
+		{name: "unknown scope", scope: "team", viewName: "x", viewMode: "list", wantError: "scope must be"},
+		{name: "missing name", scope: "user", viewMode: "list", wantError: "name is required"},
+		{name: "long name", scope: "user", viewName: strings.Repeat("ä", maxSavedViewNameLength+1), viewMode: "list", wantError: "name is too long"},
+		{name: "view mode", scope: "user", viewName: "x", viewMode: "table", wantError: "viewMode must be"},
+		{name: "filter", scope: "user", viewName: "x", viewMode: "list", filter: "login", wantError: "invalid filter: unknown filter"},
+		{name: "sort", scope: "user", viewName: "x", viewMode: "list", sortKey: "title", wantError: "invalid sort"},
+		{name: "group by", scope: "user", viewName: "x", viewMode: "list", groupBy: "milestone", wantError: "groupBy must be"},
+		{name: "settings array", scope: "user", viewName: "x", viewMode: "list", settings: `[]`, wantError: "settings must be a JSON object"},
+		{name: "settings null", scope: "user", viewName: "x", viewMode: "list", settings: `null`, wantError: "settings must be a JSON object"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var settings json.RawMessage
+			if tc.settings != "" {
+				settings = json.RawMessage(tc.settings)
+			}
+			got := validateSavedView(tc.scope, &tc.viewName, &tc.viewMode, &tc.filter, &tc.sortKey, &tc.groupBy, settings)
+			if tc.wantError == "" && got != "" || !strings.HasPrefix(got, tc.wantError) {
+				t.Fatalf("validateSavedView() = %q, want prefix %q", got, tc.wantError)
+			}
+			if got == "" && strings.TrimSpace(tc.filter) != tc.filter {
+				t.Fatalf("filter %q was not trimmed", tc.filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"html"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchQueryLen  = 256
+)
+
+// searchTypes are the record kinds /api/search can return.
+var searchTypes = []string{"task", "comment", "wiki", "installation", "snippet"}
+
+type SearchHandler struct{ repo *repository.Repo }
+
+func NewSearchHandler(repo *repository.Repo) *SearchHandler {
+	return &SearchHandler{repo: repo}
+}
+
+// Search runs a full-text query. q accepts web search syntax: quoted phrases,
+// OR, and -word. workspaceId, projectId, and a comma-separated type narrow
+// the results.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	params := r.URL.Query()
+	query := models.SearchQuery{
+		Text:        strings.TrimSpace(params.Get("q")),
+		WorkspaceID: params.Get("workspaceId"),
+		ProjectID:   params.Get("projectId"),
+		Types:       searchTypes,
+		Limit:       defaultSearchLimit,
+	}
+	if query.Text == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if utf8.RuneCountInString(query.Text) > maxSearchQueryLen {
+		writeError(w, http.StatusBadRequest, "q must be at most 256 characters")
+		return
+	}
+	if value := params.Get("type"); value != "" {
+		query.Types = nil
+		for _, kind := range strings.Split(value, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(searchTypes, kind) {
+				writeError(w, http.StatusBadRequest, "type must be task, comment, wiki, installation, or snippet")
+				return
+			}
+			query.Types = append(query.Types, kind)
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		query.Limit = min(limit, maxSearchLimit)
+	}
+
+	results, err := h.repo.Search(r.Context(), userID, query)
+	if err != nil {
+		log.Printf("Search error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to search")
+		return
+	}
+	for i := range results {
+		results[i].Highlight = searchHighlightHTML(results[i].Highlight)
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.SearchResult]{Total: len(results), Documents: results})
+}
+
+// searchHighlightHTML escapes a highlight from Repo.Search and turns its
+// \x01 and \x02 match delimiters into <mark> tags, so stored text can never
+// inject markup.
+func searchHighlightHTML(highlight string) string {
+	escaped := html.EscapeString(highlight)
+	return strings.NewReplacer("\x01", "<mark>", "\x02", "</mark>").Replace(escaped)
+}
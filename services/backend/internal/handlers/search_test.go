@@ -0,0 +1,16 @@
+package handlers
+
+import "testing"
+
+func TestSearchHighlightHTML(t *testing.T) {
+	for input, want := range map[string]string{
+		"deploy the \x01backend\x02 first": "deploy the <mark>backend</mark> first",
+		"<script>\x01alert\x02</script>":   "&lt;script&gt;<mark>alert</mark>&lt;/script&gt;",
+		"a & b \x01<mark>\x02":             "a &amp; b <mark>&lt;mark&gt;</mark>",
+		"no matches":                       "no matches",
+	} {
+		if got := searchHighlightHTML(input); got != want {
+			t.Errorf("searchHighlightHTML(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+const sessionTTL = 7 * 24 * time.Hour
+
+// deviceLabel turns a user agent into a short "Browser on OS" description for
+// the session list. Unknown parts are omitted rather than guessed.
+func deviceLabel(userAgent string) string {
+	browser := ""
+	for _, candidate := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also announce Chrome, Chrome announces Safari.
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+	platform := ""
+	for _, candidate := range []struct{ token, name string }{
+		// iOS and Android user agents also mention Mac OS X and Linux.
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
+
+func truncateRunes(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
+
+// currentSession returns the user and session named by the request cookie on
+// public routes such as logout, or empty strings if the token is not valid.
+func (h *AuthHandler) currentSession(r *http.Request) (string, string) {
+	cookie, err := r.Cookie("js_token")
+	if err != nil || cookie.Value == "" {
+		return "", ""
+	}
+	token, err := jwt.Parse(cookie.Value, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(h.jwtSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", ""
+	}
+	userID, _ := claims["sub"].(string)
+	sessionID, _ := claims["jti"].(string)
+	return userID, sessionID
+}
+
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.repo.ListUserSessions(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+	current := middleware.GetSessionID(r)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.UserSession]{Total: len(sessions), Documents: sessions})
+}
+
+// RevokeSession signs out a single device. DisconnectUser drops all of the
+// user's WebSockets; only the revoked session fails to reconnect.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sessionID := chi.URLParam(r, "sessionId")
+	revoked, err := h.repo.RevokeUserSession(r.Context(), userID, sessionID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+	if !revoked {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if h.hub != nil {
+		h.hub.DisconnectUser(userID)
+	}
+	if sessionID == middleware.GetSessionID(r) {
+		clearTokenCookie(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs out every device except the one making the request.
+func (h *AuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	count, err := h.repo.RevokeOtherUserSessions(r.Context(), userID, middleware.GetSessionID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke sessions")
+		return
+	}
+	if count > 0 && h.hub != nil {
+		h.hub.DisconnectUser(userID)
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"revoked": count})
+}
+
+func (h *AuthHandler) AdminUserSessions(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlatformAdmin(w, r) {
+		return
+	}
+	sessions, err := h.repo.ListUserSessions(r.Context(), chi.URLParam(r, "userId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.UserSession]{Total: len(sessions), Documents: sessions})
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import "testing"
+
+func TestDeviceLabel(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{name: "chrome on windows", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", want: "Chrome on Windows"},
+		{name: "edge on windows", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51", want: "Edge on Windows"},
+		{name: "firefox on linux", userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0", want: "Firefox on Linux"},
+		{name: "safari on macos", userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15", want: "Safari on macOS"},
+		{name: "safari on iphone", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", want: "Safari on iOS"},
+		{name: "chrome on android", userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36", want: "Chrome on Android"},
+		{name: "cli client", userAgent: "curl/8.5.0", want: "Unknown device"},
+		{name: "empty", userAgent: "", want: "Unknown device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deviceLabel(tt.userAgent); got != tt.want {
+				t.Fatalf("deviceLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
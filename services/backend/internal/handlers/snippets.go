@@ -23,12 +23,16 @@ func NewSnippetHandler(repo *repository.Repo, hub *websocket.Hub) *SnippetHandle
 
 func (h *SnippetHandler) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
-	snippets, err := h.repo.ListSnippets(r.Context(), userID, r.URL.Query().Get("workspaceId"))
+	page, ok := pageRequest(w, r, 0)
+	if !ok {
+		return
+	}
+	snippets, nextCursor, err := h.repo.ListSnippets(r.Context(), userID, r.URL.Query().Get("workspaceId"), page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list snippets")
+		writeListError(w, err, "failed to list snippets")
 		return
 	}
-	writeJSON(w, http.StatusOK, models.ListResponse[models.Snippet]{Total: len(snippets), Documents: snippets})
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Snippet]{Total: len(snippets), Documents: snippets, NextCursor: nextCursor})
 }
 
 func (h *SnippetHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -91,12 +95,16 @@ func NewActivityHandler(repo *repository.Repo) *ActivityHandler {
 
 func (h *ActivityHandler) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
-	activity, err := h.repo.ListActivity(r.Context(), userID)
+	page, ok := pageRequest(w, r, defaultPageSize)
+	if !ok {
+		return
+	}
+	activity, nextCursor, err := h.repo.ListActivity(r.Context(), userID, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list activity")
+		writeListError(w, err, "failed to list activity")
 		return
 	}
-	writeJSON(w, http.StatusOK, models.ListResponse[models.ActivityLog]{Total: len(activity), Documents: activity})
+	writeJSON(w, http.StatusOK, models.ListResponse[models.ActivityLog]{Total: len(activity), Documents: activity, NextCursor: nextCursor})
 }
 
 // ---- Vault ----
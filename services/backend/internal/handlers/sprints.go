@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
+)
+
+const (
+	maxSprintNameLength = 255
+	maxSprintTaskBatch  = 200
+)
+
+type SprintHandler struct {
+	repo *repository.Repo
+	hub  *websocket.Hub
+}
+
+func NewSprintHandler(repo *repository.Repo, hub *websocket.Hub) *SprintHandler {
+	return &SprintHandler{repo: repo, hub: hub}
+}
+
+func (h *SprintHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	projectID := chi.URLParam(r, "projectId")
+	if !ensureProjectAccess(w, r, h.repo, projectID, userID) {
+		return
+	}
+	sprints, err := h.repo.ListProjectSprints(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sprints")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Sprint]{Total: len(sprints), Documents: sprints})
+}
+
+func (h *SprintHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	projectID := chi.URLParam(r, "projectId")
+	if !ensureProjectRole(w, r, h.repo, projectID, userID, "owner", "admin", "editor") {
+		return
+	}
+	var req models.CreateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	startDate, endDate, message := dateSpan(req.StartDate, req.EndDate)
+	if message == "" {
+		message = validateSprintName(name)
+	}
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	sprint, err := h.repo.CreateSprint(r.Context(), projectID, userID, name, req.Goal, startDate, endDate)
+	if err != nil {
+		log.Printf("CreateSprint error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to create sprint")
+		return
+	}
+	h.broadcastProject(projectID, models.WSEvent{Type: "create", Collection: "project_sprints", Document: sprint, UserID: userID})
+	writeJSON(w, http.StatusCreated, sprint)
+}
+
+func (h *SprintHandler) Get(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.loadSprint(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, sprint)
+}
+
+func (h *SprintHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sprint, ok := h.loadEditableSprint(w, r)
+	if !ok {
+		return
+	}
+	var req models.UpdateSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if message := validateSprintName(name); message != "" {
+			writeError(w, http.StatusBadRequest, message)
+			return
+		}
+		req.Name = &name
+	}
+	start, end := sprint.StartDate.Format(time.DateOnly), sprint.EndDate.Format(time.DateOnly)
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	if req.EndDate != nil {
+		end = *req.EndDate
+	}
+	startDate, endDate, message := dateSpan(start, end)
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	updated, err := h.repo.UpdateSprint(r.Context(), sprint.ID, req.Name, req.Goal, &startDate, &endDate)
+	if err != nil {
+		h.writeSprintError(w, err, "failed to update sprint")
+		return
+	}
+	h.broadcastProject(updated.ProjectID, models.WSEvent{Type: "update", Collection: "project_sprints", Document: updated, UserID: userID})
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *SprintHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sprint, ok := h.loadEditableSprint(w, r)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteSprint(r.Context(), sprint.ID); err != nil {
+		h.writeSprintError(w, err, "failed to delete sprint")
+		return
+	}
+	h.broadcastProject(sprint.ProjectID, models.WSEvent{Type: "delete", Collection: "project_sprints", Document: map[string]string{"id": sprint.ID}, UserID: userID})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+func (h *SprintHandler) Start(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sprint, ok := h.loadEditableSprint(w, r)
+	if !ok {
+		return
+	}
+	started, err := h.repo.StartSprint(r.Context(), sprint.ID)
+	if err != nil {
+		h.writeSprintError(w, err, "failed to start sprint")
+		return
+	}
+	h.broadcastProject(started.ProjectID, models.WSEvent{Type: "update", Collection: "project_sprints", Document: started, UserID: userID})
+	writeJSON(w, http.StatusOK, started)
+}
+
+func (h *SprintHandler) Close(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sprint, ok := h.loadEditableSprint(w, r)
+	if !ok {
+		return
+	}
+	var req models.CloseSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	closed, err := h.repo.CloseSprint(r.Context(), sprint.ID, req.RolloverSprintID)
+	if err != nil {
+		h.writeSprintError(w, err, "failed to close sprint")
+		return
+	}
+	h.broadcastProject(closed.ProjectID, models.WSEvent{Type: "update", Collection: "project_sprints", Document: closed, UserID: userID})
+	if req.RolloverSprintID != nil {
+		h.broadcastSprint(r.Context(), *req.RolloverSprintID, userID)
+	}
+	writeJSON(w, http.StatusOK, closed)
+}
+
+func (h *SprintHandler) Report(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.loadSprint(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.repo.SprintReport(r.Context(), sprint)
+	if err != nil {
+		h.writeSprintError(w, err, "failed to build sprint report")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *SprintHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := h.loadSprint(w, r)
+	if !ok {
+		return
+	}
+	tasks, err := h.repo.ListSprintTasks(r.Context(), sprint.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list sprint tasks")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Task]{Total: len(tasks), Documents: tasks})
+}
+
+func (h *SprintHandler) AddTasks(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sprint, ok := h.loadEditableSprint(w, r)
+	if !ok {
+		return
+	}
+	var req models.SprintTasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.TaskIDs) == 0 || len(req.TaskIDs) > maxSprintTaskBatch {
+		writeError(w, http.StatusBadRequest, "taskIds must list between 1 and 200 tasks")
+		return
+	}
+	if err := h.repo.AddSprintTasks(r.Context(), sprint.ID, req.TaskIDs); err != nil {
+		h.writeSprintError(w, err, "failed to add sprint tasks")
+		return
+	}
+	// Other open sprints of the project may have lost tasks; resend them all.
+	if sprints, err := h.repo.ListProjectSprints(r.Context(), sprint.ProjectID); err == nil {
+		for i := range sprints {
+			if sprints[i].State != "closed" {
+				h.broadcastProject(sprint.ProjectID, models.WSEvent{Type: "update", Collection: "project_sprints", Document: &sprints[i], UserID: userID})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "added"})
+}
+
+func (h *SprintHandler) RemoveTask(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	sprint, ok := h.loadEditableSprint(w, r)
+	if !ok {
+		return
+	}
+	removed, err := h.repo.RemoveSprintTask(r.Context(), sprint.ID, chi.URLParam(r, "taskId"))
+	if err != nil {
+		h.writeSprintError(w, err, "failed to remove sprint task")
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "task is not in this sprint")
+		return
+	}
+	h.broadcastSprint(r.Context(), sprint.ID, userID)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "removed"})
+}
+
+// loadSprint loads the sprint of the request for a project member.
+func (h *SprintHandler) loadSprint(w http.ResponseWriter, r *http.Request) (*models.Sprint, bool) {
+	sprint, err := h.repo.GetSprint(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load sprint")
+		return nil, false
+	}
+	if sprint == nil {
+		writeError(w, http.StatusNotFound, "sprint not found")
+		return nil, false
+	}
+	allowed, err := h.repo.CanAccessProject(r.Context(), sprint.ProjectID, middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate project access")
+		return nil, false
+	}
+	if !allowed {
+		writeError(w, http.StatusNotFound, "sprint not found")
+		return nil, false
+	}
+	return sprint, ensureTokenProject(w, r, h.repo, sprint.ProjectID)
+}
+
+// loadEditableSprint loads the sprint of the request for a project editor.
+func (h *SprintHandler) loadEditableSprint(w http.ResponseWriter, r *http.Request) (*models.Sprint, bool) {
+	sprint, ok := h.loadSprint(w, r)
+	if !ok {
+		return nil, false
+	}
+	return sprint, ensureProjectRole(w, r, h.repo, sprint.ProjectID, middleware.GetUserID(r), "owner", "admin", "editor")
+}
+
+func (h *SprintHandler) writeSprintError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrSprintState):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, repository.ErrSprintTask):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
+
+func (h *SprintHandler) broadcastSprint(ctx context.Context, sprintID, userID string) {
+	sprint, err := h.repo.GetSprint(ctx, sprintID)
+	if err == nil && sprint != nil {
+		h.broadcastProject(sprint.ProjectID, models.WSEvent{Type: "update", Collection: "project_sprints", Document: sprint, UserID: userID})
+	}
+}
+
+func (h *SprintHandler) broadcastProject(projectID string, event models.WSEvent) {
+	memberIDs, err := h.repo.ListProjectMemberUserIDs(context.Background(), projectID)
+	if err == nil {
+		h.hub.BroadcastUsers(memberIDs, event)
+	}
+}
+
+func validateSprintName(name string) string {
+	if name == "" {
+		return "name is required"
+	}
+	if utf8.RuneCountInString(name) > maxSprintNameLength {
+		return "name is too long"
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSprintDates(t *testing.T) {
+	tests := []struct {
+		name        string
+		start, end  string
+		wantMessage string
+	}{
+		{name: "two weeks", start: "2026-10-19", end: "2026-10-30"},
+		{name: "single day", start: "2026-10-19", end: "2026-10-19"},
+		{name: "ends before start", start: "2026-10-19", end: "2026-10-18", wantMessage: "endDate must not be before startDate"},
+		{name: "bad start", start: "19.10.2026", end: "2026-10-30", wantMessage: "startDate must be a date such as 2026-10-20"},
+		{name: "missing end", start: "2026-10-19", wantMessage: "endDate must be a date such as 2026-10-20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, message := dateSpan(tt.start, tt.end)
+			if message != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", message, tt.wantMessage)
+			}
+			if message == "" && (start.Format(time.DateOnly) != tt.start || end.Format(time.DateOnly) != tt.end) {
+				t.Fatalf("dates = %s, %s", start, end)
+			}
+		})
+	}
+}
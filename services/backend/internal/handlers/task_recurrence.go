@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/recurrence"
+)
+
+const (
+	defaultOccurrencePreview = 5
+	maxOccurrencePreview     = 50
+)
+
+// Recurrence previews the next occurrences of a recurring task, up to limit,
+// assuming each is completed on time.
+func (h *TaskHandler) Recurrence(w http.ResponseWriter, r *http.Request) {
+	task, series, ok := h.loadRecurringTask(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultOccurrencePreview
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxOccurrencePreview {
+			writeError(w, http.StatusBadRequest, "limit must be a number from 1 to 50")
+			return
+		}
+		limit = parsed
+	}
+	writeJSON(w, http.StatusOK, models.TaskRecurrence{
+		Rule:        series.Settings,
+		Paused:      series.Settings.Paused,
+		Occurrences: series.Upcoming(recurrenceBase(task), limit),
+	})
+}
+
+// SkipOccurrence moves a recurring task to its next occurrence without
+// completing it.
+func (h *TaskHandler) SkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	task, series, ok := h.loadRecurringTask(w, r)
+	if !ok {
+		return
+	}
+	if !ensureProjectRole(w, r, h.repo, task.ProjectID, userID, "owner", "admin", "editor") {
+		return
+	}
+	if task.Completed {
+		writeError(w, http.StatusConflict, "completed tasks cannot skip an occurrence")
+		return
+	}
+	next, value, ok := series.Skip(recurrenceBase(task))
+	if !ok {
+		writeError(w, http.StatusConflict, "the recurrence has no further occurrences")
+		return
+	}
+	deadline := next.UTC().Format(time.RFC3339)
+	updated, err := h.repo.UpdateTask(r.Context(), task.ID, userID, models.UpdateTaskRequest{Deadline: &deadline, Recurrence: &value})
+	if err != nil {
+		log.Printf("SkipOccurrence error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to skip occurrence")
+		return
+	}
+	summary := "Skipped occurrence, next due " + next.Format(time.DateOnly)
+	h.repo.LogActivity(r.Context(), userID, "update", "Task", updated.Title, &updated.ProjectID, &updated.ID, &summary)
+	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), updated.ProjectID)
+	event := models.WSEvent{Type: "update", Collection: "tasks", Document: updated, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(updated.ProjectID, event)
+	h.broadcastTaskActivity(updated.ProjectID, updated.ID, userID)
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *TaskHandler) loadRecurringTask(w http.ResponseWriter, r *http.Request) (*models.Task, *recurrence.Series, bool) {
+	userID := middleware.GetUserID(r)
+	task, err := h.repo.GetTask(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		log.Printf("GetTask error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to load task")
+		return nil, nil, false
+	}
+	if task == nil {
+		writeError(w, http.StatusNotFound, "task not found")
+		return nil, nil, false
+	}
+	if !ensureTokenProject(w, r, h.repo, task.ProjectID) {
+		return nil, nil, false
+	}
+	var series *recurrence.Series
+	if task.Recurrence != nil {
+		series, err = recurrence.Parse(*task.Recurrence)
+		if err != nil {
+			writeError(w, http.StatusConflict, "invalid recurrence: "+err.Error())
+			return nil, nil, false
+		}
+	}
+	if series == nil {
+		writeError(w, http.StatusNotFound, "task does not recur")
+		return nil, nil, false
+	}
+	return task, series, true
+}
+
+// recurrenceBase is the occurrence a task stands for: its deadline, or its
+// creation time when it has none.
+func recurrenceBase(task *models.Task) time.Time {
+	if task.Deadline != nil {
+		return *task.Deadline
+	}
+	return task.CreatedAt
+}
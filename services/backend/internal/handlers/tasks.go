@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -13,13 +14,17 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/recurrence"
 	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/taskquery"
+	"github.com/justlabv1/justspace/backend/internal/webhooks"
 	"github.com/justlabv1/justspace/backend/internal/websocket"
 )
 
 type TaskHandler struct {
-	repo *repository.Repo
-	hub  *websocket.Hub
+	repo     *repository.Repo
+	hub      *websocket.Hub
+	webhooks *webhooks.Dispatcher
 }
 
 func isEncryptedEnvelope(value string) bool {
@@ -30,8 +35,8 @@ func isEncryptedEnvelope(value string) bool {
 	return json.Unmarshal([]byte(value), &envelope) == nil && envelope.Ciphertext != "" && envelope.IV != ""
 }
 
-func NewTaskHandler(repo *repository.Repo, hub *websocket.Hub) *TaskHandler {
-	return &TaskHandler{repo: repo, hub: hub}
+func NewTaskHandler(repo *repository.Repo, hub *websocket.Hub, dispatcher *webhooks.Dispatcher) *TaskHandler {
+	return &TaskHandler{repo: repo, hub: hub, webhooks: dispatcher}
 }
 
 func (h *TaskHandler) broadcastTaskActivity(projectID, taskID, actorUserID string) {
@@ -49,38 +54,30 @@ func (h *TaskHandler) broadcastTaskActivity(projectID, taskID, actorUserID strin
 	})
 }
 
-func nextRecurringDeadline(task *models.Task) *time.Time {
-	if task.Recurrence == nil || *task.Recurrence == "" {
-		return nil
-	}
-
-	var recurrence models.RecurrenceRule
-	if err := json.Unmarshal([]byte(*task.Recurrence), &recurrence); err != nil {
-		return nil
-	}
+// nextRecurrence returns the deadline and recurrence of the task that follows
+// a completed recurring task. ok is false when the task does not recur or its
+// series has ended.
+func nextRecurrence(task *models.Task, completedAt time.Time) (time.Time, string, bool) {
+	if task.Recurrence == nil {
+		return time.Time{}, "", false
+	}
+	series, err := recurrence.Parse(*task.Recurrence)
+	if err != nil || series == nil {
+		return time.Time{}, "", false
+	}
+	return series.Next(recurrenceBase(task), completedAt)
+}
 
-	if recurrence.Interval <= 0 {
-		recurrence.Interval = 1
+// validRecurrence rejects a recurrence the task could not follow.
+func validRecurrence(w http.ResponseWriter, value *string) bool {
+	if value == nil {
+		return true
 	}
-
-	base := task.CreatedAt
-	if task.Deadline != nil {
-		base = *task.Deadline
+	if _, err := recurrence.Parse(*value); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid recurrence: "+err.Error())
+		return false
 	}
-
-	var next time.Time
-	switch recurrence.Type {
-	case "daily":
-		next = base.AddDate(0, 0, recurrence.Interval)
-	case "weekly":
-		next = base.AddDate(0, 0, 7*recurrence.Interval)
-	case "monthly":
-		next = base.AddDate(0, recurrence.Interval, 0)
-	default:
-		return nil
-	}
-
-	return &next
+	return true
 }
 
 func (h *TaskHandler) ListByProject(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +86,15 @@ func (h *TaskHandler) ListByProject(w http.ResponseWriter, r *http.Request) {
 	if !ensureProjectAccess(w, r, h.repo, projectID, userID) {
 		return
 	}
+	query, paged, ok := taskPageQuery(w, r, "order")
+	if !ok {
+		return
+	}
+	if paged {
+		query.ProjectID = projectID
+		h.writeTaskPage(w, r, userID, query)
+		return
+	}
 	tasks, err := h.repo.ListTasks(r.Context(), projectID, userID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to list tasks")
@@ -127,6 +133,9 @@ func (h *TaskHandler) Get(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "task not found")
 		return
 	}
+	if !ensureTokenProject(w, r, h.repo, task.ProjectID) {
+		return
+	}
 	writeJSON(w, http.StatusOK, task)
 }
 
@@ -140,7 +149,19 @@ func (h *TaskHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 	}
 	sortByDeadline := r.URL.Query().Get("sort") == "deadline"
 	openOnly := r.URL.Query().Get("openOnly") == "true"
-	workspaceID := r.URL.Query().Get("workspaceId")
+	workspaceID, ok := tokenWorkspaceFilter(w, r)
+	if !ok {
+		return
+	}
+	query, paged, ok := taskPageQuery(w, r, "created")
+	if !ok {
+		return
+	}
+	if paged {
+		query.WorkspaceID, query.OpenOnly = workspaceID, openOnly
+		h.writeTaskPage(w, r, userID, query)
+		return
+	}
 	tasks, err := h.repo.ListAllTasks(r.Context(), userID, limit, sortByDeadline, openOnly, workspaceID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to list tasks")
@@ -149,6 +170,45 @@ func (h *TaskHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, models.ListResponse[models.Task]{Total: len(tasks), Documents: tasks})
 }
 
+// taskPageQuery reads the paged form of the task list endpoints, which a
+// request selects by sending q, even empty, or cursor. q is parsed with
+// taskquery; sort, limit, and cursor page through the matches.
+func taskPageQuery(w http.ResponseWriter, r *http.Request, defaultSort string) (models.TaskListQuery, bool, bool) {
+	params := r.URL.Query()
+	if !params.Has("q") && !params.Has("cursor") {
+		return models.TaskListQuery{}, false, true
+	}
+	query := models.TaskListQuery{
+		Sort:   cmp.Or(params.Get("sort"), defaultSort),
+		Cursor: params.Get("cursor"),
+	}
+	if !repository.ValidTaskSort(query.Sort) {
+		writeError(w, http.StatusBadRequest, "sort must be order, created, updated, or deadline")
+		return query, true, false
+	}
+	limit, ok := pageLimit(w, r, defaultPageSize)
+	if !ok {
+		return query, true, false
+	}
+	query.Limit = limit
+	filter, err := taskquery.Parse(params.Get("q"), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid filter: "+err.Error())
+		return query, true, false
+	}
+	query.Filter = filter
+	return query, true, true
+}
+
+func (h *TaskHandler) writeTaskPage(w http.ResponseWriter, r *http.Request, userID string, query models.TaskListQuery) {
+	tasks, nextCursor, err := h.repo.ListTasksPage(r.Context(), userID, query)
+	if err != nil {
+		writeListError(w, err, "failed to list tasks")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Task]{Total: len(tasks), Documents: tasks, NextCursor: nextCursor})
+}
+
 func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	var req models.CreateTaskRequest
@@ -159,6 +219,9 @@ func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if !ensureProjectRole(w, r, h.repo, req.ProjectID, userID, "owner", "admin", "editor") {
 		return
 	}
+	if !validRecurrence(w, req.Recurrence) {
+		return
+	}
 	task, err := h.repo.CreateTask(r.Context(), userID, req)
 	if err != nil {
 		log.Printf("CreateTask error: %v", err)
@@ -171,7 +234,9 @@ func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	h.repo.LogActivity(r.Context(), userID, "create", "Task", task.Title, &task.ProjectID, &task.ID, nil)
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), task.ProjectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "create", Collection: "tasks", Document: task, UserID: userID})
+	event := models.WSEvent{Type: "create", Collection: "tasks", Document: task, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(task.ProjectID, event)
 	if activity, err := h.repo.ListProjectActivity(r.Context(), task.ProjectID, 25); err == nil {
 		h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_activity", Document: activity, UserID: userID})
 	}
@@ -198,7 +263,9 @@ func (h *TaskHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
 	name := strconv.Itoa(len(tasks)) + " tasks"
 	h.repo.LogActivity(r.Context(), userID, "create", "Task", name, &req.ProjectID, nil, nil)
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), req.ProjectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "create", Collection: "tasks", Document: tasks, UserID: userID})
+	event := models.WSEvent{Type: "create", Collection: "tasks", Document: tasks, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(req.ProjectID, event)
 	if activity, err := h.repo.ListProjectActivity(r.Context(), req.ProjectID, 25); err == nil {
 		h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_activity", Document: activity, UserID: userID})
 	}
@@ -218,11 +285,17 @@ func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "task not found")
 		return
 	}
+	if !ensureTokenProject(w, r, h.repo, existingTask.ProjectID) {
+		return
+	}
 	var req models.UpdateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	if !validRecurrence(w, req.Recurrence) {
+		return
+	}
 	project, projectErr := h.repo.GetProject(r.Context(), existingTask.ProjectID, userID)
 	if projectErr != nil || project == nil {
 		writeError(w, http.StatusNotFound, "project not found")
@@ -278,6 +351,27 @@ func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
 		completed := status.IsCompletedState
 		req.Completed = &completed
 	}
+	// Tracked time lives in time entries. The legacy timer flag starts or
+	// stops the caller's timer; UpdateTask ignores the derived totals.
+	stoppedTimer := false
+	if req.IsTimerRunning != nil {
+		if !ensureProjectRole(w, r, h.repo, existingTask.ProjectID, userID, "owner", "admin", "editor") {
+			return
+		}
+		var timerErr error
+		if *req.IsTimerRunning {
+			_, _, timerErr = startTimer(r.Context(), h.repo, h.hub, userID, id, "", true)
+		} else {
+			var stopped *models.TimeEntry
+			stopped, timerErr = stopTimer(r.Context(), h.repo, h.hub, userID, id)
+			stoppedTimer = stopped != nil
+		}
+		if timerErr != nil {
+			log.Printf("task timer error: %v", timerErr)
+			writeError(w, http.StatusInternalServerError, "failed to update timer")
+			return
+		}
+	}
 
 	task, err := h.repo.UpdateTask(r.Context(), id, userID, req)
 	if err != nil {
@@ -288,14 +382,18 @@ func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
 	activitySummary := summarizeTaskUpdate(existingTask, task, req)
 
 	if req.Completed != nil && *req.Completed && !existingTask.Completed && task.ParentID == nil {
-		if nextDeadline := nextRecurringDeadline(task); nextDeadline != nil {
-			nextTask, recurringErr := h.repo.CreateRecurringTask(r.Context(), userID, *task, *nextDeadline)
+		if nextDeadline, nextRecurrenceValue, ok := nextRecurrence(task, time.Now()); ok {
+			source := *task
+			source.Recurrence = &nextRecurrenceValue
+			nextTask, recurringErr := h.repo.CreateRecurringTask(r.Context(), userID, source, nextDeadline)
 			if recurringErr != nil {
 				log.Printf("CreateRecurringTask error: %v", recurringErr)
 			} else {
 				h.repo.LogActivity(r.Context(), userID, "create", "Task", nextTask.Title, &nextTask.ProjectID, &nextTask.ID, nil)
 				memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), nextTask.ProjectID)
-				h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "create", Collection: "tasks", Document: nextTask, UserID: userID})
+				event := models.WSEvent{Type: "create", Collection: "tasks", Document: nextTask, UserID: userID}
+				h.hub.BroadcastUsers(memberIDs, event)
+				h.webhooks.Emit(nextTask.ProjectID, event)
 				h.broadcastTaskActivity(nextTask.ProjectID, nextTask.ID, userID)
 			}
 		}
@@ -303,14 +401,16 @@ func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	if req.Completed != nil && *req.Completed && !existingTask.Completed {
 		h.repo.LogActivity(r.Context(), userID, "complete", "Task", task.Title, &task.ProjectID, &task.ID, activitySummary)
-	} else if req.IsTimerRunning != nil && !*req.IsTimerRunning && req.WorkDuration != nil {
+	} else if stoppedTimer && req.WorkDuration != nil {
 		workSummary := fmt.Sprintf("Logged %s of work", *req.WorkDuration)
 		h.repo.LogActivity(r.Context(), userID, "work", "Task", task.Title, &task.ProjectID, &task.ID, &workSummary)
 	} else if activitySummary != nil {
 		h.repo.LogActivity(r.Context(), userID, "update", "Task", task.Title, &task.ProjectID, &task.ID, activitySummary)
 	}
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), task.ProjectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "tasks", Document: task, UserID: userID})
+	event := models.WSEvent{Type: "update", Collection: "tasks", Document: task, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(task.ProjectID, event)
 	if activity, err := h.repo.ListProjectActivity(r.Context(), task.ProjectID, 25); err == nil {
 		h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_activity", Document: activity, UserID: userID})
 	}
@@ -372,13 +472,18 @@ func (h *TaskHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "task not found")
 		return
 	}
+	if !ensureTokenProject(w, r, h.repo, existingTask.ProjectID) {
+		return
+	}
 	if err := h.repo.DeleteTask(r.Context(), id, userID); err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to delete task")
 		return
 	}
 	h.repo.LogActivity(r.Context(), userID, "delete", "Task", "Task", &existingTask.ProjectID, &existingTask.ID, nil)
 	memberIDs, _ := h.repo.ListProjectMemberUserIDs(r.Context(), existingTask.ProjectID)
-	h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "delete", Collection: "tasks", Document: map[string]string{"id": id, "projectId": existingTask.ProjectID}, UserID: userID})
+	event := models.WSEvent{Type: "delete", Collection: "tasks", Document: map[string]string{"id": id, "projectId": existingTask.ProjectID}, UserID: userID}
+	h.hub.BroadcastUsers(memberIDs, event)
+	h.webhooks.Emit(existingTask.ProjectID, event)
 	if activity, err := h.repo.ListProjectActivity(r.Context(), existingTask.ProjectID, 25); err == nil {
 		h.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_activity", Document: activity, UserID: userID})
 	}
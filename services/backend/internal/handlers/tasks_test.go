@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTaskPageQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantPaged  bool
+		wantSort   string
+		wantLimit  int
+		wantTerms  int
+		wantStatus int
+	}{
+		{name: "legacy list", query: "limit=10&sort=deadline", wantPaged: false},
+		{name: "empty filter pages", query: "q=", wantPaged: true, wantSort: "order", wantLimit: defaultPageSize},
+		{name: "cursor pages", query: "cursor=abc&sort=created&limit=20", wantPaged: true, wantSort: "created", wantLimit: 20},
+		{name: "filter", query: "q=status:todo+-blocked", wantPaged: true, wantSort: "order", wantLimit: defaultPageSize, wantTerms: 2},
+		{name: "limit capped", query: "q=&limit=5000", wantPaged: true, wantSort: "order", wantLimit: maxPageSize},
+		{name: "invalid filter", query: "q=priority:critical", wantStatus: http.StatusBadRequest},
+		{name: "invalid sort", query: "q=&sort=title", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "q=&limit=0", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/projects/p1/tasks?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			query, paged, ok := taskPageQuery(rec, req, "order")
+			if tt.wantStatus != 0 {
+				if ok || rec.Code != tt.wantStatus {
+					t.Fatalf("ok = %v, status = %d, want status %d", ok, rec.Code, tt.wantStatus)
+				}
+				return
+			}
+			if !ok || paged != tt.wantPaged {
+				t.Fatalf("paged, ok = %v, %v, want %v, true", paged, ok, tt.wantPaged)
+			}
+			if !paged {
+				return
+			}
+			if query.Sort != tt.wantSort || query.Limit != tt.wantLimit || len(query.Filter.Terms) != tt.wantTerms {
+				t.Fatalf("query = %+v, want sort %q, limit %d, %d terms", query, tt.wantSort, tt.wantLimit, tt.wantTerms)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
+)
+
+const (
+	maxTimeEntryNoteLength = 2000
+	maxTimeEntryDuration   = 24 * time.Hour
+)
+
+type TimeEntryHandler struct {
+	repo *repository.Repo
+	hub  *websocket.Hub
+}
+
+func NewTimeEntryHandler(repo *repository.Repo, hub *websocket.Hub) *TimeEntryHandler {
+	return &TimeEntryHandler{repo: repo, hub: hub}
+}
+
+// List returns the time entries in the user's projects whose start falls
+// between the from and to days, the last seven days by default. projectId,
+// workspaceId, and userId ("me" for the caller) narrow the list.
+func (h *TimeEntryHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	query := r.URL.Query()
+	workspaceID, ok := tokenWorkspaceFilter(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := dayRange(query.Get("from"), query.Get("to"), 6, time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+	filter := models.TimeEntryQuery{WorkspaceID: workspaceID, ProjectID: query.Get("projectId"), UserID: query.Get("userId"), From: &from, To: &end}
+	if filter.ProjectID != "" && !ensureProjectAccess(w, r, h.repo, filter.ProjectID, userID) {
+		return
+	}
+	if filter.UserID == "me" {
+		filter.UserID = userID
+	}
+	entries, err := h.repo.ListTimeEntries(r.Context(), userID, filter)
+	if err != nil {
+		log.Printf("ListTimeEntries error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to list time entries")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.TimeEntry]{Total: len(entries), Documents: entries})
+}
+
+func (h *TimeEntryHandler) ListByTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := ensureTaskAccess(w, r, h.repo, chi.URLParam(r, "taskId"), middleware.GetUserID(r))
+	if !ok {
+		return
+	}
+	entries, err := h.repo.ListTaskTimeEntries(r.Context(), task.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list time entries")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.TimeEntry]{Total: len(entries), Documents: entries})
+}
+
+// CreateForTask records finished work by the caller on a task.
+func (h *TimeEntryHandler) CreateForTask(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	task, ok := h.loadEditableTask(w, r, chi.URLParam(r, "taskId"), userID)
+	if !ok {
+		return
+	}
+	var req models.CreateTimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.StartedAt.IsZero() {
+		writeError(w, http.StatusBadRequest, "startedAt is required")
+		return
+	}
+	endedAt, message := timeEntryEnd(req.StartedAt, req.EndedAt, req.DurationSeconds, time.Now())
+	if message == "" {
+		message = validateTimeEntryNote(&req.Note)
+	}
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	billable := req.Billable == nil || *req.Billable
+	entry, err := h.repo.CreateTimeEntry(r.Context(), userID, task.ID, req.StartedAt, endedAt, req.Note, billable)
+	if err != nil {
+		log.Printf("CreateTimeEntry error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to create time entry")
+		return
+	}
+	broadcastTimeEntry(r.Context(), h.repo, h.hub, "create", entry, userID)
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// Running returns the caller's running timer, or null.
+func (h *TimeEntryHandler) Running(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.repo.GetRunningTimeEntry(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load running timer")
+		return
+	}
+	// Timers in workspaces outside a token's scope stay hidden from it.
+	if scope := middleware.GetTokenScope(r); entry != nil && scope != nil && len(scope.WorkspaceIDs) > 0 {
+		workspaceID, err := h.repo.GetProjectWorkspaceID(r.Context(), entry.ProjectID)
+		if err != nil || !scope.AllowsWorkspace(workspaceID) {
+			entry = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"running": entry})
+}
+
+// Start starts a timer for the caller, stopping the one already running.
+func (h *TimeEntryHandler) Start(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	var req models.StartTimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.TaskID == "" {
+		writeError(w, http.StatusBadRequest, "taskId is required")
+		return
+	}
+	if message := validateTimeEntryNote(&req.Note); message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	task, ok := h.loadEditableTask(w, r, req.TaskID, userID)
+	if !ok {
+		return
+	}
+	started, stopped, err := startTimer(r.Context(), h.repo, h.hub, userID, task.ID, req.Note, req.Billable == nil || *req.Billable)
+	if err != nil {
+		log.Printf("StartTimer error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to start timer")
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"started": started, "stopped": stopped})
+}
+
+// Stop stops the caller's running timer.
+func (h *TimeEntryHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	running, err := h.repo.GetRunningTimeEntry(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load running timer")
+		return
+	}
+	if running == nil {
+		writeError(w, http.StatusNotFound, "no timer is running")
+		return
+	}
+	if !ensureTokenProject(w, r, h.repo, running.ProjectID) {
+		return
+	}
+	stopped, err := stopTimer(r.Context(), h.repo, h.hub, userID, running.TaskID)
+	if err != nil {
+		log.Printf("StopTimer error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to stop timer")
+		return
+	}
+	if stopped == nil {
+		writeError(w, http.StatusNotFound, "no timer is running")
+		return
+	}
+	writeJSON(w, http.StatusOK, stopped)
+}
+
+func (h *TimeEntryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	entry, ok := h.loadEditableEntry(w, r, userID)
+	if !ok {
+		return
+	}
+	var req models.UpdateTimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	now := time.Now()
+	startedAt, endedAt := entry.StartedAt, entry.EndedAt
+	if req.StartedAt != nil {
+		startedAt = *req.StartedAt
+	}
+	message := ""
+	switch {
+	case req.EndedAt != nil || req.DurationSeconds != nil:
+		var end time.Time
+		end, message = timeEntryEnd(startedAt, req.EndedAt, req.DurationSeconds, now)
+		endedAt = &end
+	case endedAt != nil && req.StartedAt != nil:
+		_, message = timeEntryEnd(startedAt, endedAt, nil, now)
+	case endedAt == nil && startedAt.After(now):
+		message = "startedAt cannot be in the future"
+	}
+	note, billable := entry.Note, entry.Billable
+	if req.Note != nil {
+		note = *req.Note
+	}
+	if req.Billable != nil {
+		billable = *req.Billable
+	}
+	if message == "" {
+		message = validateTimeEntryNote(&note)
+	}
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	updated, err := h.repo.UpdateTimeEntry(r.Context(), entry.ID, startedAt, endedAt, note, billable)
+	if err != nil {
+		log.Printf("UpdateTimeEntry error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to update time entry")
+		return
+	}
+	if updated == nil {
+		writeError(w, http.StatusNotFound, "time entry not found")
+		return
+	}
+	broadcastTimeEntry(r.Context(), h.repo, h.hub, "update", updated, userID)
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *TimeEntryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	entry, ok := h.loadEditableEntry(w, r, userID)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteTimeEntry(r.Context(), entry.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete time entry")
+		return
+	}
+	broadcastTimeEntry(r.Context(), h.repo, h.hub, "delete", entry, userID)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// loadEditableTask loads a task the user may log time on.
+func (h *TimeEntryHandler) loadEditableTask(w http.ResponseWriter, r *http.Request, taskID, userID string) (*models.Task, bool) {
+	task, ok := ensureTaskAccess(w, r, h.repo, taskID, userID)
+	if !ok {
+		return nil, false
+	}
+	return task, ensureProjectRole(w, r, h.repo, task.ProjectID, userID, "owner", "admin", "editor")
+}
+
+// loadEditableEntry loads the entry of the request. Editors change their own
+// entries; project owners and admins change everyone's.
+func (h *TimeEntryHandler) loadEditableEntry(w http.ResponseWriter, r *http.Request, userID string) (*models.TimeEntry, bool) {
+	entry, err := h.repo.GetTimeEntry(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load time entry")
+		return nil, false
+	}
+	if entry != nil {
+		allowed, err := h.repo.CanAccessProject(r.Context(), entry.ProjectID, userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to validate project access")
+			return nil, false
+		}
+		if !allowed {
+			entry = nil
+		}
+	}
+	if entry == nil {
+		writeError(w, http.StatusNotFound, "time entry not found")
+		return nil, false
+	}
+	if !ensureTokenProject(w, r, h.repo, entry.ProjectID) {
+		return nil, false
+	}
+	if entry.UserID == userID {
+		return entry, ensureProjectRole(w, r, h.repo, entry.ProjectID, userID, "owner", "admin", "editor")
+	}
+	return entry, ensureProjectRole(w, r, h.repo, entry.ProjectID, userID, "owner", "admin")
+}
+
+// startTimer starts the user's timer on a task and announces both the new
+// timer and the one it stopped.
+func startTimer(ctx context.Context, repo *repository.Repo, hub *websocket.Hub, userID, taskID, note string, billable bool) (*models.TimeEntry, *models.TimeEntry, error) {
+	started, stopped, err := repo.StartTimer(ctx, userID, taskID, note, billable)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stopped != nil {
+		broadcastTimeEntry(ctx, repo, hub, "update", stopped, userID)
+	}
+	broadcastTimeEntry(ctx, repo, hub, "create", started, userID)
+	return started, stopped, nil
+}
+
+// stopTimer stops the user's running timer, if it runs on taskID when taskID
+// is set, and announces it.
+func stopTimer(ctx context.Context, repo *repository.Repo, hub *websocket.Hub, userID, taskID string) (*models.TimeEntry, error) {
+	stopped, err := repo.StopTimer(ctx, userID, taskID)
+	if err != nil || stopped == nil {
+		return nil, err
+	}
+	broadcastTimeEntry(ctx, repo, hub, "update", stopped, userID)
+	return stopped, nil
+}
+
+// broadcastTimeEntry sends a time entry change and the task whose tracked
+// totals it changed to the project's members.
+func broadcastTimeEntry(ctx context.Context, repo *repository.Repo, hub *websocket.Hub, eventType string, entry *models.TimeEntry, userID string) {
+	memberIDs, err := repo.ListProjectMemberUserIDs(ctx, entry.ProjectID)
+	if err != nil {
+		return
+	}
+	var document any = entry
+	if eventType == "delete" {
+		document = map[string]string{"id": entry.ID, "taskId": entry.TaskID}
+	}
+	hub.BroadcastUsers(memberIDs, models.WSEvent{Type: eventType, Collection: "time_entries", Document: document, UserID: userID})
+	if task, err := repo.GetTask(ctx, entry.TaskID, userID); err == nil && task != nil {
+		hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "tasks", Document: task, UserID: userID})
+	}
+}
+
+// timeEntryEnd returns the end of finished work that starts at startedAt and
+// lasts until endedAt or for durationSeconds, exactly one of which is set,
+// or an error message.
+func timeEntryEnd(startedAt time.Time, endedAt *time.Time, durationSeconds *int, now time.Time) (time.Time, string) {
+	var end time.Time
+	switch {
+	case endedAt != nil && durationSeconds != nil:
+		return time.Time{}, "send either endedAt or durationSeconds"
+	case endedAt != nil:
+		end = *endedAt
+	case durationSeconds != nil:
+		end = startedAt.Add(time.Duration(*durationSeconds) * time.Second)
+	default:
+		return time.Time{}, "endedAt or durationSeconds is required"
+	}
+	if !end.After(startedAt) {
+		return time.Time{}, "time entry must end after it starts"
+	}
+	if end.After(now) {
+		return time.Time{}, "time entry cannot end in the future"
+	}
+	if end.Sub(startedAt) > maxTimeEntryDuration {
+		return time.Time{}, "time entry cannot be longer than 24 hours"
+	}
+	return end, ""
+}
+
+func validateTimeEntryNote(note *string) string {
+	*note = strings.TrimSpace(*note)
+	if utf8.RuneCountInString(*note) > maxTimeEntryNoteLength {
+		return "note is too long"
+	}
+	return ""
+}
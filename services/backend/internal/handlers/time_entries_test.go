@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeEntryEnd(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	at := func(value time.Time) *time.Time { return &value }
+	seconds := func(value int) *int { return &value }
+	tests := []struct {
+		name            string
+		endedAt         *time.Time
+		durationSeconds *int
+		want            time.Time
+		wantMessage     string
+	}{
+		{name: "end time", endedAt: at(start.Add(90 * time.Minute)), want: start.Add(90 * time.Minute)},
+		{name: "duration", durationSeconds: seconds(5400), want: start.Add(90 * time.Minute)},
+		{name: "full day", durationSeconds: seconds(86400), want: start.Add(24 * time.Hour)},
+		{name: "neither", wantMessage: "endedAt or durationSeconds is required"},
+		{name: "both", endedAt: at(start.Add(time.Hour)), durationSeconds: seconds(3600), wantMessage: "send either endedAt or durationSeconds"},
+		{name: "zero duration", durationSeconds: seconds(0), wantMessage: "time entry must end after it starts"},
+		{name: "ends before start", endedAt: at(start.Add(-time.Minute)), wantMessage: "time entry must end after it starts"},
+		{name: "too long", durationSeconds: seconds(86401), wantMessage: "time entry cannot be longer than 24 hours"},
+		{name: "future", endedAt: at(now.Add(time.Minute)), wantMessage: "time entry cannot end in the future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, message := timeEntryEnd(start, tt.endedAt, tt.durationSeconds, now)
+			if message != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", message, tt.wantMessage)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("end = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTimeEntryNote(t *testing.T) {
+	note := "  Reviewed the migration  "
+	if message := validateTimeEntryNote(&note); message != "" || note != "Reviewed the migration" {
+		t.Fatalf("validateTimeEntryNote = %q, note %q", message, note)
+	}
+	long := strings.Repeat("ä", maxTimeEntryNoteLength+1)
+	if message := validateTimeEntryNote(&long); message != "note is too long" {
+		t.Fatalf("long note message = %q", message)
+	}
+}
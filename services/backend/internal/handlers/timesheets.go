@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+const (
+	// budgetForecastWeeks is the window whose pace budget forecasts extend.
+	budgetForecastWeeks = 4
+	// maxForecastDays drops forecasts too far out to be meaningful.
+	maxForecastDays = 10 * 366
+)
+
+var (
+	// timesheetGroupings lists the timesheet dimensions in column order.
+	timesheetGroupings   = []string{"customer", "project", "member", "week"}
+	timesheetColumnNames = map[string]string{"customer": "Customer", "project": "Project", "member": "Member", "week": "Week"}
+)
+
+// Timesheet sums the logged time of a consulting workspace between the from
+// and to days, the last 30 by default, grouped by the comma-separated groupBy
+// dimensions.
+func (h *CustomerHandler) Timesheet(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !h.ensureConsulting(w, r, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	format, ok := reportFormat(w, r)
+	if !ok {
+		return
+	}
+	params := r.URL.Query()
+	from, to, message := dayRange(params.Get("from"), params.Get("to"), 29, time.Now())
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	groupBy, message := timesheetGroupBy(params.Get("groupBy"))
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	query := models.TimesheetQuery{
+		GroupBy:    groupBy,
+		CustomerID: params.Get("customerId"),
+		ProjectID:  params.Get("projectId"),
+		UserID:     params.Get("userId"),
+		From:       from,
+		To:         to.AddDate(0, 0, 1),
+	}
+	if value := params.Get("billable"); value != "" {
+		billable, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "billable must be true or false")
+			return
+		}
+		query.Billable = &billable
+	}
+	rows, err := h.repo.Timesheet(r.Context(), workspaceID, query)
+	if err != nil {
+		log.Printf("Timesheet error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to build timesheet")
+		return
+	}
+	sheet := models.Timesheet{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly), GroupBy: groupBy, Rows: rows}
+	for _, row := range rows {
+		sheet.Hours += row.Hours
+		sheet.BillableHours += row.BillableHours
+	}
+	if format == "json" {
+		writeJSON(w, http.StatusOK, sheet)
+		return
+	}
+	writeCSV(w, "timesheet-"+sheet.From+"-"+sheet.To+".csv", format == "excel", timesheetRecords(sheet))
+}
+
+// BudgetReport compares the logged time of the workspace's customer and
+// budgeted projects with their hour budgets.
+func (h *CustomerHandler) BudgetReport(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !h.ensureConsulting(w, r, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	format, ok := reportFormat(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	budgets, err := h.repo.ListProjectBudgets(r.Context(), workspaceID, r.URL.Query().Get("customerId"), now.AddDate(0, 0, -7*budgetForecastWeeks))
+	if err != nil {
+		log.Printf("ListProjectBudgets error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to build budget report")
+		return
+	}
+	for i := range budgets {
+		forecastBudget(&budgets[i], now)
+	}
+	if format == "json" {
+		writeJSON(w, http.StatusOK, models.ListResponse[models.ProjectBudget]{Total: len(budgets), Documents: budgets})
+		return
+	}
+	writeCSV(w, "budgets-"+now.UTC().Format(time.DateOnly)+".csv", format == "excel", budgetRecords(budgets))
+}
+
+// timesheetGroupBy parses a comma-separated list of timesheet dimensions into
+// column order. It defaults to project, member, and week.
+func timesheetGroupBy(value string) ([]string, string) {
+	if strings.TrimSpace(value) == "" {
+		return []string{"project", "member", "week"}, ""
+	}
+	requested := map[string]bool{}
+	for _, dimension := range strings.Split(value, ",") {
+		dimension = strings.TrimSpace(dimension)
+		if !slices.Contains(timesheetGroupings, dimension) {
+			return nil, "groupBy must list customer, project, member, or week"
+		}
+		requested[dimension] = true
+	}
+	groupBy := []string{}
+	for _, dimension := range timesheetGroupings {
+		if requested[dimension] {
+			groupBy = append(groupBy, dimension)
+		}
+	}
+	return groupBy, ""
+}
+
+// forecastBudget fills in the remaining hours, burn percentage, and the day
+// the budget runs out at the pace of the last budgetForecastWeeks weeks.
+func forecastBudget(budget *models.ProjectBudget, now time.Time) {
+	if budget.HourBudget == nil || *budget.HourBudget <= 0 {
+		return
+	}
+	remaining := *budget.HourBudget - budget.LoggedHours
+	burn := budget.LoggedHours / *budget.HourBudget * 100
+	budget.RemainingHours, budget.BurnPercent = &remaining, &burn
+	if remaining <= 0 || budget.RecentHours <= 0 {
+		return
+	}
+	days := math.Ceil(remaining / (budget.RecentHours / (7 * budgetForecastWeeks)))
+	if days > maxForecastDays {
+		return
+	}
+	exhaustion := now.UTC().AddDate(0, 0, int(days)).Format(time.DateOnly)
+	budget.ForecastExhaustion = &exhaustion
+}
+
+func timesheetRecords(sheet models.Timesheet) [][]string {
+	header := []string{}
+	for _, dimension := range sheet.GroupBy {
+		header = append(header, timesheetColumnNames[dimension])
+	}
+	records := [][]string{append(header, "Hours", "Billable hours", "Entries")}
+	total := 0
+	for _, row := range sheet.Rows {
+		record := []string{}
+		for _, dimension := range sheet.GroupBy {
+			switch dimension {
+			case "customer":
+				record = append(record, csvText(*row.CustomerName))
+			case "project":
+				record = append(record, exportProjectName(*row.ProjectName, row.ProjectEncrypted))
+			case "member":
+				record = append(record, csvText(*row.UserName))
+			case "week":
+				record = append(record, *row.WeekStart)
+			}
+		}
+		records = append(records, append(record, formatHours(row.Hours), formatHours(row.BillableHours), strconv.Itoa(row.Entries)))
+		total += row.Entries
+	}
+	totals := make([]string, len(sheet.GroupBy))
+	if len(totals) > 0 {
+		totals[0] = "Total"
+	}
+	return append(records, append(totals, formatHours(sheet.Hours), formatHours(sheet.BillableHours), strconv.Itoa(total)))
+}
+
+func budgetRecords(budgets []models.ProjectBudget) [][]string {
+	records := [][]string{{"Customer", "Project", "Budget hours", "Logged hours", "Billable hours", "Remaining hours", "Burn %", "Hours last 4 weeks", "Forecast exhaustion"}}
+	optional := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return formatHours(*value)
+	}
+	for _, budget := range budgets {
+		customer, exhaustion := "", ""
+		if budget.CustomerName != nil {
+			customer = *budget.CustomerName
+		}
+		if budget.ForecastExhaustion != nil {
+			exhaustion = *budget.ForecastExhaustion
+		}
+		records = append(records, []string{
+			csvText(customer), exportProjectName(budget.ProjectName, budget.ProjectEncrypted), optional(budget.HourBudget), formatHours(budget.LoggedHours), formatHours(budget.BillableHours),
+			optional(budget.RemainingHours), optional(budget.BurnPercent), formatHours(budget.RecentHours), exhaustion,
+		})
+	}
+	return records
+}
+
+// exportProjectName is the name of a project in exports. Names of encrypted
+// projects are ciphertext that only clients can decrypt, so they are replaced.
+func exportProjectName(name string, encrypted bool) string {
+	if encrypted {
+		return "Encrypted project"
+	}
+	return csvText(name)
+}
+
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(hours, 'f', 2, 64)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+func TestTimesheetGroupBy(t *testing.T) {
+	tests := []struct {
+		value       string
+		want        []string
+		wantMessage string
+	}{
+		{value: "", want: []string{"project", "member", "week"}},
+		{value: "week, customer", want: []string{"customer", "week"}},
+		{value: "member,member", want: []string{"member"}},
+		{value: "project,task", wantMessage: "groupBy must list customer, project, member, or week"},
+		{value: "project,", wantMessage: "groupBy must list customer, project, member, or week"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, message := timesheetGroupBy(tt.value)
+			if message != tt.wantMessage || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("timesheetGroupBy(%q) = %v, %q, want %v, %q", tt.value, got, message, tt.want, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestForecastBudget(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC)
+	hours := func(value float64) *float64 { return &value }
+	tests := []struct {
+		name          string
+		budget        models.ProjectBudget
+		wantRemaining *float64
+		wantBurn      *float64
+		wantForecast  string
+	}{
+		{name: "no budget", budget: models.ProjectBudget{LoggedHours: 10, RecentHours: 10}},
+		{name: "on pace", budget: models.ProjectBudget{HourBudget: hours(100), LoggedHours: 40, RecentHours: 28}, wantRemaining: hours(60), wantBurn: hours(40), wantForecast: "2026-12-16"},
+		{name: "partial day rounds up", budget: models.ProjectBudget{HourBudget: hours(100), LoggedHours: 99, RecentHours: 56}, wantRemaining: hours(1), wantBurn: hours(99), wantForecast: "2026-10-18"},
+		{name: "no recent time", budget: models.ProjectBudget{HourBudget: hours(50), LoggedHours: 25}, wantRemaining: hours(25), wantBurn: hours(50)},
+		{name: "exhausted", budget: models.ProjectBudget{HourBudget: hours(20), LoggedHours: 30, RecentHours: 10}, wantRemaining: hours(-10), wantBurn: hours(150)},
+		{name: "too slow to forecast", budget: models.ProjectBudget{HourBudget: hours(10000), LoggedHours: 1, RecentHours: 0.5}, wantRemaining: hours(9999), wantBurn: hours(0.01)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := tt.budget
+			forecastBudget(&budget, now)
+			if !reflect.DeepEqual(budget.RemainingHours, tt.wantRemaining) || !reflect.DeepEqual(budget.BurnPercent, tt.wantBurn) {
+				t.Fatalf("remaining, burn = %v, %v", deref(budget.RemainingHours), deref(budget.BurnPercent))
+			}
+			forecast := ""
+			if budget.ForecastExhaustion != nil {
+				forecast = *budget.ForecastExhaustion
+			}
+			if forecast != tt.wantForecast {
+				t.Fatalf("forecast = %q, want %q", forecast, tt.wantForecast)
+			}
+		})
+	}
+}
+
+func deref(value *float64) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func TestTimesheetRecords(t *testing.T) {
+	text := func(value string) *string { return &value }
+	sheet := models.Timesheet{
+		GroupBy:       []string{"customer", "week"},
+		Hours:         3.5,
+		BillableHours: 2,
+		Rows: []models.TimesheetRow{
+			{CustomerName: text("Acme"), WeekStart: text("2026-10-12"), Hours: 1.5, BillableHours: 1.5, Entries: 2},
+			{CustomerName: text("=HYPERLINK(\"x\")"), WeekStart: text("2026-10-12"), Hours: 2, BillableHours: 0.5, Entries: 1},
+		},
+	}
+	want := [][]string{
+		{"Customer", "Week", "Hours", "Billable hours", "Entries"},
+		{"Acme", "2026-10-12", "1.50", "1.50", "2"},
+		{"'=HYPERLINK(\"x\")", "2026-10-12", "2.00", "0.50", "1"},
+		{"Total", "", "3.50", "2.00", "3"},
+	}
+	if got := timesheetRecords(sheet); !reflect.DeepEqual(got, want) {
+		t.Fatalf("timesheetRecords = %q, want %q", got, want)
+	}
+}
+
+func TestBudgetRecords(t *testing.T) {
+	customer, budget := "Acme", 10.0
+	budgets := []models.ProjectBudget{
+		{ProjectName: "Website", CustomerName: &customer, HourBudget: &budget, LoggedHours: 4, RecentHours: 2},
+		{ProjectName: "b64ciphertext", ProjectEncrypted: true, LoggedHours: 1.25},
+	}
+	want := [][]string{
+		{"Customer", "Project", "Budget hours", "Logged hours", "Billable hours", "Remaining hours", "Burn %", "Hours last 4 weeks", "Forecast exhaustion"},
+		{"Acme", "Website", "10.00", "4.00", "0.00", "", "", "2.00", ""},
+		{"", "Encrypted project", "", "1.25", "0.00", "", "", "0.00", ""},
+	}
+	if got := budgetRecords(budgets); !reflect.DeepEqual(got, want) {
+		t.Fatalf("budgetRecords = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+const maxPersonalAccessTokenDays = 365
+
+func (h *AuthHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.repo.ListPersonalAccessTokens(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list tokens")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.PersonalAccessToken]{Total: len(tokens), Documents: tokens})
+}
+
+func (h *AuthHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	var req models.CreatePersonalAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > 120 {
+		writeError(w, http.StatusBadRequest, "token name must contain between 1 and 120 characters")
+		return
+	}
+	req.Access = strings.ToLower(strings.TrimSpace(req.Access))
+	if req.Access == "" {
+		req.Access = "read"
+	}
+	if req.Access != "read" && req.Access != "write" {
+		writeError(w, http.StatusBadRequest, "access must be read or write")
+		return
+	}
+	if req.WorkspaceIDs == nil {
+		req.WorkspaceIDs = []string{}
+	}
+	for _, workspaceID := range req.WorkspaceIDs {
+		allowed, err := h.repo.CanAccessWorkspace(r.Context(), workspaceID, userID)
+		if err != nil || !allowed {
+			writeError(w, http.StatusBadRequest, "tokens can only be scoped to your workspaces")
+			return
+		}
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxPersonalAccessTokenDays {
+			writeError(w, http.StatusBadRequest, "expiresInDays must be between 1 and 365")
+			return
+		}
+		expiry := time.Now().Add(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &expiry
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	plaintext := middleware.PersonalAccessTokenPrefix + hex.EncodeToString(secret)
+	token, err := h.repo.CreatePersonalAccessToken(r.Context(), userID, req, middleware.HashPersonalAccessToken(plaintext), plaintext[:len(middleware.PersonalAccessTokenPrefix)+6], expiresAt)
+	if err != nil {
+		log.Printf("CreatePersonalAccessToken error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+	token.Token = &plaintext
+	writeJSON(w, http.StatusCreated, token)
+}
+
+func (h *AuthHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.repo.DeletePersonalAccessToken(r.Context(), middleware.GetUserID(r), chi.URLParam(r, "tokenId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke token")
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+)
+
+func TestTokenWorkspaceFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      *middleware.TokenScope
+		query      string
+		want       string
+		wantOK     bool
+		wantStatus int
+	}{
+		{name: "session without filter", query: "", want: "", wantOK: true},
+		{name: "session with filter", query: "ws-a", want: "ws-a", wantOK: true},
+		{name: "unscoped token", scope: &middleware.TokenScope{}, query: "ws-b", want: "ws-b", wantOK: true},
+		{name: "single workspace defaults", scope: &middleware.TokenScope{WorkspaceIDs: []string{"ws-a"}}, want: "ws-a", wantOK: true},
+		{name: "multiple workspaces need filter", scope: &middleware.TokenScope{WorkspaceIDs: []string{"ws-a", "ws-b"}}, wantStatus: http.StatusBadRequest},
+		{name: "workspace in scope", scope: &middleware.TokenScope{WorkspaceIDs: []string{"ws-a", "ws-b"}}, query: "ws-b", want: "ws-b", wantOK: true},
+		{name: "workspace outside scope", scope: &middleware.TokenScope{WorkspaceIDs: []string{"ws-a"}}, query: "ws-b", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/api/projects"
+			if tt.query != "" {
+				target += "?workspaceId=" + tt.query
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.scope != nil {
+				req = req.WithContext(context.WithValue(req.Context(), middleware.TokenScopeKey, tt.scope))
+			}
+			rec := httptest.NewRecorder()
+			got, ok := tokenWorkspaceFilter(rec, req)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("tokenWorkspaceFilter() = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.wantOK)
+			}
+			if !ok && rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
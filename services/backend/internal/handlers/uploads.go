@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/storage"
+)
+
+// Resumable uploads follow the tus 1.0 core protocol for transferring bytes:
+// HEAD reports Upload-Offset and PATCH appends a chunk at exactly that offset.
+// Sessions are created and finalized through justspace endpoints so the result
+// becomes a regular project file.
+const tusVersion = "1.0.0"
+
+// uploadSessionTTL is how long a session survives without receiving a chunk.
+const uploadSessionTTL = 24 * time.Hour
+
+func (h *CollaborationHandler) CreateProjectUpload(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	projectID := chi.URLParam(r, "projectId")
+	if !ensureProjectRole(w, r, h.repo, projectID, userID, "owner", "admin", "editor") {
+		return
+	}
+	h.createUploadSession(w, r, userID, projectID, "")
+}
+
+func (h *CollaborationHandler) CreateTaskUpload(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	taskID := chi.URLParam(r, "taskId")
+	task, err := h.repo.GetTask(r.Context(), taskID, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load task")
+		return
+	}
+	if task == nil {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+	if !ensureProjectRole(w, r, h.repo, task.ProjectID, userID, "owner", "admin", "editor") {
+		return
+	}
+	h.createUploadSession(w, r, userID, task.ProjectID, task.ID)
+}
+
+// createUploadSession reads the file size from Upload-Length and the file
+// metadata from Upload-Metadata. The metadata keys match the multipart upload
+// form fields; the tus client defaults filename and filetype are accepted too.
+func (h *CollaborationHandler) createUploadSession(w http.ResponseWriter, r *http.Request, userID, projectID, taskID string) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		writeError(w, http.StatusBadRequest, "Upload-Length must be a non-negative integer")
+		return
+	}
+	if size > h.limits.MaxResumableUploadBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "file exceeds the maximum upload size")
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid Upload-Metadata")
+		return
+	}
+
+	req := models.CreateProjectFileRequest{
+		ProjectID:     projectID,
+		TaskID:        taskID,
+		EncryptedName: cmp.Or(metadata["encryptedName"], metadata["filename"]),
+		ContentType:   cmp.Or(metadata["contentType"], metadata["filetype"]),
+		IV:            metadata["iv"],
+		IsEncrypted:   metadata["isEncrypted"] != "false",
+	}
+	if req.EncryptedName == "" {
+		writeError(w, http.StatusBadRequest, "encryptedName is required")
+		return
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
+	}
+	if !h.ensureStorageQuota(w, r, projectID, size) {
+		return
+	}
+
+	session, err := h.repo.CreateUploadSession(r.Context(), userID, req, size, time.Now().Add(uploadSessionTTL))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create upload")
+		return
+	}
+	w.Header().Set("Location", "/api/uploads/"+session.ID)
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// UploadOffset answers the tus HEAD request a client sends before resuming.
+func (h *CollaborationHandler) UploadOffset(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	writeUploadHeaders(w, session)
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.SizeBytes, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// UploadChunk appends the request body at Upload-Offset. A chunk is stored
+// only once it was received completely; an interrupted chunk is discarded and
+// the client resumes from the offset HEAD reports.
+func (h *CollaborationHandler) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/offset+octet-stream" {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "Upload-Offset must be a non-negative integer")
+		return
+	}
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	if session.Status != "active" {
+		writeError(w, http.StatusConflict, "upload is being finalized")
+		return
+	}
+	if offset != session.OffsetBytes {
+		writeError(w, http.StatusConflict, "Upload-Offset does not match the upload")
+		return
+	}
+
+	limit := min(session.SizeBytes-offset, h.limits.MaxUploadBytes)
+	if r.ContentLength > limit {
+		writeError(w, http.StatusRequestEntityTooLarge, "chunk exceeds the allowed size")
+		return
+	}
+	partID, err := randomToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to allocate chunk id")
+		return
+	}
+	part := models.UploadPart{
+		OffsetBytes: offset,
+		StoragePath: fmt.Sprintf("uploads/%s/%d-%s.part", session.ID, offset, partID),
+	}
+	_, part.SizeBytes, err = storage.SaveWithChecksum(r.Context(), h.fileStore, part.StoragePath, http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), part.StoragePath)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "chunk exceeds the allowed size")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to store chunk")
+		return
+	}
+	if part.SizeBytes == 0 {
+		_ = h.fileStore.Delete(r.Context(), part.StoragePath)
+		writeUploadHeaders(w, session)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	expiresAt := time.Now().Add(uploadSessionTTL)
+	appended, err := h.repo.AppendUploadPart(r.Context(), session.ID, part, expiresAt)
+	if err != nil || !appended {
+		_ = h.fileStore.Delete(context.WithoutCancel(r.Context()), part.StoragePath)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to record chunk")
+			return
+		}
+		writeError(w, http.StatusConflict, "upload changed while the chunk was received")
+		return
+	}
+	session.OffsetBytes += part.SizeBytes
+	session.ExpiresAt = expiresAt
+	writeUploadHeaders(w, session)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// FinalizeUpload assembles a complete upload into a project file. A failed
+// attempt leaves the session intact so the client can retry.
+func (h *CollaborationHandler) FinalizeUpload(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	started, err := h.repo.BeginUploadFinalize(r.Context(), session.ID, time.Now().Add(uploadSessionTTL))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to finalize upload")
+		return
+	}
+	if !started {
+		writeError(w, http.StatusConflict, "upload is incomplete or already being finalized")
+		return
+	}
+
+	projectFile, err := h.assembleUpload(r.Context(), userID, session)
+	if err != nil {
+		log.Printf("finalize upload %s error: %v", session.ID, err)
+		if err := h.repo.ResetUploadFinalize(context.WithoutCancel(r.Context()), session.ID); err != nil {
+			log.Printf("reset upload %s error: %v", session.ID, err)
+		}
+		writeError(w, http.StatusInternalServerError, "failed to finalize upload")
+		return
+	}
+	h.removeUploadSession(context.WithoutCancel(r.Context()), session.ID, "finalizing")
+	h.queueThumbnail(r.Context(), projectFile)
+	h.announceFileUpload(r.Context(), userID, projectFile)
+	writeJSON(w, http.StatusCreated, projectFile)
+}
+
+// CancelUpload discards an unfinished upload and its chunks.
+func (h *CollaborationHandler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	if !h.removeUploadSession(r.Context(), session.ID, "active") {
+		writeError(w, http.StatusConflict, "upload is being finalized")
+		return
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadUploadSession returns the session named in the URL if it belongs to the
+// caller, who must still be allowed to upload to its project.
+func (h *CollaborationHandler) loadUploadSession(w http.ResponseWriter, r *http.Request) (*models.UploadSession, bool) {
+	userID := middleware.GetUserID(r)
+	session, err := h.repo.GetUploadSession(r.Context(), chi.URLParam(r, "uploadId"), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load upload")
+		return nil, false
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "upload not found")
+		return nil, false
+	}
+	if !ensureProjectRole(w, r, h.repo, session.ProjectID, userID, "owner", "admin", "editor") {
+		return nil, false
+	}
+	return session, true
+}
+
+// assembleUpload streams the chunks of session into a fresh file blob and
+// records the project file.
+func (h *CollaborationHandler) assembleUpload(ctx context.Context, userID string, session *models.UploadSession) (*models.ProjectFile, error) {
+	parts, err := h.repo.ListUploadParts(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(parts))
+	for _, part := range parts {
+		keys = append(keys, part.StoragePath)
+	}
+	taskID := ""
+	if session.TaskID != nil {
+		taskID = *session.TaskID
+	}
+	storagePath, err := projectFileStoragePath(session.ProjectID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := storage.OpenParts(ctx, h.fileStore, keys)
+	checksum, size, err := storage.SaveWithChecksum(ctx, h.fileStore, storagePath, reader)
+	reader.Close()
+	if err == nil && size != session.SizeBytes {
+		err = fmt.Errorf("assembled %d bytes, expected %d", size, session.SizeBytes)
+	}
+	if err != nil {
+		_ = h.fileStore.Delete(context.WithoutCancel(ctx), storagePath)
+		return nil, err
+	}
+
+	req := models.CreateProjectFileRequest{
+		ProjectID:     session.ProjectID,
+		TaskID:        taskID,
+		EncryptedName: session.EncryptedName,
+		ContentType:   session.ContentType,
+		IsEncrypted:   session.IsEncrypted,
+	}
+	if session.IV != nil {
+		req.IV = *session.IV
+	}
+	return h.recordProjectFile(ctx, userID, req, size, storagePath, checksum)
+}
+
+// removeUploadSession deletes the session if it is in status, then its
+// chunks. Chunks that fail to delete are retried by the cleanup job.
+func (h *CollaborationHandler) removeUploadSession(ctx context.Context, sessionID, status string) bool {
+	removed, err := h.repo.DeleteUploadSession(ctx, sessionID, status)
+	if err != nil {
+		log.Printf("delete upload session error: %v", err)
+		return false
+	}
+	if !removed {
+		return false
+	}
+	paths, err := h.repo.ReleaseUploadParts(ctx, sessionID)
+	if err != nil {
+		log.Printf("release upload parts error: %v", err)
+		return true
+	}
+	for _, path := range paths {
+		if err := h.fileStore.Delete(ctx, path); err != nil {
+			log.Printf("upload part cleanup error: %v", err)
+		}
+	}
+	return true
+}
+
+func writeUploadHeaders(w http.ResponseWriter, session *models.UploadSession) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.OffsetBytes, 10))
+	w.Header().Set("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header: comma-separated
+// pairs of a key and a base64 value, where the value may be omitted.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid metadata pair %q", pair)
+		}
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata value for %q: %w", fields[0], err)
+			}
+			value = string(decoded)
+		}
+		if _, exists := metadata[fields[0]]; exists {
+			return nil, fmt.Errorf("duplicate metadata key %q", fields[0])
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata, nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{header: "", want: map[string]string{}},
+		{header: "encryptedName cmVwb3J0LnBkZg==,contentType YXBwbGljYXRpb24vcGRm", want: map[string]string{"encryptedName": "report.pdf", "contentType": "application/pdf"}},
+		{header: "filename ZGVtby5tcDQ=, isEncrypted ZmFsc2U=, flag", want: map[string]string{"filename": "demo.mp4", "isEncrypted": "false", "flag": ""}},
+		{header: "filename not-base64", wantErr: true},
+		{header: "filename ZGVtby5tcDQ= extra", wantErr: true},
+		{header: "flag,flag", wantErr: true},
+		{header: "filename ZGVtby5tcDQ=,", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseUploadMetadata(tt.header)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseUploadMetadata(%q) accepted invalid metadata", tt.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseUploadMetadata(%q) error = %v", tt.header, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseUploadMetadata(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
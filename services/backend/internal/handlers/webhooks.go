@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/middleware"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/webhooks"
+)
+
+var webhookEventTypes = map[string]bool{"create": true, "update": true, "delete": true}
+
+type WebhookHandler struct{ repo *repository.Repo }
+
+func NewWebhookHandler(repo *repository.Repo) *WebhookHandler { return &WebhookHandler{repo: repo} }
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	hooks, err := h.repo.ListWebhooks(r.Context(), workspaceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.Webhook]{Total: len(hooks), Documents: hooks})
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if message := validateWebhookURL(req.URL); message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	collections, eventTypes, message := normalizeWebhookFilters(req.Collections, req.EventTypes)
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+	req.Collections, req.EventTypes = collections, eventTypes
+	req.Secret = strings.TrimSpace(req.Secret)
+	if req.Secret == "" {
+		secret, err := randomWorkspaceToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to generate webhook secret")
+			return
+		}
+		req.Secret = secret
+	}
+	if len(req.Secret) < 16 {
+		writeError(w, http.StatusBadRequest, "webhook secret must contain at least 16 characters")
+		return
+	}
+	hook, err := h.repo.CreateWebhook(r.Context(), workspaceID, userID, req)
+	if err != nil {
+		log.Printf("CreateWebhook error: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+	hook.Secret = &req.Secret
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	var req models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.URL != nil {
+		trimmed := strings.TrimSpace(*req.URL)
+		if message := validateWebhookURL(trimmed); message != "" {
+			writeError(w, http.StatusBadRequest, message)
+			return
+		}
+		req.URL = &trimmed
+	}
+	if req.Collections != nil || req.EventTypes != nil {
+		collections, eventTypes, message := normalizeWebhookFilters(req.Collections, req.EventTypes)
+		if message != "" {
+			writeError(w, http.StatusBadRequest, message)
+			return
+		}
+		// Omitted filters stay NULL so the repository keeps the stored value.
+		if req.Collections != nil {
+			req.Collections = collections
+		}
+		if req.EventTypes != nil {
+			req.EventTypes = eventTypes
+		}
+	}
+	hook, err := h.repo.UpdateWebhook(r.Context(), workspaceID, chi.URLParam(r, "webhookId"), req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update webhook")
+		return
+	}
+	if hook == nil {
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	deleted, err := h.repo.DeleteWebhook(r.Context(), workspaceID, chi.URLParam(r, "webhookId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	limit, offset := boundedPagination(r)
+	deliveries, err := h.repo.ListWebhookDeliveries(r.Context(), workspaceID, chi.URLParam(r, "webhookId"), limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list webhook deliveries")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ListResponse[models.WebhookDelivery]{Total: len(deliveries), Documents: deliveries})
+}
+
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	workspaceID, userID := chi.URLParam(r, "workspaceId"), middleware.GetUserID(r)
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, userID, "owner", "admin") {
+		return
+	}
+	delivery, err := h.repo.RedeliverWebhookDelivery(r.Context(), workspaceID, chi.URLParam(r, "webhookId"), chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to redeliver webhook")
+		return
+	}
+	if delivery == nil {
+		writeError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// validateWebhookURL requires https, except for loopback receivers used during
+// local development.
+func validateWebhookURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "webhook url must be an absolute url"
+	}
+	switch parsed.Scheme {
+	case "https":
+		return ""
+	case "http":
+		host := parsed.Hostname()
+		if host == "localhost" {
+			return ""
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return ""
+		}
+	}
+	return "webhook url must use https"
+}
+
+func normalizeWebhookFilters(collections, eventTypes []string) ([]string, []string, string) {
+	outCollections := make([]string, 0, len(collections))
+	for _, collection := range collections {
+		collection = strings.TrimSpace(collection)
+		if !webhooks.Collections[collection] {
+			return nil, nil, "unsupported webhook collection: " + collection
+		}
+		outCollections = append(outCollections, collection)
+	}
+	outTypes := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		eventType = strings.ToLower(strings.TrimSpace(eventType))
+		if !webhookEventTypes[eventType] {
+			return nil, nil, "event types must be create, update, or delete"
+		}
+		outTypes = append(outTypes, eventType)
+	}
+	return outCollections, outTypes, ""
+}
@@ -23,12 +23,16 @@ func NewWikiHandler(repo *repository.Repo, hub *websocket.Hub) *WikiHandler {
 
 func (h *WikiHandler) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
-	guides, err := h.repo.ListGuides(r.Context(), userID, r.URL.Query().Get("workspaceId"))
+	page, ok := pageRequest(w, r, 0)
+	if !ok {
+		return
+	}
+	guides, nextCursor, err := h.repo.ListGuides(r.Context(), userID, r.URL.Query().Get("workspaceId"), page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list guides")
+		writeListError(w, err, "failed to list guides")
 		return
 	}
-	writeJSON(w, http.StatusOK, models.ListResponse[models.WikiGuide]{Total: len(guides), Documents: guides})
+	writeJSON(w, http.StatusOK, models.ListResponse[models.WikiGuide]{Total: len(guides), Documents: guides, NextCursor: nextCursor})
 }
 
 func (h *WikiHandler) Get(w http.ResponseWriter, r *http.Request) {
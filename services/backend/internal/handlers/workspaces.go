@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/justlabv1/justspace/backend/internal/mailer"
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/models"
 	"github.com/justlabv1/justspace/backend/internal/repository"
@@ -17,12 +18,13 @@ import (
 )
 
 type WorkspaceHandler struct {
-	repo *repository.Repo
-	hub  *websocket.Hub
+	repo   *repository.Repo
+	hub    *websocket.Hub
+	mailer *mailer.Mailer
 }
 
-func NewWorkspaceHandler(repo *repository.Repo, hub *websocket.Hub) *WorkspaceHandler {
-	return &WorkspaceHandler{repo: repo, hub: hub}
+func NewWorkspaceHandler(repo *repository.Repo, hub *websocket.Hub, m *mailer.Mailer) *WorkspaceHandler {
+	return &WorkspaceHandler{repo: repo, hub: hub, mailer: m}
 }
 
 func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +33,15 @@ func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to list workspaces")
 		return
 	}
+	if scope := middleware.GetTokenScope(r); scope != nil && len(scope.WorkspaceIDs) > 0 {
+		scoped := make([]models.Workspace, 0, len(workspaces))
+		for _, workspace := range workspaces {
+			if scope.AllowsWorkspace(workspace.ID) {
+				scoped = append(scoped, workspace)
+			}
+		}
+		workspaces = scoped
+	}
 	writeJSON(w, http.StatusOK, models.ListResponse[models.Workspace]{Total: len(workspaces), Documents: workspaces})
 }
 
@@ -50,6 +61,9 @@ func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *WorkspaceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if !ensureTokenWorkspace(w, r, chi.URLParam(r, "id")) {
+		return
+	}
 	var req models.UpdateWorkspaceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -64,6 +78,28 @@ func (h *WorkspaceHandler) Update(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, workspace)
 }
 
+// storageBreakdownLimit bounds each list of the storage breakdown.
+const storageBreakdownLimit = 50
+
+// StorageUsage reports the workspace's stored bytes against its quota, split
+// by project, task, and uploader.
+func (h *WorkspaceHandler) StorageUsage(w http.ResponseWriter, r *http.Request) {
+	workspaceID := chi.URLParam(r, "workspaceId")
+	if !ensureWorkspaceRole(w, r, h.repo, workspaceID, middleware.GetUserID(r), "owner", "admin") {
+		return
+	}
+	breakdown, err := h.repo.GetWorkspaceStorageBreakdown(r.Context(), workspaceID, storageBreakdownLimit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load storage usage")
+		return
+	}
+	if breakdown == nil {
+		writeError(w, http.StatusNotFound, "workspace not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, breakdown)
+}
+
 func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
 	workspaceID := chi.URLParam(r, "workspaceId")
 	userID := middleware.GetUserID(r)
@@ -227,6 +263,8 @@ func (h *WorkspaceHandler) CreateInvitation(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	invite.Token = &token
+	workspaceName, _ := h.repo.GetWorkspaceName(r.Context(), workspaceID)
+	deliverInvitationEmail(r.Context(), h.repo, h.mailer, invite.Email, userID, "workspace", workspaceName, invite.Role, token, invite.ExpiresAt)
 	h.broadcastWorkspace(r.Context(), workspaceID, models.WSEvent{Type: "create", Collection: "workspace_invitations", Document: invite, UserID: userID})
 	writeJSON(w, http.StatusCreated, invite)
 }
@@ -0,0 +1,211 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/config"
+)
+
+const (
+	dialTimeout    = 10 * time.Second
+	sessionTimeout = 30 * time.Second
+)
+
+// Message is a rendered email with plain-text and HTML alternatives.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Mailer struct {
+	host      string
+	port      int
+	username  string
+	password  string
+	from      *mail.Address
+	tlsMode   string
+	tlsConfig *tls.Config
+	publicURL string
+}
+
+// New returns a mailer for the configured SMTP relay, or nil when email is not
+// configured. All methods are safe to call on a nil Mailer.
+func New(cfg *config.Config) (*Mailer, error) {
+	if !cfg.MailEnabled() {
+		return nil, nil
+	}
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return nil, fmt.Errorf("parse SMTP_FROM: %w", err)
+	}
+	pool, err := cfg.CustomCAPool()
+	if err != nil {
+		return nil, err
+	}
+	return &Mailer{
+		host:      cfg.SMTPHost,
+		port:      cfg.SMTPPort,
+		username:  cfg.SMTPUsername,
+		password:  cfg.SMTPPassword,
+		from:      from,
+		tlsMode:   cfg.SMTPTLSMode,
+		tlsConfig: &tls.Config{ServerName: cfg.SMTPHost, RootCAs: pool, MinVersion: tls.VersionTLS12},
+		publicURL: cfg.PublicURL,
+	}, nil
+}
+
+func (m *Mailer) Enabled() bool {
+	return m != nil
+}
+
+// URL returns an absolute link into the web app.
+func (m *Mailer) URL(path string) string {
+	if m == nil {
+		return path
+	}
+	return m.publicURL + path
+}
+
+// Deliver sends msg in the background. Request handlers use it so a slow or
+// unavailable relay never delays the API response.
+func (m *Mailer) Deliver(msg Message) {
+	if m == nil || msg.To == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionTimeout)
+		defer cancel()
+		if err := m.Send(ctx, msg); err != nil {
+			log.Printf("email delivery error: %v", err)
+		}
+	}()
+}
+
+func (m *Mailer) Send(ctx context.Context, msg Message) error {
+	if m == nil {
+		return nil
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("parse recipient: %w", err)
+	}
+	body, err := buildMIME(m.from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	if m.tlsMode == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: m.tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("connect smtp: %w", err)
+	}
+	deadline := time.Now().Add(sessionTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+	if m.tlsMode == "starttls" {
+		if err := client.StartTLS(m.tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp rcpt to: %w", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := writer.Write(body); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildMIME renders a multipart/alternative message with quoted-printable
+// text and HTML parts.
+func buildMIME(from, to *mail.Address, msg Message, now time.Time) ([]byte, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	boundary := "justspace-" + hex.EncodeToString(random)
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(random)+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Auto-Submitted", "auto-generated")
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		buf.WriteString("--" + boundary + "\r\n")
+		header("Content-Type", part.contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.Bytes(), nil
+}
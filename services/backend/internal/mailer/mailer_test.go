@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+func TestPreferencesWants(t *testing.T) {
+	tests := []struct {
+		name             string
+		preferences      string
+		notificationType string
+		wantMode         string
+		want             bool
+	}{
+		{name: "defaults to instant", preferences: `{}`, notificationType: "mention", wantMode: "instant", want: true},
+		{name: "invalid json falls back", preferences: `not json`, notificationType: "task_assigned", wantMode: "instant", want: true},
+		{name: "unknown mode falls back", preferences: `{"emailNotifications":{"mode":"weekly"}}`, notificationType: "mention", wantMode: "instant", want: true},
+		{name: "off disables everything", preferences: `{"emailNotifications":{"mode":"off"}}`, notificationType: "mention", wantMode: "off", want: false},
+		{name: "digest keeps categories", preferences: `{"emailNotifications":{"mode":"daily"}}`, notificationType: "deadline_4h", wantMode: "daily", want: true},
+		{name: "deadlines disabled", preferences: `{"emailNotifications":{"mode":"hourly","deadlines":false}}`, notificationType: "deadline_due", wantMode: "hourly", want: false},
+		{name: "mentions disabled", preferences: `{"emailNotifications":{"mentions":false}}`, notificationType: "mention", wantMode: "instant", want: false},
+		{name: "unknown type", preferences: `{}`, notificationType: "something_else", wantMode: "instant", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := ParsePreferences(json.RawMessage(tt.preferences))
+			if prefs.Mode != tt.wantMode {
+				t.Fatalf("Mode = %q, want %q", prefs.Mode, tt.wantMode)
+			}
+			if got := prefs.Wants(tt.notificationType); got != tt.want {
+				t.Fatalf("Wants(%q) = %v, want %v", tt.notificationType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMIME(t *testing.T) {
+	from := &mail.Address{Name: "justspace", Address: "noreply@example.com"}
+	to := &mail.Address{Address: "ada@example.com"}
+	body, err := buildMIME(from, to, Message{Subject: "Grüße", Text: "plain body", HTML: "<p>html body</p>"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); subject != "Grüße" {
+		t.Fatalf("Subject = %q, want Grüße", subject)
+	}
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("parts = %v, want text/plain and text/html", types)
+	}
+}
+
+func TestRenderDigestOmitsEncryptedNames(t *testing.T) {
+	m := &Mailer{publicURL: "https://app.example.com"}
+	notifications := []models.Notification{
+		{Type: "mention", ActorName: "Ada", ProjectID: "p1", ProjectName: "Apollo", TaskID: "t1", TaskKey: "APO-1", TaskTitle: "Launch <plan>"},
+		{Type: "deadline_due", ProjectID: "p2", TaskID: "t2", TaskKey: "SEC-4"},
+	}
+	msg, err := m.RenderDigest("grace@example.com", "justspace", "Grace", "daily", notifications)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "2 unread notifications on justspace" {
+		t.Fatalf("Subject = %q", msg.Subject)
+	}
+	for _, want := range []string{"Ada mentioned you in APO-1 (Apollo · Launch <plan>)", "SEC-4 is now due\n", "https://app.example.com/projects/p2?taskId=t2"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Fatalf("text part does not contain %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "Launch &lt;plan&gt;") {
+		t.Fatalf("html part is not escaped:\n%s", msg.HTML)
+	}
+}
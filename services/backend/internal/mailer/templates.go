@@ -0,0 +1,285 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+// Preferences is the "emailNotifications" object in User.Preferences. Mode is
+// instant (default), hourly, daily, or off. Category switches default to on;
+// invitations are always sent because they cannot be delivered in-app.
+type Preferences struct {
+	Mode        string `json:"mode"`
+	Mentions    *bool  `json:"mentions,omitempty"`
+	Assignments *bool  `json:"assignments,omitempty"`
+	Deadlines   *bool  `json:"deadlines,omitempty"`
+}
+
+func ParsePreferences(raw json.RawMessage) Preferences {
+	var stored struct {
+		Email Preferences `json:"emailNotifications"`
+	}
+	_ = json.Unmarshal(raw, &stored)
+	prefs := stored.Email
+	switch prefs.Mode {
+	case "instant", "hourly", "daily", "off":
+	default:
+		prefs.Mode = "instant"
+	}
+	return prefs
+}
+
+// Wants reports whether notifications of the given type are emailed at all.
+func (p Preferences) Wants(notificationType string) bool {
+	if p.Mode == "off" {
+		return false
+	}
+	enabled := func(value *bool) bool { return value == nil || *value }
+	switch {
+	case notificationType == "mention":
+		return enabled(p.Mentions)
+	case notificationType == "task_assigned":
+		return enabled(p.Assignments)
+	case strings.HasPrefix(notificationType, "deadline_"):
+		return enabled(p.Deadlines)
+	}
+	return false
+}
+
+type InvitationData struct {
+	BrandName   string
+	InviterName string
+	// TargetKind is "workspace" or "project".
+	TargetKind string
+	TargetName string
+	Role       string
+	URL        string
+	ExpiresAt  time.Time
+}
+
+// AccountLinkData describes password reset and email verification emails.
+type AccountLinkData struct {
+	BrandName string
+	Name      string
+	URL       string
+	ExpiresAt time.Time
+}
+
+type NotificationData struct {
+	BrandName     string
+	RecipientName string
+	Notification  models.Notification
+	URL           string
+}
+
+type DigestItem struct {
+	Notification models.Notification
+	URL          string
+}
+
+type DigestData struct {
+	BrandName     string
+	RecipientName string
+	Period        string
+	Items         []DigestItem
+}
+
+type template struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+func newTemplate(name, subject, text, html string) template {
+	return template{
+		subject: texttemplate.Must(texttemplate.New(name).Funcs(funcs).Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New(name).Funcs(funcs).Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(htmlLayoutStart + html + htmlLayoutEnd)),
+	}
+}
+
+func (t template) render(to string, data any) (Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: strings.TrimSpace(subject.String()), Text: text.String(), HTML: html.String()}, nil
+}
+
+// summary is the one-line description shared by instant and digest emails,
+// worded like the in-app notification inbox.
+func summary(n models.Notification) string {
+	switch n.Type {
+	case "mention":
+		return n.ActorName + " mentioned you in " + n.TaskKey
+	case "task_assigned":
+		return n.ActorName + " assigned you to " + n.TaskKey
+	case "deadline_due":
+		return n.TaskKey + " is now due"
+	case "deadline_4h":
+		return n.TaskKey + " is due within 4 hours"
+	case "deadline_24h":
+		return n.TaskKey + " is due within 24 hours"
+	}
+	return "New activity on " + n.TaskKey
+}
+
+// location describes where a notification happened; it is empty for encrypted
+// projects because their names are not available to the server.
+func location(n models.Notification) string {
+	if n.ProjectName == "" {
+		return ""
+	}
+	if n.TaskTitle == "" {
+		return n.ProjectName
+	}
+	return n.ProjectName + " · " + n.TaskTitle
+}
+
+var funcs = texttemplate.FuncMap{
+	"summary":  summary,
+	"location": location,
+	"date":     func(t time.Time) string { return t.UTC().Format("2 Jan 2006 15:04 MST") },
+}
+
+const htmlLayoutStart = `<!doctype html>
+<html><body style="margin:0;padding:24px;background:#f5f5f4;font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;color:#1c1917">
+<div style="max-width:560px;margin:0 auto;background:#ffffff;border-radius:12px;padding:28px">
+`
+
+const htmlLayoutEnd = `<p style="margin-top:28px;font-size:12px;color:#78716c">{{.BrandName}}</p>
+</div></body></html>`
+
+var (
+	invitationTemplate = newTemplate("invitation",
+		`{{.InviterName}} invited you to {{if .TargetName}}{{.TargetName}}{{else}}a {{.TargetKind}}{{end}} on {{.BrandName}}`,
+		`{{.InviterName}} invited you to join {{if .TargetName}}the {{.TargetKind}} "{{.TargetName}}"{{else}}an encrypted {{.TargetKind}}{{end}} on {{.BrandName}} as {{.Role}}.
+
+Accept the invitation:
+{{.URL}}
+
+The invitation expires on {{date .ExpiresAt}}.
+`,
+		`<p><strong>{{.InviterName}}</strong> invited you to join {{if .TargetName}}the {{.TargetKind}} <strong>{{.TargetName}}</strong>{{else}}an encrypted {{.TargetKind}}{{end}} on {{.BrandName}} as {{.Role}}.</p>
+<p><a href="{{.URL}}" style="display:inline-block;padding:10px 18px;border-radius:8px;background:#1c1917;color:#ffffff;text-decoration:none">Accept invitation</a></p>
+<p style="font-size:13px;color:#78716c">The invitation expires on {{date .ExpiresAt}}.</p>
+`)
+
+	passwordResetTemplate = newTemplate("password-reset",
+		`Reset your {{.BrandName}} password`,
+		`Hi {{.Name}},
+
+someone asked to reset the password of your {{.BrandName}} account. Choose a new password here:
+{{.URL}}
+
+The link can be used once and expires on {{date .ExpiresAt}}. If you did not ask for a reset, you can ignore this email; your password stays unchanged.
+`,
+		`<p>Hi {{.Name}},</p>
+<p>someone asked to reset the password of your {{.BrandName}} account.</p>
+<p><a href="{{.URL}}" style="display:inline-block;padding:10px 18px;border-radius:8px;background:#1c1917;color:#ffffff;text-decoration:none">Choose a new password</a></p>
+<p style="font-size:13px;color:#78716c">The link can be used once and expires on {{date .ExpiresAt}}. If you did not ask for a reset, you can ignore this email; your password stays unchanged.</p>
+`)
+
+	emailVerificationTemplate = newTemplate("email-verification",
+		`Verify your email for {{.BrandName}}`,
+		`Hi {{.Name}},
+
+please confirm that this address belongs to your {{.BrandName}} account:
+{{.URL}}
+
+The link expires on {{date .ExpiresAt}}.
+`,
+		`<p>Hi {{.Name}},</p>
+<p>please confirm that this address belongs to your {{.BrandName}} account.</p>
+<p><a href="{{.URL}}" style="display:inline-block;padding:10px 18px;border-radius:8px;background:#1c1917;color:#ffffff;text-decoration:none">Verify email</a></p>
+<p style="font-size:13px;color:#78716c">The link expires on {{date .ExpiresAt}}.</p>
+`)
+
+	notificationTemplate = newTemplate("notification",
+		`{{summary .Notification}}`,
+		`{{summary .Notification}}
+{{with location .Notification}}{{.}}
+{{end}}
+Open the task:
+{{.URL}}
+
+You can change email notifications in your {{.BrandName}} settings.
+`,
+		`<p style="font-size:15px">{{summary .Notification}}</p>
+{{with location .Notification}}<p style="font-size:13px;color:#78716c">{{.}}</p>{{end}}
+<p><a href="{{.URL}}" style="display:inline-block;padding:10px 18px;border-radius:8px;background:#1c1917;color:#ffffff;text-decoration:none">Open task</a></p>
+<p style="font-size:12px;color:#78716c">You can change email notifications in your settings.</p>
+`)
+
+	digestTemplate = newTemplate("digest",
+		`{{len .Items}} unread notification{{if ne (len .Items) 1}}s{{end}} on {{.BrandName}}`,
+		`Hi {{.RecipientName}}, here is your {{.Period}} summary.
+{{range .Items}}
+- {{summary .Notification}}{{with location .Notification}} ({{.}}){{end}}
+  {{.URL}}
+{{end}}
+You can change email notifications in your {{.BrandName}} settings.
+`,
+		`<p>Hi {{.RecipientName}}, here is your {{.Period}} summary.</p>
+<ul style="padding-left:18px">
+{{range .Items}}<li style="margin-bottom:10px"><a href="{{.URL}}" style="color:#1c1917">{{summary .Notification}}</a>{{with location .Notification}}<br><span style="font-size:13px;color:#78716c">{{.}}</span>{{end}}</li>
+{{end}}</ul>
+<p style="font-size:12px;color:#78716c">You can change email notifications in your settings.</p>
+`)
+)
+
+func (m *Mailer) taskURL(n models.Notification) string {
+	return m.URL("/projects/" + url.PathEscape(n.ProjectID) + "?taskId=" + url.QueryEscape(n.TaskID))
+}
+
+// InvitationURL is the accept link for a one-time invitation token.
+func (m *Mailer) InvitationURL(token string) string {
+	return m.URL("/invite?token=" + url.QueryEscape(token))
+}
+
+// PasswordResetURL and EmailVerificationURL are the web app pages that submit
+// the token to the API.
+func (m *Mailer) PasswordResetURL(token string) string {
+	return m.URL("/reset-password?token=" + url.QueryEscape(token))
+}
+
+func (m *Mailer) EmailVerificationURL(token string) string {
+	return m.URL("/verify-email?token=" + url.QueryEscape(token))
+}
+
+func RenderPasswordReset(to string, data AccountLinkData) (Message, error) {
+	return passwordResetTemplate.render(to, data)
+}
+
+func RenderEmailVerification(to string, data AccountLinkData) (Message, error) {
+	return emailVerificationTemplate.render(to, data)
+}
+
+func RenderInvitation(to string, data InvitationData) (Message, error) {
+	return invitationTemplate.render(to, data)
+}
+
+func (m *Mailer) RenderNotification(to, brandName, recipientName string, n models.Notification) (Message, error) {
+	return notificationTemplate.render(to, NotificationData{BrandName: brandName, RecipientName: recipientName, Notification: n, URL: m.taskURL(n)})
+}
+
+func (m *Mailer) RenderDigest(to, brandName, recipientName, period string, notifications []models.Notification) (Message, error) {
+	items := make([]DigestItem, 0, len(notifications))
+	for _, n := range notifications {
+		items = append(items, DigestItem{Notification: n, URL: m.taskURL(n)})
+	}
+	return digestTemplate.render(to, DigestData{BrandName: brandName, RecipientName: recipientName, Period: period, Items: items})
+}
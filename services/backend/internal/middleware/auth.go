@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/justlabv1/justspace/backend/internal/repository"
@@ -13,6 +17,41 @@ type contextKey string
 
 const UserIDKey contextKey = "userID"
 
+const TokenScopeKey contextKey = "tokenScope"
+
+const SessionIDKey contextKey = "sessionID"
+
+// PersonalAccessTokenPrefix marks bearer values that are personal access tokens
+// rather than session JWTs.
+const PersonalAccessTokenPrefix = "jspat_"
+
+// TokenScope describes the limits of a personal access token. Requests made with
+// a browser session carry no scope.
+type TokenScope struct {
+	TokenID      string
+	ReadOnly     bool
+	WorkspaceIDs []string
+}
+
+// AllowsWorkspace reports whether the token may act inside workspaceID. Tokens
+// without workspace restrictions may act in every workspace of their user.
+func (s *TokenScope) AllowsWorkspace(workspaceID string) bool {
+	if s == nil || len(s.WorkspaceIDs) == 0 {
+		return true
+	}
+	for _, id := range s.WorkspaceIDs {
+		if id == workspaceID {
+			return true
+		}
+	}
+	return false
+}
+
+func HashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func Auth(jwtSecret string, repo *repository.Repo) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +70,10 @@ func Auth(jwtSecret string, repo *repository.Repo) func(http.Handler) http.Handl
 				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 				return
 			}
+			if strings.HasPrefix(tokenStr, PersonalAccessTokenPrefix) {
+				authenticatePersonalAccessToken(w, r, next, repo, tokenStr)
+				return
+			}
 			token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
 				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, jwt.ErrSignatureInvalid
@@ -61,12 +104,100 @@ func Auth(jwtSecret string, repo *repository.Repo) func(http.Handler) http.Handl
 				http.Error(w, `{"error":"session expired"}`, http.StatusUnauthorized)
 				return
 			}
+			// Every session JWT carries the id of its user_sessions row; tokens
+			// issued before the registry existed have none and must sign in again.
+			sessionID, _ := claims["jti"].(string)
+			if sessionID == "" {
+				http.Error(w, `{"error":"session expired"}`, http.StatusUnauthorized)
+				return
+			}
+			if valid, err := repo.ValidateUserSession(r.Context(), sessionID, userID, ClientIP(r)); err != nil || !valid {
+				http.Error(w, `{"error":"session revoked"}`, http.StatusUnauthorized)
+				return
+			}
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			ctx = context.WithValue(ctx, SessionIDKey, sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+func authenticatePersonalAccessToken(w http.ResponseWriter, r *http.Request, next http.Handler, repo *repository.Repo, tokenStr string) {
+	pat, err := repo.GetPersonalAccessTokenByHash(r.Context(), HashPersonalAccessToken(tokenStr))
+	if err != nil || pat == nil || (pat.ExpiresAt != nil && time.Now().After(*pat.ExpiresAt)) {
+		http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+		return
+	}
+	active, currentVersion, stateErr := repo.GetUserAuthState(r.Context(), pat.UserID)
+	if stateErr != nil || !active {
+		http.Error(w, `{"error":"account disabled"}`, http.StatusUnauthorized)
+		return
+	}
+	if pat.SessionVersion != currentVersion {
+		http.Error(w, `{"error":"token revoked"}`, http.StatusUnauthorized)
+		return
+	}
+	scope := &TokenScope{TokenID: pat.ID, ReadOnly: pat.Access != "write", WorkspaceIDs: pat.WorkspaceIDs}
+	if scope.ReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		http.Error(w, `{"error":"token is read-only"}`, http.StatusForbidden)
+		return
+	}
+	_ = repo.TouchPersonalAccessToken(r.Context(), pat.ID)
+	ctx := context.WithValue(r.Context(), UserIDKey, pat.UserID)
+	ctx = context.WithValue(ctx, TokenScopeKey, scope)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireSession rejects personal access tokens on account-management routes,
+// so a leaked token cannot mint new tokens or change credentials.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetTokenScope(r) != nil {
+			http.Error(w, `{"error":"this endpoint requires a signed-in session"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireUnscopedToken rejects workspace-scoped tokens on routes whose
+// resources are not tied to a single workspace.
+func RequireUnscopedToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scope := GetTokenScope(r); scope != nil && len(scope.WorkspaceIDs) > 0 {
+			http.Error(w, `{"error":"this endpoint is not available to workspace-scoped tokens"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func GetTokenScope(r *http.Request) *TokenScope {
+	if scope, ok := r.Context().Value(TokenScopeKey).(*TokenScope); ok {
+		return scope
+	}
+	return nil
+}
+
+// GetSessionID returns the browser session of the request, or "" for personal
+// access tokens.
+func GetSessionID(r *http.Request) string {
+	if id, ok := r.Context().Value(SessionIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// ClientIP returns the caller address without port. chi's RealIP middleware
+// has already applied X-Forwarded-For / X-Real-IP at this point.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func GetUserID(r *http.Request) string {
 	if id, ok := r.Context().Value(UserIDKey).(string); ok {
 		return id
@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/taskquery"
 )
 
 type User struct {
@@ -13,16 +15,22 @@ type User struct {
 	IsPlatformAdmin bool            `json:"isPlatformAdmin"`
 	IsActive        bool            `json:"isActive"`
 	SessionVersion  int64           `json:"-"`
+	EmailVerifiedAt *time.Time      `json:"emailVerifiedAt,omitempty"`
 	Preferences     json.RawMessage `json:"preferences"`
 	CreatedAt       time.Time       `json:"createdAt"`
 	UpdatedAt       time.Time       `json:"updatedAt"`
 }
 
 type PlatformSettings struct {
-	LocalAuthEnabled   bool       `json:"localAuthEnabled"`
-	BrandName          string     `json:"brandName"`
-	BrandLogoKey       *string    `json:"-"`
-	BrandLogoUpdatedAt *time.Time `json:"-"`
+	LocalAuthEnabled     bool       `json:"localAuthEnabled"`
+	RequireVerifiedEmail bool       `json:"requireVerifiedEmail"`
+	RequireAdminMFA      bool       `json:"requireAdminMfa"`
+	BrandName            string     `json:"brandName"`
+	BrandLogoKey         *string    `json:"-"`
+	BrandLogoUpdatedAt   *time.Time `json:"-"`
+	// DefaultWorkspaceStorageQuotaBytes applies to workspaces without their
+	// own quota. Zero means unlimited.
+	DefaultWorkspaceStorageQuotaBytes int64 `json:"defaultWorkspaceStorageQuotaBytes"`
 }
 
 type PlatformBranding struct {
@@ -42,6 +50,42 @@ type AdminOverview struct {
 	EnabledOIDCProviders int    `json:"enabledOidcProviders"`
 	TotalOIDCProviders   int    `json:"totalOidcProviders"`
 	LocalAuthEnabled     bool   `json:"localAuthEnabled"`
+	StorageUsedBytes     int64  `json:"storageUsedBytes"`
+	// WorkspaceStorage lists the workspaces using the most storage.
+	WorkspaceStorage []WorkspaceStorageUsage `json:"workspaceStorage"`
+}
+
+// WorkspaceStorageUsage sums the files stored in a workspace's projects.
+// PendingUploadBytes is the declared size of unfinished resumable uploads,
+// which counts towards the quota. QuotaBytes is the effective quota, zero
+// when unlimited; QuotaOverrideBytes is set when the workspace does not use
+// the platform default.
+type WorkspaceStorageUsage struct {
+	WorkspaceID        string `json:"workspaceId"`
+	Name               string `json:"name"`
+	UsedBytes          int64  `json:"usedBytes"`
+	PendingUploadBytes int64  `json:"pendingUploadBytes"`
+	Files              int    `json:"files"`
+	QuotaBytes         int64  `json:"quotaBytes"`
+	QuotaOverrideBytes *int64 `json:"quotaOverrideBytes,omitempty"`
+}
+
+// StorageUsageEntry is one row of a storage breakdown. ProjectID is set for
+// task rows. Project and task names are returned as stored, so encrypted
+// names have to be decrypted by the client.
+type StorageUsageEntry struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ProjectID string `json:"projectId,omitempty"`
+	Bytes     int64  `json:"bytes"`
+	Files     int    `json:"files"`
+}
+
+type WorkspaceStorageBreakdown struct {
+	WorkspaceStorageUsage
+	Projects  []StorageUsageEntry `json:"projects"`
+	Tasks     []StorageUsageEntry `json:"tasks"`
+	Uploaders []StorageUsageEntry `json:"uploaders"`
 }
 
 type AdminAuditEvent struct {
@@ -86,6 +130,7 @@ type AdminUser struct {
 	Name            string    `json:"name"`
 	IsPlatformAdmin bool      `json:"isPlatformAdmin"`
 	IsActive        bool      `json:"isActive"`
+	MFAEnabled      bool      `json:"mfaEnabled"`
 	CreatedAt       time.Time `json:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt"`
 }
@@ -135,6 +180,41 @@ type TeamInvitation struct {
 }
 
 type ProjectFile struct {
+	ID            string  `json:"id"`
+	ProjectID     string  `json:"projectId"`
+	TaskID        *string `json:"taskId,omitempty"`
+	UploaderID    string  `json:"uploaderId"`
+	EncryptedName string  `json:"encryptedName"`
+	ContentType   string  `json:"contentType"`
+	IV            *string `json:"iv,omitempty"`
+	SizeBytes     int64   `json:"sizeBytes"`
+	StoragePath   string  `json:"storagePath"`
+	IsEncrypted   bool    `json:"isEncrypted"`
+	// ChecksumSHA256 is the hex SHA-256 of the stored bytes. Files uploaded
+	// before checksums were recorded have none until storage is reconciled.
+	ChecksumSHA256 *string   `json:"checksumSha256,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UploaderName   *string   `json:"uploaderName,omitempty"`
+	DownloadURL    *string   `json:"downloadUrl,omitempty"`
+	// ThumbnailURL is set once thumbnails of a plaintext image in a
+	// non-encrypted project are ready. Clients may append ?size=.
+	ThumbnailURL         *string `json:"thumbnailUrl,omitempty"`
+	ThumbnailContentType *string `json:"-"`
+}
+
+// StoredFile is a blob in file_blobs, which one or more project files refer
+// to. It is used when reconciling or migrating the file store.
+type StoredFile struct {
+	StoragePath    string
+	SizeBytes      int64
+	ChecksumSHA256 *string
+	// HasThumbnails reports whether thumbnails of the blob may be stored.
+	HasThumbnails bool
+}
+
+// UploadSession is a resumable upload that has not been finalized into a
+// project file yet. OffsetBytes is how much of SizeBytes has been received.
+type UploadSession struct {
 	ID            string    `json:"id"`
 	ProjectID     string    `json:"projectId"`
 	TaskID        *string   `json:"taskId,omitempty"`
@@ -142,12 +222,19 @@ type ProjectFile struct {
 	EncryptedName string    `json:"encryptedName"`
 	ContentType   string    `json:"contentType"`
 	IV            *string   `json:"iv,omitempty"`
-	SizeBytes     int64     `json:"sizeBytes"`
-	StoragePath   string    `json:"storagePath"`
 	IsEncrypted   bool      `json:"isEncrypted"`
+	SizeBytes     int64     `json:"sizeBytes"`
+	OffsetBytes   int64     `json:"offsetBytes"`
+	Status        string    `json:"status"`
+	ExpiresAt     time.Time `json:"expiresAt"`
 	CreatedAt     time.Time `json:"createdAt"`
-	UploaderName  *string   `json:"uploaderName,omitempty"`
-	DownloadURL   *string   `json:"downloadUrl,omitempty"`
+}
+
+// UploadPart is one stored chunk of an upload session.
+type UploadPart struct {
+	OffsetBytes int64
+	SizeBytes   int64
+	StoragePath string
 }
 
 type Task struct {
@@ -202,6 +289,137 @@ type ProjectMilestone struct {
 	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
+// Sprint is a time-boxed iteration of a project. TaskCount and
+// CompletedTaskCount cover the tasks currently in the sprint; Report is set
+// when the sprint is closed.
+type Sprint struct {
+	ID                 string        `json:"id"`
+	ProjectID          string        `json:"projectId"`
+	CreatedBy          string        `json:"createdBy"`
+	Name               string        `json:"name"`
+	Goal               string        `json:"goal"`
+	State              string        `json:"state"`
+	StartDate          time.Time     `json:"startDate"`
+	EndDate            time.Time     `json:"endDate"`
+	StartedAt          *time.Time    `json:"startedAt,omitempty"`
+	ClosedAt           *time.Time    `json:"closedAt,omitempty"`
+	TaskCount          int           `json:"taskCount"`
+	CompletedTaskCount int           `json:"completedTaskCount"`
+	Report             *SprintReport `json:"report,omitempty"`
+	CreatedAt          time.Time     `json:"createdAt"`
+	UpdatedAt          time.Time     `json:"updatedAt"`
+}
+
+// SprintReport compares the work committed when a sprint started with what
+// was done by its end. Committed tasks were in the sprint when it started;
+// added tasks joined later. Removed tasks left the sprint before it closed
+// and count towards committed but not completed.
+type SprintReport struct {
+	Committed          int      `json:"committed"`
+	CommittedCompleted int      `json:"committedCompleted"`
+	AddedMidSprint     int      `json:"addedMidSprint"`
+	AddedCompleted     int      `json:"addedCompleted"`
+	Removed            int      `json:"removed"`
+	Completed          int      `json:"completed"`
+	Incomplete         int      `json:"incomplete"`
+	RolledOver         int      `json:"rolledOver"`
+	RolloverSprintID   *string  `json:"rolloverSprintId,omitempty"`
+	CompletedTaskIDs   []string `json:"completedTaskIds"`
+	IncompleteTaskIDs  []string `json:"incompleteTaskIds"`
+}
+
+// BurndownPoint is the state of a project or sprint at the end of a UTC day.
+// Total is the scope, so Total and Completed form the burnup chart.
+type BurndownPoint struct {
+	Date      string `json:"date"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Remaining int    `json:"remaining"`
+}
+
+// ThroughputWeek counts the tasks completed in the ISO week starting on
+// WeekStart.
+type ThroughputWeek struct {
+	WeekStart string `json:"weekStart"`
+	Completed int    `json:"completed"`
+}
+
+// TaskCycleTime measures one completed task. Lead time runs from creation to
+// completion, cycle time from the first move into an open status to
+// completion; StartedAt is nil when the task was never moved before it was
+// completed.
+type TaskCycleTime struct {
+	TaskID         string     `json:"taskId"`
+	TaskKey        string     `json:"taskKey"`
+	Title          string     `json:"title"`
+	IsEncrypted    bool       `json:"isEncrypted"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	CompletedAt    time.Time  `json:"completedAt"`
+	LeadTimeHours  float64    `json:"leadTimeHours"`
+	CycleTimeHours *float64   `json:"cycleTimeHours,omitempty"`
+}
+
+type DurationStats struct {
+	Count        int     `json:"count"`
+	AverageHours float64 `json:"averageHours"`
+	MedianHours  float64 `json:"medianHours"`
+	P85Hours     float64 `json:"p85Hours"`
+}
+
+type CycleTimeReport struct {
+	LeadTime  *DurationStats  `json:"leadTime"`
+	CycleTime *DurationStats  `json:"cycleTime"`
+	Tasks     []TaskCycleTime `json:"tasks"`
+}
+
+// StatusTime is the time open tasks spent in one status. Statuses that were
+// removed from the project keep their key as label.
+type StatusTime struct {
+	Key          string  `json:"key"`
+	Label        string  `json:"label"`
+	Tasks        int     `json:"tasks"`
+	TotalHours   float64 `json:"totalHours"`
+	AverageHours float64 `json:"averageHours"`
+}
+
+// TimeEntry is time a user spent on a task. Entries without EndedAt are
+// running timers; a user runs at most one. DurationSeconds is zero while the
+// timer runs.
+type TimeEntry struct {
+	ID              string     `json:"id"`
+	TaskID          string     `json:"taskId"`
+	ProjectID       string     `json:"projectId"`
+	UserID          string     `json:"userId"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndedAt         *time.Time `json:"endedAt"`
+	DurationSeconds int        `json:"durationSeconds"`
+	Note            string     `json:"note"`
+	Billable        bool       `json:"billable"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// SavedView is a named task list setup: a taskquery filter, sort, grouping,
+// and view mode. It belongs to one project or workspace; Scope decides whether
+// only its owner ("user") or every member of the project or workspace sees it.
+// Settings holds client-only state such as the search box and tag chips.
+type SavedView struct {
+	ID          string          `json:"id"`
+	OwnerID     string          `json:"ownerId"`
+	Scope       string          `json:"scope"`
+	ProjectID   *string         `json:"projectId,omitempty"`
+	WorkspaceID *string         `json:"workspaceId,omitempty"`
+	Name        string          `json:"name"`
+	ViewMode    string          `json:"viewMode"`
+	Filter      string          `json:"filter"`
+	Sort        string          `json:"sort"`
+	GroupBy     string          `json:"groupBy"`
+	Settings    json.RawMessage `json:"settings"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
 type TaskAssignee struct {
 	TaskID     string    `json:"taskId"`
 	UserID     string    `json:"userId"`
@@ -231,9 +449,28 @@ type PresenceSession struct {
 	LastSeen time.Time `json:"lastSeen"`
 }
 
+// RecurrenceRule is the JSON stored in Task.Recurrence. Type, Interval, and
+// EndDate are the legacy shorthand; RRule, an RFC 5545 rule, takes precedence
+// when set. Start and Occurrence track the series across the tasks it
+// creates, and Skip lists local dates whose occurrences are left out.
 type RecurrenceRule struct {
-	Type     string `json:"type"`
-	Interval int    `json:"interval"`
+	Type       string     `json:"type,omitempty"`
+	Interval   int        `json:"interval,omitempty"`
+	EndDate    string     `json:"endDate,omitempty"`
+	RRule      string     `json:"rrule,omitempty"`
+	Anchor     string     `json:"anchor,omitempty"`
+	TimeZone   string     `json:"timeZone,omitempty"`
+	Start      *time.Time `json:"start,omitempty"`
+	Occurrence int        `json:"occurrence,omitempty"`
+	Skip       []string   `json:"skip,omitempty"`
+	Paused     bool       `json:"paused,omitempty"`
+}
+
+// TaskRecurrence previews the upcoming occurrences of a recurring task.
+type TaskRecurrence struct {
+	Rule        RecurrenceRule `json:"rule"`
+	Paused      bool           `json:"paused"`
+	Occurrences []time.Time    `json:"occurrences"`
 }
 
 type WikiGuide struct {
@@ -278,21 +515,25 @@ type ActivityLog struct {
 	CreatedAt  time.Time `json:"createdAt"`
 }
 
+// Notification is an inbox entry. The task fields are empty for notifications
+// about a project as a whole, such as over_allocation, whose details are in
+// Metadata.
 type Notification struct {
-	ID              string     `json:"id"`
-	RecipientUserID string     `json:"recipientUserId"`
-	ActorUserID     string     `json:"actorUserId"`
-	ActorName       string     `json:"actorName"`
-	Type            string     `json:"type"`
-	ProjectID       string     `json:"projectId"`
-	ProjectName     string     `json:"projectName"`
-	TaskID          string     `json:"taskId"`
-	TaskKey         string     `json:"taskKey"`
-	TaskTitle       string     `json:"taskTitle"`
-	CommentID       *string    `json:"commentId,omitempty"`
-	DeadlineAt      *time.Time `json:"deadlineAt,omitempty"`
-	ReadAt          *time.Time `json:"readAt,omitempty"`
-	CreatedAt       time.Time  `json:"createdAt"`
+	ID              string          `json:"id"`
+	RecipientUserID string          `json:"recipientUserId"`
+	ActorUserID     string          `json:"actorUserId"`
+	ActorName       string          `json:"actorName"`
+	Type            string          `json:"type"`
+	ProjectID       string          `json:"projectId"`
+	ProjectName     string          `json:"projectName"`
+	TaskID          string          `json:"taskId"`
+	TaskKey         string          `json:"taskKey"`
+	TaskTitle       string          `json:"taskTitle"`
+	CommentID       *string         `json:"commentId,omitempty"`
+	DeadlineAt      *time.Time      `json:"deadlineAt,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	ReadAt          *time.Time      `json:"readAt,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
 }
 
 type Snippet struct {
@@ -348,10 +589,114 @@ type Customer struct {
 	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
+// ProjectMemberAllocation plans DaysPerWeek of a member's time for a project
+// between StartDate and EndDate; a missing date leaves that side open.
+// ProjectName is set by workspace capacity reports.
 type ProjectMemberAllocation struct {
-	ProjectID   string  `json:"projectId"`
-	UserID      string  `json:"userId"`
-	DaysPerWeek float64 `json:"daysPerWeek"`
+	ProjectID   string     `json:"projectId"`
+	ProjectName string     `json:"projectName,omitempty"`
+	UserID      string     `json:"userId"`
+	DaysPerWeek float64    `json:"daysPerWeek"`
+	StartDate   *time.Time `json:"startDate,omitempty"`
+	EndDate     *time.Time `json:"endDate,omitempty"`
+}
+
+// MemberAbsence is planned leave of a workspace member; both days are included.
+type MemberAbsence struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspaceId"`
+	UserID      string    `json:"userId"`
+	UserName    string    `json:"userName"`
+	StartDate   time.Time `json:"startDate"`
+	EndDate     time.Time `json:"endDate"`
+	Note        string    `json:"note"`
+	CreatedBy   *string   `json:"createdBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// CapacityWeek compares a member's capacity in the week starting WeekStart, a
+// Monday, with the days allocated to projects. Absent weekdays reduce the
+// capacity but not the allocations.
+type CapacityWeek struct {
+	WeekStart     string  `json:"weekStart"`
+	CapacityDays  float64 `json:"capacityDays"`
+	AllocatedDays float64 `json:"allocatedDays"`
+	AbsenceDays   int     `json:"absenceDays"`
+	OverAllocated bool    `json:"overAllocated"`
+}
+
+// MemberCapacity is one member's row of a workspace capacity report.
+// OverAllocated is set when any of the weeks is.
+type MemberCapacity struct {
+	UserID             string                    `json:"userId"`
+	Name               string                    `json:"name"`
+	WeeklyCapacityDays float64                   `json:"weeklyCapacityDays"`
+	OverAllocated      bool                      `json:"overAllocated"`
+	Allocations        []ProjectMemberAllocation `json:"allocations"`
+	Absences           []MemberAbsence           `json:"absences"`
+	Weeks              []CapacityWeek            `json:"weeks"`
+}
+
+type WorkspaceCapacity struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Members []MemberCapacity `json:"members"`
+}
+
+// TimesheetRow is logged time for one combination of the grouped dimensions.
+// Fields of dimensions that were not grouped by are omitted, as is CustomerID
+// for time on projects without a customer. ProjectName is ciphertext when
+// ProjectEncrypted is set.
+type TimesheetRow struct {
+	CustomerID       *string `json:"customerId,omitempty"`
+	CustomerName     *string `json:"customerName,omitempty"`
+	ProjectID        *string `json:"projectId,omitempty"`
+	ProjectName      *string `json:"projectName,omitempty"`
+	ProjectEncrypted bool    `json:"projectEncrypted,omitempty"`
+	UserID           *string `json:"userId,omitempty"`
+	UserName         *string `json:"userName,omitempty"`
+	WeekStart        *string `json:"weekStart,omitempty"`
+	Hours            float64 `json:"hours"`
+	BillableHours    float64 `json:"billableHours"`
+	Entries          int     `json:"entries"`
+}
+
+type Timesheet struct {
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	GroupBy       []string       `json:"groupBy"`
+	Hours         float64        `json:"hours"`
+	BillableHours float64        `json:"billableHours"`
+	Rows          []TimesheetRow `json:"rows"`
+}
+
+// ProjectBudget compares the time logged on a project with its HourBudget.
+// RecentHours is the time logged in the last four weeks; the forecast assumes
+// that pace continues and is omitted without a budget, without recent time,
+// or once the budget is used up. ProjectName is ciphertext when
+// ProjectEncrypted is set.
+type ProjectBudget struct {
+	ProjectID          string   `json:"projectId"`
+	ProjectName        string   `json:"projectName"`
+	ProjectEncrypted   bool     `json:"projectEncrypted"`
+	CustomerID         *string  `json:"customerId"`
+	CustomerName       *string  `json:"customerName"`
+	HourBudget         *float64 `json:"hourBudget"`
+	LoggedHours        float64  `json:"loggedHours"`
+	BillableHours      float64  `json:"billableHours"`
+	RecentHours        float64  `json:"recentHours"`
+	RemainingHours     *float64 `json:"remainingHours"`
+	BurnPercent        *float64 `json:"burnPercent"`
+	ForecastExhaustion *string  `json:"forecastExhaustion"`
+}
+
+// BudgetUsage is the finished time logged on a project with an hour budget,
+// as checked by budget alerts.
+type BudgetUsage struct {
+	ProjectID   string
+	OwnerID     string
+	HourBudget  float64
+	LoggedHours float64
 }
 
 type WorkspaceInvitation struct {
@@ -368,6 +713,61 @@ type WorkspaceInvitation struct {
 	CreatedAt     time.Time  `json:"createdAt"`
 }
 
+// Webhook is a per-workspace subscription to realtime events. Empty Collections
+// or EventTypes match every value. Secret is only returned when it is created.
+type Webhook struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspaceId"`
+	CreatedByID *string   `json:"createdById,omitempty"`
+	URL         string    `json:"url"`
+	Secret      *string   `json:"secret,omitempty"`
+	Collections []string  `json:"collections"`
+	EventTypes  []string  `json:"eventTypes"`
+	IsActive    bool      `json:"isActive"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      *string         `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// WebhookDispatch is a claimed delivery together with the target it is sent to.
+type WebhookDispatch struct {
+	DeliveryID string
+	WebhookID  string
+	Event      string
+	Payload    json.RawMessage
+	Attempts   int
+	URL        string
+	Secret     string
+}
+
+// WebhookEvent is the JSON body posted to webhook receivers.
+type WebhookEvent struct {
+	ID          string      `json:"id"`
+	Event       string      `json:"event"`
+	Type        string      `json:"type"`
+	Collection  string      `json:"collection"`
+	WorkspaceID string      `json:"workspaceId"`
+	ProjectID   string      `json:"projectId"`
+	ActorUserID string      `json:"actorUserId"`
+	IsEncrypted bool        `json:"isEncrypted"`
+	OccurredAt  time.Time   `json:"occurredAt"`
+	Document    interface{} `json:"document"`
+}
+
 type UserKeys struct {
 	ID                  string    `json:"id"`
 	UserID              string    `json:"userId"`
@@ -389,6 +789,72 @@ type UserLookup struct {
 	HasVault  bool    `json:"hasVault"`
 }
 
+// PageRequest selects a page of a list ordered newest first. Cursor is the
+// nextCursor of the previous page; a zero Limit lists everything.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// TaskListQuery selects a page of tasks. ProjectID and WorkspaceID are
+// optional scopes; Sort is order, created, updated, or deadline.
+type TaskListQuery struct {
+	ProjectID   string
+	WorkspaceID string
+	OpenOnly    bool
+	Filter      *taskquery.Query
+	Sort        string
+	Cursor      string
+	Limit       int
+}
+
+// TimeEntryQuery filters time entry lists. From and To bound StartedAt.
+type TimeEntryQuery struct {
+	WorkspaceID string
+	ProjectID   string
+	UserID      string
+	From        *time.Time
+	To          *time.Time
+}
+
+// TimesheetQuery selects the finished time entries of a workspace timesheet.
+// GroupBy lists dimensions among customer, project, member, and week.
+type TimesheetQuery struct {
+	GroupBy    []string
+	CustomerID string
+	ProjectID  string
+	UserID     string
+	Billable   *bool
+	From       time.Time
+	To         time.Time
+}
+
+// SearchResult is one full-text search hit. Highlight is HTML-escaped text
+// with matched words wrapped in <mark>. Comments carry the task they belong
+// to and installations the guide they belong to.
+type SearchResult struct {
+	Type        string    `json:"type"`
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Highlight   string    `json:"highlight"`
+	Rank        float64   `json:"rank"`
+	WorkspaceID *string   `json:"workspaceId,omitempty"`
+	ProjectID   *string   `json:"projectId,omitempty"`
+	TaskID      *string   `json:"taskId,omitempty"`
+	GuideID     *string   `json:"guideId,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SearchQuery filters a full-text search. Empty filters match everything the
+// user can read. Types are task, comment, wiki, installation, and snippet.
+type SearchQuery struct {
+	Text        string
+	WorkspaceID string
+	ProjectID   string
+	Types       []string
+	Limit       int
+}
+
 type AccessControl struct {
 	ID           string    `json:"id"`
 	ResourceID   string    `json:"resourceId"`
@@ -411,6 +877,36 @@ type ResourceVersion struct {
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
+// PersonalAccessToken is a user-managed API credential. Token is only set in the
+// response that creates it; afterwards only the prefix is shown.
+// UserSession is a signed-in browser session. Its ID is the "jti" claim of the
+// session JWT.
+type UserSession struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+type PersonalAccessToken struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"userId"`
+	Name           string     `json:"name"`
+	TokenPrefix    string     `json:"tokenPrefix"`
+	Access         string     `json:"access"`
+	WorkspaceIDs   []string   `json:"workspaceIds"`
+	SessionVersion int64      `json:"-"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	Token          *string    `json:"token,omitempty"`
+}
+
 // --- Request/Response DTOs ---
 
 type SignupRequest struct {
@@ -426,6 +922,64 @@ type LoginRequest struct {
 
 type AuthResponse struct {
 	User User `json:"user"`
+	// VerificationRequired is set when signup succeeded but no session was
+	// issued because the account must verify its email first.
+	VerificationRequired bool `json:"verificationRequired,omitempty"`
+}
+
+// MFAChallengeResponse is returned by login instead of AuthResponse when the
+// password was accepted but a second factor must be submitted with MFAToken.
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfaRequired"`
+	MFAToken    string    `json:"mfaToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// UserMFA is a TOTP enrollment. EnabledAt is nil while setup is pending.
+type UserMFA struct {
+	UserID          string
+	SecretEncrypted string
+	EnabledAt       *time.Time
+	LastUsedStep    int64
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"`
+	// Required is set for platform admins while admin MFA is enforced.
+	Required bool `json:"required"`
+}
+
+type MFASetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauthUrl"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
 }
 
 type UpdateProfileRequest struct {
@@ -433,14 +987,35 @@ type UpdateProfileRequest struct {
 	Preferences *json.RawMessage `json:"preferences,omitempty"`
 }
 
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Access        string   `json:"access"`
+	WorkspaceIDs  []string `json:"workspaceIds"`
+	ExpiresInDays *int     `json:"expiresInDays,omitempty"`
+}
+
 type AdminUserUpdateRequest struct {
 	IsPlatformAdmin *bool `json:"isPlatformAdmin,omitempty"`
 	IsActive        *bool `json:"isActive,omitempty"`
+	// RevokeSessionIDs and RevokeAllSessions sign out browser sessions without
+	// affecting the account's personal access tokens.
+	RevokeSessionIDs  []string `json:"revokeSessionIds,omitempty"`
+	RevokeAllSessions bool     `json:"revokeAllSessions,omitempty"`
 }
 
 type PlatformSettingsUpdateRequest struct {
-	LocalAuthEnabled *bool   `json:"localAuthEnabled,omitempty"`
-	BrandName        *string `json:"brandName,omitempty"`
+	LocalAuthEnabled     *bool   `json:"localAuthEnabled,omitempty"`
+	BrandName            *string `json:"brandName,omitempty"`
+	RequireVerifiedEmail *bool   `json:"requireVerifiedEmail,omitempty"`
+	RequireAdminMFA      *bool   `json:"requireAdminMfa,omitempty"`
+
+	DefaultWorkspaceStorageQuotaBytes *int64 `json:"defaultWorkspaceStorageQuotaBytes,omitempty"`
+}
+
+// WorkspaceStorageQuotaRequest sets a workspace quota override in bytes. A
+// null QuotaBytes returns the workspace to the platform default.
+type WorkspaceStorageQuotaRequest struct {
+	QuotaBytes *int64 `json:"quotaBytes"`
 }
 
 type CreateWorkspaceRequest struct {
@@ -542,8 +1117,33 @@ type UpdateCustomerRequest struct {
 	Archived     *bool   `json:"archived,omitempty"`
 }
 
+// UpsertProjectMemberAllocationRequest replaces an allocation; omitted dates
+// make it open-ended.
 type UpsertProjectMemberAllocationRequest struct {
 	DaysPerWeek float64 `json:"daysPerWeek"`
+	StartDate   *string `json:"startDate,omitempty"`
+	EndDate     *string `json:"endDate,omitempty"`
+}
+
+type CreateMemberAbsenceRequest struct {
+	UserID    string `json:"userId"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	Note      string `json:"note,omitempty"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"`
+	Collections []string `json:"collections"`
+	EventTypes  []string `json:"eventTypes"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url,omitempty"`
+	Collections []string `json:"collections,omitempty"`
+	EventTypes  []string `json:"eventTypes,omitempty"`
+	IsActive    *bool    `json:"isActive,omitempty"`
 }
 
 type CreateProjectMemberRequest struct {
@@ -598,6 +1198,9 @@ type CreateTasksBatchRequest struct {
 	IsEncrypted bool     `json:"isEncrypted"`
 }
 
+// UpdateTaskRequest changes the fields that are set. TimeSpent,
+// TimerStartedAt, and TimeEntries are derived from time entries and ignored;
+// IsTimerRunning starts or stops the caller's timer on the task.
 type UpdateTaskRequest struct {
 	Title          *string          `json:"title,omitempty"`
 	Description    *string          `json:"description,omitempty"`
@@ -637,6 +1240,75 @@ type UpdateProjectMilestoneRequest struct {
 	DueDate     *string `json:"dueDate,omitempty"`
 }
 
+type CreateSprintRequest struct {
+	Name      string `json:"name"`
+	Goal      string `json:"goal,omitempty"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+type UpdateSprintRequest struct {
+	Name      *string `json:"name,omitempty"`
+	Goal      *string `json:"goal,omitempty"`
+	StartDate *string `json:"startDate,omitempty"`
+	EndDate   *string `json:"endDate,omitempty"`
+}
+
+// CloseSprintRequest moves the incomplete tasks of a closing sprint into a
+// planned sprint of the same project; without one they return to the backlog.
+type CloseSprintRequest struct {
+	RolloverSprintID *string `json:"rolloverSprintId,omitempty"`
+}
+
+type SprintTasksRequest struct {
+	TaskIDs []string `json:"taskIds"`
+}
+
+// CreateTimeEntryRequest records finished work. The end is EndedAt or
+// StartedAt plus DurationSeconds.
+type CreateTimeEntryRequest struct {
+	StartedAt       time.Time  `json:"startedAt"`
+	EndedAt         *time.Time `json:"endedAt,omitempty"`
+	DurationSeconds *int       `json:"durationSeconds,omitempty"`
+	Note            string     `json:"note,omitempty"`
+	Billable        *bool      `json:"billable,omitempty"`
+}
+
+type UpdateTimeEntryRequest struct {
+	StartedAt       *time.Time `json:"startedAt,omitempty"`
+	EndedAt         *time.Time `json:"endedAt,omitempty"`
+	DurationSeconds *int       `json:"durationSeconds,omitempty"`
+	Note            *string    `json:"note,omitempty"`
+	Billable        *bool      `json:"billable,omitempty"`
+}
+
+// StartTimerRequest starts a timer on a task, stopping the user's running
+// timer first.
+type StartTimerRequest struct {
+	TaskID   string `json:"taskId"`
+	Note     string `json:"note,omitempty"`
+	Billable *bool  `json:"billable,omitempty"`
+}
+
+type CreateSavedViewRequest struct {
+	Scope    string          `json:"scope"`
+	Name     string          `json:"name"`
+	ViewMode string          `json:"viewMode,omitempty"`
+	Filter   string          `json:"filter,omitempty"`
+	Sort     string          `json:"sort,omitempty"`
+	GroupBy  string          `json:"groupBy,omitempty"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+type UpdateSavedViewRequest struct {
+	Name     *string         `json:"name,omitempty"`
+	ViewMode *string         `json:"viewMode,omitempty"`
+	Filter   *string         `json:"filter,omitempty"`
+	Sort     *string         `json:"sort,omitempty"`
+	GroupBy  *string         `json:"groupBy,omitempty"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
 type UpdateProjectTaskStatusRequest struct {
 	Label            *string `json:"label,omitempty"`
 	ColorToken       *string `json:"colorToken,omitempty"`
@@ -777,6 +1449,9 @@ type CreateVersionRequest struct {
 type ListResponse[T any] struct {
 	Total     int `json:"total"`
 	Documents []T `json:"documents"`
+	// NextCursor fetches the following page of a paginated list. It is
+	// empty on the last page and for unpaginated lists.
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type WSEvent struct {
@@ -0,0 +1,574 @@
+// Package recurrence computes the deadlines of recurring tasks. A task's
+// recurrence is a models.RecurrenceRule encoded as JSON, either the legacy
+// shorthand
+//
+//	{"type":"weekly","interval":2}
+//
+// or an RFC 5545 RRULE with options, for example the last business day of
+// every month in Berlin, counted from the day the task is completed:
+//
+//	{"rrule":"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1","timeZone":"Europe/Berlin","anchor":"completion"}
+//
+// Occurrences keep the local clock time of the deadline in the rule's time
+// zone, UTC by default, so they do not drift across daylight saving changes.
+// As in RFC 5545, periods without a matching day are skipped: a monthly rule
+// started on the 31st only recurs in months with 31 days. The legacy monthly
+// and yearly shorthand instead falls back to the last day of shorter months
+// and returns to the 31st afterwards. The date arithmetic it replaces ran
+// over into the following month, so a task due on January 31 came back on
+// March 3 and stayed on the 3rd.
+package recurrence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	// Embed the zone database; the runtime image does not ship one.
+	_ "time/tzdata"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+// Anchors of a series.
+const (
+	AnchorSchedule   = "schedule"
+	AnchorCompletion = "completion"
+)
+
+const (
+	maxInterval = 1000
+	maxCount    = 10000
+	maxSkips    = 100
+	// maxPeriods bounds how many periods in a row may pass without an
+	// occurrence, which ends rules that rarely or never match, such as the
+	// 30th of February.
+	maxPeriods = 5000
+)
+
+var (
+	frequencies = []string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+	weekdays    = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+	legacyTypes = map[string]string{"daily": "DAILY", "weekly": "WEEKLY", "monthly": "MONTHLY", "yearly": "YEARLY"}
+	unsupported = []string{"BYSECOND", "BYMINUTE", "BYHOUR", "BYYEARDAY", "BYWEEKNO", "RSCALE", "SKIP"}
+)
+
+// Weekday is a BYDAY entry: a weekday, optionally limited to the Nth (or,
+// when negative, the Nth last) one of the month or year.
+type Weekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed RRULE. Besides FREQ it supports INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, and WKST; time-of-day parts are
+// rejected because a task's deadline sets the time.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []Weekday
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+	// clamp moves a start day missing from a month to its last day.
+	clamp bool
+}
+
+// ParseRule parses an RRULE value, with or without the "RRULE:" prefix. A
+// floating or date-only UNTIL is read in loc; a date-only UNTIL includes the
+// whole day.
+func ParseRule(value string, loc *time.Location) (*Rule, error) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 6 && strings.EqualFold(value[:6], "RRULE:") {
+		value = value[6:]
+	}
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		name, list, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		list = strings.ToUpper(strings.TrimSpace(list))
+		if !ok || name == "" || list == "" {
+			return nil, fmt.Errorf("rule part %q must be NAME=VALUE", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s is given more than once", name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			if !slices.Contains(frequencies, list) {
+				return nil, errors.New("FREQ must be DAILY, WEEKLY, MONTHLY, or YEARLY")
+			}
+			rule.Freq = list
+		case "INTERVAL":
+			rule.Interval, err = parseNumber(name, list, 1, maxInterval)
+		case "COUNT":
+			rule.Count, err = parseNumber(name, list, 1, maxCount)
+		case "UNTIL":
+			until, untilErr := parseUntil(list, loc)
+			rule.Until, err = &until, untilErr
+		case "BYDAY":
+			rule.ByDay, err = parseWeekdays(list)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseNumbers(name, list, 31, true)
+		case "BYMONTH":
+			rule.ByMonth, err = parseNumbers(name, list, 12, false)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseNumbers(name, list, 366, true)
+		case "WKST":
+			day, ok := weekdays[list]
+			if !ok {
+				return nil, errors.New("WKST must be a weekday such as MO")
+			}
+			rule.WeekStart = day
+		default:
+			if slices.Contains(unsupported, name) {
+				return nil, fmt.Errorf("%s is not supported", name)
+			}
+			return nil, fmt.Errorf("unknown rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	return rule, rule.validate()
+}
+
+func (r *Rule) validate() error {
+	if r.Count > 0 && r.Until != nil {
+		return errors.New("COUNT and UNTIL cannot be combined")
+	}
+	if r.Freq == "WEEKLY" && len(r.ByMonthDay) > 0 {
+		return errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	if r.Freq != "MONTHLY" && r.Freq != "YEARLY" {
+		for _, day := range r.ByDay {
+			if day.N != 0 {
+				return errors.New("numbered BYDAY needs FREQ=MONTHLY or YEARLY")
+			}
+		}
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return errors.New("BYSETPOS needs BYDAY, BYMONTHDAY, or BYMONTH")
+	}
+	return nil
+}
+
+func parseNumber(name, value string, minimum, maximum int) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < minimum || number > maximum {
+		return 0, fmt.Errorf("%s must be a number from %d to %d", name, minimum, maximum)
+	}
+	return number, nil
+}
+
+// parseNumbers parses a comma-separated list of numbers from 1 to maximum,
+// or from -maximum to -1 too when signed.
+func parseNumbers(name, value string, maximum int, signed bool) ([]int, error) {
+	numbers := []int{}
+	for _, item := range strings.Split(value, ",") {
+		number, err := strconv.Atoi(strings.TrimPrefix(item, "+"))
+		if err != nil || number == 0 || number > maximum || number < -maximum || (!signed && number < 0) {
+			if signed {
+				return nil, fmt.Errorf("%s must list numbers from 1 to %d or -%d to -1", name, maximum, maximum)
+			}
+			return nil, fmt.Errorf("%s must list numbers from 1 to %d", name, maximum)
+		}
+		if !slices.Contains(numbers, number) {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
+
+func parseWeekdays(value string) ([]Weekday, error) {
+	days := []Weekday{}
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("BYDAY entry %q must be a weekday such as MO, 2TU, or -1FR", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("BYDAY entry %q must be a weekday such as MO, 2TU, or -1FR", item)
+		}
+		n := 0
+		if prefix := strings.TrimPrefix(item[:len(item)-2], "+"); prefix != "" {
+			number, err := strconv.Atoi(prefix)
+			if err != nil || number == 0 || number > 53 || number < -53 {
+				return nil, fmt.Errorf("BYDAY entry %q must be a weekday such as MO, 2TU, or -1FR", item)
+			}
+			n = number
+		}
+		if weekday := (Weekday{N: n, Weekday: day}); !slices.Contains(days, weekday) {
+			days = append(days, weekday)
+		}
+	}
+	return days, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	if until, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return until, nil
+	}
+	if until, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return endOfDay(until), nil
+	}
+	return time.Time{}, errors.New("UNTIL must be a date such as 20261231 or 20261231T170000Z")
+}
+
+func endOfDay(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, day.Location())
+}
+
+// occurrences calls yield with the 1-based index and time of each occurrence
+// of the rule starting at start, which is the first one, until yield returns
+// false or UNTIL passes. COUNT is left to the caller, which knows where the
+// series began. Later occurrences take the local clock time of start.
+func (r *Rule) occurrences(start time.Time, yield func(index int, at time.Time) bool) {
+	if r.Until != nil && start.After(*r.Until) {
+		return
+	}
+	if !yield(1, start) {
+		return
+	}
+	loc := start.Location()
+	hour, minute, second := start.Clock()
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	index := 1
+	for period, empty := 0, 0; empty < maxPeriods; period++ {
+		days := r.setPositions(r.periodDays(first, period))
+		if len(days) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, day := range days {
+			at := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, loc)
+			if !at.After(start) {
+				continue
+			}
+			if r.Until != nil && at.After(*r.Until) {
+				return
+			}
+			index++
+			if !yield(index, at) {
+				return
+			}
+		}
+	}
+}
+
+// periodDays returns the matching days, as UTC midnights, of the period-th
+// period after the one containing first.
+func (r *Rule) periodDays(first time.Time, period int) []time.Time {
+	days := []time.Time{}
+	switch r.Freq {
+	case "DAILY":
+		day := first.AddDate(0, 0, period*r.Interval)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+	case "WEEKLY":
+		offset := (int(first.Weekday()) - int(r.WeekStart) + 7) % 7
+		week := first.AddDate(0, 0, 7*period*r.Interval-offset)
+		for i := range 7 {
+			day := week.AddDate(0, 0, i)
+			matches := day.Weekday() == first.Weekday()
+			if len(r.ByDay) > 0 {
+				matches = r.matchesWeekday(day)
+			}
+			if matches && r.matchesMonth(day) {
+				days = append(days, day)
+			}
+		}
+	case "MONTHLY":
+		month := time.Date(first.Year(), first.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(month) {
+			days = r.monthDays(month, first.Day())
+		}
+	case "YEARLY":
+		year := first.Year() + period*r.Interval
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, month := range slices.Sorted(slices.Values(r.ByMonth)) {
+				days = append(days, r.monthDays(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), first.Day())...)
+			}
+		case len(r.ByMonthDay) > 0:
+			for month := time.January; month <= time.December; month++ {
+				days = append(days, r.monthDays(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), first.Day())...)
+			}
+		case len(r.ByDay) > 0:
+			start, end := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+			for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+				if r.matchesNthWeekday(day, start, end) {
+					days = append(days, day)
+				}
+			}
+		default:
+			days = r.monthDays(time.Date(year, first.Month(), 1, 0, 0, 0, 0, time.UTC), first.Day())
+		}
+	}
+	return days
+}
+
+// monthDays returns the matching days of the month starting at month. Without
+// BYMONTHDAY or BYDAY that is the day of the series start, if the month has
+// one, or its last day when the rule clamps.
+func (r *Rule) monthDays(month time.Time, startDay int) []time.Time {
+	end := month.AddDate(0, 1, -1)
+	days := []time.Time{}
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if startDay <= end.Day() {
+			days = append(days, month.AddDate(0, 0, startDay-1))
+		} else if r.clamp {
+			days = append(days, end)
+		}
+		return days
+	}
+	for day := month; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if r.matchesMonthDay(day) && (len(r.ByDay) == 0 || r.matchesNthWeekday(day, month, end)) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesMonth(day time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, int(day.Month()))
+}
+
+func (r *Rule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := day.AddDate(0, 1, -day.Day()).Day()
+	return slices.Contains(r.ByMonthDay, day.Day()) || slices.Contains(r.ByMonthDay, day.Day()-last-1)
+}
+
+func (r *Rule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.ByDay, func(weekday Weekday) bool { return weekday.Weekday == day.Weekday() })
+}
+
+// matchesNthWeekday matches BYDAY, counting numbered entries within the
+// days from start to end.
+func (r *Rule) matchesNthWeekday(day, start, end time.Time) bool {
+	fromStart := int(day.Sub(start).Hours()/24)/7 + 1
+	fromEnd := int(end.Sub(day).Hours()/24)/7 + 1
+	return slices.ContainsFunc(r.ByDay, func(weekday Weekday) bool {
+		return weekday.Weekday == day.Weekday() && (weekday.N == 0 || weekday.N == fromStart || weekday.N == -fromEnd)
+	})
+}
+
+// setPositions keeps the BYSETPOS positions of a period's sorted days.
+func (r *Rule) setPositions(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return days
+	}
+	kept := []time.Time{}
+	for _, position := range r.BySetPos {
+		index := position - 1
+		if position < 0 {
+			index = len(days) + position
+		}
+		if index >= 0 && index < len(days) && !slices.Contains(kept, days[index]) {
+			kept = append(kept, days[index])
+		}
+	}
+	slices.SortFunc(kept, func(a, b time.Time) int { return a.Compare(b) })
+	return kept
+}
+
+// Series is a validated task recurrence.
+type Series struct {
+	Settings models.RecurrenceRule
+	raw      string
+	rule     *Rule
+	loc      *time.Location
+	skip     map[string]bool
+}
+
+// Parse validates a task's recurrence value. It returns nil for an empty
+// value, which means the task does not recur. A legacy interval below one
+// counts as one.
+func Parse(value string) (*Series, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	series := &Series{raw: value, loc: time.UTC, skip: map[string]bool{}}
+	if err := json.Unmarshal([]byte(value), &series.Settings); err != nil {
+		return nil, errors.New("recurrence must be a JSON object")
+	}
+	settings := series.Settings
+	if settings.TimeZone != "" {
+		loc, err := time.LoadLocation(settings.TimeZone)
+		if err != nil || settings.TimeZone == "Local" {
+			return nil, fmt.Errorf("unknown time zone %q", settings.TimeZone)
+		}
+		series.loc = loc
+	}
+	if settings.Anchor != "" && settings.Anchor != AnchorSchedule && settings.Anchor != AnchorCompletion {
+		return nil, errors.New("anchor must be schedule or completion")
+	}
+	if settings.Occurrence < 0 {
+		return nil, errors.New("occurrence must not be negative")
+	}
+	if len(settings.Skip) > maxSkips {
+		return nil, fmt.Errorf("skip must not list more than %d dates", maxSkips)
+	}
+	for _, day := range settings.Skip {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			return nil, errors.New("skip must list dates such as 2026-10-20")
+		}
+		series.skip[day] = true
+	}
+	var err error
+	if settings.RRule != "" {
+		series.rule, err = ParseRule(settings.RRule, series.loc)
+	} else {
+		series.rule, err = legacyRule(settings, series.loc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// legacyRule maps the {"type","interval","endDate"} shorthand to a rule.
+// Monthly and yearly rules clamp: a start day that a shorter month lacks
+// falls back to the month's last day instead of skipping the month.
+func legacyRule(settings models.RecurrenceRule, loc *time.Location) (*Rule, error) {
+	freq, ok := legacyTypes[settings.Type]
+	if !ok {
+		return nil, errors.New("type must be daily, weekly, monthly, or yearly, or rrule must be set")
+	}
+	rule := &Rule{Freq: freq, Interval: max(settings.Interval, 1), WeekStart: time.Monday, clamp: freq == "MONTHLY" || freq == "YEARLY"}
+	if rule.Interval > maxInterval {
+		return nil, fmt.Errorf("interval must not exceed %d", maxInterval)
+	}
+	if settings.EndDate != "" {
+		end, err := time.ParseInLocation(time.DateOnly, settings.EndDate, loc)
+		if err != nil {
+			parsed, rfcErr := time.Parse(time.RFC3339, settings.EndDate)
+			if rfcErr != nil {
+				return nil, errors.New("endDate must be a date such as 2026-12-31")
+			}
+			end = parsed.In(loc)
+		}
+		until := endOfDay(end)
+		rule.Until = &until
+	}
+	return rule, nil
+}
+
+// Next returns the deadline of the task that follows one due at deadline and
+// completed at completedAt, and the recurrence value to store on it. ok is
+// false when the series is paused or has ended.
+func (s *Series) Next(deadline, completedAt time.Time) (next time.Time, value string, ok bool) {
+	if s.Settings.Paused {
+		return time.Time{}, "", false
+	}
+	if s.Settings.Anchor == AnchorCompletion {
+		completed := completedAt.In(s.loc)
+		hour, minute, second := deadline.In(s.loc).Clock()
+		return s.advance(time.Date(completed.Year(), completed.Month(), completed.Day(), hour, minute, second, 0, s.loc), false)
+	}
+	return s.advance(deadline, true)
+}
+
+// Skip returns the scheduled occurrence after the one due at deadline, so a
+// task can move there without being completed. It works on paused series.
+func (s *Series) Skip(deadline time.Time) (next time.Time, value string, ok bool) {
+	return s.advance(deadline, s.Settings.Anchor != AnchorCompletion)
+}
+
+// Upcoming lists up to limit scheduled occurrences after deadline, assuming
+// each is completed on time.
+func (s *Series) Upcoming(deadline time.Time, limit int) []time.Time {
+	upcoming := []time.Time{}
+	s.walk(deadline, s.Settings.Anchor != AnchorCompletion, func(_ int, at time.Time) bool {
+		upcoming = append(upcoming, at)
+		return len(upcoming) < limit
+	})
+	return upcoming
+}
+
+// walk calls yield with the series index and time of the occurrences after
+// from that are not skipped. A scheduled walk follows the stored series start
+// when there is one; otherwise the series restarts at from.
+func (s *Series) walk(from time.Time, scheduled bool, yield func(index int, at time.Time) bool) {
+	start, offset := from.In(s.loc), max(s.Settings.Occurrence, 1)-1
+	if scheduled && s.Settings.Start != nil {
+		start, offset = s.Settings.Start.In(s.loc), 0
+	}
+	s.rule.occurrences(start, func(index int, at time.Time) bool {
+		if s.rule.Count > 0 && index+offset > s.rule.Count {
+			return false
+		}
+		if !at.After(from) || s.skip[at.Format(time.DateOnly)] {
+			return true
+		}
+		return yield(index+offset, at)
+	})
+}
+
+func (s *Series) advance(from time.Time, scheduled bool) (time.Time, string, bool) {
+	var next time.Time
+	occurrence := 0
+	s.walk(from, scheduled, func(index int, at time.Time) bool {
+		next, occurrence = at, index
+		return false
+	})
+	if next.IsZero() {
+		return time.Time{}, "", false
+	}
+	if !scheduled {
+		// A completion-anchored series restarts at every completion, so its
+		// count goes up by one no matter which occurrence comes next.
+		occurrence = max(s.Settings.Occurrence, 1) + 1
+		if s.rule.Count > 0 && occurrence > s.rule.Count {
+			return time.Time{}, "", false
+		}
+	}
+	// The legacy shorthand is stored unchanged, so clients that only know it
+	// keep reading the same value. Clamping rules keep their start instead:
+	// continuing from February 28 would move a series on the 31st to the 28th.
+	if s.Settings.RRule == "" && len(s.Settings.Skip) == 0 && !(scheduled && s.rule.clamp) {
+		return next, s.raw, true
+	}
+	settings := s.Settings
+	settings.Occurrence, settings.Start = occurrence, nil
+	if scheduled {
+		start := from.In(s.loc)
+		if s.Settings.Start != nil {
+			start = s.Settings.Start.In(s.loc)
+		}
+		settings.Start = &start
+	}
+	// Dates before the next occurrence can no longer be skipped.
+	settings.Skip = slices.DeleteFunc(slices.Clone(settings.Skip), func(day string) bool { return day < next.Format(time.DateOnly) })
+	if len(settings.Skip) == 0 {
+		settings.Skip = nil
+	}
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return next, string(encoded), true
+}
@@ -0,0 +1,230 @@
+package recurrence
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+func mustParse(t *testing.T, value string) *Series {
+	t.Helper()
+	series, err := Parse(value)
+	if err != nil {
+		t.Fatalf("Parse(%s) error = %v", value, err)
+	}
+	return series
+}
+
+func dates(times []time.Time) string {
+	formatted := []string{}
+	for _, at := range times {
+		formatted = append(formatted, at.Format(time.DateOnly))
+	}
+	return strings.Join(formatted, " ")
+}
+
+func TestUpcoming(t *testing.T) {
+	// 2026-10-16 is a Friday.
+	deadline := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "legacy daily", value: `{"type":"daily","interval":3}`, want: "2026-10-19 2026-10-22 2026-10-25 2026-10-28"},
+		{name: "legacy interval below one", value: `{"type":"weekly","interval":0}`, want: "2026-10-23 2026-10-30 2026-11-06 2026-11-13"},
+		{name: "legacy end date", value: `{"type":"weekly","interval":1,"endDate":"2026-10-30"}`, want: "2026-10-23 2026-10-30"},
+		{name: "weekdays", value: `{"rrule":"FREQ=WEEKLY;BYDAY=MO,WE,FR"}`, want: "2026-10-19 2026-10-21 2026-10-23 2026-10-26"},
+		{name: "every other week", value: `{"rrule":"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,FR"}`, want: "2026-10-27 2026-10-30 2026-11-10 2026-11-13"},
+		{name: "month day", value: `{"rrule":"FREQ=MONTHLY;BYMONTHDAY=1,15"}`, want: "2026-11-01 2026-11-15 2026-12-01 2026-12-15"},
+		{name: "last day of month", value: `{"rrule":"FREQ=MONTHLY;BYMONTHDAY=-1"}`, want: "2026-10-31 2026-11-30 2026-12-31 2027-01-31"},
+		{name: "last business day of month", value: `{"rrule":"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"}`, want: "2026-10-30 2026-11-30 2026-12-31 2027-01-29"},
+		{name: "second tuesday", value: `{"rrule":"FREQ=MONTHLY;BYDAY=2TU"}`, want: "2026-11-10 2026-12-08 2027-01-12 2027-02-09"},
+		{name: "count includes the first", value: `{"rrule":"FREQ=DAILY;COUNT=3"}`, want: "2026-10-17 2026-10-18"},
+		{name: "until", value: `{"rrule":"FREQ=DAILY;UNTIL=20261018"}`, want: "2026-10-17 2026-10-18"},
+		{name: "skipped dates", value: `{"rrule":"FREQ=DAILY;COUNT=4","skip":["2026-10-18"]}`, want: "2026-10-17 2026-10-19"},
+		{name: "yearly in march", value: `{"rrule":"FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU"}`, want: "2027-03-28 2028-03-26 2029-03-25 2030-03-31"},
+		{name: "legacy monthly", value: `{"type":"monthly","interval":1}`, want: "2026-11-16 2026-12-16 2027-01-16 2027-02-16"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dates(mustParse(t, tt.value).Upcoming(deadline, 4)); got != tt.want {
+				t.Fatalf("Upcoming() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortMonths(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		deadline time.Time
+		want     string
+	}{
+		{name: "rrule skips them", value: `{"rrule":"FREQ=MONTHLY"}`, deadline: time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC), want: "2026-03-31 2026-05-31 2026-07-31"},
+		{name: "legacy monthly clamps", value: `{"type":"monthly","interval":1}`, deadline: time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC), want: "2026-02-28 2026-03-31 2026-04-30"},
+		{name: "legacy monthly in a leap year", value: `{"type":"monthly","interval":1}`, deadline: time.Date(2028, 1, 31, 9, 0, 0, 0, time.UTC), want: "2028-02-29 2028-03-31 2028-04-30"},
+		{name: "legacy yearly clamps", value: `{"type":"yearly","interval":1}`, deadline: time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC), want: "2029-02-28 2030-02-28 2031-02-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dates(mustParse(t, tt.value).Upcoming(tt.deadline, 3)); got != tt.want {
+				t.Fatalf("Upcoming() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	next, _, ok := mustParse(t, `{"type":"monthly","interval":1}`).Next(time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC))
+	if !ok || next.Format(time.DateOnly) != "2026-02-28" {
+		t.Fatalf("Next() = %s, %v, want 2026-02-28", next, ok)
+	}
+}
+
+func TestNextChainMatchesUpcoming(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		deadline time.Time
+	}{
+		{name: "legacy monthly on the 31st", value: `{"type":"monthly","interval":1}`, deadline: time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)},
+		{name: "legacy yearly on a leap day", value: `{"type":"yearly","interval":1}`, deadline: time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC)},
+		{name: "legacy weekly", value: `{"type":"weekly","interval":2}`, deadline: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)},
+		{name: "rrule month day", value: `{"rrule":"FREQ=MONTHLY;BYMONTHDAY=31"}`, deadline: time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := dates(mustParse(t, tt.value).Upcoming(tt.deadline, 3))
+			chained := []time.Time{}
+			deadline, value := tt.deadline, tt.value
+			for range 3 {
+				next, nextValue, ok := mustParse(t, value).Next(deadline, deadline)
+				if !ok {
+					t.Fatalf("Next() ended the series after %s", dates(chained))
+				}
+				chained = append(chained, next)
+				deadline, value = next, nextValue
+			}
+			if got := dates(chained); got != want {
+				t.Fatalf("chained Next() = %s, Upcoming() = %s", got, want)
+			}
+		})
+	}
+}
+
+func TestNextFarFromTheStart(t *testing.T) {
+	start := time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC)
+	value := `{"rrule":"FREQ=DAILY","occurrence":9000,"start":"` + start.Format(time.RFC3339) + `"}`
+	deadline := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	next, _, ok := mustParse(t, value).Next(deadline, deadline)
+	if !ok || next.Format(time.DateOnly) != "2026-10-17" {
+		t.Fatalf("Next() = %s, %v, want 2026-10-17", next, ok)
+	}
+	if _, _, ok := mustParse(t, `{"rrule":"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30"}`).Next(deadline, deadline); ok {
+		t.Fatal("Next() found a 30th of February")
+	}
+}
+
+func TestNextKeepsLocalTimeAcrossDaylightSaving(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	deadline := time.Date(2026, 10, 23, 9, 0, 0, 0, berlin)
+	next, _, ok := mustParse(t, `{"type":"weekly","interval":1,"timeZone":"Europe/Berlin"}`).Next(deadline, deadline)
+	if !ok {
+		t.Fatal("Next() ended the series")
+	}
+	if want := time.Date(2026, 10, 30, 8, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("Next() = %s, want %s", next.UTC(), want)
+	}
+}
+
+func TestNextFollowsTheSchedule(t *testing.T) {
+	series := mustParse(t, `{"rrule":"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3"}`)
+	deadline := time.Date(2026, 10, 30, 17, 0, 0, 0, time.UTC)
+	late := time.Date(2026, 11, 3, 12, 0, 0, 0, time.UTC)
+
+	next, value, ok := series.Next(deadline, late)
+	if !ok || next.Format(time.DateOnly) != "2026-11-30" {
+		t.Fatalf("Next() = %s, %v", next, ok)
+	}
+	var settings models.RecurrenceRule
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Occurrence != 2 || settings.Start == nil || !settings.Start.Equal(deadline) {
+		t.Fatalf("settings = %+v", settings)
+	}
+
+	next, value, ok = mustParse(t, value).Next(next, late)
+	if !ok || next.Format(time.DateOnly) != "2026-12-31" {
+		t.Fatalf("second Next() = %s, %v", next, ok)
+	}
+	if _, _, ok := mustParse(t, value).Next(next, next); ok {
+		t.Fatal("Next() continued past COUNT")
+	}
+}
+
+func TestNextFromCompletion(t *testing.T) {
+	series := mustParse(t, `{"rrule":"FREQ=WEEKLY;BYDAY=MO","anchor":"completion","occurrence":2,"skip":["2026-10-26"]}`)
+	deadline := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	completed := time.Date(2026, 10, 21, 18, 30, 0, 0, time.UTC)
+	next, value, ok := series.Next(deadline, completed)
+	if !ok {
+		t.Fatal("Next() ended the series")
+	}
+	if want := time.Date(2026, 11, 2, 10, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("Next() = %s, want %s", next, want)
+	}
+	if want := `{"rrule":"FREQ=WEEKLY;BYDAY=MO","anchor":"completion","occurrence":3}`; value != want {
+		t.Fatalf("value = %s, want %s", value, want)
+	}
+}
+
+func TestNextPausedAndLegacy(t *testing.T) {
+	deadline := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	if _, _, ok := mustParse(t, `{"type":"daily","interval":1,"paused":true}`).Next(deadline, deadline); ok {
+		t.Fatal("paused series recurred")
+	}
+	legacy := `{"type":"weekly","interval":2}`
+	next, value, ok := mustParse(t, legacy).Next(deadline, deadline.AddDate(0, 0, 5))
+	if !ok || next.Format(time.DateOnly) != "2026-10-30" || value != legacy {
+		t.Fatalf("Next() = %s, %s, %v", next, value, ok)
+	}
+	skipped, _, ok := mustParse(t, `{"type":"daily","interval":1,"paused":true}`).Skip(deadline)
+	if !ok || skipped.Format(time.DateOnly) != "2026-10-17" {
+		t.Fatalf("Skip() = %s, %v", skipped, ok)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: `[]`, want: "recurrence must be a JSON object"},
+		{value: `{"type":"hourly"}`, want: "type must be daily, weekly, monthly, or yearly, or rrule must be set"},
+		{value: `{"rrule":"INTERVAL=2"}`, want: "FREQ is required"},
+		{value: `{"rrule":"FREQ=HOURLY"}`, want: "FREQ must be DAILY, WEEKLY, MONTHLY, or YEARLY"},
+		{value: `{"rrule":"FREQ=DAILY;BYHOUR=9"}`, want: "BYHOUR is not supported"},
+		{value: `{"rrule":"FREQ=DAILY;COLOR=RED"}`, want: "unknown rule part COLOR"},
+		{value: `{"rrule":"FREQ=DAILY;COUNT=3;UNTIL=20261231"}`, want: "COUNT and UNTIL cannot be combined"},
+		{value: `{"rrule":"FREQ=WEEKLY;BYDAY=1MO"}`, want: "numbered BYDAY needs FREQ=MONTHLY or YEARLY"},
+		{value: `{"rrule":"FREQ=MONTHLY;BYMONTHDAY=0"}`, want: "BYMONTHDAY must list numbers from 1 to 31 or -31 to -1"},
+		{value: `{"rrule":"FREQ=MONTHLY;BYSETPOS=1"}`, want: "BYSETPOS needs BYDAY, BYMONTHDAY, or BYMONTH"},
+		{value: `{"rrule":"FREQ=DAILY","timeZone":"Mars/Olympus"}`, want: `unknown time zone "Mars/Olympus"`},
+		{value: `{"rrule":"FREQ=DAILY","anchor":"never"}`, want: "anchor must be schedule or completion"},
+		{value: `{"rrule":"FREQ=DAILY","skip":["tomorrow"]}`, want: "skip must list dates such as 2026-10-20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, err := Parse(tt.value)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if series, err := Parse(" "); series != nil || err != nil {
+		t.Fatalf("Parse(blank) = %v, %v", series, err)
+	}
+}
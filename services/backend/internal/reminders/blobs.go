@@ -0,0 +1,50 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/storage"
+	"github.com/justlabv1/justspace/backend/internal/thumbnails"
+)
+
+const fileBlobCleanupBatch = 100
+
+// RunFileBlobCleanup deletes stored blobs whose last file disappeared without
+// going through the file API, for example when a project or task was deleted.
+// Cleanup runs once on startup and once per hour.
+func RunFileBlobCleanup(repo *repository.Repo, store storage.FileStore) {
+	cleanup := func() {
+		ctx := context.Background()
+		for {
+			released, err := repo.ReleaseUnreferencedFileBlobs(ctx, fileBlobCleanupBatch, func(blob models.StoredFile) error {
+				if err := store.Delete(ctx, blob.StoragePath); err != nil {
+					log.Printf("file blob cleanup error for %s: %v", blob.StoragePath, err)
+					return err
+				}
+				if blob.HasThumbnails {
+					if err := thumbnails.Delete(ctx, store, blob.StoragePath); err != nil {
+						log.Printf("file thumbnail cleanup error for %s: %v", blob.StoragePath, err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				log.Printf("file blob cleanup error: %v", err)
+				return
+			}
+			if released < fileBlobCleanupBatch {
+				return
+			}
+		}
+	}
+	cleanup()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		cleanup()
+	}
+}
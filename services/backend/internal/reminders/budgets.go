@@ -0,0 +1,108 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
+)
+
+// budgetCheckInterval spaces out budget checks, which sum every time entry of
+// the budgeted projects.
+const budgetCheckInterval = 5 * time.Minute
+
+// budgetStore is the part of *repository.Repo budget alerts use.
+type budgetStore interface {
+	ListBudgetUsage(ctx context.Context, minPercent int) ([]models.BudgetUsage, error)
+	ClaimBudgetAlerts(ctx context.Context, projectID string, hourBudget float64, thresholds []int) ([]int, error)
+	ListProjectAdminIDs(ctx context.Context, projectID string) ([]string, error)
+	CreateNotification(ctx context.Context, recipientUserID, actorUserID, notificationType, projectID, taskID string, commentID *string, metadata any) (*models.Notification, error)
+}
+
+// BudgetAlertService notifies the owners and admins of a project when the
+// time logged on it reaches one of the thresholds, in percent of its hour
+// budget. Each threshold is alerted once per budget; when several are reached
+// at once only the highest is announced.
+type BudgetAlertService struct {
+	repo       budgetStore
+	hub        *websocket.Hub
+	thresholds []int
+}
+
+func NewBudgetAlertService(repo *repository.Repo, hub *websocket.Hub, thresholds []int) *BudgetAlertService {
+	return &BudgetAlertService{repo: repo, hub: hub, thresholds: thresholds}
+}
+
+func (s *BudgetAlertService) Run() {
+	if len(s.thresholds) == 0 {
+		return
+	}
+	s.check(context.Background())
+	ticker := time.NewTicker(budgetCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.check(context.Background())
+	}
+}
+
+func (s *BudgetAlertService) check(ctx context.Context) {
+	projects, err := s.repo.ListBudgetUsage(ctx, s.thresholds[0])
+	if err != nil {
+		log.Printf("budget alert query error: %v", err)
+		return
+	}
+	for _, project := range projects {
+		reached := reachedThresholds(project, s.thresholds)
+		if len(reached) == 0 {
+			continue
+		}
+		claimed, err := s.repo.ClaimBudgetAlerts(ctx, project.ProjectID, project.HourBudget, reached)
+		if err != nil {
+			log.Printf("budget alert claim error: %v", err)
+			continue
+		}
+		if len(claimed) == 0 {
+			continue
+		}
+		s.notify(ctx, project, slices.Max(claimed))
+	}
+}
+
+// reachedThresholds returns the thresholds, in percent of the hour budget,
+// that the project's logged time has reached. Projects without a budget
+// reach none.
+func reachedThresholds(project models.BudgetUsage, thresholds []int) []int {
+	reached := []int{}
+	if project.HourBudget <= 0 {
+		return reached
+	}
+	for _, threshold := range thresholds {
+		if project.LoggedHours*100 >= project.HourBudget*float64(threshold) {
+			reached = append(reached, threshold)
+		}
+	}
+	return reached
+}
+
+func (s *BudgetAlertService) notify(ctx context.Context, project models.BudgetUsage, threshold int) {
+	recipients, err := s.repo.ListProjectAdminIDs(ctx, project.ProjectID)
+	if err != nil {
+		log.Printf("budget alert recipients error: %v", err)
+		return
+	}
+	metadata := map[string]any{"threshold": threshold, "hourBudget": project.HourBudget, "loggedHours": project.LoggedHours}
+	for _, recipientID := range recipients {
+		notification, err := s.repo.CreateNotification(ctx, recipientID, project.OwnerID, "budget_alert", project.ProjectID, "", nil, metadata)
+		if err != nil {
+			log.Printf("budget notification error: %v", err)
+			continue
+		}
+		if notification != nil {
+			s.hub.Broadcast(recipientID, models.WSEvent{Type: "create", Collection: "notifications", Document: notification, UserID: project.OwnerID})
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
+)
+
+func TestReachedThresholds(t *testing.T) {
+	thresholds := []int{50, 80, 100}
+	tests := []struct {
+		name    string
+		project models.BudgetUsage
+		want    []int
+	}{
+		{name: "below every threshold", project: models.BudgetUsage{HourBudget: 100, LoggedHours: 49.9}, want: []int{}},
+		{name: "exactly at a threshold", project: models.BudgetUsage{HourBudget: 100, LoggedHours: 50}, want: []int{50}},
+		{name: "several at once", project: models.BudgetUsage{HourBudget: 40, LoggedHours: 35}, want: []int{50, 80}},
+		{name: "over budget", project: models.BudgetUsage{HourBudget: 10, LoggedHours: 12}, want: []int{50, 80, 100}},
+		{name: "no budget", project: models.BudgetUsage{LoggedHours: 12}, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reachedThresholds(tt.project, thresholds); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("reachedThresholds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeBudgetStore claims each threshold once per project and budget, like
+// the project_budget_alerts table.
+type fakeBudgetStore struct {
+	usage         []models.BudgetUsage
+	claimed       map[string]bool
+	notifications []map[string]any
+}
+
+func (f *fakeBudgetStore) ListBudgetUsage(context.Context, int) ([]models.BudgetUsage, error) {
+	return f.usage, nil
+}
+
+func (f *fakeBudgetStore) ClaimBudgetAlerts(_ context.Context, projectID string, hourBudget float64, thresholds []int) ([]int, error) {
+	claimed := []int{}
+	for _, threshold := range thresholds {
+		key := fmt.Sprintf("%s/%g/%d", projectID, hourBudget, threshold)
+		if !f.claimed[key] {
+			f.claimed[key] = true
+			claimed = append(claimed, threshold)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeBudgetStore) ListProjectAdminIDs(context.Context, string) ([]string, error) {
+	return []string{"owner"}, nil
+}
+
+func (f *fakeBudgetStore) CreateNotification(_ context.Context, _, _, _, projectID, _ string, _ *string, metadata any) (*models.Notification, error) {
+	values := metadata.(map[string]any)
+	f.notifications = append(f.notifications, map[string]any{"project": projectID, "threshold": values["threshold"]})
+	return &models.Notification{ID: fmt.Sprint(len(f.notifications))}, nil
+}
+
+func TestBudgetAlertCheck(t *testing.T) {
+	store := &fakeBudgetStore{claimed: map[string]bool{}}
+	service := &BudgetAlertService{repo: store, hub: websocket.NewHub("secret", "", nil), thresholds: []int{50, 80, 100}}
+	check := func(usage ...models.BudgetUsage) []map[string]any {
+		store.usage, store.notifications = usage, nil
+		service.check(context.Background())
+		return store.notifications
+	}
+
+	if got := check(models.BudgetUsage{ProjectID: "p1", HourBudget: 10, LoggedHours: 8.5}); !reflect.DeepEqual(got, []map[string]any{{"project": "p1", "threshold": 80}}) {
+		t.Fatalf("first check notified %v, want only the highest threshold", got)
+	}
+	if got := check(models.BudgetUsage{ProjectID: "p1", HourBudget: 10, LoggedHours: 9}); len(got) != 0 {
+		t.Fatalf("repeated check notified %v", got)
+	}
+	if got := check(models.BudgetUsage{ProjectID: "p1", HourBudget: 10, LoggedHours: 10}); !reflect.DeepEqual(got, []map[string]any{{"project": "p1", "threshold": 100}}) {
+		t.Fatalf("crossing 100%% notified %v", got)
+	}
+	if got := check(models.BudgetUsage{ProjectID: "p1", HourBudget: 20, LoggedHours: 10}); !reflect.DeepEqual(got, []map[string]any{{"project": "p1", "threshold": 50}}) {
+		t.Fatalf("raised budget notified %v, want the alerts re-armed", got)
+	}
+	if got := check(models.BudgetUsage{ProjectID: "p2", LoggedHours: 10}); len(got) != 0 {
+		t.Fatalf("project without a budget notified %v", got)
+	}
+}
@@ -0,0 +1,133 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/mailer"
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+)
+
+const (
+	emailBatchSize = 50
+	// Notifications older than this are not emailed, e.g. after SMTP is
+	// configured on an existing installation or after a longer outage.
+	staleEmailAge = 48 * time.Hour
+)
+
+// digestIntervals are the minimum gaps between two digests per delivery mode.
+var digestIntervals = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+}
+
+// EmailService mirrors in-app notifications to email, either one message per
+// notification or as hourly/daily digests depending on each user's preferences.
+type EmailService struct {
+	repo   *repository.Repo
+	mailer *mailer.Mailer
+}
+
+func NewEmailService(repo *repository.Repo, m *mailer.Mailer) *EmailService {
+	return &EmailService{repo: repo, mailer: m}
+}
+
+func (s *EmailService) Run() {
+	if !s.mailer.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.check(context.Background())
+	}
+}
+
+func (s *EmailService) check(ctx context.Context) {
+	brandName := "justspace"
+	if settings, err := s.repo.GetPlatformSettings(ctx); err == nil && settings != nil && settings.BrandName != "" {
+		brandName = settings.BrandName
+	}
+	recipients := map[string]*models.User{}
+	recipient := func(userID string) *models.User {
+		if user, ok := recipients[userID]; ok {
+			return user
+		}
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			log.Printf("email recipient error: %v", err)
+		}
+		if user != nil && !user.IsActive {
+			user = nil
+		}
+		recipients[userID] = user
+		return user
+	}
+
+	for {
+		notifications, err := s.repo.ClaimInstantEmailNotifications(ctx, emailBatchSize)
+		if err != nil {
+			log.Printf("email notification claim error: %v", err)
+			break
+		}
+		for _, notification := range notifications {
+			user := recipient(notification.RecipientUserID)
+			if user == nil || notification.ReadAt != nil || time.Since(notification.CreatedAt) > staleEmailAge || !mailer.ParsePreferences(user.Preferences).Wants(notification.Type) {
+				continue
+			}
+			msg, err := s.mailer.RenderNotification(user.Email, brandName, user.Name, notification)
+			if err != nil {
+				log.Printf("email notification render error: %v", err)
+				continue
+			}
+			if err := s.mailer.Send(ctx, msg); err != nil {
+				log.Printf("email notification send error: %v", err)
+			}
+		}
+		if len(notifications) < emailBatchSize {
+			break
+		}
+	}
+
+	userIDs, err := s.repo.ListEmailDigestRecipients(ctx)
+	if err != nil {
+		log.Printf("email digest query error: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, userID := range userIDs {
+		user := recipient(userID)
+		if user == nil {
+			continue
+		}
+		prefs := mailer.ParsePreferences(user.Preferences)
+		interval, ok := digestIntervals[prefs.Mode]
+		if !ok {
+			continue
+		}
+		notifications, err := s.repo.ClaimEmailDigest(ctx, userID, now.Add(-interval))
+		if err != nil {
+			log.Printf("email digest claim error: %v", err)
+			continue
+		}
+		wanted := make([]models.Notification, 0, len(notifications))
+		for _, notification := range notifications {
+			if prefs.Wants(notification.Type) && now.Sub(notification.CreatedAt) <= staleEmailAge {
+				wanted = append(wanted, notification)
+			}
+		}
+		if len(wanted) == 0 {
+			continue
+		}
+		msg, err := s.mailer.RenderDigest(user.Email, brandName, user.Name, prefs.Mode, wanted)
+		if err != nil {
+			log.Printf("email digest render error: %v", err)
+			continue
+		}
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("email digest send error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/repository"
+)
+
+// RunSessionRetention removes sessions that expired or were revoked more than
+// 30 days ago. Cleanup runs once on startup and once per day.
+func RunSessionRetention(repo *repository.Repo) {
+	cleanup := func() {
+		if err := repo.DeleteExpiredUserSessions(context.Background()); err != nil {
+			log.Printf("session retention error: %v", err)
+		}
+	}
+	cleanup()
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		cleanup()
+	}
+}
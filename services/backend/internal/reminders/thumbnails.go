@@ -0,0 +1,99 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/storage"
+	"github.com/justlabv1/justspace/backend/internal/thumbnails"
+	"github.com/justlabv1/justspace/backend/internal/websocket"
+)
+
+const thumbnailBatch = 10
+
+// ThumbnailService renders thumbnails for queued image uploads outside the
+// upload request and tells project members once they are ready.
+type ThumbnailService struct {
+	repo  *repository.Repo
+	store storage.FileStore
+	hub   *websocket.Hub
+}
+
+func NewThumbnailService(repo *repository.Repo, store storage.FileStore, hub *websocket.Hub) *ThumbnailService {
+	return &ThumbnailService{repo: repo, store: store, hub: hub}
+}
+
+// Run polls for queued blobs every few seconds. Several replicas can run it
+// at once because every blob is claimed before rendering.
+func (s *ThumbnailService) Run() {
+	s.process(context.Background())
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.process(context.Background())
+	}
+}
+
+func (s *ThumbnailService) process(ctx context.Context) {
+	for {
+		blobs, err := s.repo.ClaimFileThumbnails(ctx, thumbnailBatch)
+		if err != nil {
+			log.Printf("thumbnail claim error: %v", err)
+			return
+		}
+		for _, blob := range blobs {
+			s.render(ctx, blob)
+		}
+		if len(blobs) < thumbnailBatch {
+			return
+		}
+	}
+}
+
+func (s *ThumbnailService) render(ctx context.Context, blob models.StoredFile) {
+	contentType, err := thumbnails.Generate(ctx, s.store, blob.StoragePath, blob.SizeBytes)
+	if errors.Is(err, thumbnails.ErrUnsupported) {
+		if err := s.repo.FailFileThumbnail(ctx, blob.StoragePath); err != nil {
+			log.Printf("thumbnail status error for %s: %v", blob.StoragePath, err)
+		}
+		return
+	}
+	if err != nil {
+		// The claim expires and the blob is retried later.
+		log.Printf("thumbnail error for %s: %v", blob.StoragePath, err)
+		return
+	}
+	completed, err := s.repo.CompleteFileThumbnail(ctx, blob.StoragePath, contentType)
+	if err != nil {
+		log.Printf("thumbnail status error for %s: %v", blob.StoragePath, err)
+		return
+	}
+	if !completed {
+		// The blob was released while rendering.
+		if err := thumbnails.Delete(ctx, s.store, blob.StoragePath); err != nil {
+			log.Printf("thumbnail cleanup error for %s: %v", blob.StoragePath, err)
+		}
+		return
+	}
+
+	files, err := s.repo.ListFilesByStoragePath(ctx, blob.StoragePath)
+	if err != nil {
+		log.Printf("thumbnail broadcast error for %s: %v", blob.StoragePath, err)
+		return
+	}
+	for _, file := range files {
+		if file.ThumbnailURL == nil {
+			continue
+		}
+		memberIDs, err := s.repo.ListProjectMemberUserIDs(ctx, file.ProjectID)
+		if err != nil {
+			log.Printf("thumbnail broadcast error for %s: %v", blob.StoragePath, err)
+			continue
+		}
+		s.hub.BroadcastUsers(memberIDs, models.WSEvent{Type: "update", Collection: "project_files", Document: file})
+	}
+}
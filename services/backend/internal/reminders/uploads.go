@@ -0,0 +1,46 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/storage"
+)
+
+const uploadPartCleanupBatch = 100
+
+// RunUploadSessionCleanup removes resumable uploads that received no chunk for
+// a day, and the stored chunks of sessions deleted with their project, task,
+// or uploader. Cleanup runs once on startup and once per hour.
+func RunUploadSessionCleanup(repo *repository.Repo, store storage.FileStore) {
+	cleanup := func() {
+		ctx := context.Background()
+		if _, err := repo.DeleteExpiredUploadSessions(ctx); err != nil {
+			log.Printf("upload session cleanup error: %v", err)
+			return
+		}
+		for {
+			paths, err := repo.ReleaseOrphanedUploadParts(ctx, uploadPartCleanupBatch)
+			if err != nil {
+				log.Printf("upload part cleanup error: %v", err)
+				return
+			}
+			for _, path := range paths {
+				if err := store.Delete(ctx, path); err != nil {
+					log.Printf("upload part cleanup error for %s: %v", path, err)
+				}
+			}
+			if len(paths) < uploadPartCleanupBatch {
+				return
+			}
+		}
+	}
+	cleanup()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		cleanup()
+	}
+}
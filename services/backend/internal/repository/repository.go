@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/taskquery"
 )
 
 type Repo struct {
@@ -209,11 +211,11 @@ func (r *Repo) CreateUser(ctx context.Context, email, name, passwordHash string)
 	}
 	u := &models.User{}
 	err = tx.QueryRow(ctx,
-		`INSERT INTO users (email, name, password_hash, is_platform_admin)
-		 VALUES ($1, $2, NULLIF($3, ''), $4)
-		 RETURNING id, email, name, preferences, is_platform_admin, is_active, session_version, created_at, updated_at`,
+		`INSERT INTO users (email, name, password_hash, is_platform_admin, email_verified_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, CASE WHEN $3 = '' THEN NOW() END)
+		 RETURNING id, email, name, preferences, is_platform_admin, is_active, session_version, email_verified_at, created_at, updated_at`,
 		email, name, passwordHash, userCount == 0,
-	).Scan(&u.ID, &u.Email, &u.Name, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.CreatedAt, &u.UpdatedAt)
+	).Scan(&u.ID, &u.Email, &u.Name, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("create user: %w", err)
 	}
@@ -227,8 +229,8 @@ func (r *Repo) GetUserByEmail(ctx context.Context, email string) (*models.User,
 	u := &models.User{}
 	var passwordHash *string
 	err := r.pool.QueryRow(ctx,
-		`SELECT id, email, name, password_hash, preferences, is_platform_admin, is_active, session_version, created_at, updated_at FROM users WHERE email = $1`, email,
-	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.CreatedAt, &u.UpdatedAt)
+		`SELECT id, email, name, password_hash, preferences, is_platform_admin, is_active, session_version, email_verified_at, created_at, updated_at FROM users WHERE email = $1`, email,
+	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -245,8 +247,8 @@ func (r *Repo) GetUserByID(ctx context.Context, id string) (*models.User, error)
 	u := &models.User{}
 	var passwordHash *string
 	err := r.pool.QueryRow(ctx,
-		`SELECT id, email, name, password_hash, preferences, is_platform_admin, is_active, session_version, created_at, updated_at FROM users WHERE id = $1`, id,
-	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.CreatedAt, &u.UpdatedAt)
+		`SELECT id, email, name, password_hash, preferences, is_platform_admin, is_active, session_version, email_verified_at, created_at, updated_at FROM users WHERE id = $1`, id,
+	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -292,9 +294,9 @@ func (r *Repo) UpdateUser(ctx context.Context, id string, name *string, prefs *j
 	u := &models.User{}
 	err := r.pool.QueryRow(ctx,
 		`UPDATE users SET name = COALESCE($2, name), preferences = COALESCE($3, preferences), updated_at = NOW()
-		 WHERE id = $1 RETURNING id, email, name, preferences, is_platform_admin, is_active, session_version, created_at, updated_at`,
+		 WHERE id = $1 RETURNING id, email, name, preferences, is_platform_admin, is_active, session_version, email_verified_at, created_at, updated_at`,
 		id, name, prefs,
-	).Scan(&u.ID, &u.Email, &u.Name, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.CreatedAt, &u.UpdatedAt)
+	).Scan(&u.ID, &u.Email, &u.Name, &u.Preferences, &u.IsPlatformAdmin, &u.IsActive, &u.SessionVersion, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("update user: %w", err)
 	}
@@ -317,9 +319,293 @@ func (r *Repo) GetUserAuthState(ctx context.Context, id string) (bool, int64, er
 	return active, version, nil
 }
 
+const personalAccessTokenColumns = `id, user_id, name, token_prefix, access, workspace_ids::text[], session_version, expires_at, last_used_at, created_at`
+
+func scanPersonalAccessToken(row pgx.Row, token *models.PersonalAccessToken) error {
+	return row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.Access, &token.WorkspaceIDs, &token.SessionVersion, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+}
+
+// CreatePersonalAccessToken binds the token to the user's current session
+// version so a password change or admin action revokes it with the sessions.
+func (r *Repo) CreatePersonalAccessToken(ctx context.Context, userID string, req models.CreatePersonalAccessTokenRequest, tokenHash, tokenPrefix string, expiresAt *time.Time) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	err := scanPersonalAccessToken(r.pool.QueryRow(ctx,
+		`INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, access, workspace_ids, session_version, expires_at)
+		 SELECT $1, $2, $3, $4, $5, $6::uuid[], u.session_version, $7 FROM users u WHERE u.id = $1
+		 RETURNING `+personalAccessTokenColumns,
+		userID, req.Name, tokenHash, tokenPrefix, req.Access, req.WorkspaceIDs, expiresAt,
+	), token)
+	if err != nil {
+		return nil, fmt.Errorf("create personal access token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *Repo) ListPersonalAccessTokens(ctx context.Context, userID string) ([]models.PersonalAccessToken, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list personal access tokens: %w", err)
+	}
+	defer rows.Close()
+	out := []models.PersonalAccessToken{}
+	for rows.Next() {
+		var token models.PersonalAccessToken
+		if err := scanPersonalAccessToken(rows, &token); err != nil {
+			return nil, err
+		}
+		out = append(out, token)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repo) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	err := scanPersonalAccessToken(r.pool.QueryRow(ctx, `SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens WHERE token_hash = $1`, tokenHash), token)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get personal access token: %w", err)
+	}
+	return token, nil
+}
+
+// TouchPersonalAccessToken records usage at most once per minute to keep busy
+// CI jobs from turning every request into a write.
+func (r *Repo) TouchPersonalAccessToken(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE personal_access_tokens SET last_used_at = NOW()
+		 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	if err != nil {
+		return fmt.Errorf("touch personal access token: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) DeletePersonalAccessToken(ctx context.Context, userID, id string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete personal access token: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CreateUserAuthToken stores a password reset or email verification token and
+// retires earlier unused tokens of the same purpose, so only the latest link in
+// a user's mailbox works.
+func (r *Repo) CreateUserAuthToken(ctx context.Context, userID, purpose, tokenHash, email string, expiresAt time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin create auth token: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE user_auth_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose); err != nil {
+		return fmt.Errorf("retire auth tokens: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO user_auth_tokens (user_id, purpose, token_hash, email, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		userID, purpose, tokenHash, email, expiresAt); err != nil {
+		return fmt.Errorf("create auth token: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit create auth token: %w", err)
+	}
+	return nil
+}
+
+// consumeUserAuthToken marks a valid token as used and returns its user. The
+// token only applies while the account still has the email it was sent to.
+func consumeUserAuthToken(ctx context.Context, tx pgx.Tx, purpose, tokenHash string) (string, error) {
+	var userID string
+	err := tx.QueryRow(ctx,
+		`UPDATE user_auth_tokens t SET used_at = NOW()
+		 FROM users u
+		 WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL AND t.expires_at > NOW()
+		   AND u.id = t.user_id AND u.email = t.email AND u.is_active
+		 RETURNING t.user_id`, tokenHash, purpose).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return userID, err
+}
+
+// ResetUserPassword consumes a password reset token, stores the new password
+// hash, and bumps session_version so existing sessions and tokens stop working.
+// Completing a reset also proves ownership of the email address.
+func (r *Repo) ResetUserPassword(ctx context.Context, tokenHash, passwordHash string) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin password reset: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	userID, err := consumeUserAuthToken(ctx, tx, "password_reset", tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("consume password reset token: %w", err)
+	}
+	if userID == "" {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE users SET password_hash = $2, session_version = session_version + 1,
+		 email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		 WHERE id = $1`, userID, passwordHash); err != nil {
+		return false, fmt.Errorf("reset password: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit password reset: %w", err)
+	}
+	return true, nil
+}
+
+// VerifyUserEmail consumes an email verification token and returns the
+// verified user, or nil when the token is invalid, used, or expired.
+func (r *Repo) VerifyUserEmail(ctx context.Context, tokenHash string) (*models.User, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin email verification: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	userID, err := consumeUserAuthToken(ctx, tx, "email_verification", tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("consume email verification token: %w", err)
+	}
+	if userID == "" {
+		return nil, nil
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("verify email: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit email verification: %w", err)
+	}
+	return r.GetUserByID(ctx, userID)
+}
+
+func (r *Repo) GetUserMFA(ctx context.Context, userID string) (*models.UserMFA, error) {
+	mfa := &models.UserMFA{}
+	err := r.pool.QueryRow(ctx,
+		`SELECT user_id, secret_encrypted, enabled_at, last_used_step FROM user_mfa WHERE user_id = $1`, userID,
+	).Scan(&mfa.UserID, &mfa.SecretEncrypted, &mfa.EnabledAt, &mfa.LastUsedStep)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get user mfa: %w", err)
+	}
+	return mfa, nil
+}
+
+// SaveUserMFASecret stores a new pending secret. It returns false when the
+// user already has MFA enabled; enrollment must be disabled first.
+func (r *Repo) SaveUserMFASecret(ctx context.Context, userID, secretEncrypted string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`INSERT INTO user_mfa (user_id, secret_encrypted) VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0
+		 WHERE user_mfa.enabled_at IS NULL`, userID, secretEncrypted)
+	if err != nil {
+		return false, fmt.Errorf("save user mfa secret: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnableUserMFA activates the pending secret and replaces the recovery codes.
+func (r *Repo) EnableUserMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin enable mfa: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1 AND enabled_at IS NULL`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("enable mfa: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit enable mfa: %w", err)
+	}
+	return true, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO user_mfa_recovery_codes (user_id, code_hash) SELECT $1, UNNEST($2::text[])`, userID, codeHashes); err != nil {
+		return fmt.Errorf("create recovery codes: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) ReplaceUserMFARecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin replace recovery codes: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit replace recovery codes: %w", err)
+	}
+	return nil
+}
+
+// RecordUserMFAStep stores the last accepted TOTP step. It returns false when
+// the step was already used, which rejects concurrent replays of one code.
+func (r *Repo) RecordUserMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("record mfa step: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repo) UseUserMFARecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE user_mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repo) CountUserMFARecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repo) DeleteUserMFA(ctx context.Context, userID string) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin delete mfa: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return false, fmt.Errorf("delete recovery codes: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete mfa: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit delete mfa: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *Repo) GetPlatformSettings(ctx context.Context) (*models.PlatformSettings, error) {
 	settings := &models.PlatformSettings{}
-	err := r.pool.QueryRow(ctx, `SELECT local_auth_enabled, brand_name, brand_logo_key, brand_logo_updated_at FROM platform_settings WHERE id = TRUE`).Scan(&settings.LocalAuthEnabled, &settings.BrandName, &settings.BrandLogoKey, &settings.BrandLogoUpdatedAt)
+	err := r.pool.QueryRow(ctx, `SELECT local_auth_enabled, require_verified_email, require_admin_mfa, brand_name, brand_logo_key, brand_logo_updated_at, default_workspace_storage_quota_bytes FROM platform_settings WHERE id = TRUE`).Scan(&settings.LocalAuthEnabled, &settings.RequireVerifiedEmail, &settings.RequireAdminMFA, &settings.BrandName, &settings.BrandLogoKey, &settings.BrandLogoUpdatedAt, &settings.DefaultWorkspaceStorageQuotaBytes)
 	if err != nil {
 		return nil, fmt.Errorf("get platform settings: %w", err)
 	}
@@ -331,10 +617,13 @@ func (r *Repo) UpdatePlatformSettings(ctx context.Context, req models.PlatformSe
 	err := r.pool.QueryRow(ctx,
 		`UPDATE platform_settings
 		 SET local_auth_enabled = COALESCE($1, local_auth_enabled),
-		     brand_name = COALESCE($2, brand_name), updated_at = NOW()
+		     brand_name = COALESCE($2, brand_name),
+		     require_verified_email = COALESCE($3, require_verified_email),
+		     require_admin_mfa = COALESCE($4, require_admin_mfa),
+		     default_workspace_storage_quota_bytes = COALESCE($5, default_workspace_storage_quota_bytes), updated_at = NOW()
 		 WHERE id = TRUE
-		 RETURNING local_auth_enabled, brand_name, brand_logo_key, brand_logo_updated_at`, req.LocalAuthEnabled, req.BrandName,
-	).Scan(&settings.LocalAuthEnabled, &settings.BrandName, &settings.BrandLogoKey, &settings.BrandLogoUpdatedAt)
+		 RETURNING local_auth_enabled, require_verified_email, require_admin_mfa, brand_name, brand_logo_key, brand_logo_updated_at, default_workspace_storage_quota_bytes`, req.LocalAuthEnabled, req.BrandName, req.RequireVerifiedEmail, req.RequireAdminMFA, req.DefaultWorkspaceStorageQuotaBytes,
+	).Scan(&settings.LocalAuthEnabled, &settings.RequireVerifiedEmail, &settings.RequireAdminMFA, &settings.BrandName, &settings.BrandLogoKey, &settings.BrandLogoUpdatedAt, &settings.DefaultWorkspaceStorageQuotaBytes)
 	if err != nil {
 		return nil, fmt.Errorf("update platform settings: %w", err)
 	}
@@ -367,6 +656,10 @@ func (r *Repo) UpdateBrandLogoKey(ctx context.Context, key *string) (*models.Pla
 	return r.GetPlatformSettings(ctx)
 }
 
+// adminOverviewWorkspaceLimit bounds the workspace storage rows in the admin
+// overview.
+const adminOverviewWorkspaceLimit = 10
+
 func (r *Repo) GetAdminOverview(ctx context.Context) (*models.AdminOverview, error) {
 	overview := &models.AdminOverview{DatabaseStatus: "healthy"}
 	if err := r.pool.QueryRow(ctx, `SELECT 1`).Scan(new(int)); err != nil {
@@ -384,6 +677,14 @@ func (r *Repo) GetAdminOverview(ctx context.Context) (*models.AdminOverview, err
 	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*), COUNT(*) FILTER (WHERE enabled) FROM oidc_providers`).Scan(&overview.TotalOIDCProviders, &overview.EnabledOIDCProviders); err != nil {
 		return nil, fmt.Errorf("get overview oidc providers: %w", err)
 	}
+	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(SUM(size_bytes), 0) FROM project_files`).Scan(&overview.StorageUsedBytes); err != nil {
+		return nil, fmt.Errorf("get overview storage: %w", err)
+	}
+	workspaceStorage, err := r.ListWorkspaceStorageUsage(ctx, adminOverviewWorkspaceLimit)
+	if err != nil {
+		return nil, err
+	}
+	overview.WorkspaceStorage = workspaceStorage
 	settings, err := r.GetPlatformSettings(ctx)
 	if err != nil {
 		return nil, err
@@ -456,6 +757,8 @@ func (r *Repo) CountEnabledOIDCProviders(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+const adminUserMFAEnabled = `EXISTS (SELECT 1 FROM user_mfa m WHERE m.user_id = users.id AND m.enabled_at IS NOT NULL)`
+
 func (r *Repo) ListAdminUsers(ctx context.Context, query string, limit, offset int) ([]models.AdminUser, int, error) {
 	pattern := "%" + strings.TrimSpace(query) + "%"
 	var total int
@@ -464,7 +767,7 @@ func (r *Repo) ListAdminUsers(ctx context.Context, query string, limit, offset i
 		return nil, 0, fmt.Errorf("count admin users: %w", err)
 	}
 	rows, err := r.pool.Query(ctx,
-		`SELECT id, email, name, is_platform_admin, is_active, created_at, updated_at
+		`SELECT id, email, name, is_platform_admin, is_active, `+adminUserMFAEnabled+`, created_at, updated_at
 		 FROM users WHERE email ILIKE $1 OR name ILIKE $1
 		 ORDER BY created_at ASC, id ASC LIMIT $2 OFFSET $3`, pattern, limit, offset)
 	if err != nil {
@@ -474,7 +777,7 @@ func (r *Repo) ListAdminUsers(ctx context.Context, query string, limit, offset i
 	users := make([]models.AdminUser, 0)
 	for rows.Next() {
 		var user models.AdminUser
-		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.IsPlatformAdmin, &user.IsActive, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.IsPlatformAdmin, &user.IsActive, &user.MFAEnabled, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			return nil, 0, fmt.Errorf("scan admin user: %w", err)
 		}
 		users = append(users, user)
@@ -494,9 +797,9 @@ func (r *Repo) UpdateAdminUser(ctx context.Context, actorID, targetID string, re
 
 	var current models.AdminUser
 	err = tx.QueryRow(ctx,
-		`SELECT id, email, name, is_platform_admin, is_active, created_at, updated_at
+		`SELECT id, email, name, is_platform_admin, is_active, `+adminUserMFAEnabled+`, created_at, updated_at
 		 FROM users WHERE id = $1 FOR UPDATE`, targetID,
-	).Scan(&current.ID, &current.Email, &current.Name, &current.IsPlatformAdmin, &current.IsActive, &current.CreatedAt, &current.UpdatedAt)
+	).Scan(&current.ID, &current.Email, &current.Name, &current.IsPlatformAdmin, &current.IsActive, &current.MFAEnabled, &current.CreatedAt, &current.UpdatedAt)
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -523,11 +826,23 @@ func (r *Repo) UpdateAdminUser(ctx context.Context, actorID, targetID string, re
 			return nil, fmt.Errorf("the last active platform admin cannot be removed")
 		}
 	}
-	if _, err := tx.Exec(ctx,
-		`UPDATE users SET is_platform_admin = COALESCE($2, is_platform_admin),
-		 is_active = COALESCE($3, is_active), session_version = session_version + 1, updated_at = NOW()
-		 WHERE id = $1`, targetID, req.IsPlatformAdmin, req.IsActive); err != nil {
-		return nil, fmt.Errorf("update admin user: %w", err)
+	// Access changes sign the user out everywhere, including personal access
+	// tokens; revoking sessions alone leaves the tokens intact.
+	if req.IsPlatformAdmin != nil || req.IsActive != nil {
+		if _, err := tx.Exec(ctx,
+			`UPDATE users SET is_platform_admin = COALESCE($2, is_platform_admin),
+			 is_active = COALESCE($3, is_active), session_version = session_version + 1, updated_at = NOW()
+			 WHERE id = $1`, targetID, req.IsPlatformAdmin, req.IsActive); err != nil {
+			return nil, fmt.Errorf("update admin user: %w", err)
+		}
+	}
+	if req.RevokeAllSessions || len(req.RevokeSessionIDs) > 0 {
+		if _, err := tx.Exec(ctx,
+			`UPDATE user_sessions SET revoked_at = NOW()
+			 WHERE user_id = $1 AND revoked_at IS NULL AND ($2 OR id::text = ANY($3::text[]))`,
+			targetID, req.RevokeAllSessions, req.RevokeSessionIDs); err != nil {
+			return nil, fmt.Errorf("revoke user sessions: %w", err)
+		}
 	}
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("commit admin user update: %w", err)
@@ -797,6 +1112,104 @@ func seedProjectTaskStatuses(ctx context.Context, tx pgx.Tx, projectID string, t
 	return nil
 }
 
+// ---- Sessions ----
+
+const userSessionColumns = `id, user_id, device, user_agent, ip_address, created_at, last_seen_at, expires_at`
+
+func scanUserSession(row pgx.Row, session *models.UserSession) error {
+	return row.Scan(&session.ID, &session.UserID, &session.Device, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+}
+
+// CreateUserSession registers a new browser session bound to the user's
+// current session version and returns its id and that version for the JWT.
+func (r *Repo) CreateUserSession(ctx context.Context, userID, device, userAgent, ipAddress string, expiresAt time.Time) (string, int64, error) {
+	var id string
+	var version int64
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO user_sessions (user_id, session_version, device, user_agent, ip_address, expires_at)
+		 SELECT u.id, u.session_version, $2, $3, $4, $5 FROM users u WHERE u.id = $1
+		 RETURNING id, session_version`, userID, device, userAgent, ipAddress, expiresAt,
+	).Scan(&id, &version)
+	if err != nil {
+		return "", 0, fmt.Errorf("create user session: %w", err)
+	}
+	return id, version, nil
+}
+
+// ValidateUserSession reports whether the session is neither revoked nor
+// expired. Like TouchPersonalAccessToken it refreshes last_seen_at at most
+// once per minute; an empty ipAddress keeps the stored address.
+func (r *Repo) ValidateUserSession(ctx context.Context, id, userID, ipAddress string) (bool, error) {
+	var valid bool
+	err := r.pool.QueryRow(ctx,
+		`WITH session AS (
+		     SELECT id, last_seen_at FROM user_sessions
+		     WHERE id::text = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+		 ), touched AS (
+		     UPDATE user_sessions s SET last_seen_at = NOW(), ip_address = COALESCE(NULLIF($3, ''), s.ip_address)
+		     FROM session WHERE s.id = session.id AND session.last_seen_at < NOW() - INTERVAL '1 minute'
+		     RETURNING s.id
+		 )
+		 SELECT EXISTS (SELECT 1 FROM session)`, id, userID, ipAddress,
+	).Scan(&valid)
+	if err != nil {
+		return false, fmt.Errorf("validate user session: %w", err)
+	}
+	return valid, nil
+}
+
+// ListUserSessions returns sessions that can still authenticate, i.e. those
+// not revoked, not expired, and issued for the current session version.
+func (r *Repo) ListUserSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+userSessionColumns+` FROM user_sessions s
+		 WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+		   AND s.session_version = (SELECT session_version FROM users WHERE id = $1)
+		 ORDER BY s.last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user sessions: %w", err)
+	}
+	defer rows.Close()
+	sessions := make([]models.UserSession, 0)
+	for rows.Next() {
+		var session models.UserSession
+		if err := scanUserSession(rows, &session); err != nil {
+			return nil, fmt.Errorf("scan user session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *Repo) RevokeUserSession(ctx context.Context, userID, id string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE user_sessions SET revoked_at = NOW() WHERE id::text = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("revoke user session: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeOtherUserSessions signs out every session of the user except keepID.
+func (r *Repo) RevokeOtherUserSessions(ctx context.Context, userID, keepID string) (int64, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND id::text <> $2 AND revoked_at IS NULL`, userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke other user sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteExpiredUserSessions keeps revoked and expired sessions for 30 days so
+// recent sign-ins remain traceable, then removes them.
+func (r *Repo) DeleteExpiredUserSessions(ctx context.Context) error {
+	if _, err := r.pool.Exec(ctx,
+		`DELETE FROM user_sessions WHERE expires_at < NOW() - INTERVAL '30 days' OR revoked_at < NOW() - INTERVAL '30 days'`); err != nil {
+		return fmt.Errorf("delete expired user sessions: %w", err)
+	}
+	return nil
+}
+
 // ---- Workspaces ----
 
 func scanWorkspace(row pgx.Row, workspace *models.Workspace) error {
@@ -991,6 +1404,18 @@ func (r *Repo) IsConsultingWorkspace(ctx context.Context, workspaceID string) (b
 	return consulting, err
 }
 
+func (r *Repo) GetWorkspaceName(ctx context.Context, workspaceID string) (string, error) {
+	var name string
+	err := r.pool.QueryRow(ctx, `SELECT name FROM workspaces WHERE id = $1`, workspaceID).Scan(&name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("get workspace name: %w", err)
+	}
+	return name, nil
+}
+
 func (r *Repo) ListCustomers(ctx context.Context, workspaceID string, includeArchived bool) ([]models.Customer, error) {
 	query := `SELECT id, workspace_id, name, contact_name, contact_email, notes, archived_at, created_at, updated_at FROM customers WHERE workspace_id = $1`
 	if !includeArchived {
@@ -1036,7 +1461,7 @@ func (r *Repo) UpdateCustomer(ctx context.Context, customerID, workspaceID strin
 }
 
 func (r *Repo) ListProjectAllocations(ctx context.Context, projectID string) ([]models.ProjectMemberAllocation, error) {
-	rows, err := r.pool.Query(ctx, `SELECT project_id, user_id, days_per_week FROM project_member_allocations WHERE project_id = $1`, projectID)
+	rows, err := r.pool.Query(ctx, `SELECT project_id, user_id, days_per_week, start_date, end_date FROM project_member_allocations WHERE project_id = $1`, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("list project allocations: %w", err)
 	}
@@ -1044,7 +1469,7 @@ func (r *Repo) ListProjectAllocations(ctx context.Context, projectID string) ([]
 	allocations := make([]models.ProjectMemberAllocation, 0)
 	for rows.Next() {
 		var allocation models.ProjectMemberAllocation
-		if err := rows.Scan(&allocation.ProjectID, &allocation.UserID, &allocation.DaysPerWeek); err != nil {
+		if err := rows.Scan(&allocation.ProjectID, &allocation.UserID, &allocation.DaysPerWeek, &allocation.StartDate, &allocation.EndDate); err != nil {
 			return nil, err
 		}
 		allocations = append(allocations, allocation)
@@ -1052,9 +1477,16 @@ func (r *Repo) ListProjectAllocations(ctx context.Context, projectID string) ([]
 	return allocations, rows.Err()
 }
 
-func (r *Repo) UpsertProjectAllocation(ctx context.Context, projectID, userID string, daysPerWeek float64) (*models.ProjectMemberAllocation, error) {
+// UpsertProjectAllocation sets a member's days per week on a project. A nil
+// date keeps the stored one and an empty date clears it.
+func (r *Repo) UpsertProjectAllocation(ctx context.Context, projectID, userID string, daysPerWeek float64, startDate, endDate *string) (*models.ProjectMemberAllocation, error) {
 	allocation := &models.ProjectMemberAllocation{}
-	err := r.pool.QueryRow(ctx, `INSERT INTO project_member_allocations (project_id, user_id, days_per_week) SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2) ON CONFLICT (project_id, user_id) DO UPDATE SET days_per_week = EXCLUDED.days_per_week RETURNING project_id, user_id, days_per_week`, projectID, userID, daysPerWeek).Scan(&allocation.ProjectID, &allocation.UserID, &allocation.DaysPerWeek)
+	err := r.pool.QueryRow(ctx, `INSERT INTO project_member_allocations (project_id, user_id, days_per_week, start_date, end_date)
+		SELECT $1, $2, $3, NULLIF($4::text, '')::date, NULLIF($5::text, '')::date WHERE EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET days_per_week = EXCLUDED.days_per_week,
+			start_date = CASE WHEN $4::text IS NOT NULL THEN EXCLUDED.start_date ELSE project_member_allocations.start_date END,
+			end_date = CASE WHEN $5::text IS NOT NULL THEN EXCLUDED.end_date ELSE project_member_allocations.end_date END
+		RETURNING project_id, user_id, days_per_week, start_date, end_date`, projectID, userID, daysPerWeek, startDate, endDate).Scan(&allocation.ProjectID, &allocation.UserID, &allocation.DaysPerWeek, &allocation.StartDate, &allocation.EndDate)
 	if err != nil {
 		return nil, fmt.Errorf("upsert project allocation: %w", err)
 	}
@@ -1204,31 +1636,38 @@ func (r *Repo) CancelWorkspaceInvitation(ctx context.Context, workspaceID, invit
 
 // ---- Projects ----
 
-func (r *Repo) ListProjects(ctx context.Context, userID string, workspaceIDs ...string) ([]models.Project, error) {
-	query := projectSelect
+func (r *Repo) ListProjects(ctx context.Context, userID, workspaceID string, page models.PageRequest) ([]models.Project, string, error) {
 	args := []any{userID}
-	if len(workspaceIDs) > 0 && workspaceIDs[0] != "" {
-		query += ` AND p.workspace_id = $2`
-		args = append(args, workspaceIDs[0])
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
 	}
-	query += ` ORDER BY p.created_at DESC`
-	rows, err := r.pool.Query(ctx, query, args...)
+	query := projectSelect
+	if workspaceID != "" {
+		query += ` AND p.workspace_id = ` + arg(workspaceID)
+	}
+	condition, order, err := createdPageClause("p", page, arg)
 	if err != nil {
-		return nil, fmt.Errorf("list projects: %w", err)
+		return nil, "", err
+	}
+	rows, err := r.pool.Query(ctx, query+condition+order, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("list projects: %w", err)
 	}
 	defer rows.Close()
 	var out []models.Project
 	for rows.Next() {
 		var p models.Project
 		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.Status, &p.DaysPerWeek, &p.AllocatedDays, &p.ClientID, &p.HourBudget, &p.IsEncrypted, &p.TaskKeyPrefix, &p.TaskKeyPrefixLocked, &p.Role, &p.CreatedAt, &p.UpdatedAt, &p.WorkspaceID); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		out = append(out, p)
 	}
 	if out == nil {
 		out = []models.Project{}
 	}
-	return out, nil
+	out, next := createdPage(out, page, func(p *models.Project) (time.Time, string) { return p.CreatedAt, p.ID })
+	return out, next, nil
 }
 
 func (r *Repo) GetProject(ctx context.Context, id, userID string) (*models.Project, error) {
@@ -1517,6 +1956,18 @@ func (r *Repo) DeleteProject(ctx context.Context, id, userID string) error {
 	return err
 }
 
+func (r *Repo) GetProjectWorkspaceID(ctx context.Context, projectID string) (string, error) {
+	var workspaceID string
+	err := r.pool.QueryRow(ctx, `SELECT workspace_id FROM projects WHERE id = $1`, projectID).Scan(&workspaceID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("get project workspace: %w", err)
+	}
+	return workspaceID, nil
+}
+
 func (r *Repo) GetProjectRole(ctx context.Context, projectID, userID string) (string, error) {
 	var role string
 	err := r.pool.QueryRow(ctx,
@@ -1879,6 +2330,18 @@ func (r *Repo) DeleteProjectMilestone(ctx context.Context, milestoneID, userID s
 	return err
 }
 
+func (r *Repo) GetProjectMilestoneProjectID(ctx context.Context, milestoneID string) (string, error) {
+	var projectID string
+	err := r.pool.QueryRow(ctx, `SELECT project_id FROM project_milestones WHERE id = $1`, milestoneID).Scan(&projectID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("get project milestone project: %w", err)
+	}
+	return projectID, nil
+}
+
 func nullableNormalizedColorToken(value *string) *string {
 	if value == nil {
 		return nil
@@ -2188,17 +2651,70 @@ func (r *Repo) CancelInvitation(ctx context.Context, invitationID string) error
 	return nil
 }
 
-func (r *Repo) CreateProjectFile(ctx context.Context, uploaderID string, req models.CreateProjectFileRequest, sizeBytes int64, storagePath string) (*models.ProjectFile, error) {
+// CreateProjectFile records a file stored at storagePath. Plaintext files are
+// content-addressed: storagePath is derived from checksum, so identical
+// uploads share a blob. When the blob is new, place writes it to storagePath
+// while its row is locked, which keeps a concurrent release from deleting the
+// object afterwards. When one exists, the new row points at it, which may be a
+// blob deduplicated under an older key, and place is not called. Encrypted
+// uploads never match because every client-side encryption uses a fresh IV.
+func (r *Repo) CreateProjectFile(ctx context.Context, uploaderID string, req models.CreateProjectFileRequest, sizeBytes int64, storagePath, checksum string, place func() error) (*models.ProjectFile, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin create project file: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	blobPath := storagePath
+	deduplicate := !req.IsEncrypted
+	for attempt := 0; ; attempt++ {
+		// Without a conflict target the insert also yields to the blob a
+		// concurrent release is deleting, and retries once it is gone.
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO file_blobs (storage_path, checksum_sha256, size_bytes, deduplicated)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT DO NOTHING`,
+			storagePath, checksum, sizeBytes, deduplicate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create file blob: %w", err)
+		}
+		if tag.RowsAffected() == 1 {
+			if place != nil {
+				if err := place(); err != nil {
+					return nil, fmt.Errorf("place file blob: %w", err)
+				}
+			}
+			break
+		}
+		// The lock keeps the blob from being released before the new
+		// reference is committed.
+		err = tx.QueryRow(ctx,
+			`SELECT storage_path FROM file_blobs WHERE checksum_sha256 = $1 AND deduplicated FOR UPDATE`,
+			checksum,
+		).Scan(&blobPath)
+		if err == nil {
+			break
+		}
+		// The matching blob was released between both statements.
+		if err != pgx.ErrNoRows || attempt > 0 {
+			return nil, fmt.Errorf("find file blob: %w", err)
+		}
+	}
+
 	file := &models.ProjectFile{}
-	err := r.pool.QueryRow(ctx,
-		`INSERT INTO project_files (project_id, task_id, uploader_id, encrypted_name, content_type, iv, size_bytes, storage_path, is_encrypted)
-		 VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
-		 RETURNING id, project_id, task_id, uploader_id, encrypted_name, content_type, iv, size_bytes, storage_path, is_encrypted, created_at`,
-		req.ProjectID, req.TaskID, uploaderID, req.EncryptedName, req.ContentType, req.IV, sizeBytes, storagePath, req.IsEncrypted,
-	).Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.CreatedAt)
+	err = tx.QueryRow(ctx,
+		`INSERT INTO project_files (project_id, task_id, uploader_id, encrypted_name, content_type, iv, size_bytes, storage_path, is_encrypted, checksum_sha256)
+		 VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10)
+		 RETURNING id, project_id, task_id, uploader_id, encrypted_name, content_type, iv, size_bytes, storage_path, is_encrypted, checksum_sha256, created_at`,
+		req.ProjectID, req.TaskID, uploaderID, req.EncryptedName, req.ContentType, req.IV, sizeBytes, blobPath, req.IsEncrypted, checksum,
+	).Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.ChecksumSHA256, &file.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("create project file: %w", err)
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit project file: %w", err)
+	}
 
 	user, err := r.GetUserByID(ctx, uploaderID)
 	if err == nil && user != nil {
@@ -2207,11 +2723,27 @@ func (r *Repo) CreateProjectFile(ctx context.Context, uploaderID string, req mod
 	return file, nil
 }
 
+// projectFileThumbnailColumn selects the thumbnail content type of pf when
+// it may show the thumbnails of its blob fb. Encrypted files and files in
+// encrypted projects never do, even when they share a blob with a plaintext
+// upload.
+const projectFileThumbnailColumn = `CASE WHEN fb.thumbnail_status = 'ready' AND NOT pf.is_encrypted AND NOT p.is_encrypted THEN fb.thumbnail_content_type END`
+
+func setFileThumbnailURL(file *models.ProjectFile) {
+	if file.ThumbnailContentType == nil {
+		return
+	}
+	url := "/api/files/" + file.ID + "/thumbnail"
+	file.ThumbnailURL = &url
+}
+
 func (r *Repo) ListProjectFiles(ctx context.Context, projectID string) ([]models.ProjectFile, error) {
 	rows, err := r.pool.Query(ctx,
-		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.created_at, u.name
+		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.checksum_sha256, pf.created_at, u.name, `+projectFileThumbnailColumn+`
 		 FROM project_files pf
 		 JOIN users u ON u.id = pf.uploader_id
+		 JOIN projects p ON p.id = pf.project_id
+		 JOIN file_blobs fb ON fb.storage_path = pf.storage_path
 		 WHERE pf.project_id = $1 AND pf.task_id IS NULL
 		 ORDER BY pf.created_at DESC`,
 		projectID,
@@ -2224,9 +2756,10 @@ func (r *Repo) ListProjectFiles(ctx context.Context, projectID string) ([]models
 	var out []models.ProjectFile
 	for rows.Next() {
 		var file models.ProjectFile
-		if err := rows.Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.CreatedAt, &file.UploaderName); err != nil {
+		if err := rows.Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.ChecksumSHA256, &file.CreatedAt, &file.UploaderName, &file.ThumbnailContentType); err != nil {
 			return nil, err
 		}
+		setFileThumbnailURL(&file)
 		out = append(out, file)
 	}
 	if out == nil {
@@ -2237,9 +2770,11 @@ func (r *Repo) ListProjectFiles(ctx context.Context, projectID string) ([]models
 
 func (r *Repo) ListTaskFiles(ctx context.Context, taskID, userID string) ([]models.ProjectFile, error) {
 	rows, err := r.pool.Query(ctx,
-		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.created_at, u.name
+		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.checksum_sha256, pf.created_at, u.name, `+projectFileThumbnailColumn+`
 		 FROM project_files pf
 		 JOIN users u ON u.id = pf.uploader_id
+		 JOIN projects p ON p.id = pf.project_id
+		 JOIN file_blobs fb ON fb.storage_path = pf.storage_path
 		 JOIN tasks t ON t.id = pf.task_id
 		 WHERE pf.task_id = $1 AND EXISTS (
 		 	SELECT 1 FROM project_members pm
@@ -2256,9 +2791,10 @@ func (r *Repo) ListTaskFiles(ctx context.Context, taskID, userID string) ([]mode
 	var out []models.ProjectFile
 	for rows.Next() {
 		var file models.ProjectFile
-		if err := rows.Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.CreatedAt, &file.UploaderName); err != nil {
+		if err := rows.Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.ChecksumSHA256, &file.CreatedAt, &file.UploaderName, &file.ThumbnailContentType); err != nil {
 			return nil, err
 		}
+		setFileThumbnailURL(&file)
 		out = append(out, file)
 	}
 	if out == nil {
@@ -2270,21 +2806,24 @@ func (r *Repo) ListTaskFiles(ctx context.Context, taskID, userID string) ([]mode
 func (r *Repo) GetProjectFile(ctx context.Context, fileID, userID string) (*models.ProjectFile, error) {
 	file := &models.ProjectFile{}
 	err := r.pool.QueryRow(ctx,
-		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.created_at, u.name
+		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.checksum_sha256, pf.created_at, u.name, `+projectFileThumbnailColumn+`
 		 FROM project_files pf
 		 JOIN users u ON u.id = pf.uploader_id
+		 JOIN projects p ON p.id = pf.project_id
+		 JOIN file_blobs fb ON fb.storage_path = pf.storage_path
 		 WHERE pf.id = $1 AND EXISTS (
 		 	SELECT 1 FROM project_members pm
 		 	WHERE pm.project_id = pf.project_id AND pm.user_id = $2
 		 )`,
 		fileID, userID,
-	).Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.CreatedAt, &file.UploaderName)
+	).Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.ChecksumSHA256, &file.CreatedAt, &file.UploaderName, &file.ThumbnailContentType)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("get project file: %w", err)
 	}
+	setFileThumbnailURL(file)
 	return file, nil
 }
 
@@ -2296,11 +2835,252 @@ func (r *Repo) DeleteProjectFile(ctx context.Context, fileID string) error {
 	return nil
 }
 
-func (r *Repo) ListTaskAssignees(ctx context.Context, taskID string) ([]models.TaskAssignee, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT ta.task_id, ta.user_id, u.name, u.email, ta.assigned_by_id, ta.created_at
-		 FROM task_assignees ta
-		 JOIN users u ON u.id = ta.user_id
+// ReleaseFileBlob deletes the blob row once no project file refers to it.
+// remove deletes the stored objects before the deletion commits: content
+// addressing reuses storage paths, so deleting them afterwards could remove
+// the copy of a concurrent upload of the same content. Rows that a concurrent
+// upload is about to reference are locked and therefore kept. When remove
+// fails the row stays for the hourly cleanup to retry.
+func (r *Repo) ReleaseFileBlob(ctx context.Context, storagePath string, remove func(models.StoredFile) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin release file blob: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var blob models.StoredFile
+	err = tx.QueryRow(ctx,
+		`DELETE FROM file_blobs WHERE storage_path = $1 AND ref_count = 0
+		 RETURNING storage_path, size_bytes, checksum_sha256, thumbnail_status = 'ready'`,
+		storagePath,
+	).Scan(&blob.StoragePath, &blob.SizeBytes, &blob.ChecksumSHA256, &blob.HasThumbnails)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("release file blob: %w", err)
+	}
+	if err := remove(blob); err != nil {
+		return fmt.Errorf("remove file blob: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit file blob release: %w", err)
+	}
+	return nil
+}
+
+// ReleaseUnreferencedFileBlobs deletes up to limit blob rows left without
+// references, typically by cascading project, task, or user deletes, and
+// calls remove for each before the deletions commit, as ReleaseFileBlob does.
+// It returns how many rows were deleted. A failing remove is left for remove
+// to report so one unreachable object cannot stall the cleanup.
+func (r *Repo) ReleaseUnreferencedFileBlobs(ctx context.Context, limit int, remove func(models.StoredFile) error) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin release unreferenced file blobs: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`DELETE FROM file_blobs
+		 WHERE storage_path IN (
+		 	SELECT storage_path FROM file_blobs WHERE ref_count = 0
+		 	ORDER BY created_at ASC
+		 	LIMIT $1
+		 	FOR UPDATE SKIP LOCKED
+		 ) AND ref_count = 0
+		 RETURNING storage_path, size_bytes, checksum_sha256, thumbnail_status = 'ready'`,
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("release unreferenced file blobs: %w", err)
+	}
+	var released []models.StoredFile
+	for rows.Next() {
+		var blob models.StoredFile
+		if err := rows.Scan(&blob.StoragePath, &blob.SizeBytes, &blob.ChecksumSHA256, &blob.HasThumbnails); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan released file blob: %w", err)
+		}
+		released = append(released, blob)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("release unreferenced file blobs: %w", err)
+	}
+	for _, blob := range released {
+		_ = remove(blob)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit unreferenced file blob release: %w", err)
+	}
+	return len(released), nil
+}
+
+// ListStoredFiles returns every stored project file blob for the STORAGE_MODE
+// maintenance commands.
+func (r *Repo) ListStoredFiles(ctx context.Context) ([]models.StoredFile, error) {
+	rows, err := r.pool.Query(ctx, `SELECT storage_path, size_bytes, checksum_sha256, thumbnail_status = 'ready' FROM file_blobs ORDER BY storage_path ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list stored files: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.StoredFile
+	for rows.Next() {
+		var file models.StoredFile
+		if err := rows.Scan(&file.StoragePath, &file.SizeBytes, &file.ChecksumSHA256, &file.HasThumbnails); err != nil {
+			return nil, fmt.Errorf("scan stored file: %w", err)
+		}
+		out = append(out, file)
+	}
+	return out, rows.Err()
+}
+
+// SetFileBlobChecksum records a checksum for blobs uploaded before checksums
+// were tracked, on the blob and every file that refers to it. Existing
+// checksums are never overwritten.
+func (r *Repo) SetFileBlobChecksum(ctx context.Context, storagePath, checksum string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin set file blob checksum: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE file_blobs SET checksum_sha256 = $2 WHERE storage_path = $1 AND checksum_sha256 IS NULL`, storagePath, checksum); err != nil {
+		return fmt.Errorf("set file blob checksum: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE project_files SET checksum_sha256 = $2 WHERE storage_path = $1 AND checksum_sha256 IS NULL`, storagePath, checksum); err != nil {
+		return fmt.Errorf("set project file checksum: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// QueueFileThumbnail asks the thumbnail worker to render the blob of file
+// unless its project is encrypted. A blob shared with an earlier upload may
+// have thumbnails already, in which case file gets its thumbnail URL.
+func (r *Repo) QueueFileThumbnail(ctx context.Context, file *models.ProjectFile) error {
+	var status string
+	var contentType *string
+	err := r.pool.QueryRow(ctx,
+		`UPDATE file_blobs fb
+		 SET thumbnail_status = CASE WHEN fb.thumbnail_status = 'none' THEN 'pending' ELSE fb.thumbnail_status END
+		 WHERE fb.storage_path = $1
+		   AND NOT EXISTS (SELECT 1 FROM projects p WHERE p.id = $2 AND p.is_encrypted)
+		 RETURNING fb.thumbnail_status, fb.thumbnail_content_type`,
+		file.StoragePath, file.ProjectID,
+	).Scan(&status, &contentType)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("queue file thumbnail: %w", err)
+	}
+	if status == "ready" {
+		file.ThumbnailContentType = contentType
+		setFileThumbnailURL(file)
+	}
+	return nil
+}
+
+// ClaimFileThumbnails marks up to limit queued blobs as being rendered and
+// returns them. Claims older than 15 minutes are taken over, so blobs whose
+// worker died or hit a storage error are retried.
+func (r *Repo) ClaimFileThumbnails(ctx context.Context, limit int) ([]models.StoredFile, error) {
+	rows, err := r.pool.Query(ctx,
+		`UPDATE file_blobs
+		 SET thumbnail_status = 'processing', thumbnail_claimed_at = NOW()
+		 WHERE storage_path IN (
+		 	SELECT storage_path FROM file_blobs
+		 	WHERE thumbnail_status = 'pending'
+		 	   OR (thumbnail_status = 'processing' AND thumbnail_claimed_at < NOW() - INTERVAL '15 minutes')
+		 	ORDER BY created_at ASC
+		 	LIMIT $1
+		 	FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING storage_path, size_bytes, checksum_sha256`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim file thumbnails: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.StoredFile
+	for rows.Next() {
+		var blob models.StoredFile
+		if err := rows.Scan(&blob.StoragePath, &blob.SizeBytes, &blob.ChecksumSHA256); err != nil {
+			return nil, fmt.Errorf("scan claimed file thumbnail: %w", err)
+		}
+		out = append(out, blob)
+	}
+	return out, rows.Err()
+}
+
+// CompleteFileThumbnail records that the thumbnails of a claimed blob were
+// stored with contentType. It reports false when the blob was released in the
+// meantime, in which case the caller deletes the thumbnails again.
+func (r *Repo) CompleteFileThumbnail(ctx context.Context, storagePath, contentType string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE file_blobs
+		 SET thumbnail_status = 'ready', thumbnail_content_type = $2, thumbnail_claimed_at = NULL
+		 WHERE storage_path = $1 AND thumbnail_status = 'processing'`,
+		storagePath, contentType,
+	)
+	if err != nil {
+		return false, fmt.Errorf("complete file thumbnail: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// FailFileThumbnail records that a claimed blob cannot be thumbnailed.
+func (r *Repo) FailFileThumbnail(ctx context.Context, storagePath string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE file_blobs
+		 SET thumbnail_status = 'failed', thumbnail_claimed_at = NULL
+		 WHERE storage_path = $1 AND thumbnail_status = 'processing'`,
+		storagePath,
+	)
+	if err != nil {
+		return fmt.Errorf("fail file thumbnail: %w", err)
+	}
+	return nil
+}
+
+// ListFilesByStoragePath returns the files that refer to a blob, for
+// announcing a change to the blob to their projects.
+func (r *Repo) ListFilesByStoragePath(ctx context.Context, storagePath string) ([]models.ProjectFile, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT pf.id, pf.project_id, pf.task_id, pf.uploader_id, pf.encrypted_name, pf.content_type, pf.iv, pf.size_bytes, pf.storage_path, pf.is_encrypted, pf.checksum_sha256, pf.created_at, u.name, `+projectFileThumbnailColumn+`
+		 FROM project_files pf
+		 JOIN users u ON u.id = pf.uploader_id
+		 JOIN projects p ON p.id = pf.project_id
+		 JOIN file_blobs fb ON fb.storage_path = pf.storage_path
+		 WHERE pf.storage_path = $1
+		 ORDER BY pf.created_at ASC`,
+		storagePath,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list files by storage path: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.ProjectFile
+	for rows.Next() {
+		var file models.ProjectFile
+		if err := rows.Scan(&file.ID, &file.ProjectID, &file.TaskID, &file.UploaderID, &file.EncryptedName, &file.ContentType, &file.IV, &file.SizeBytes, &file.StoragePath, &file.IsEncrypted, &file.ChecksumSHA256, &file.CreatedAt, &file.UploaderName, &file.ThumbnailContentType); err != nil {
+			return nil, err
+		}
+		setFileThumbnailURL(&file)
+		out = append(out, file)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repo) ListTaskAssignees(ctx context.Context, taskID string) ([]models.TaskAssignee, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT ta.task_id, ta.user_id, u.name, u.email, ta.assigned_by_id, ta.created_at
+		 FROM task_assignees ta
+		 JOIN users u ON u.id = ta.user_id
 		 WHERE ta.task_id = $1
 		 ORDER BY ta.created_at ASC`,
 		taskID,
@@ -2491,147 +3271,1348 @@ func (r *Repo) ListTaskPresence(ctx context.Context, taskID string) ([]models.Pr
 	return out, nil
 }
 
-// ---- Tasks ----
+// ---- Sprints ----
 
-func scanTasks(rows pgx.Rows) ([]models.Task, error) {
-	var out []models.Task
+// ErrSprintState reports a sprint change its current state does not allow,
+// such as starting a second active sprint or editing a closed one.
+var ErrSprintState = errors.New("sprint state does not allow this change")
+
+// ErrSprintTask reports task IDs that are not tasks of the sprint's project.
+var ErrSprintTask = errors.New("tasks must belong to the sprint's project")
+
+const sprintSelect = `SELECT s.id, s.project_id, s.created_by, s.name, s.goal, s.state, s.start_date, s.end_date, s.started_at, s.closed_at,
+	(SELECT COUNT(*) FROM sprint_tasks st WHERE st.sprint_id = s.id AND st.removed_at IS NULL),
+	(SELECT COUNT(*) FROM sprint_tasks st JOIN tasks t ON t.id = st.task_id WHERE st.sprint_id = s.id AND st.removed_at IS NULL AND t.completed),
+	s.report, s.created_at, s.updated_at
+	FROM sprints s`
+
+func scanSprint(row pgx.Row) (*models.Sprint, error) {
+	sprint := &models.Sprint{}
+	err := row.Scan(&sprint.ID, &sprint.ProjectID, &sprint.CreatedBy, &sprint.Name, &sprint.Goal, &sprint.State, &sprint.StartDate, &sprint.EndDate, &sprint.StartedAt, &sprint.ClosedAt, &sprint.TaskCount, &sprint.CompletedTaskCount, &sprint.Report, &sprint.CreatedAt, &sprint.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return sprint, nil
+}
+
+// ListProjectSprints lists the active sprint first, then planned sprints by
+// start date, then closed sprints from the most recent.
+func (r *Repo) ListProjectSprints(ctx context.Context, projectID string) ([]models.Sprint, error) {
+	rows, err := r.pool.Query(ctx, sprintSelect+` WHERE s.project_id = $1
+		ORDER BY CASE s.state WHEN 'active' THEN 0 WHEN 'planned' THEN 1 ELSE 2 END,
+			CASE WHEN s.state = 'closed' THEN NULL ELSE s.start_date END ASC,
+			s.start_date DESC, s.created_at ASC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list project sprints: %w", err)
+	}
+	defer rows.Close()
+
+	sprints := []models.Sprint{}
 	for rows.Next() {
-		var t models.Task
-		if err := scanTaskRow(rows, &t); err != nil {
-			return nil, err
+		sprint, err := scanSprint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan sprint: %w", err)
 		}
-		out = append(out, t)
-	}
-	if out == nil {
-		out = []models.Task{}
+		sprints = append(sprints, *sprint)
 	}
-	return out, nil
+	return sprints, rows.Err()
 }
 
-func (r *Repo) GetTask(ctx context.Context, id, userID string) (*models.Task, error) {
-	t := &models.Task{}
-	row := r.pool.QueryRow(ctx,
-		`SELECT `+taskSelectColumns+`
-		 FROM tasks
-		 WHERE id = $1 AND EXISTS (
-		 	SELECT 1 FROM project_members pm
-		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $2
-		 )`, id, userID,
-	)
-	if err := scanTaskRow(row, t); err != nil {
+func (r *Repo) GetSprint(ctx context.Context, sprintID string) (*models.Sprint, error) {
+	sprint, err := scanSprint(r.pool.QueryRow(ctx, sprintSelect+` WHERE s.id = $1`, sprintID))
+	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("get task: %w", err)
+		return nil, fmt.Errorf("get sprint: %w", err)
 	}
-	return t, nil
+	return sprint, nil
 }
 
-func (r *Repo) GetTaskByKey(ctx context.Context, projectID, taskKey, userID string) (*models.Task, error) {
-	t := &models.Task{}
-	row := r.pool.QueryRow(ctx,
-		`SELECT `+taskSelectColumns+`
-		 FROM tasks
-		 WHERE project_id = $1
-		   AND task_key = $2
-		   AND EXISTS (
-		   	SELECT 1 FROM project_members pm
-		   	WHERE pm.project_id = tasks.project_id AND pm.user_id = $3
-		   )`,
-		projectID, taskKey, userID,
+func (r *Repo) CreateSprint(ctx context.Context, projectID, userID, name, goal string, startDate, endDate time.Time) (*models.Sprint, error) {
+	var id string
+	if err := r.pool.QueryRow(ctx,
+		`INSERT INTO sprints (project_id, created_by, name, goal, start_date, end_date)
+		 VALUES ($1, $2, $3, $4, $5::date, $6::date)
+		 RETURNING id`,
+		projectID, userID, name, goal, startDate, endDate,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("create sprint: %w", err)
+	}
+	return r.GetSprint(ctx, id)
+}
+
+// UpdateSprint changes the details of a planned or active sprint.
+func (r *Repo) UpdateSprint(ctx context.Context, sprintID string, name, goal *string, startDate, endDate *time.Time) (*models.Sprint, error) {
+	result, err := r.pool.Exec(ctx,
+		`UPDATE sprints
+		 SET name = COALESCE($2, name),
+		     goal = COALESCE($3, goal),
+		     start_date = COALESCE($4::date, start_date),
+		     end_date = COALESCE($5::date, end_date)
+		 WHERE id = $1 AND state <> 'closed'`,
+		sprintID, name, goal, startDate, endDate,
 	)
-	if err := scanTaskRow(row, t); err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("get task by key: %w", err)
+	if err != nil {
+		return nil, fmt.Errorf("update sprint: %w", err)
 	}
-	return t, nil
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("%w: closed sprints cannot be edited", ErrSprintState)
+	}
+	return r.GetSprint(ctx, sprintID)
 }
 
-func (r *Repo) HasIncompleteDependencies(ctx context.Context, userID string, dependencyIDs []string) (bool, error) {
-	if len(dependencyIDs) == 0 {
-		return false, nil
+// DeleteSprint removes a planned or closed sprint. Its tasks stay in the
+// project.
+func (r *Repo) DeleteSprint(ctx context.Context, sprintID string) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM sprints WHERE id = $1 AND state <> 'active'`, sprintID)
+	if err != nil {
+		return fmt.Errorf("delete sprint: %w", err)
 	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: close the sprint before deleting it", ErrSprintState)
+	}
+	return nil
+}
 
-	var count int
-	err := r.pool.QueryRow(ctx,
-		`SELECT COUNT(*)
-		 FROM tasks t
-		 JOIN project_members pm ON pm.project_id = t.project_id
-		 WHERE pm.user_id = $1 AND t.id = ANY($2::uuid[]) AND t.completed = false`,
-		userID, dependencyIDs,
-	).Scan(&count)
+// lockSprint locks a sprint row for a membership or state change and returns
+// its project and state.
+func lockSprint(ctx context.Context, tx pgx.Tx, sprintID string) (string, string, error) {
+	var projectID, state string
+	err := tx.QueryRow(ctx, `SELECT project_id, state FROM sprints WHERE id = $1 FOR UPDATE`, sprintID).Scan(&projectID, &state)
 	if err != nil {
-		return false, fmt.Errorf("check incomplete dependencies: %w", err)
+		return "", "", fmt.Errorf("lock sprint: %w", err)
 	}
-
-	return count > 0, nil
+	return projectID, state, nil
 }
 
-func (r *Repo) ListTasks(ctx context.Context, projectID, userID string) ([]models.Task, error) {
-	rows, err := r.pool.Query(ctx,
-		`SELECT `+taskSelectColumns+`
-		 FROM tasks
-		 WHERE project_id = $1 AND EXISTS (
-		 	SELECT 1 FROM project_members pm
-		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $2
-		 )
-		 ORDER BY sort_order ASC`, projectID, userID)
+// StartSprint activates a planned sprint. The tasks in it at this moment are
+// its committed work.
+func (r *Repo) StartSprint(ctx context.Context, sprintID string) (*models.Sprint, error) {
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("list tasks: %w", err)
+		return nil, fmt.Errorf("begin start sprint: %w", err)
 	}
-	defer rows.Close()
-	return scanTasks(rows)
-}
+	defer tx.Rollback(ctx)
 
-func (r *Repo) ListAllTasks(ctx context.Context, userID string, limit int, sortByDeadline, openOnly bool, workspaceID string) ([]models.Task, error) {
-	whereClause := `
-		 WHERE EXISTS (
-		 	SELECT 1 FROM project_members pm
-		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $1
-		 )`
-	args := []any{userID}
-	if openOnly {
-		whereClause += ` AND tasks.completed = false`
+	projectID, state, err := lockSprint(ctx, tx, sprintID)
+	if err != nil {
+		return nil, err
 	}
-	if workspaceID != "" {
-		whereClause += ` AND EXISTS (
-			SELECT 1 FROM projects p
-			WHERE p.id = tasks.project_id AND p.workspace_id = $2
-		)`
-		args = append(args, workspaceID)
+	if state != "planned" {
+		return nil, fmt.Errorf("%w: only planned sprints can be started", ErrSprintState)
 	}
-
-	orderClause := ` ORDER BY created_at DESC`
-	if sortByDeadline {
-		orderClause = `
-			ORDER BY
-				CASE WHEN deadline IS NULL THEN 1 ELSE 0 END ASC,
-				deadline ASC NULLS LAST,
-				CASE priority
-					WHEN 'urgent' THEN 0
-					WHEN 'high' THEN 1
-					WHEN 'medium' THEN 2
-					WHEN 'low' THEN 3
-					ELSE 4
-				END ASC,
-				sort_order ASC,
-				created_at DESC`
+	var active bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM sprints WHERE project_id = $1 AND state = 'active')`, projectID).Scan(&active); err != nil {
+		return nil, fmt.Errorf("check active sprint: %w", err)
 	}
-
-	args = append(args, limit)
-	rows, err := r.pool.Query(ctx,
-		`SELECT `+taskSelectColumns+`
-		 FROM tasks`+whereClause+orderClause+` LIMIT $`+strconv.Itoa(len(args)), args...)
-	if err != nil {
-		return nil, fmt.Errorf("list all tasks: %w", err)
+	if active {
+		return nil, fmt.Errorf("%w: the project already has an active sprint", ErrSprintState)
 	}
-	defer rows.Close()
-	return scanTasks(rows)
+	if _, err := tx.Exec(ctx, `UPDATE sprints SET state = 'active', started_at = NOW() WHERE id = $1`, sprintID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: the project already has an active sprint", ErrSprintState)
+		}
+		return nil, fmt.Errorf("start sprint: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE sprint_tasks SET committed = TRUE WHERE sprint_id = $1 AND removed_at IS NULL`, sprintID); err != nil {
+		return nil, fmt.Errorf("commit sprint tasks: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit start sprint: %w", err)
+	}
+	return r.GetSprint(ctx, sprintID)
 }
 
-func (r *Repo) CreateTask(ctx context.Context, userID string, req models.CreateTaskRequest) (*models.Task, error) {
+// CloseSprint closes the active sprint and stores its report. Incomplete
+// tasks move to rolloverSprintID, a planned sprint of the same project, when
+// given. Tasks already planned into another open sprint stay there, since a
+// task belongs to at most one open sprint.
+func (r *Repo) CloseSprint(ctx context.Context, sprintID string, rolloverSprintID *string) (*models.Sprint, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin close sprint: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	projectID, state, err := lockSprint(ctx, tx, sprintID)
+	if err != nil {
+		return nil, err
+	}
+	if state != "active" {
+		return nil, fmt.Errorf("%w: only active sprints can be closed", ErrSprintState)
+	}
+	report, err := sprintReport(ctx, tx, sprintID)
+	if err != nil {
+		return nil, err
+	}
+	if rolloverSprintID != nil {
+		if !uuidPattern.MatchString(*rolloverSprintID) {
+			return nil, fmt.Errorf("%w: rollover sprint not found", ErrSprintState)
+		}
+		targetProjectID, targetState, err := lockSprint(ctx, tx, *rolloverSprintID)
+		if errors.Is(err, pgx.ErrNoRows) || err == nil && targetProjectID != projectID {
+			return nil, fmt.Errorf("%w: rollover sprint not found", ErrSprintState)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if targetState != "planned" {
+			return nil, fmt.Errorf("%w: tasks can only roll over into a planned sprint", ErrSprintState)
+		}
+		if len(report.IncompleteTaskIDs) > 0 {
+			tag, err := tx.Exec(ctx,
+				`INSERT INTO sprint_tasks (sprint_id, task_id)
+				 SELECT $1::uuid, incomplete.id FROM unnest($2::uuid[]) AS incomplete(id)
+				 WHERE NOT EXISTS (
+				 	SELECT 1 FROM sprint_tasks st
+				 	JOIN sprints s ON s.id = st.sprint_id
+				 	WHERE st.task_id = incomplete.id AND st.removed_at IS NULL
+				 	  AND s.state <> 'closed' AND s.id NOT IN ($1::uuid, $3::uuid)
+				 )
+				 ON CONFLICT (sprint_id, task_id) DO UPDATE SET removed_at = NULL, added_at = NOW()`,
+				*rolloverSprintID, report.IncompleteTaskIDs, sprintID,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("roll over sprint tasks: %w", err)
+			}
+			report.RolledOver = int(tag.RowsAffected())
+		}
+		report.RolloverSprintID = rolloverSprintID
+	}
+	if _, err := tx.Exec(ctx, `UPDATE sprints SET state = 'closed', closed_at = NOW(), report = $2 WHERE id = $1`, sprintID, report); err != nil {
+		return nil, fmt.Errorf("close sprint: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit close sprint: %w", err)
+	}
+	return r.GetSprint(ctx, sprintID)
+}
+
+// SprintReport returns the stored report of a closed sprint or the report of
+// an active sprint so far.
+func (r *Repo) SprintReport(ctx context.Context, sprint *models.Sprint) (*models.SprintReport, error) {
+	if sprint.State == "closed" && sprint.Report != nil {
+		return sprint.Report, nil
+	}
+	if sprint.State == "planned" {
+		return nil, fmt.Errorf("%w: the sprint has not started", ErrSprintState)
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin sprint report: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	return sprintReport(ctx, tx, sprint.ID)
+}
+
+func sprintReport(ctx context.Context, tx pgx.Tx, sprintID string) (*models.SprintReport, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT st.task_id::text, st.committed, st.removed_at IS NOT NULL, t.completed
+		 FROM sprint_tasks st
+		 JOIN tasks t ON t.id = st.task_id
+		 WHERE st.sprint_id = $1
+		 ORDER BY t.sort_order, t.id`, sprintID)
+	if err != nil {
+		return nil, fmt.Errorf("load sprint tasks: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.SprintReport{CompletedTaskIDs: []string{}, IncompleteTaskIDs: []string{}}
+	for rows.Next() {
+		var taskID string
+		var committed, removed, completed bool
+		if err := rows.Scan(&taskID, &committed, &removed, &completed); err != nil {
+			return nil, fmt.Errorf("scan sprint task: %w", err)
+		}
+		if committed {
+			report.Committed++
+		}
+		if removed {
+			report.Removed++
+			continue
+		}
+		if !committed {
+			report.AddedMidSprint++
+		}
+		if !completed {
+			report.Incomplete++
+			report.IncompleteTaskIDs = append(report.IncompleteTaskIDs, taskID)
+			continue
+		}
+		report.Completed++
+		report.CompletedTaskIDs = append(report.CompletedTaskIDs, taskID)
+		if committed {
+			report.CommittedCompleted++
+		} else {
+			report.AddedCompleted++
+		}
+	}
+	return report, rows.Err()
+}
+
+func (r *Repo) ListSprintTasks(ctx context.Context, sprintID string) ([]models.Task, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+taskSelectColumns+`
+		 FROM tasks
+		 WHERE id IN (SELECT task_id FROM sprint_tasks WHERE sprint_id = $1 AND removed_at IS NULL)
+		 ORDER BY sort_order ASC`, sprintID)
+	if err != nil {
+		return nil, fmt.Errorf("list sprint tasks: %w", err)
+	}
+	defer rows.Close()
+	return scanTasks(rows)
+}
+
+// AddSprintTasks puts tasks into a planned or active sprint, taking them out
+// of any other open sprint of the project. Tasks added to an active sprint
+// count as added mid-sprint.
+func (r *Repo) AddSprintTasks(ctx context.Context, sprintID string, taskIDs []string) error {
+	for _, taskID := range taskIDs {
+		if !uuidPattern.MatchString(taskID) {
+			return ErrSprintTask
+		}
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin add sprint tasks: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	projectID, state, err := lockSprint(ctx, tx, sprintID)
+	if err != nil {
+		return err
+	}
+	if state == "closed" {
+		return fmt.Errorf("%w: the sprint is closed", ErrSprintState)
+	}
+	var missing bool
+	if err := tx.QueryRow(ctx,
+		`SELECT EXISTS (
+		 	SELECT 1 FROM unnest($1::uuid[]) AS requested(id)
+		 	WHERE NOT EXISTS (SELECT 1 FROM tasks t WHERE t.id = requested.id AND t.project_id = $2)
+		 )`, taskIDs, projectID,
+	).Scan(&missing); err != nil {
+		return fmt.Errorf("check sprint tasks: %w", err)
+	}
+	if missing {
+		return ErrSprintTask
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE sprint_tasks st SET removed_at = NOW()
+		 FROM sprints s
+		 WHERE s.id = st.sprint_id AND s.project_id = $1 AND s.state <> 'closed' AND s.id <> $2
+		   AND st.task_id = ANY($3::uuid[]) AND st.removed_at IS NULL`,
+		projectID, sprintID, taskIDs,
+	); err != nil {
+		return fmt.Errorf("move tasks out of other sprints: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO sprint_tasks (sprint_id, task_id)
+		 SELECT DISTINCT $1::uuid, unnest($2::uuid[])
+		 ON CONFLICT (sprint_id, task_id) DO UPDATE
+		 SET removed_at = NULL,
+		     added_at = CASE WHEN sprint_tasks.removed_at IS NULL THEN sprint_tasks.added_at ELSE NOW() END`,
+		sprintID, taskIDs,
+	); err != nil {
+		return fmt.Errorf("add sprint tasks: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit add sprint tasks: %w", err)
+	}
+	return nil
+}
+
+// RemoveSprintTask takes a task out of a planned or active sprint. Active
+// sprints keep the row so the report can count the removal.
+func (r *Repo) RemoveSprintTask(ctx context.Context, sprintID, taskID string) (bool, error) {
+	if !uuidPattern.MatchString(taskID) {
+		return false, nil
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin remove sprint task: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, state, err := lockSprint(ctx, tx, sprintID)
+	if err != nil {
+		return false, err
+	}
+	var result pgconn.CommandTag
+	switch state {
+	case "closed":
+		return false, fmt.Errorf("%w: the sprint is closed", ErrSprintState)
+	case "active":
+		result, err = tx.Exec(ctx, `UPDATE sprint_tasks SET removed_at = NOW() WHERE sprint_id = $1 AND task_id = $2 AND removed_at IS NULL`, sprintID, taskID)
+	default:
+		result, err = tx.Exec(ctx, `DELETE FROM sprint_tasks WHERE sprint_id = $1 AND task_id = $2`, sprintID, taskID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("remove sprint task: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit remove sprint task: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// ---- Project analytics ----
+
+// Analytics ranges are UTC days; to is the last day included.
+
+// completionEvent matches the status events that complete a task.
+const completionEvent = `e.completed AND NOT COALESCE(e.from_completed, FALSE)`
+
+// ProjectBurndown returns one point per day from from to to. With a sprint,
+// only tasks in the sprint on that day count.
+func (r *Repo) ProjectBurndown(ctx context.Context, projectID string, sprintID *string, from, to time.Time) ([]models.BurndownPoint, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT d.day::date,
+		        COUNT(t.id),
+		        COUNT(t.id) FILTER (WHERE COALESCE((
+		        	SELECT e.completed FROM task_status_events e
+		        	WHERE e.task_id = t.id AND e.changed_at < b.day_end
+		        	ORDER BY e.changed_at DESC, e.id DESC
+		        	LIMIT 1
+		        ), FALSE))
+		 FROM generate_series($2::date::timestamp, $3::date::timestamp, INTERVAL '1 day') AS d(day)
+		 CROSS JOIN LATERAL (SELECT (d.day + INTERVAL '1 day') AT TIME ZONE 'UTC' AS day_end) b
+		 LEFT JOIN tasks t ON t.project_id = $1 AND t.created_at < b.day_end
+		   AND ($4::uuid IS NULL OR EXISTS (
+		   	SELECT 1 FROM sprint_tasks st
+		   	WHERE st.sprint_id = $4 AND st.task_id = t.id AND st.added_at < b.day_end
+		   	  AND (st.removed_at IS NULL OR st.removed_at >= b.day_end)
+		   ))
+		 GROUP BY d.day
+		 ORDER BY d.day`,
+		projectID, from, to, sprintID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("project burndown: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.BurndownPoint{}
+	for rows.Next() {
+		var day time.Time
+		var point models.BurndownPoint
+		if err := rows.Scan(&day, &point.Total, &point.Completed); err != nil {
+			return nil, fmt.Errorf("scan burndown point: %w", err)
+		}
+		point.Date = day.Format(time.DateOnly)
+		point.Remaining = point.Total - point.Completed
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// ProjectThroughput counts completed tasks per ISO week for the weeks
+// touching from to to.
+func (r *Repo) ProjectThroughput(ctx context.Context, projectID string, from, to time.Time) ([]models.ThroughputWeek, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT w.week::date, COUNT(DISTINCT e.task_id)
+		 FROM generate_series(date_trunc('week', $2::date::timestamp), date_trunc('week', $3::date::timestamp), INTERVAL '1 week') AS w(week)
+		 LEFT JOIN task_status_events e ON e.project_id = $1 AND `+completionEvent+`
+		   AND e.changed_at >= w.week AT TIME ZONE 'UTC'
+		   AND e.changed_at < (w.week + INTERVAL '1 week') AT TIME ZONE 'UTC'
+		 GROUP BY w.week
+		 ORDER BY w.week`,
+		projectID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("project throughput: %w", err)
+	}
+	defer rows.Close()
+
+	weeks := []models.ThroughputWeek{}
+	for rows.Next() {
+		var weekStart time.Time
+		var week models.ThroughputWeek
+		if err := rows.Scan(&weekStart, &week.Completed); err != nil {
+			return nil, fmt.Errorf("scan throughput week: %w", err)
+		}
+		week.WeekStart = weekStart.Format(time.DateOnly)
+		weeks = append(weeks, week)
+	}
+	return weeks, rows.Err()
+}
+
+// ListTaskCycleTimes measures the tasks whose latest completion falls in the
+// range and that are still completed, most recent first.
+func (r *Repo) ListTaskCycleTimes(ctx context.Context, projectID string, from, to time.Time) ([]models.TaskCycleTime, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT t.id, t.task_key, t.title, t.is_encrypted, t.created_at, started.changed_at, done.changed_at
+		 FROM tasks t
+		 CROSS JOIN LATERAL (
+		 	SELECT e.changed_at FROM task_status_events e
+		 	WHERE e.task_id = t.id AND `+completionEvent+`
+		 	ORDER BY e.changed_at DESC, e.id DESC
+		 	LIMIT 1
+		 ) done
+		 LEFT JOIN LATERAL (
+		 	SELECT e.changed_at FROM task_status_events e
+		 	WHERE e.task_id = t.id AND e.from_status IS NOT NULL AND NOT e.completed AND e.changed_at <= done.changed_at
+		 	ORDER BY e.changed_at, e.id
+		 	LIMIT 1
+		 ) started ON TRUE
+		 WHERE t.project_id = $1 AND t.completed
+		   AND done.changed_at >= $2::date::timestamp AT TIME ZONE 'UTC'
+		   AND done.changed_at < ($3::date + 1)::timestamp AT TIME ZONE 'UTC'
+		 ORDER BY done.changed_at DESC
+		 LIMIT 1000`,
+		projectID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list task cycle times: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []models.TaskCycleTime{}
+	for rows.Next() {
+		var task models.TaskCycleTime
+		if err := rows.Scan(&task.TaskID, &task.TaskKey, &task.Title, &task.IsEncrypted, &task.CreatedAt, &task.StartedAt, &task.CompletedAt); err != nil {
+			return nil, fmt.Errorf("scan task cycle time: %w", err)
+		}
+		task.LeadTimeHours = task.CompletedAt.Sub(task.CreatedAt).Hours()
+		if task.StartedAt != nil {
+			hours := task.CompletedAt.Sub(*task.StartedAt).Hours()
+			task.CycleTimeHours = &hours
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// ProjectTimeInStatus sums the time open tasks spent in each status within
+// the range, in workflow order. Time after completion is not counted.
+func (r *Repo) ProjectTimeInStatus(ctx context.Context, projectID string, from, to time.Time) ([]models.StatusTime, error) {
+	rows, err := r.pool.Query(ctx,
+		`WITH bounds AS (
+		 	SELECT $2::date::timestamp AT TIME ZONE 'UTC' AS range_start,
+		 	       LEAST(($3::date + 1)::timestamp AT TIME ZONE 'UTC', NOW()) AS range_end
+		 ), spans AS (
+		 	SELECT e.task_id, e.to_status, e.completed, e.changed_at AS entered_at,
+		 	       COALESCE(LEAD(e.changed_at) OVER (PARTITION BY e.task_id ORDER BY e.changed_at, e.id), NOW()) AS left_at
+		 	FROM task_status_events e
+		 	WHERE e.project_id = $1
+		 )
+		 SELECT s.to_status, COALESCE(ps.label, s.to_status), COUNT(DISTINCT s.task_id),
+		        SUM(EXTRACT(EPOCH FROM LEAST(s.left_at, b.range_end) - GREATEST(s.entered_at, b.range_start))) / 3600
+		 FROM spans s
+		 CROSS JOIN bounds b
+		 LEFT JOIN project_task_statuses ps ON ps.project_id = $1 AND ps.key = s.to_status
+		 WHERE NOT s.completed AND s.entered_at < b.range_end AND s.left_at > b.range_start
+		 GROUP BY s.to_status, ps.label, ps.position
+		 ORDER BY ps.position ASC NULLS LAST, s.to_status`,
+		projectID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("project time in status: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := []models.StatusTime{}
+	for rows.Next() {
+		var status models.StatusTime
+		if err := rows.Scan(&status.Key, &status.Label, &status.Tasks, &status.TotalHours); err != nil {
+			return nil, fmt.Errorf("scan status time: %w", err)
+		}
+		if status.Tasks > 0 {
+			status.AverageHours = status.TotalHours / float64(status.Tasks)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// ---- Storage usage ----
+
+// workspaceStorageUsageQuery sums file and pending upload bytes per workspace.
+// Callers append conditions on w.
+const workspaceStorageUsageQuery = `SELECT w.id, w.name, COALESCE(f.bytes, 0), COALESCE(u.bytes, 0), COALESCE(f.files, 0),
+		COALESCE(w.storage_quota_bytes, ps.default_workspace_storage_quota_bytes), w.storage_quota_bytes
+	 FROM workspaces w
+	 CROSS JOIN platform_settings ps
+	 LEFT JOIN LATERAL (
+	 	SELECT SUM(pf.size_bytes) AS bytes, COUNT(*) AS files
+	 	FROM project_files pf JOIN projects p ON p.id = pf.project_id
+	 	WHERE p.workspace_id = w.id
+	 ) f ON TRUE
+	 LEFT JOIN LATERAL (
+	 	SELECT SUM(us.size_bytes) AS bytes
+	 	FROM upload_sessions us JOIN projects p ON p.id = us.project_id
+	 	WHERE p.workspace_id = w.id
+	 ) u ON TRUE
+	 WHERE ps.id = TRUE`
+
+func scanWorkspaceStorageUsage(row pgx.Row) (*models.WorkspaceStorageUsage, error) {
+	usage := &models.WorkspaceStorageUsage{}
+	if err := row.Scan(&usage.WorkspaceID, &usage.Name, &usage.UsedBytes, &usage.PendingUploadBytes, &usage.Files, &usage.QuotaBytes, &usage.QuotaOverrideBytes); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (r *Repo) GetWorkspaceStorageUsage(ctx context.Context, workspaceID string) (*models.WorkspaceStorageUsage, error) {
+	usage, err := scanWorkspaceStorageUsage(r.pool.QueryRow(ctx, workspaceStorageUsageQuery+` AND w.id = $1`, workspaceID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get workspace storage usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ListWorkspaceStorageUsage returns the limit workspaces using the most
+// storage, pending uploads included.
+func (r *Repo) ListWorkspaceStorageUsage(ctx context.Context, limit int) ([]models.WorkspaceStorageUsage, error) {
+	rows, err := r.pool.Query(ctx, workspaceStorageUsageQuery+`
+	 ORDER BY COALESCE(f.bytes, 0) + COALESCE(u.bytes, 0) DESC, w.name ASC
+	 LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list workspace storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.WorkspaceStorageUsage{}
+	for rows.Next() {
+		usage, err := scanWorkspaceStorageUsage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan workspace storage usage: %w", err)
+		}
+		out = append(out, *usage)
+	}
+	return out, rows.Err()
+}
+
+// GetWorkspaceStorageBreakdown splits a workspace's file storage by project,
+// task, and uploader, returning at most limit of the largest rows for each.
+func (r *Repo) GetWorkspaceStorageBreakdown(ctx context.Context, workspaceID string, limit int) (*models.WorkspaceStorageBreakdown, error) {
+	usage, err := r.GetWorkspaceStorageUsage(ctx, workspaceID)
+	if err != nil || usage == nil {
+		return nil, err
+	}
+	breakdown := &models.WorkspaceStorageBreakdown{WorkspaceStorageUsage: *usage}
+	breakdown.Projects, err = r.listStorageUsage(ctx,
+		`SELECT p.id, p.name, '', SUM(pf.size_bytes), COUNT(*)
+		 FROM project_files pf JOIN projects p ON p.id = pf.project_id
+		 WHERE p.workspace_id = $1
+		 GROUP BY p.id, p.name
+		 ORDER BY 4 DESC, 2 ASC
+		 LIMIT $2`, workspaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage usage by project: %w", err)
+	}
+	breakdown.Tasks, err = r.listStorageUsage(ctx,
+		`SELECT t.id, t.title, t.project_id::text, SUM(pf.size_bytes), COUNT(*)
+		 FROM project_files pf
+		 JOIN tasks t ON t.id = pf.task_id
+		 JOIN projects p ON p.id = pf.project_id
+		 WHERE p.workspace_id = $1
+		 GROUP BY t.id, t.title, t.project_id
+		 ORDER BY 4 DESC, 2 ASC
+		 LIMIT $2`, workspaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage usage by task: %w", err)
+	}
+	breakdown.Uploaders, err = r.listStorageUsage(ctx,
+		`SELECT u.id, u.name, '', SUM(pf.size_bytes), COUNT(*)
+		 FROM project_files pf
+		 JOIN projects p ON p.id = pf.project_id
+		 JOIN users u ON u.id = pf.uploader_id
+		 WHERE p.workspace_id = $1
+		 GROUP BY u.id, u.name
+		 ORDER BY 4 DESC, 2 ASC
+		 LIMIT $2`, workspaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage usage by uploader: %w", err)
+	}
+	return breakdown, nil
+}
+
+func (r *Repo) listStorageUsage(ctx context.Context, query string, args ...any) ([]models.StorageUsageEntry, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.StorageUsageEntry{}
+	for rows.Next() {
+		var entry models.StorageUsageEntry
+		if err := rows.Scan(&entry.ID, &entry.Name, &entry.ProjectID, &entry.Bytes, &entry.Files); err != nil {
+			return nil, err
+		}
+		out = append(out, entry)
+	}
+	return out, rows.Err()
+}
+
+// SetWorkspaceStorageQuota sets or, with a nil quota, clears the quota
+// override of a workspace. It returns false when the workspace does not exist.
+func (r *Repo) SetWorkspaceStorageQuota(ctx context.Context, workspaceID string, quotaBytes *int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE workspaces SET storage_quota_bytes = $2 WHERE id = $1`, workspaceID, quotaBytes)
+	if err != nil {
+		return false, fmt.Errorf("set workspace storage quota: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ---- Upload sessions ----
+
+const uploadSessionColumns = `id, project_id, task_id, uploader_id, encrypted_name, content_type, iv, is_encrypted, size_bytes, offset_bytes, status, expires_at, created_at`
+
+func scanUploadSession(row pgx.Row) (*models.UploadSession, error) {
+	session := &models.UploadSession{}
+	err := row.Scan(&session.ID, &session.ProjectID, &session.TaskID, &session.UploaderID, &session.EncryptedName, &session.ContentType, &session.IV, &session.IsEncrypted, &session.SizeBytes, &session.OffsetBytes, &session.Status, &session.ExpiresAt, &session.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ProjectStorageUsage returns the bytes a project's files use plus the
+// declared size of its unfinished upload sessions, which are reserved so
+// parallel uploads cannot overshoot the quota together.
+func (r *Repo) ProjectStorageUsage(ctx context.Context, projectID string) (int64, error) {
+	var used int64
+	err := r.pool.QueryRow(ctx,
+		`SELECT COALESCE((SELECT SUM(size_bytes) FROM project_files WHERE project_id = $1), 0)
+		      + COALESCE((SELECT SUM(size_bytes) FROM upload_sessions WHERE project_id = $1), 0)`,
+		projectID,
+	).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("project storage usage: %w", err)
+	}
+	return used, nil
+}
+
+func (r *Repo) CreateUploadSession(ctx context.Context, uploaderID string, req models.CreateProjectFileRequest, sizeBytes int64, expiresAt time.Time) (*models.UploadSession, error) {
+	session, err := scanUploadSession(r.pool.QueryRow(ctx,
+		`INSERT INTO upload_sessions (project_id, task_id, uploader_id, encrypted_name, content_type, iv, is_encrypted, size_bytes, expires_at)
+		 VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+		 RETURNING `+uploadSessionColumns,
+		req.ProjectID, req.TaskID, uploaderID, req.EncryptedName, req.ContentType, req.IV, req.IsEncrypted, sizeBytes, expiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create upload session: %w", err)
+	}
+	return session, nil
+}
+
+// GetUploadSession returns an unexpired session of uploaderID. Sessions are
+// private to the user who created them.
+func (r *Repo) GetUploadSession(ctx context.Context, sessionID, uploaderID string) (*models.UploadSession, error) {
+	session, err := scanUploadSession(r.pool.QueryRow(ctx,
+		`SELECT `+uploadSessionColumns+` FROM upload_sessions
+		 WHERE id = $1 AND uploader_id = $2 AND expires_at > NOW()`,
+		sessionID, uploaderID,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get upload session: %w", err)
+	}
+	return session, nil
+}
+
+// AppendUploadPart records a stored chunk and advances the session offset. It
+// returns false when the session is gone, is being finalized, or has moved
+// past offset because a concurrent request appended first.
+func (r *Repo) AppendUploadPart(ctx context.Context, sessionID string, part models.UploadPart, expiresAt time.Time) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin append upload part: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE upload_sessions
+		 SET offset_bytes = offset_bytes + $3, expires_at = $4
+		 WHERE id = $1 AND offset_bytes = $2 AND status = 'active' AND offset_bytes + $3 <= size_bytes`,
+		sessionID, part.OffsetBytes, part.SizeBytes, expiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("advance upload session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO upload_session_parts (session_id, offset_bytes, size_bytes, storage_path) VALUES ($1, $2, $3, $4)`,
+		sessionID, part.OffsetBytes, part.SizeBytes, part.StoragePath,
+	); err != nil {
+		return false, fmt.Errorf("create upload part: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit upload part: %w", err)
+	}
+	return true, nil
+}
+
+func (r *Repo) ListUploadParts(ctx context.Context, sessionID string) ([]models.UploadPart, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT offset_bytes, size_bytes, storage_path FROM upload_session_parts WHERE session_id = $1 ORDER BY offset_bytes ASC`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list upload parts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.UploadPart
+	for rows.Next() {
+		var part models.UploadPart
+		if err := rows.Scan(&part.OffsetBytes, &part.SizeBytes, &part.StoragePath); err != nil {
+			return nil, fmt.Errorf("scan upload part: %w", err)
+		}
+		out = append(out, part)
+	}
+	return out, rows.Err()
+}
+
+// BeginUploadFinalize moves a complete session to finalizing, which stops
+// further chunks and cancellation. It returns false when the session is not
+// complete or another request is already finalizing it.
+func (r *Repo) BeginUploadFinalize(ctx context.Context, sessionID string, expiresAt time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE upload_sessions SET status = 'finalizing', expires_at = $2
+		 WHERE id = $1 AND status = 'active' AND offset_bytes = size_bytes`,
+		sessionID, expiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("begin upload finalize: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ResetUploadFinalize returns a session to active after a failed finalize so
+// the client can retry.
+func (r *Repo) ResetUploadFinalize(ctx context.Context, sessionID string) error {
+	_, err := r.pool.Exec(ctx, `UPDATE upload_sessions SET status = 'active' WHERE id = $1 AND status = 'finalizing'`, sessionID)
+	if err != nil {
+		return fmt.Errorf("reset upload finalize: %w", err)
+	}
+	return nil
+}
+
+// DeleteUploadSession deletes the session if it is in status and reports
+// whether it did. Its parts stay behind for ReleaseUploadParts.
+func (r *Repo) DeleteUploadSession(ctx context.Context, sessionID, status string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM upload_sessions WHERE id = $1 AND status = $2`, sessionID, status)
+	if err != nil {
+		return false, fmt.Errorf("delete upload session: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseUploadParts deletes the part rows of a deleted session and returns
+// their storage paths.
+func (r *Repo) ReleaseUploadParts(ctx context.Context, sessionID string) ([]string, error) {
+	rows, err := r.pool.Query(ctx,
+		`DELETE FROM upload_session_parts p
+		 WHERE p.session_id = $1 AND NOT EXISTS (SELECT 1 FROM upload_sessions s WHERE s.id = p.session_id)
+		 RETURNING p.storage_path`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("release upload parts: %w", err)
+	}
+	return scanStoragePaths(rows)
+}
+
+// DeleteExpiredUploadSessions removes sessions that made no progress before
+// their expiry and returns how many were removed.
+func (r *Repo) DeleteExpiredUploadSessions(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM upload_sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired upload sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ReleaseOrphanedUploadParts deletes up to limit part rows whose session no
+// longer exists, because it expired or its project, task, or uploader was
+// deleted, and returns their storage paths.
+func (r *Repo) ReleaseOrphanedUploadParts(ctx context.Context, limit int) ([]string, error) {
+	rows, err := r.pool.Query(ctx,
+		`DELETE FROM upload_session_parts
+		 WHERE storage_path IN (
+		 	SELECT p.storage_path FROM upload_session_parts p
+		 	WHERE NOT EXISTS (SELECT 1 FROM upload_sessions s WHERE s.id = p.session_id)
+		 	ORDER BY p.created_at ASC
+		 	LIMIT $1
+		 	FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING storage_path`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("release orphaned upload parts: %w", err)
+	}
+	return scanStoragePaths(rows)
+}
+
+// ListStoredUploadParts returns every stored chunk of unfinished uploads for
+// the STORAGE_MODE maintenance commands.
+func (r *Repo) ListStoredUploadParts(ctx context.Context) ([]models.UploadPart, error) {
+	rows, err := r.pool.Query(ctx, `SELECT offset_bytes, size_bytes, storage_path FROM upload_session_parts ORDER BY storage_path ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list stored upload parts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.UploadPart
+	for rows.Next() {
+		var part models.UploadPart
+		if err := rows.Scan(&part.OffsetBytes, &part.SizeBytes, &part.StoragePath); err != nil {
+			return nil, fmt.Errorf("scan stored upload part: %w", err)
+		}
+		out = append(out, part)
+	}
+	return out, rows.Err()
+}
+
+func scanStoragePaths(rows pgx.Rows) ([]string, error) {
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("scan storage path: %w", err)
+		}
+		out = append(out, path)
+	}
+	return out, rows.Err()
+}
+
+// ---- Tasks ----
+
+func scanTasks(rows pgx.Rows) ([]models.Task, error) {
+	var out []models.Task
+	for rows.Next() {
+		var t models.Task
+		if err := scanTaskRow(rows, &t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if out == nil {
+		out = []models.Task{}
+	}
+	return out, nil
+}
+
+func (r *Repo) GetTask(ctx context.Context, id, userID string) (*models.Task, error) {
+	t := &models.Task{}
+	row := r.pool.QueryRow(ctx,
+		`SELECT `+taskSelectColumns+`
+		 FROM tasks
+		 WHERE id = $1 AND EXISTS (
+		 	SELECT 1 FROM project_members pm
+		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $2
+		 )`, id, userID,
+	)
+	if err := scanTaskRow(row, t); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	return t, nil
+}
+
+func (r *Repo) GetTaskByKey(ctx context.Context, projectID, taskKey, userID string) (*models.Task, error) {
+	t := &models.Task{}
+	row := r.pool.QueryRow(ctx,
+		`SELECT `+taskSelectColumns+`
+		 FROM tasks
+		 WHERE project_id = $1
+		   AND task_key = $2
+		   AND EXISTS (
+		   	SELECT 1 FROM project_members pm
+		   	WHERE pm.project_id = tasks.project_id AND pm.user_id = $3
+		   )`,
+		projectID, taskKey, userID,
+	)
+	if err := scanTaskRow(row, t); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get task by key: %w", err)
+	}
+	return t, nil
+}
+
+func (r *Repo) HasIncompleteDependencies(ctx context.Context, userID string, dependencyIDs []string) (bool, error) {
+	if len(dependencyIDs) == 0 {
+		return false, nil
+	}
+
+	var count int
+	err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*)
+		 FROM tasks t
+		 JOIN project_members pm ON pm.project_id = t.project_id
+		 WHERE pm.user_id = $1 AND t.id = ANY($2::uuid[]) AND t.completed = false`,
+		userID, dependencyIDs,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("check incomplete dependencies: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *Repo) ListTasks(ctx context.Context, projectID, userID string) ([]models.Task, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+taskSelectColumns+`
+		 FROM tasks
+		 WHERE project_id = $1 AND EXISTS (
+		 	SELECT 1 FROM project_members pm
+		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $2
+		 )
+		 ORDER BY sort_order ASC`, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	defer rows.Close()
+	return scanTasks(rows)
+}
+
+func (r *Repo) ListAllTasks(ctx context.Context, userID string, limit int, sortByDeadline, openOnly bool, workspaceID string) ([]models.Task, error) {
+	whereClause := `
+		 WHERE EXISTS (
+		 	SELECT 1 FROM project_members pm
+		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $1
+		 )`
+	args := []any{userID}
+	if openOnly {
+		whereClause += ` AND tasks.completed = false`
+	}
+	if workspaceID != "" {
+		whereClause += ` AND EXISTS (
+			SELECT 1 FROM projects p
+			WHERE p.id = tasks.project_id AND p.workspace_id = $2
+		)`
+		args = append(args, workspaceID)
+	}
+
+	orderClause := ` ORDER BY created_at DESC`
+	if sortByDeadline {
+		orderClause = `
+			ORDER BY
+				CASE WHEN deadline IS NULL THEN 1 ELSE 0 END ASC,
+				deadline ASC NULLS LAST,
+				CASE priority
+					WHEN 'urgent' THEN 0
+					WHEN 'high' THEN 1
+					WHEN 'medium' THEN 2
+					WHEN 'low' THEN 3
+					ELSE 4
+				END ASC,
+				sort_order ASC,
+				created_at DESC`
+	}
+
+	args = append(args, limit)
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+taskSelectColumns+`
+		 FROM tasks`+whereClause+orderClause+` LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list all tasks: %w", err)
+	}
+	defer rows.Close()
+	return scanTasks(rows)
+}
+
+// ErrInvalidCursor reports a pagination cursor that was not issued for the
+// requested list and sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the position after the last row of a page: the sort key
+// value of that row, or nil when it is NULL, and its ID as a tie-breaker.
+type pageCursor struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v,omitempty"`
+	ID    string  `json:"id"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value, sort string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || !uuidPattern.MatchString(cursor.ID) {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// createdPageClause returns the keyset condition and the ORDER BY and LIMIT
+// clause of a page ordered newest first by the created_at and id columns of
+// table. The condition is empty on the first page and the whole clause is
+// empty for an unpaged request. One row more than the limit is fetched so
+// createdPage can tell whether another page follows.
+func createdPageClause(table string, page models.PageRequest, arg func(any) string) (string, string, error) {
+	if page.Limit <= 0 {
+		return "", ` ORDER BY ` + table + `.created_at DESC`, nil
+	}
+	condition := ""
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor, "created")
+		if err != nil {
+			return "", "", err
+		}
+		if cursor.Value == nil {
+			return "", "", ErrInvalidCursor
+		}
+		condition = ` AND (` + table + `.created_at, ` + table + `.id) < (` + arg(*cursor.Value) + `::timestamptz, ` + arg(cursor.ID) + `::uuid)`
+	}
+	return condition, ` ORDER BY ` + table + `.created_at DESC, ` + table + `.id DESC LIMIT ` + arg(page.Limit+1), nil
+}
+
+// createdPage trims the rows fetched with createdPageClause to the page and
+// returns the cursor of the next page, or "" on the last page.
+func createdPage[T any](rows []T, page models.PageRequest, key func(*T) (time.Time, string)) ([]T, string) {
+	if page.Limit <= 0 || len(rows) <= page.Limit {
+		return rows, ""
+	}
+	rows = rows[:page.Limit]
+	createdAt, id := key(&rows[len(rows)-1])
+	return rows, encodeCursor(pageCursor{Sort: "created", Value: formatTime(createdAt), ID: id})
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// taskSort is a keyset order of task pages. id breaks ties in the same
+// direction as the sort column.
+type taskSort struct {
+	column   string
+	desc     bool
+	nullable bool
+	cast     string
+	value    func(t *models.Task) *string
+}
+
+func formatTime(t time.Time) *string {
+	value := t.Format(time.RFC3339Nano)
+	return &value
+}
+
+var taskSorts = map[string]taskSort{
+	"order": {column: "tasks.sort_order", cast: "integer", value: func(t *models.Task) *string {
+		value := strconv.Itoa(t.Order)
+		return &value
+	}},
+	"created": {column: "tasks.created_at", desc: true, cast: "timestamptz", value: func(t *models.Task) *string { return formatTime(t.CreatedAt) }},
+	"updated": {column: "tasks.updated_at", desc: true, cast: "timestamptz", value: func(t *models.Task) *string { return formatTime(t.UpdatedAt) }},
+	"deadline": {column: "tasks.deadline", nullable: true, cast: "timestamptz", value: func(t *models.Task) *string {
+		if t.Deadline == nil {
+			return nil
+		}
+		return formatTime(*t.Deadline)
+	}},
+}
+
+// ValidTaskSort reports whether sort can order a task page.
+func ValidTaskSort(sort string) bool {
+	_, ok := taskSorts[sort]
+	return ok
+}
+
+// ListTasksPage returns a page of the tasks the user can see that match
+// query, and the cursor of the next page, which is empty on the last page.
+func (r *Repo) ListTasksPage(ctx context.Context, userID string, query models.TaskListQuery) ([]models.Task, string, error) {
+	sort, ok := taskSorts[query.Sort]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown task sort %q", query.Sort)
+	}
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := []string{`EXISTS (
+		 	SELECT 1 FROM project_members pm
+		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $1
+		 )`}
+	if query.ProjectID != "" {
+		where = append(where, `tasks.project_id = `+arg(query.ProjectID)+`::uuid`)
+	}
+	if query.WorkspaceID != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM projects p WHERE p.id = tasks.project_id AND p.workspace_id = `+arg(query.WorkspaceID)+`::uuid)`)
+	}
+	if query.OpenOnly {
+		where = append(where, `NOT tasks.completed`)
+	}
+	if query.Filter != nil {
+		for _, term := range query.Filter.Terms {
+			condition := taskFilterCondition(term, userID, arg)
+			if term.Negate {
+				condition = `NOT COALESCE(` + condition + `, false)`
+			}
+			where = append(where, condition)
+		}
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+		where = append(where, taskCursorCondition(sort, cursor, arg))
+	}
+
+	direction, nulls := "ASC", ""
+	if sort.desc {
+		direction = "DESC"
+	}
+	if sort.nullable {
+		nulls = " NULLS LAST"
+	}
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+taskSelectColumns+`
+		 FROM tasks
+		 WHERE `+strings.Join(where, "\n\t\t   AND ")+`
+		 ORDER BY `+sort.column+` `+direction+nulls+`, tasks.id `+direction+`
+		 LIMIT `+arg(query.Limit+1), args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("list tasks page: %w", err)
+	}
+	defer rows.Close()
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tasks) <= query.Limit {
+		return tasks, "", nil
+	}
+	tasks = tasks[:query.Limit]
+	last := &tasks[len(tasks)-1]
+	return tasks, encodeCursor(pageCursor{Sort: query.Sort, Value: sort.value(last), ID: last.ID}), nil
+}
+
+// taskCursorCondition selects the rows after cursor in sort order. NULLs of
+// nullable columns sort last.
+func taskCursorCondition(sort taskSort, cursor *pageCursor, arg func(any) string) string {
+	if cursor.Value == nil {
+		return `(` + sort.column + ` IS NULL AND tasks.id > ` + arg(cursor.ID) + `::uuid)`
+	}
+	op := ">"
+	if sort.desc {
+		op = "<"
+	}
+	condition := `(` + sort.column + `, tasks.id) ` + op + ` (` + arg(*cursor.Value) + `::` + sort.cast + `, ` + arg(cursor.ID) + `::uuid)`
+	if sort.nullable {
+		condition = `(` + condition + ` OR ` + sort.column + ` IS NULL)`
+	}
+	return condition
+}
+
+// taskFilterCondition turns one parsed filter term into a SQL condition on
+// tasks. Every value is passed as a parameter.
+func taskFilterCondition(term taskquery.Term, userID string, arg func(any) string) string {
+	switch term.Field {
+	case taskquery.FieldStatus:
+		keys := make([]string, 0, len(term.Values))
+		for _, value := range term.Values {
+			keys = append(keys, normalizeStatusKey(value))
+		}
+		return `tasks.kanban_status = ANY(` + arg(keys) + `::text[])`
+	case taskquery.FieldPriority:
+		if term.Op == ":" {
+			return `tasks.priority = ANY(` + arg(term.Values) + `::text[])`
+		}
+		return `array_position(` + arg(taskquery.Priorities) + `::text[], tasks.priority::text) - 1 ` + term.Op + ` ` + arg(taskquery.PriorityRank(term.Values[0]))
+	case taskquery.FieldAssignee:
+		var conditions, userIDs, emails []string
+		for _, value := range term.Values {
+			switch {
+			case value == "none":
+				conditions = append(conditions, `NOT EXISTS (SELECT 1 FROM task_assignees ta WHERE ta.task_id = tasks.id)`)
+			case value == "me":
+				userIDs = append(userIDs, userID)
+			case strings.Contains(value, "@"):
+				emails = append(emails, value)
+			default:
+				userIDs = append(userIDs, value)
+			}
+		}
+		if len(userIDs) > 0 || len(emails) > 0 {
+			conditions = append(conditions, `EXISTS (
+		 	SELECT 1 FROM task_assignees ta
+		 	JOIN users u ON u.id = ta.user_id
+		 	WHERE ta.task_id = tasks.id AND (ta.user_id::text = ANY(`+arg(userIDs)+`::text[]) OR lower(u.email) = ANY(`+arg(emails)+`::text[]))
+		 )`)
+		}
+		return `(` + strings.Join(conditions, " OR ") + `)`
+	case taskquery.FieldTag:
+		return `tasks.tags && ` + arg(term.Values) + `::text[]`
+	case taskquery.FieldKey:
+		return `tasks.task_key = ANY(` + arg(term.Values) + `::text[])`
+	case taskquery.FieldText:
+		// Encrypted titles and descriptions are ciphertext.
+		pattern := "%" + likeEscaper.Replace(term.Values[0]) + "%"
+		placeholder := arg(pattern)
+		return `(NOT tasks.is_encrypted AND (tasks.title ILIKE ` + placeholder + ` OR tasks.description ILIKE ` + placeholder + `))`
+	case taskquery.FieldDue, taskquery.FieldCreated, taskquery.FieldUpdated:
+		column := map[string]string{
+			taskquery.FieldDue:     "tasks.deadline",
+			taskquery.FieldCreated: "tasks.created_at",
+			taskquery.FieldUpdated: "tasks.updated_at",
+		}[term.Field]
+		if term.Range.None {
+			return column + ` IS NULL`
+		}
+		var conditions []string
+		if term.Range.From != nil {
+			conditions = append(conditions, column+` >= `+arg(*term.Range.From))
+		}
+		if term.Range.Until != nil {
+			conditions = append(conditions, column+` < `+arg(*term.Range.Until))
+		}
+		return `(` + strings.Join(conditions, " AND ") + `)`
+	case taskquery.FieldIs:
+		conditions := make([]string, 0, len(term.Values))
+		for _, flag := range term.Values {
+			switch flag {
+			case "open":
+				conditions = append(conditions, `NOT tasks.completed`)
+			case "completed":
+				conditions = append(conditions, `tasks.completed`)
+			case "blocked":
+				conditions = append(conditions, `EXISTS (
+		 	SELECT 1 FROM tasks dependency
+		 	WHERE dependency.id::text = ANY(tasks.dependencies) AND NOT dependency.completed
+		 )`)
+			case "overdue":
+				conditions = append(conditions, `(tasks.deadline < NOW() AND NOT tasks.completed)`)
+			case "subtask":
+				conditions = append(conditions, `tasks.parent_id IS NOT NULL`)
+			case "recurring":
+				// Tasks created with a cleared recurrence store an empty string.
+				conditions = append(conditions, `COALESCE(tasks.recurrence, '') <> ''`)
+			}
+		}
+		return `(` + strings.Join(conditions, " OR ") + `)`
+	}
+	// The parser rejects unknown fields.
+	return `false`
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *Repo) CreateTask(ctx context.Context, userID string, req models.CreateTaskRequest) (*models.Task, error) {
 	t := &models.Task{}
 	kanban := req.KanbanStatus
 	if kanban == "" {
@@ -2801,20 +4782,18 @@ func (r *Repo) UpdateTask(ctx context.Context, id, userID string, req models.Upd
 	row := r.pool.QueryRow(ctx,
 		`UPDATE tasks SET
 				title = COALESCE($3, title), description = COALESCE($4, description), completed = COALESCE($5, completed), parent_id = COALESCE($6, parent_id),
-				time_spent = COALESCE($7, time_spent), is_timer_running = COALESCE($8, is_timer_running),
-				timer_started_at = CASE WHEN $9::text IS NOT NULL THEN $9::timestamptz ELSE timer_started_at END,
-				time_entries = COALESCE($10, time_entries), sort_order = COALESCE($11, sort_order),
-				priority = COALESCE($12, priority), kanban_status = COALESCE($13, kanban_status),
-				deadline = CASE WHEN $14::text IS NOT NULL THEN $14::timestamptz ELSE deadline END,
-				tags = COALESCE($15, tags), dependencies = COALESCE($16, dependencies),
-				recurrence = CASE WHEN $17::text IS NOT NULL THEN NULLIF($17::text, '') ELSE recurrence END,
-				is_encrypted = COALESCE($18, is_encrypted)
+				sort_order = COALESCE($7, sort_order),
+				priority = COALESCE($8, priority), kanban_status = COALESCE($9, kanban_status),
+				deadline = CASE WHEN $10::text IS NOT NULL THEN $10::timestamptz ELSE deadline END,
+				tags = COALESCE($11, tags), dependencies = COALESCE($12, dependencies),
+				recurrence = CASE WHEN $13::text IS NOT NULL THEN NULLIF($13::text, '') ELSE recurrence END,
+				is_encrypted = COALESCE($14, is_encrypted)
 		 WHERE id = $1 AND EXISTS (
 		 	SELECT 1 FROM project_members pm
 		 	WHERE pm.project_id = tasks.project_id AND pm.user_id = $2 AND pm.role IN ('owner', 'admin', 'editor')
 		 )
 		 RETURNING `+taskSelectColumns,
-		id, userID, req.Title, req.Description, req.Completed, req.ParentID, req.TimeSpent, req.IsTimerRunning, req.TimerStartedAt, req.TimeEntries, req.Order, req.Priority, req.KanbanStatus, req.Deadline, req.Tags, req.Dependencies, req.Recurrence, req.IsEncrypted,
+		id, userID, req.Title, req.Description, req.Completed, req.ParentID, req.Order, req.Priority, req.KanbanStatus, req.Deadline, req.Tags, req.Dependencies, req.Recurrence, req.IsEncrypted,
 	)
 	if err := scanTaskRow(row, t); err != nil {
 		return nil, fmt.Errorf("update task: %w", err)
@@ -2833,33 +4812,621 @@ func (r *Repo) DeleteTask(ctx context.Context, id, userID string) error {
 	return err
 }
 
+// ---- Time entries ----
+
+// A trigger on time_entries keeps the time_spent, is_timer_running,
+// timer_started_at, and time_entries columns of the task up to date.
+
+// maxTimeEntries caps time entry lists.
+const maxTimeEntries = 2000
+
+const timeEntrySelect = `SELECT e.id, e.task_id, t.project_id, e.user_id, e.started_at, e.ended_at, e.duration_seconds, e.note, e.billable, e.created_at, e.updated_at
+	FROM time_entries e
+	JOIN tasks t ON t.id = e.task_id`
+
+// timeEntryDuration computes duration_seconds from the start and end
+// parameters the same way the table's check constraint does.
+func timeEntryDuration(start, end string) string {
+	return `CASE WHEN ` + end + `::timestamptz IS NULL THEN 0 ELSE EXTRACT(EPOCH FROM ` + end + `::timestamptz - ` + start + `::timestamptz)::integer END`
+}
+
+func scanTimeEntry(row pgx.Row) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := row.Scan(&entry.ID, &entry.TaskID, &entry.ProjectID, &entry.UserID, &entry.StartedAt, &entry.EndedAt, &entry.DurationSeconds, &entry.Note, &entry.Billable, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func collectTimeEntries(rows pgx.Rows) ([]models.TimeEntry, error) {
+	defer rows.Close()
+	entries := []models.TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan time entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *Repo) ListTaskTimeEntries(ctx context.Context, taskID string) ([]models.TimeEntry, error) {
+	rows, err := r.pool.Query(ctx, timeEntrySelect+`
+		WHERE e.task_id = $1
+		ORDER BY e.started_at DESC, e.id
+		LIMIT `+strconv.Itoa(maxTimeEntries), taskID)
+	if err != nil {
+		return nil, fmt.Errorf("list task time entries: %w", err)
+	}
+	return collectTimeEntries(rows)
+}
+
+// ListTimeEntries returns the entries in viewerID's projects that match
+// query, newest first.
+func (r *Repo) ListTimeEntries(ctx context.Context, viewerID string, query models.TimeEntryQuery) ([]models.TimeEntry, error) {
+	args := []any{}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	conditions := []string{`EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = t.project_id AND pm.user_id = ` + arg(viewerID) + `)`}
+	if query.WorkspaceID != "" {
+		conditions = append(conditions, `t.project_id IN (SELECT p.id FROM projects p WHERE p.workspace_id = `+arg(query.WorkspaceID)+`)`)
+	}
+	if query.ProjectID != "" {
+		conditions = append(conditions, `t.project_id = `+arg(query.ProjectID))
+	}
+	if query.UserID != "" {
+		conditions = append(conditions, `e.user_id = `+arg(query.UserID))
+	}
+	if query.From != nil {
+		conditions = append(conditions, `e.started_at >= `+arg(*query.From))
+	}
+	if query.To != nil {
+		conditions = append(conditions, `e.started_at < `+arg(*query.To))
+	}
+	rows, err := r.pool.Query(ctx, timeEntrySelect+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY e.started_at DESC, e.id
+		LIMIT `+strconv.Itoa(maxTimeEntries), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list time entries: %w", err)
+	}
+	return collectTimeEntries(rows)
+}
+
+func (r *Repo) GetTimeEntry(ctx context.Context, id string) (*models.TimeEntry, error) {
+	entry, err := scanTimeEntry(r.pool.QueryRow(ctx, timeEntrySelect+` WHERE e.id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get time entry: %w", err)
+	}
+	return entry, nil
+}
+
+// GetRunningTimeEntry returns the user's running timer, or nil.
+func (r *Repo) GetRunningTimeEntry(ctx context.Context, userID string) (*models.TimeEntry, error) {
+	entry, err := scanTimeEntry(r.pool.QueryRow(ctx, timeEntrySelect+` WHERE e.user_id = $1 AND e.ended_at IS NULL`, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get running time entry: %w", err)
+	}
+	return entry, nil
+}
+
+// CreateTimeEntry records finished work on a task.
+func (r *Repo) CreateTimeEntry(ctx context.Context, userID, taskID string, startedAt, endedAt time.Time, note string, billable bool) (*models.TimeEntry, error) {
+	var id string
+	if err := r.pool.QueryRow(ctx,
+		`INSERT INTO time_entries (task_id, user_id, started_at, ended_at, duration_seconds, note, billable)
+		 VALUES ($1, $2, $3, $4, `+timeEntryDuration("$3", "$4")+`, $5, $6)
+		 RETURNING id`,
+		taskID, userID, startedAt, endedAt, note, billable,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("create time entry: %w", err)
+	}
+	return r.GetTimeEntry(ctx, id)
+}
+
+// StartTimer starts a timer for the user on a task. A timer the user already
+// runs is stopped first and returned as stopped.
+func (r *Repo) StartTimer(ctx context.Context, userID, taskID, note string, billable bool) (started, stopped *models.TimeEntry, err error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin start timer: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize timer changes per user so concurrent starts cannot both pass
+	// the one-running-timer index check.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, nil, fmt.Errorf("lock user timer: %w", err)
+	}
+	stoppedID, err := stopRunningTimer(ctx, tx, userID, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	var startedID string
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO time_entries (task_id, user_id, started_at, note, billable)
+		 VALUES ($1, $2, NOW(), $3, $4)
+		 RETURNING id`,
+		taskID, userID, note, billable,
+	).Scan(&startedID); err != nil {
+		return nil, nil, fmt.Errorf("start timer: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("commit start timer: %w", err)
+	}
+
+	if started, err = r.GetTimeEntry(ctx, startedID); err != nil {
+		return nil, nil, err
+	}
+	if stoppedID != "" {
+		if stopped, err = r.GetTimeEntry(ctx, stoppedID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return started, stopped, nil
+}
+
+// StopTimer stops the user's running timer, only when it runs on taskID if
+// taskID is set, and returns it. It returns nil when nothing was stopped.
+func (r *Repo) StopTimer(ctx context.Context, userID, taskID string) (*models.TimeEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin stop timer: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	stoppedID, err := stopRunningTimer(ctx, tx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit stop timer: %w", err)
+	}
+	if stoppedID == "" {
+		return nil, nil
+	}
+	return r.GetTimeEntry(ctx, stoppedID)
+}
+
+func stopRunningTimer(ctx context.Context, tx pgx.Tx, userID, taskID string) (string, error) {
+	var id string
+	err := tx.QueryRow(ctx,
+		`UPDATE time_entries
+		 SET ended_at = GREATEST(NOW(), started_at),
+		     duration_seconds = EXTRACT(EPOCH FROM GREATEST(NOW(), started_at) - started_at)::integer
+		 WHERE user_id = $1 AND ended_at IS NULL AND ($2 = '' OR task_id::text = $2)
+		 RETURNING id`,
+		userID, taskID,
+	).Scan(&id)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", fmt.Errorf("stop timer: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateTimeEntry replaces the times, note, and billable flag of an entry.
+// endedAt nil keeps a running timer running.
+func (r *Repo) UpdateTimeEntry(ctx context.Context, id string, startedAt time.Time, endedAt *time.Time, note string, billable bool) (*models.TimeEntry, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE time_entries
+		 SET started_at = $2, ended_at = $3, duration_seconds = `+timeEntryDuration("$2", "$3")+`, note = $4, billable = $5
+		 WHERE id = $1`,
+		id, startedAt, endedAt, note, billable,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update time entry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, nil
+	}
+	return r.GetTimeEntry(ctx, id)
+}
+
+func (r *Repo) DeleteTimeEntry(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM time_entries WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete time entry: %w", err)
+	}
+	return nil
+}
+
+// ---- Timesheets ----
+
+// timesheetDimensions maps each timesheet grouping to its key columns: the id
+// first and the name last.
+var timesheetDimensions = map[string][]string{
+	"customer": {"c.id", "COALESCE(c.name, '')"},
+	"project":  {"p.id", "p.is_encrypted", "p.name"},
+	"member":   {"u.id", "u.name"},
+	"week":     {"date_trunc('week', e.started_at AT TIME ZONE 'UTC')::date"},
+}
+
+// Timesheet sums the finished time entries of a workspace by the dimensions
+// in query.GroupBy, which must be keys of timesheetDimensions.
+func (r *Repo) Timesheet(ctx context.Context, workspaceID string, query models.TimesheetQuery) ([]models.TimesheetRow, error) {
+	args := []any{}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	conditions := []string{
+		`p.workspace_id = ` + arg(workspaceID),
+		`e.ended_at IS NOT NULL`,
+		`e.started_at >= ` + arg(query.From),
+		`e.started_at < ` + arg(query.To),
+	}
+	if query.CustomerID != "" {
+		conditions = append(conditions, `p.client_id = `+arg(query.CustomerID))
+	}
+	if query.ProjectID != "" {
+		conditions = append(conditions, `p.id = `+arg(query.ProjectID))
+	}
+	if query.UserID != "" {
+		conditions = append(conditions, `e.user_id = `+arg(query.UserID))
+	}
+	if query.Billable != nil {
+		conditions = append(conditions, `e.billable = `+arg(*query.Billable))
+	}
+	columns := []string{}
+	for _, dimension := range query.GroupBy {
+		columns = append(columns, timesheetDimensions[dimension]...)
+	}
+	selectList := append(append([]string{}, columns...),
+		`COALESCE(SUM(e.duration_seconds), 0)`, `COALESCE(SUM(e.duration_seconds) FILTER (WHERE e.billable), 0)`, `COUNT(*)`,
+	)
+	sql := `SELECT ` + strings.Join(selectList, ", ") + `
+		FROM time_entries e
+		JOIN tasks t ON t.id = e.task_id
+		JOIN projects p ON p.id = t.project_id
+		JOIN users u ON u.id = e.user_id
+		LEFT JOIN customers c ON c.id = p.client_id
+		WHERE ` + strings.Join(conditions, " AND ")
+	if len(columns) > 0 {
+		// Names sort before ids so rows read alphabetically.
+		order := []string{}
+		for _, dimension := range query.GroupBy {
+			keys := timesheetDimensions[dimension]
+			order = append(order, keys[len(keys)-1], keys[0])
+		}
+		sql += ` GROUP BY ` + strings.Join(columns, ", ") + ` ORDER BY ` + strings.Join(order, ", ")
+	}
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("timesheet: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.TimesheetRow{}
+	for rows.Next() {
+		var row models.TimesheetRow
+		var week time.Time
+		var seconds, billableSeconds int64
+		targets := []any{}
+		for _, dimension := range query.GroupBy {
+			switch dimension {
+			case "customer":
+				row.CustomerName = new(string)
+				targets = append(targets, &row.CustomerID, row.CustomerName)
+			case "project":
+				row.ProjectID, row.ProjectName = new(string), new(string)
+				targets = append(targets, row.ProjectID, &row.ProjectEncrypted, row.ProjectName)
+			case "member":
+				row.UserID, row.UserName = new(string), new(string)
+				targets = append(targets, row.UserID, row.UserName)
+			case "week":
+				row.WeekStart = new(string)
+				targets = append(targets, &week)
+			}
+		}
+		targets = append(targets, &seconds, &billableSeconds, &row.Entries)
+		if err := rows.Scan(targets...); err != nil {
+			return nil, fmt.Errorf("scan timesheet row: %w", err)
+		}
+		if row.WeekStart != nil {
+			*row.WeekStart = week.Format(time.DateOnly)
+		}
+		row.Hours = float64(seconds) / 3600
+		row.BillableHours = float64(billableSeconds) / 3600
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// ListProjectBudgets returns the logged hours of the workspace's projects that
+// have a customer or an hour budget, optionally only those of one customer.
+// Time logged since recentSince is reported as RecentHours.
+func (r *Repo) ListProjectBudgets(ctx context.Context, workspaceID, customerID string, recentSince time.Time) ([]models.ProjectBudget, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT p.id, p.name, p.is_encrypted, p.client_id, c.name, p.hour_budget,
+		        COALESCE(SUM(e.duration_seconds), 0),
+		        COALESCE(SUM(e.duration_seconds) FILTER (WHERE e.billable), 0),
+		        COALESCE(SUM(e.duration_seconds) FILTER (WHERE e.started_at >= $3), 0)
+		 FROM projects p
+		 LEFT JOIN customers c ON c.id = p.client_id
+		 LEFT JOIN tasks t ON t.project_id = p.id
+		 LEFT JOIN time_entries e ON e.task_id = t.id AND e.ended_at IS NOT NULL
+		 WHERE p.workspace_id = $1 AND (p.client_id IS NOT NULL OR p.hour_budget IS NOT NULL)
+		   AND ($2 = '' OR p.client_id::text = $2)
+		 GROUP BY p.id, c.name
+		 ORDER BY c.name ASC NULLS LAST, p.name ASC`,
+		workspaceID, customerID, recentSince,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list project budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []models.ProjectBudget{}
+	for rows.Next() {
+		var budget models.ProjectBudget
+		var seconds, billableSeconds, recentSeconds int64
+		if err := rows.Scan(&budget.ProjectID, &budget.ProjectName, &budget.ProjectEncrypted, &budget.CustomerID, &budget.CustomerName, &budget.HourBudget, &seconds, &billableSeconds, &recentSeconds); err != nil {
+			return nil, fmt.Errorf("scan project budget: %w", err)
+		}
+		budget.LoggedHours = float64(seconds) / 3600
+		budget.BillableHours = float64(billableSeconds) / 3600
+		budget.RecentHours = float64(recentSeconds) / 3600
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// ---- Capacity ----
+
+// ListWorkspaceAllocations returns the allocations on the workspace's projects
+// that overlap the from and to days, only userID's when it is set. Allocations
+// of users who left the project are ignored.
+func (r *Repo) ListWorkspaceAllocations(ctx context.Context, workspaceID, userID string, from, to time.Time) ([]models.ProjectMemberAllocation, error) {
+	rows, err := r.pool.Query(ctx, `SELECT a.project_id, p.name, a.user_id, a.days_per_week, a.start_date, a.end_date
+		FROM project_member_allocations a
+		JOIN projects p ON p.id = a.project_id
+		JOIN project_members pm ON pm.project_id = a.project_id AND pm.user_id = a.user_id
+		WHERE p.workspace_id = $1 AND ($2::text = '' OR a.user_id::text = $2) AND a.days_per_week > 0
+		AND (a.start_date IS NULL OR a.start_date <= $4::date) AND (a.end_date IS NULL OR a.end_date >= $3::date)
+		ORDER BY p.name ASC, a.project_id ASC`, workspaceID, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list workspace allocations: %w", err)
+	}
+	defer rows.Close()
+	allocations := []models.ProjectMemberAllocation{}
+	for rows.Next() {
+		var allocation models.ProjectMemberAllocation
+		if err := rows.Scan(&allocation.ProjectID, &allocation.ProjectName, &allocation.UserID, &allocation.DaysPerWeek, &allocation.StartDate, &allocation.EndDate); err != nil {
+			return nil, fmt.Errorf("scan workspace allocation: %w", err)
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, rows.Err()
+}
+
+const memberAbsenceSelect = `SELECT a.id, a.workspace_id, a.user_id, u.name, a.start_date, a.end_date, a.note, a.created_by, a.created_at
+	FROM member_absences a
+	JOIN users u ON u.id = a.user_id`
+
+func scanMemberAbsence(row pgx.Row) (*models.MemberAbsence, error) {
+	absence := &models.MemberAbsence{}
+	err := row.Scan(&absence.ID, &absence.WorkspaceID, &absence.UserID, &absence.UserName, &absence.StartDate, &absence.EndDate, &absence.Note, &absence.CreatedBy, &absence.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return absence, nil
+}
+
+// ListMemberAbsences returns the workspace's absences that overlap the from
+// and to days, only userID's when it is set.
+func (r *Repo) ListMemberAbsences(ctx context.Context, workspaceID, userID string, from, to time.Time) ([]models.MemberAbsence, error) {
+	rows, err := r.pool.Query(ctx, memberAbsenceSelect+`
+		WHERE a.workspace_id = $1 AND ($2::text = '' OR a.user_id::text = $2)
+		AND a.start_date <= $4::date AND a.end_date >= $3::date
+		ORDER BY a.start_date ASC, u.name ASC`, workspaceID, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list member absences: %w", err)
+	}
+	defer rows.Close()
+	absences := []models.MemberAbsence{}
+	for rows.Next() {
+		absence, err := scanMemberAbsence(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan member absence: %w", err)
+		}
+		absences = append(absences, *absence)
+	}
+	return absences, rows.Err()
+}
+
+func (r *Repo) GetMemberAbsence(ctx context.Context, absenceID string) (*models.MemberAbsence, error) {
+	absence, err := scanMemberAbsence(r.pool.QueryRow(ctx, memberAbsenceSelect+` WHERE a.id = $1`, absenceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get member absence: %w", err)
+	}
+	return absence, nil
+}
+
+func (r *Repo) CreateMemberAbsence(ctx context.Context, workspaceID, createdBy string, req models.CreateMemberAbsenceRequest, startDate, endDate time.Time) (*models.MemberAbsence, error) {
+	var id string
+	err := r.pool.QueryRow(ctx, `INSERT INTO member_absences (workspace_id, user_id, start_date, end_date, note, created_by)
+		VALUES ($1, $2, $3::date, $4::date, $5, $6) RETURNING id`,
+		workspaceID, req.UserID, startDate, endDate, req.Note, createdBy).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("create member absence: %w", err)
+	}
+	return r.GetMemberAbsence(ctx, id)
+}
+
+func (r *Repo) DeleteMemberAbsence(ctx context.Context, absenceID string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM member_absences WHERE id = $1`, absenceID); err != nil {
+		return fmt.Errorf("delete member absence: %w", err)
+	}
+	return nil
+}
+
+// ---- Saved views ----
+
+const savedViewColumns = `id, owner_id, scope, project_id, workspace_id, name, view_mode, filter, sort, group_by, settings, created_at, updated_at`
+
+func scanSavedView(row pgx.Row) (*models.SavedView, error) {
+	view := &models.SavedView{}
+	err := row.Scan(&view.ID, &view.OwnerID, &view.Scope, &view.ProjectID, &view.WorkspaceID, &view.Name, &view.ViewMode, &view.Filter, &view.Sort, &view.GroupBy, &view.Settings, &view.CreatedAt, &view.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+func (r *Repo) listSavedViews(ctx context.Context, query string, args ...any) ([]models.SavedView, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list saved views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []models.SavedView{}
+	for rows.Next() {
+		view, err := scanSavedView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan saved view: %w", err)
+		}
+		views = append(views, *view)
+	}
+	return views, rows.Err()
+}
+
+// savedViewOrder lists personal views first, then project, then workspace views.
+const savedViewOrder = ` ORDER BY CASE scope WHEN 'user' THEN 0 WHEN 'project' THEN 1 ELSE 2 END, lower(name), id`
+
+// ListProjectSavedViews returns the views usable on a project's task list:
+// the user's personal views and the shared views of the project, plus the
+// shared views of its workspace.
+func (r *Repo) ListProjectSavedViews(ctx context.Context, projectID, userID string) ([]models.SavedView, error) {
+	return r.listSavedViews(ctx,
+		`SELECT `+savedViewColumns+`
+		 FROM saved_views
+		 WHERE (project_id = $1 AND (scope = 'project' OR owner_id = $2))
+		    OR (scope = 'workspace' AND workspace_id = (SELECT workspace_id FROM projects WHERE id = $1))`+savedViewOrder,
+		projectID, userID,
+	)
+}
+
+// ListWorkspaceSavedViews returns the user's personal views and the shared
+// views of a workspace.
+func (r *Repo) ListWorkspaceSavedViews(ctx context.Context, workspaceID, userID string) ([]models.SavedView, error) {
+	return r.listSavedViews(ctx,
+		`SELECT `+savedViewColumns+`
+		 FROM saved_views
+		 WHERE workspace_id = $1 AND (scope = 'workspace' OR owner_id = $2)`+savedViewOrder,
+		workspaceID, userID,
+	)
+}
+
+func (r *Repo) GetSavedView(ctx context.Context, id string) (*models.SavedView, error) {
+	view, err := scanSavedView(r.pool.QueryRow(ctx, `SELECT `+savedViewColumns+` FROM saved_views WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get saved view: %w", err)
+	}
+	return view, nil
+}
+
+// CreateSavedView stores a view on projectID or, when that is empty, on
+// workspaceID. Callers validate the request and the user's role.
+func (r *Repo) CreateSavedView(ctx context.Context, userID, projectID, workspaceID string, req models.CreateSavedViewRequest) (*models.SavedView, error) {
+	settings := req.Settings
+	if len(settings) == 0 {
+		settings = json.RawMessage(`{}`)
+	}
+	view, err := scanSavedView(r.pool.QueryRow(ctx,
+		`INSERT INTO saved_views (owner_id, scope, project_id, workspace_id, name, view_mode, filter, sort, group_by, settings)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, $5, $6, $7, $8, $9, $10)
+		 RETURNING `+savedViewColumns,
+		userID, req.Scope, projectID, workspaceID, req.Name, req.ViewMode, req.Filter, req.Sort, req.GroupBy, settings,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create saved view: %w", err)
+	}
+	return view, nil
+}
+
+func (r *Repo) UpdateSavedView(ctx context.Context, id string, req models.UpdateSavedViewRequest) (*models.SavedView, error) {
+	var settings *json.RawMessage
+	if len(req.Settings) > 0 {
+		settings = &req.Settings
+	}
+	view, err := scanSavedView(r.pool.QueryRow(ctx,
+		`UPDATE saved_views
+		 SET name = COALESCE($2, name),
+		     view_mode = COALESCE($3, view_mode),
+		     filter = COALESCE($4, filter),
+		     sort = COALESCE($5, sort),
+		     group_by = COALESCE($6, group_by),
+		     settings = COALESCE($7, settings)
+		 WHERE id = $1
+		 RETURNING `+savedViewColumns,
+		id, req.Name, req.ViewMode, req.Filter, req.Sort, req.GroupBy, settings,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("update saved view: %w", err)
+	}
+	return view, nil
+}
+
+func (r *Repo) DeleteSavedView(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM saved_views WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete saved view: %w", err)
+	}
+	return nil
+}
+
 // ---- Wiki Guides ----
 
-func (r *Repo) ListGuides(ctx context.Context, userID string, workspaceIDs ...string) ([]models.WikiGuide, error) {
-	query := `SELECT id, user_id, title, description, is_encrypted, created_at, updated_at, workspace_id, project_id, parent_id FROM wiki_guides WHERE user_id = $1`
+func (r *Repo) ListGuides(ctx context.Context, userID, workspaceID string, page models.PageRequest) ([]models.WikiGuide, string, error) {
 	args := []any{userID}
-	if len(workspaceIDs) > 0 && workspaceIDs[0] != "" {
-		query += ` AND workspace_id = $2`
-		args = append(args, workspaceIDs[0])
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
 	}
-	query += ` ORDER BY created_at DESC`
-	rows, err := r.pool.Query(ctx, query, args...)
+	query := `SELECT id, user_id, title, description, is_encrypted, created_at, updated_at, workspace_id, project_id, parent_id FROM wiki_guides WHERE user_id = $1`
+	if workspaceID != "" {
+		query += ` AND workspace_id = ` + arg(workspaceID)
+	}
+	condition, order, err := createdPageClause("wiki_guides", page, arg)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := r.pool.Query(ctx, query+condition+order, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list guides: %w", err)
+		return nil, "", fmt.Errorf("list guides: %w", err)
 	}
 	defer rows.Close()
 	var out []models.WikiGuide
 	for rows.Next() {
 		var g models.WikiGuide
 		if err := rows.Scan(&g.ID, &g.UserID, &g.Title, &g.Description, &g.IsEncrypted, &g.CreatedAt, &g.UpdatedAt, &g.WorkspaceID, &g.ProjectID, &g.ParentID); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		out = append(out, g)
 	}
 	if out == nil {
 		out = []models.WikiGuide{}
 	}
-	return out, nil
+	out, next := createdPage(out, page, func(g *models.WikiGuide) (time.Time, string) { return g.CreatedAt, g.ID })
+	return out, next, nil
 }
 
 func (r *Repo) GetGuide(ctx context.Context, id, userID string) (*models.WikiGuide, error) {
@@ -3032,19 +5599,32 @@ func scanActivityRows(rows pgx.Rows) ([]models.ActivityLog, error) {
 	return out, nil
 }
 
-func (r *Repo) ListActivity(ctx context.Context, userID string) ([]models.ActivityLog, error) {
+func (r *Repo) ListActivity(ctx context.Context, userID string, page models.PageRequest) ([]models.ActivityLog, string, error) {
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	condition, order, err := createdPageClause("a", page, arg)
+	if err != nil {
+		return nil, "", err
+	}
 	rows, err := r.pool.Query(ctx,
 		`SELECT a.id, a.user_id, u.name, a.type, a.entity_type, a.entity_name, a.project_id, a.task_id, a.metadata, a.created_at
 		 FROM activity a
 		 JOIN users u ON u.id = a.user_id
 		 WHERE a.project_id IS NOT NULL
-		   AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = a.project_id AND pm.user_id = $1)
-		 ORDER BY a.created_at DESC LIMIT 50`, userID)
+		   AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = a.project_id AND pm.user_id = $1)`+condition+order, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list activity: %w", err)
+		return nil, "", fmt.Errorf("list activity: %w", err)
 	}
 	defer rows.Close()
-	return scanActivityRows(rows)
+	activity, err := scanActivityRows(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	activity, next := createdPage(activity, page, func(a *models.ActivityLog) (time.Time, string) { return a.CreatedAt, a.ID })
+	return activity, next, nil
 }
 
 func (r *Repo) IsProjectMember(ctx context.Context, projectID, userID string) (bool, error) {
@@ -3059,7 +5639,7 @@ func scanNotifications(rows pgx.Rows) ([]models.Notification, error) {
 	out := []models.Notification{}
 	for rows.Next() {
 		var n models.Notification
-		if err := rows.Scan(&n.ID, &n.RecipientUserID, &n.ActorUserID, &n.ActorName, &n.Type, &n.ProjectID, &n.ProjectName, &n.TaskID, &n.TaskKey, &n.TaskTitle, &n.CommentID, &n.DeadlineAt, &n.ReadAt, &n.CreatedAt); err != nil {
+		if err := rows.Scan(&n.ID, &n.RecipientUserID, &n.ActorUserID, &n.ActorName, &n.Type, &n.ProjectID, &n.ProjectName, &n.TaskID, &n.TaskKey, &n.TaskTitle, &n.CommentID, &n.DeadlineAt, &n.Metadata, &n.ReadAt, &n.CreatedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, n)
@@ -3068,21 +5648,51 @@ func scanNotifications(rows pgx.Rows) ([]models.Notification, error) {
 }
 
 const notificationSelect = `SELECT n.id, n.recipient_user_id, n.actor_user_id, actor.name, n.type,
- n.project_id, p.name, n.task_id, t.task_key, t.title, n.comment_id, n.deadline_at, n.read_at, n.created_at
+ n.project_id, p.name, COALESCE(n.task_id::text, ''), COALESCE(t.task_key, ''), COALESCE(t.title, ''), n.comment_id, n.deadline_at, n.metadata, n.read_at, n.created_at
  FROM notifications n
  JOIN users actor ON actor.id = n.actor_user_id
  JOIN projects p ON p.id = n.project_id
- JOIN tasks t ON t.id = n.task_id`
-
-func (r *Repo) CreateNotification(ctx context.Context, recipientUserID, actorUserID, notificationType, projectID, taskID string, commentID *string) (*models.Notification, error) {
-	if recipientUserID == actorUserID {
+ LEFT JOIN tasks t ON t.id = n.task_id`
+
+// notificationVisible holds for notifications whose recipient can still see
+// them: members of the project and, for over-allocation alerts, owners and
+// admins of the project's workspace.
+const notificationVisible = `(EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = n.project_id AND pm.user_id = n.recipient_user_id)
+	OR n.type = 'over_allocation' AND EXISTS (SELECT 1 FROM projects vp
+		JOIN workspace_members wm ON wm.workspace_id = vp.workspace_id
+		WHERE vp.id = n.project_id AND wm.user_id = n.recipient_user_id AND wm.role IN ('owner', 'admin')))`
+
+// automaticNotificationTypes are raised by the backend rather than by their
+// actor, who is only credited, so they reach the actor as well.
+var automaticNotificationTypes = map[string]bool{"budget_alert": true}
+
+// CreateNotification notifies the recipient about a task of the project, or
+// about the project as a whole when taskID is empty. metadata is stored as
+// JSON unless it is nil. Actors are not notified of their own changes.
+func (r *Repo) CreateNotification(ctx context.Context, recipientUserID, actorUserID, notificationType, projectID, taskID string, commentID *string, metadata any) (*models.Notification, error) {
+	if recipientUserID == actorUserID && !automaticNotificationTypes[notificationType] {
 		return nil, nil
 	}
+	var encoded []byte
+	if metadata != nil {
+		var err error
+		if encoded, err = json.Marshal(metadata); err != nil {
+			return nil, fmt.Errorf("encode notification metadata: %w", err)
+		}
+	}
 	var id string
-	err := r.pool.QueryRow(ctx, `INSERT INTO notifications (recipient_user_id, actor_user_id, type, project_id, task_id, comment_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`, recipientUserID, actorUserID, notificationType, projectID, taskID, commentID).Scan(&id)
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO notifications (recipient_user_id, actor_user_id, type, project_id, task_id, comment_id, metadata)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7) RETURNING id`,
+		recipientUserID, actorUserID, notificationType, projectID, taskID, commentID, encoded,
+	).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("create notification: %w", err)
 	}
+	return r.loadNotification(ctx, id)
+}
+
+func (r *Repo) loadNotification(ctx context.Context, id string) (*models.Notification, error) {
 	rows, err := r.pool.Query(ctx, notificationSelect+` WHERE n.id = $1`, id)
 	if err != nil {
 		return nil, fmt.Errorf("load notification: %w", err)
@@ -3095,21 +5705,33 @@ func (r *Repo) CreateNotification(ctx context.Context, recipientUserID, actorUse
 	return &notifications[0], nil
 }
 
-func (r *Repo) ListNotifications(ctx context.Context, userID string) ([]models.Notification, error) {
-	rows, err := r.pool.Query(ctx, notificationSelect+` WHERE n.recipient_user_id = $1
-		AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = n.project_id AND pm.user_id = $1)
-		ORDER BY n.created_at DESC LIMIT 50`, userID)
+func (r *Repo) ListNotifications(ctx context.Context, userID string, page models.PageRequest) ([]models.Notification, string, error) {
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	condition, order, err := createdPageClause("n", page, arg)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := r.pool.Query(ctx, notificationSelect+` WHERE n.recipient_user_id = $1 AND `+notificationVisible+condition+order, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list notifications: %w", err)
+		return nil, "", fmt.Errorf("list notifications: %w", err)
 	}
 	defer rows.Close()
-	return scanNotifications(rows)
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	notifications, next := createdPage(notifications, page, func(n *models.Notification) (time.Time, string) { return n.CreatedAt, n.ID })
+	return notifications, next, nil
 }
 
 func (r *Repo) UnreadNotificationCount(ctx context.Context, userID string) (int, error) {
 	var count int
 	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM notifications n WHERE n.recipient_user_id = $1 AND n.read_at IS NULL
-		AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = n.project_id AND pm.user_id = $1)`, userID).Scan(&count); err != nil {
+		AND `+notificationVisible, userID).Scan(&count); err != nil {
 		return 0, fmt.Errorf("count notifications: %w", err)
 	}
 	return count, nil
@@ -3117,31 +5739,21 @@ func (r *Repo) UnreadNotificationCount(ctx context.Context, userID string) (int,
 
 func (r *Repo) MarkNotificationRead(ctx context.Context, notificationID, userID string) (*models.Notification, error) {
 	var id string
-	err := r.pool.QueryRow(ctx, `UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND recipient_user_id = $2
-		AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = notifications.project_id AND pm.user_id = $2)
-		RETURNING id`, notificationID, userID).Scan(&id)
+	err := r.pool.QueryRow(ctx, `UPDATE notifications n SET read_at = COALESCE(n.read_at, NOW()) WHERE n.id = $1 AND n.recipient_user_id = $2
+		AND `+notificationVisible+`
+		RETURNING n.id`, notificationID, userID).Scan(&id)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("mark notification read: %w", err)
 	}
-	rows, err := r.pool.Query(ctx, notificationSelect+` WHERE n.id = $1`, id)
-	if err != nil {
-		return nil, fmt.Errorf("load notification: %w", err)
-	}
-	defer rows.Close()
-	notifications, err := scanNotifications(rows)
-	if err != nil || len(notifications) != 1 {
-		return nil, err
-	}
-	return &notifications[0], nil
+	return r.loadNotification(ctx, id)
 }
 
 func (r *Repo) DeleteNotification(ctx context.Context, notificationID, userID string) (bool, error) {
 	result, err := r.pool.Exec(ctx, `DELETE FROM notifications n
-		WHERE n.id = $1 AND n.recipient_user_id = $2
-		AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = n.project_id AND pm.user_id = $2)`, notificationID, userID)
+		WHERE n.id = $1 AND n.recipient_user_id = $2 AND `+notificationVisible, notificationID, userID)
 	if err != nil {
 		return false, fmt.Errorf("delete notification: %w", err)
 	}
@@ -3381,6 +5993,203 @@ func (r *Repo) CreateDeadlineNotification(ctx context.Context, recipientUserID,
 	return &notifications[0], nil
 }
 
+// ListBudgetUsage returns the projects whose finished time entries reach
+// minPercent of their hour budget.
+func (r *Repo) ListBudgetUsage(ctx context.Context, minPercent int) ([]models.BudgetUsage, error) {
+	rows, err := r.pool.Query(ctx, `SELECT p.id, p.user_id, p.hour_budget, SUM(e.duration_seconds)
+		FROM projects p
+		JOIN tasks t ON t.project_id = p.id
+		JOIN time_entries e ON e.task_id = t.id AND e.ended_at IS NOT NULL
+		WHERE p.hour_budget > 0
+		GROUP BY p.id
+		HAVING SUM(e.duration_seconds) >= p.hour_budget * 36 * $1`, minPercent)
+	if err != nil {
+		return nil, fmt.Errorf("list budget usage: %w", err)
+	}
+	defer rows.Close()
+	usage := []models.BudgetUsage{}
+	for rows.Next() {
+		var project models.BudgetUsage
+		var seconds int64
+		if err := rows.Scan(&project.ProjectID, &project.OwnerID, &project.HourBudget, &seconds); err != nil {
+			return nil, fmt.Errorf("scan budget usage: %w", err)
+		}
+		project.LoggedHours = float64(seconds) / 3600
+		usage = append(usage, project)
+	}
+	return usage, rows.Err()
+}
+
+// ClaimBudgetAlerts records that the project's budget of hourBudget hours
+// reached the thresholds and returns those that were not recorded before.
+// Claiming first keeps concurrent replicas from alerting twice. hourBudget is
+// read from the REAL column, so it is converted back through real before it
+// becomes part of the key.
+func (r *Repo) ClaimBudgetAlerts(ctx context.Context, projectID string, hourBudget float64, thresholds []int) ([]int, error) {
+	rows, err := r.pool.Query(ctx, `INSERT INTO project_budget_alerts (project_id, threshold, hour_budget)
+		SELECT $1, threshold, $3::real::numeric FROM unnest($2::integer[]) AS threshold
+		ON CONFLICT DO NOTHING
+		RETURNING threshold`, projectID, thresholds, hourBudget)
+	if err != nil {
+		return nil, fmt.Errorf("claim budget alerts: %w", err)
+	}
+	defer rows.Close()
+	claimed := []int{}
+	for rows.Next() {
+		var threshold int
+		if err := rows.Scan(&threshold); err != nil {
+			return nil, fmt.Errorf("scan budget alert: %w", err)
+		}
+		claimed = append(claimed, threshold)
+	}
+	return claimed, rows.Err()
+}
+
+// ListProjectAdminIDs returns the project's owners and admins.
+func (r *Repo) ListProjectAdminIDs(ctx context.Context, projectID string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `SELECT user_id FROM project_members WHERE project_id = $1 AND role IN ('owner', 'admin')`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list project admins: %w", err)
+	}
+	defer rows.Close()
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("scan project admin: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// emailPreferenceMode reads the user's email delivery mode from preferences;
+// users without a stored preference receive instant emails.
+const emailPreferenceMode = `COALESCE(u.preferences->'emailNotifications'->>'mode', 'instant')`
+
+func scanNotificationIDs(rows pgx.Rows) ([]string, error) {
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimInstantEmailNotifications marks up to limit pending notifications of
+// users without a digest mode as emailed and returns them for sending. Claiming
+// first keeps concurrent replicas from sending duplicates; a failed send is
+// logged rather than retried.
+func (r *Repo) ClaimInstantEmailNotifications(ctx context.Context, limit int) ([]models.Notification, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT n.id FROM notifications n
+			JOIN users u ON u.id = n.recipient_user_id
+			WHERE n.emailed_at IS NULL AND `+emailPreferenceMode+` NOT IN ('hourly', 'daily')
+			ORDER BY n.created_at ASC
+			LIMIT $1
+			FOR UPDATE OF n SKIP LOCKED
+		)
+		UPDATE notifications n SET emailed_at = NOW() FROM due WHERE n.id = due.id
+		RETURNING n.id`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim email notifications: %w", err)
+	}
+	ids, err := scanNotificationIDs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scan email notification: %w", err)
+	}
+	return r.listEmailNotifications(ctx, ids)
+}
+
+// ListEmailDigestRecipients returns users in hourly or daily digest mode who
+// have notifications that were not emailed yet.
+func (r *Repo) ListEmailDigestRecipients(ctx context.Context) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `SELECT DISTINCT n.recipient_user_id FROM notifications n
+		JOIN users u ON u.id = n.recipient_user_id
+		WHERE n.emailed_at IS NULL AND `+emailPreferenceMode+` IN ('hourly', 'daily')`)
+	if err != nil {
+		return nil, fmt.Errorf("list email digest recipients: %w", err)
+	}
+	userIDs, err := scanNotificationIDs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scan email digest recipient: %w", err)
+	}
+	return userIDs, nil
+}
+
+// ClaimEmailDigest returns the unread notifications for the user's next digest
+// when the previous digest was sent before dueBefore, and nil when it is not due
+// yet. Notifications read in the meantime are marked as emailed without being
+// included.
+func (r *Repo) ClaimEmailDigest(ctx context.Context, userID string, dueBefore time.Time) ([]models.Notification, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin email digest: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET last_email_digest_at = NOW()
+		WHERE id = $1 AND (last_email_digest_at IS NULL OR last_email_digest_at <= $2)`, userID, dueBefore)
+	if err != nil {
+		return nil, fmt.Errorf("claim email digest: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, nil
+	}
+	rows, err := tx.Query(ctx, `UPDATE notifications SET emailed_at = NOW()
+		WHERE recipient_user_id = $1 AND emailed_at IS NULL RETURNING id, read_at IS NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("claim digest notifications: %w", err)
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		var unread bool
+		if err := rows.Scan(&id, &unread); err != nil {
+			return nil, fmt.Errorf("scan digest notification: %w", err)
+		}
+		if unread {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim digest notifications: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit email digest: %w", err)
+	}
+	return r.listEmailNotifications(ctx, ids)
+}
+
+// listEmailNotifications loads notifications their recipient can still see.
+// Project names and task titles of encrypted projects are client-side
+// envelopes and are returned empty so they never reach a mailbox.
+func (r *Repo) listEmailNotifications(ctx context.Context, ids []string) ([]models.Notification, error) {
+	if len(ids) == 0 {
+		return []models.Notification{}, nil
+	}
+	rows, err := r.pool.Query(ctx, `SELECT n.id, n.recipient_user_id, n.actor_user_id, actor.name, n.type,
+		 n.project_id, CASE WHEN p.is_encrypted THEN '' ELSE p.name END, COALESCE(n.task_id::text, ''), COALESCE(t.task_key, ''),
+		 CASE WHEN p.is_encrypted THEN '' ELSE COALESCE(t.title, '') END, n.comment_id, n.deadline_at, n.metadata, n.read_at, n.created_at
+		 FROM notifications n
+		 JOIN users actor ON actor.id = n.actor_user_id
+		 JOIN projects p ON p.id = n.project_id
+		 LEFT JOIN tasks t ON t.id = n.task_id
+		 WHERE n.id = ANY($1::uuid[]) AND `+notificationVisible+`
+		ORDER BY n.created_at ASC`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load email notifications: %w", err)
+	}
+	defer rows.Close()
+	return scanNotifications(rows)
+}
+
 func (r *Repo) ListProjectActivity(ctx context.Context, projectID string, limit int) ([]models.ActivityLog, error) {
 	rows, err := r.pool.Query(ctx,
 		`SELECT a.id, a.user_id, u.name, a.type, a.entity_type, a.entity_name, a.project_id, a.task_id, a.metadata, a.created_at
@@ -3438,31 +6247,38 @@ func (r *Repo) LogActivity(ctx context.Context, userID, actType, entityType, ent
 
 // ---- Snippets ----
 
-func (r *Repo) ListSnippets(ctx context.Context, userID string, workspaceIDs ...string) ([]models.Snippet, error) {
-	query := `SELECT id, user_id, title, content, blocks, language, tags, description, is_encrypted, created_at, updated_at, workspace_id, project_id, collection FROM snippets WHERE user_id = $1`
+func (r *Repo) ListSnippets(ctx context.Context, userID, workspaceID string, page models.PageRequest) ([]models.Snippet, string, error) {
 	args := []any{userID}
-	if len(workspaceIDs) > 0 && workspaceIDs[0] != "" {
-		query += ` AND workspace_id = $2`
-		args = append(args, workspaceIDs[0])
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
 	}
-	query += ` ORDER BY created_at DESC`
-	rows, err := r.pool.Query(ctx, query, args...)
+	query := `SELECT id, user_id, title, content, blocks, language, tags, description, is_encrypted, created_at, updated_at, workspace_id, project_id, collection FROM snippets WHERE user_id = $1`
+	if workspaceID != "" {
+		query += ` AND workspace_id = ` + arg(workspaceID)
+	}
+	condition, order, err := createdPageClause("snippets", page, arg)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := r.pool.Query(ctx, query+condition+order, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list snippets: %w", err)
+		return nil, "", fmt.Errorf("list snippets: %w", err)
 	}
 	defer rows.Close()
 	var out []models.Snippet
 	for rows.Next() {
 		var s models.Snippet
 		if err := rows.Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Blocks, &s.Language, &s.Tags, &s.Description, &s.IsEncrypted, &s.CreatedAt, &s.UpdatedAt, &s.WorkspaceID, &s.ProjectID, &s.Collection); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		out = append(out, s)
 	}
 	if out == nil {
 		out = []models.Snippet{}
 	}
-	return out, nil
+	out, next := createdPage(out, page, func(s *models.Snippet) (time.Time, string) { return s.CreatedAt, s.ID })
+	return out, next, nil
 }
 
 func (r *Repo) CreateSnippet(ctx context.Context, userID string, req models.CreateSnippetRequest) (*models.Snippet, error) {
@@ -3554,6 +6370,98 @@ func (r *Repo) DeleteSnippet(ctx context.Context, id, userID string) error {
 	return err
 }
 
+// ---- Search ----
+
+// searchHighlightOptions mark matches with \x01 and \x02 rather than HTML,
+// so the handler can escape the text before adding <mark> tags.
+const searchHighlightOptions = "StartSel=\"\x01\", StopSel=\"\x02\", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// Search runs a full-text query over everything the user can read: tasks and
+// comments of their projects, and their own guides, installations, and
+// snippets. Encrypted rows have no search vector and are excluded again here,
+// together with rows whose task, guide, or project is encrypted. Highlights
+// are computed only for the returned page.
+func (r *Repo) Search(ctx context.Context, userID string, query models.SearchQuery) ([]models.SearchResult, error) {
+	rows, err := r.pool.Query(ctx,
+		`WITH q AS (SELECT websearch_to_tsquery('simple', $2) AS query),
+		 hits AS (
+		 	SELECT 'task'::text AS type, t.id, t.title::text AS title, concat_ws(' ', t.title, t.description) AS body,
+		 	       ts_rank(t.search_vector, q.query) AS rank, p.workspace_id, t.project_id, NULL::uuid AS task_id, NULL::uuid AS guide_id, t.updated_at
+		 	FROM tasks t
+		 	JOIN projects p ON p.id = t.project_id
+		 	CROSS JOIN q
+		 	WHERE 'task' = ANY($5::text[]) AND t.search_vector @@ q.query
+		 	  AND NOT t.is_encrypted AND NOT p.is_encrypted
+		 	  AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = t.project_id AND pm.user_id = $1)
+		 	  AND ($3 = '' OR p.workspace_id::text = $3) AND ($4 = '' OR t.project_id::text = $4)
+		 	UNION ALL
+		 	SELECT 'comment', tc.id, t.title, tc.body,
+		 	       ts_rank(tc.search_vector, q.query), p.workspace_id, t.project_id, t.id, NULL, tc.updated_at
+		 	FROM task_comments tc
+		 	JOIN tasks t ON t.id = tc.task_id
+		 	JOIN projects p ON p.id = t.project_id
+		 	CROSS JOIN q
+		 	WHERE 'comment' = ANY($5::text[]) AND tc.search_vector @@ q.query
+		 	  AND NOT tc.is_encrypted AND NOT t.is_encrypted AND NOT p.is_encrypted
+		 	  AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = t.project_id AND pm.user_id = $1)
+		 	  AND ($3 = '' OR p.workspace_id::text = $3) AND ($4 = '' OR t.project_id::text = $4)
+		 	UNION ALL
+		 	SELECT 'wiki', g.id, g.title, concat_ws(' ', g.title, g.description),
+		 	       ts_rank(g.search_vector, q.query), g.workspace_id, g.project_id, NULL, NULL, g.updated_at
+		 	FROM wiki_guides g
+		 	CROSS JOIN q
+		 	WHERE 'wiki' = ANY($5::text[]) AND g.search_vector @@ q.query
+		 	  AND NOT g.is_encrypted AND g.user_id = $1
+		 	  AND ($3 = '' OR g.workspace_id::text = $3) AND ($4 = '' OR g.project_id::text = $4)
+		 	UNION ALL
+		 	SELECT 'installation', i.id, i.target, concat_ws(' ', i.target, i.notes),
+		 	       ts_rank(i.search_vector, q.query), g.workspace_id, g.project_id, NULL, g.id, i.updated_at
+		 	FROM installations i
+		 	JOIN wiki_guides g ON g.id = i.guide_id
+		 	CROSS JOIN q
+		 	WHERE 'installation' = ANY($5::text[]) AND i.search_vector @@ q.query
+		 	  AND NOT i.is_encrypted AND NOT g.is_encrypted AND i.user_id = $1
+		 	  AND ($3 = '' OR g.workspace_id::text = $3) AND ($4 = '' OR g.project_id::text = $4)
+		 	UNION ALL
+		 	SELECT 'snippet', s.id, s.title, concat_ws(' ', s.title, s.description, s.content),
+		 	       ts_rank(s.search_vector, q.query), s.workspace_id, s.project_id, NULL, NULL, s.updated_at
+		 	FROM snippets s
+		 	CROSS JOIN q
+		 	WHERE 'snippet' = ANY($5::text[]) AND s.search_vector @@ q.query
+		 	  AND NOT s.is_encrypted AND s.user_id = $1
+		 	  AND ($3 = '' OR s.workspace_id::text = $3) AND ($4 = '' OR s.project_id::text = $4)
+		 	ORDER BY rank DESC, updated_at DESC
+		 	LIMIT $6
+		 )
+		 SELECT hits.type, hits.id, hits.title, ts_headline('simple', left(hits.body, 100000), q.query, $7),
+		        hits.rank, hits.workspace_id, hits.project_id, hits.task_id, hits.guide_id, hits.updated_at
+		 FROM hits
+		 CROSS JOIN q
+		 ORDER BY hits.rank DESC, hits.updated_at DESC`,
+		userID, query.Text, query.WorkspaceID, query.ProjectID, query.Types, query.Limit, searchHighlightOptions,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.SearchResult
+	for rows.Next() {
+		var result models.SearchResult
+		if err := rows.Scan(&result.Type, &result.ID, &result.Title, &result.Highlight, &result.Rank, &result.WorkspaceID, &result.ProjectID, &result.TaskID, &result.GuideID, &result.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan search result: %w", err)
+		}
+		out = append(out, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	if out == nil {
+		out = []models.SearchResult{}
+	}
+	return out, nil
+}
+
 // ---- User Keys (Vault) ----
 
 func (r *Repo) GetUserKeys(ctx context.Context, userID string) (*models.UserKeys, error) {
@@ -3692,3 +6600,244 @@ func (r *Repo) CreateVersion(ctx context.Context, userID string, req models.Crea
 	}
 	return v, nil
 }
+
+// ---- Realtime fan-out ----
+
+// PublishRealtime sends payload to every backend replica listening on channel.
+func (r *Repo) PublishRealtime(ctx context.Context, channel, payload string) error {
+	if _, err := r.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, payload); err != nil {
+		return fmt.Errorf("publish realtime: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) CreateRealtimePayload(ctx context.Context, payload string) (int64, error) {
+	var id int64
+	if err := r.pool.QueryRow(ctx, `INSERT INTO realtime_payloads (payload) VALUES ($1) RETURNING id`, payload).Scan(&id); err != nil {
+		return 0, fmt.Errorf("create realtime payload: %w", err)
+	}
+	return id, nil
+}
+
+func (r *Repo) GetRealtimePayload(ctx context.Context, id int64) (string, error) {
+	var payload string
+	err := r.pool.QueryRow(ctx, `SELECT payload FROM realtime_payloads WHERE id = $1`, id).Scan(&payload)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("get realtime payload: %w", err)
+	}
+	return payload, nil
+}
+
+func (r *Repo) DeleteExpiredRealtimePayloads(ctx context.Context, before time.Time) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM realtime_payloads WHERE created_at < $1`, before); err != nil {
+		return fmt.Errorf("delete expired realtime payloads: %w", err)
+	}
+	return nil
+}
+
+// ListenRealtime takes a connection out of the pool, subscribes it to channel and
+// hands every notification payload to handle. It returns when ctx is cancelled
+// or the connection fails; the connection is closed rather than returned to the
+// pool so no LISTEN state leaks into other queries.
+func (r *Repo) ListenRealtime(ctx context.Context, channel string, handle func(payload string)) error {
+	pooled, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire realtime listener: %w", err)
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen realtime: %w", err)
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for realtime notification: %w", err)
+		}
+		handle(notification.Payload)
+	}
+}
+
+// ---- Webhooks ----
+
+const webhookColumns = `id, workspace_id, created_by_id, url, collections, event_types, is_active, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, created_at, updated_at`
+
+func scanWebhook(row pgx.Row, webhook *models.Webhook) error {
+	return row.Scan(&webhook.ID, &webhook.WorkspaceID, &webhook.CreatedByID, &webhook.URL, &webhook.Collections, &webhook.EventTypes, &webhook.IsActive, &webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+func scanWebhookDelivery(row pgx.Row, delivery *models.WebhookDelivery) error {
+	return row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &delivery.DeliveredAt, &delivery.CreatedAt, &delivery.UpdatedAt)
+}
+
+func (r *Repo) ListWebhooks(ctx context.Context, workspaceID string) ([]models.Webhook, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE workspace_id = $1 ORDER BY created_at ASC`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *Repo) CreateWebhook(ctx context.Context, workspaceID, userID string, req models.CreateWebhookRequest) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := scanWebhook(r.pool.QueryRow(ctx,
+		`INSERT INTO webhooks (workspace_id, created_by_id, url, secret, collections, event_types)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+webhookColumns,
+		workspaceID, userID, req.URL, req.Secret, req.Collections, req.EventTypes), webhook)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (r *Repo) UpdateWebhook(ctx context.Context, workspaceID, webhookID string, req models.UpdateWebhookRequest) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := scanWebhook(r.pool.QueryRow(ctx,
+		`UPDATE webhooks SET url = COALESCE($3, url), collections = COALESCE($4, collections), event_types = COALESCE($5, event_types), is_active = COALESCE($6, is_active)
+		 WHERE id = $1 AND workspace_id = $2 RETURNING `+webhookColumns,
+		webhookID, workspaceID, req.URL, req.Collections, req.EventTypes, req.IsActive), webhook)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (r *Repo) DeleteWebhook(ctx context.Context, workspaceID, webhookID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND workspace_id = $2`, webhookID, workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("delete webhook: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetWebhookProjectScope returns the workspace and encryption flag used to route
+// and redact webhook events for a project. An empty workspace means the project
+// no longer exists.
+func (r *Repo) GetWebhookProjectScope(ctx context.Context, projectID string) (string, bool, error) {
+	var workspaceID string
+	var encrypted bool
+	err := r.pool.QueryRow(ctx, `SELECT workspace_id, is_encrypted FROM projects WHERE id = $1`, projectID).Scan(&workspaceID, &encrypted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("get webhook project scope: %w", err)
+	}
+	return workspaceID, encrypted, nil
+}
+
+// EnqueueWebhookDeliveries queues payload for every active webhook in the
+// workspace whose collection and type filters match the event.
+func (r *Repo) EnqueueWebhookDeliveries(ctx context.Context, workspaceID, collection, eventType, event string, payload []byte) (int64, error) {
+	tag, err := r.pool.Exec(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		 SELECT id, $4, $5 FROM webhooks
+		 WHERE workspace_id = $1 AND is_active
+		   AND (cardinality(collections) = 0 OR $2 = ANY(collections))
+		   AND (cardinality(event_types) = 0 OR $3 = ANY(event_types))`,
+		workspaceID, collection, eventType, event, payload)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhookDeliveries leases due deliveries for two minutes so concurrent
+// replicas never post the same delivery twice.
+func (r *Repo) ClaimWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDispatch, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id AND w.is_active
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+			ORDER BY d.next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + INTERVAL '2 minutes'
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts, w.url, w.secret`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	dispatches := make([]models.WebhookDispatch, 0)
+	for rows.Next() {
+		var dispatch models.WebhookDispatch
+		if err := rows.Scan(&dispatch.DeliveryID, &dispatch.WebhookID, &dispatch.Event, &dispatch.Payload, &dispatch.Attempts, &dispatch.URL, &dispatch.Secret); err != nil {
+			return nil, fmt.Errorf("scan webhook dispatch: %w", err)
+		}
+		dispatches = append(dispatches, dispatch)
+	}
+	return dispatches, rows.Err()
+}
+
+func (r *Repo) RecordWebhookAttempt(ctx context.Context, deliveryID, status string, attempts int, statusCode *int, lastError *string, nextAttemptAt time.Time) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = $3, last_status_code = $4, last_error = $5, next_attempt_at = $6,
+		 delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() ELSE delivered_at END
+		 WHERE id = $1`,
+		deliveryID, status, attempts, statusCode, lastError, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) ListWebhookDeliveries(ctx context.Context, workspaceID, webhookID string, limit, offset int) ([]models.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at, d.updated_at
+		 FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		 WHERE d.webhook_id = $1 AND w.workspace_id = $2
+		 ORDER BY d.created_at DESC LIMIT $3 OFFSET $4`, webhookID, workspaceID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// RedeliverWebhookDelivery queues a fresh copy of an earlier delivery, keeping
+// the original attempt history intact.
+func (r *Repo) RedeliverWebhookDelivery(ctx context.Context, workspaceID, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := scanWebhookDelivery(r.pool.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		 SELECT d.webhook_id, d.event, d.payload FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		 WHERE d.id = $1 AND d.webhook_id = $2 AND w.workspace_id = $3
+		 RETURNING `+webhookDeliveryColumns,
+		deliveryID, webhookID, workspaceID), delivery)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redeliver webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justlabv1/justspace/backend/internal/taskquery"
+)
+
+func TestTaskFilterConditionFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		want  string
+	}{
+		// A cleared recurrence may be stored as an empty string, which must
+		// not count as recurring.
+		{name: "recurring skips cleared tasks", flags: []string{"recurring"}, want: `(COALESCE(tasks.recurrence, '') <> '')`},
+		{name: "several flags", flags: []string{"open", "subtask"}, want: `(NOT tasks.completed OR tasks.parent_id IS NOT NULL)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []any{}
+			arg := func(value any) string {
+				args = append(args, value)
+				return fmt.Sprintf("$%d", len(args))
+			}
+			got := taskFilterCondition(taskquery.Term{Field: taskquery.FieldIs, Op: ":", Values: tt.flags}, "u1", arg)
+			if got != tt.want || len(args) != 0 {
+				t.Fatalf("taskFilterCondition() = %s with %v, want %s", got, args, tt.want)
+			}
+		})
+	}
+}
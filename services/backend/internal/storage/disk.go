@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DiskStore keeps blobs below a local directory. Every backend replica needs
+// the same directory, which in Kubernetes means a ReadWriteMany volume.
+type DiskStore struct {
+	root string
+}
+
+func NewDiskStore(root string) (*DiskStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create storage root: %w", err)
+	}
+	return &DiskStore{root: root}, nil
+}
+
+func (s *DiskStore) Save(ctx context.Context, storagePath string, src io.Reader) error {
+	fullPath := filepath.Join(s.root, storagePath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return fmt.Errorf("create storage directory: %w", err)
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return fmt.Errorf("create storage file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, &contextReader{ctx: ctx, r: src}); err != nil {
+		return fmt.Errorf("write storage file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) Open(ctx context.Context, storagePath string) (io.ReadCloser, error) {
+	fullPath := filepath.Join(s.root, storagePath)
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("open storage file: %w", err)
+	}
+	return file, nil
+}
+
+func (s *DiskStore) OpenRange(ctx context.Context, storagePath string, offset, length int64) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(s.root, storagePath))
+	if err != nil {
+		return nil, fmt.Errorf("open storage file: %w", err)
+	}
+	return readCloser{Reader: io.NewSectionReader(file, offset, length), Closer: file}, nil
+}
+
+func (s *DiskStore) Delete(ctx context.Context, storagePath string) error {
+	fullPath := filepath.Join(s.root, storagePath)
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete storage file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) List(ctx context.Context, fn func(ObjectInfo) error) error {
+	return filepath.WalkDir(s.root, func(fullPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("list storage files: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("stat storage file: %w", err)
+		}
+		rel, err := filepath.Rel(s.root, fullPath)
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"time"
+)
+
+// DefaultOrphanGrace keeps reconciliation away from uploads that are saved but
+// whose database row has not been committed yet.
+const DefaultOrphanGrace = 24 * time.Hour
+
+// Blob is a store key referenced by the database.
+type Blob struct {
+	Key string
+	// Size is the expected size in bytes, or -1 when the database does not
+	// record one.
+	Size int64
+	// Checksum is the recorded hex SHA-256, empty when none is recorded yet.
+	Checksum string
+	// File marks project file blobs, which can record a checksum. Other
+	// assets such as logos have nowhere to store one.
+	File bool
+}
+
+// ChecksumRecorder stores the checksum computed for a blob that had none.
+type ChecksumRecorder func(ctx context.Context, blob Blob, checksum string) error
+
+type ReconcileOptions struct {
+	// VerifyChecksums reads every referenced blob. Without it only presence
+	// and size are checked, which needs nothing but the object listing.
+	VerifyChecksums bool
+	DeleteOrphans   bool
+	// OrphanGrace skips unreferenced blobs modified more recently than this.
+	OrphanGrace    time.Duration
+	RecordChecksum ChecksumRecorder
+}
+
+type ReconcileReport struct {
+	Referenced         int
+	Missing            int
+	SizeMismatches     int
+	ChecksumMismatches int
+	ChecksumsAdded     int
+	ReadErrors         int
+	Orphans            int
+	OrphanBytes        int64
+	OrphansDeleted     int
+}
+
+// Healthy reports whether every referenced blob was found intact. Orphans do
+// not count: they waste space but break nothing.
+func (r ReconcileReport) Healthy() bool {
+	return r.Missing == 0 && r.SizeMismatches == 0 && r.ChecksumMismatches == 0 && r.ReadErrors == 0
+}
+
+// Reconcile compares blobs with the contents of store. It logs every problem
+// it finds and returns the totals; the error is reserved for failures that
+// stop the scan, such as an unreadable listing.
+func Reconcile(ctx context.Context, store FileStore, blobs []Blob, opts ReconcileOptions) (ReconcileReport, error) {
+	report := ReconcileReport{Referenced: len(blobs)}
+	lister, ok := store.(Lister)
+	if !ok {
+		return report, fmt.Errorf("storage backend cannot list its contents")
+	}
+	objects := map[string]ObjectInfo{}
+	if err := lister.List(ctx, func(object ObjectInfo) error {
+		objects[object.Key] = object
+		return nil
+	}); err != nil {
+		return report, err
+	}
+
+	referenced := make(map[string]bool, len(blobs))
+	for _, blob := range blobs {
+		referenced[blob.Key] = true
+		object, found := objects[blob.Key]
+		if !found {
+			report.Missing++
+			log.Printf("storage: missing %s", blob.Key)
+			continue
+		}
+		if blob.Size >= 0 && object.Size != blob.Size {
+			report.SizeMismatches++
+			log.Printf("storage: size mismatch for %s: stored %d bytes, expected %d", blob.Key, object.Size, blob.Size)
+			continue
+		}
+		if !opts.VerifyChecksums {
+			continue
+		}
+		sum, err := checksum(ctx, store, blob.Key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			report.ReadErrors++
+			log.Printf("storage: read %s: %v", blob.Key, err)
+			continue
+		}
+		if blob.Checksum != "" {
+			if sum != blob.Checksum {
+				report.ChecksumMismatches++
+				log.Printf("storage: checksum mismatch for %s: stored %s, expected %s", blob.Key, sum, blob.Checksum)
+			}
+			continue
+		}
+		if blob.File && opts.RecordChecksum != nil {
+			if err := opts.RecordChecksum(ctx, blob, sum); err != nil {
+				return report, err
+			}
+			report.ChecksumsAdded++
+		}
+	}
+
+	cutoff := time.Now().Add(-opts.OrphanGrace)
+	for key, object := range objects {
+		if referenced[key] || object.ModTime.After(cutoff) {
+			continue
+		}
+		report.Orphans++
+		report.OrphanBytes += object.Size
+		if !opts.DeleteOrphans {
+			log.Printf("storage: orphan %s (%d bytes)", key, object.Size)
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("storage: delete orphan %s: %v", key, err)
+			continue
+		}
+		report.OrphansDeleted++
+		log.Printf("storage: deleted orphan %s (%d bytes)", key, object.Size)
+	}
+	return report, nil
+}
+
+type MigrateReport struct {
+	Copied   int
+	Skipped  int
+	Missing  int
+	Failed   int
+	Bytes    int64
+	Verified int
+}
+
+// Healthy reports whether every referenced blob now exists in the destination.
+func (r MigrateReport) Healthy() bool {
+	return r.Missing == 0 && r.Failed == 0
+}
+
+// Migrate copies every referenced blob from src to dst, verifying sizes and
+// recorded checksums on the way. Blobs already present in dst with the
+// expected size are skipped so an interrupted migration can be resumed.
+// Unreferenced blobs are not copied; run Reconcile on the source to review
+// them first.
+func Migrate(ctx context.Context, src, dst FileStore, blobs []Blob, recordChecksum ChecksumRecorder) (MigrateReport, error) {
+	report := MigrateReport{}
+	existing := map[string]int64{}
+	if lister, ok := dst.(Lister); ok {
+		if err := lister.List(ctx, func(object ObjectInfo) error {
+			existing[object.Key] = object.Size
+			return nil
+		}); err != nil {
+			return report, err
+		}
+	}
+
+	for _, blob := range blobs {
+		if size, found := existing[blob.Key]; found && blob.Size >= 0 && size == blob.Size {
+			report.Skipped++
+			continue
+		}
+		sum, size, err := copyBlob(ctx, src, dst, blob.Key)
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			report.Missing++
+			log.Printf("storage: missing %s in source", blob.Key)
+			continue
+		}
+		if err != nil {
+			report.Failed++
+			log.Printf("storage: copy %s: %v", blob.Key, err)
+			continue
+		}
+		if (blob.Size >= 0 && size != blob.Size) || (blob.Checksum != "" && sum != blob.Checksum) {
+			// Copying a damaged blob would hide the damage behind a fresh
+			// modification time.
+			_ = dst.Delete(context.WithoutCancel(ctx), blob.Key)
+			report.Failed++
+			log.Printf("storage: %s does not match its recorded size or checksum; not copied", blob.Key)
+			continue
+		}
+		if blob.Checksum != "" {
+			report.Verified++
+		} else if blob.File && recordChecksum != nil {
+			if err := recordChecksum(ctx, blob, sum); err != nil {
+				return report, err
+			}
+		}
+		report.Copied++
+		report.Bytes += size
+	}
+	return report, nil
+}
+
+func copyBlob(ctx context.Context, src, dst FileStore, key string) (string, int64, error) {
+	reader, err := src.Open(ctx, key)
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+	return SaveWithChecksum(ctx, dst, key, reader)
+}
+
+func checksum(ctx context.Context, store FileStore, key string) (string, error) {
+	reader, err := store.Open(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, &contextReader{ctx: ctx, r: reader}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
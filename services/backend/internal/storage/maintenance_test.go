@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDiskStore(t *testing.T, objects map[string]string) *DiskStore {
+	t.Helper()
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, data := range objects {
+		if err := store.Save(context.Background(), key, strings.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func backdate(t *testing.T, store *DiskStore, key string) {
+	t.Helper()
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(store.root, key), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	store := newTestDiskStore(t, map[string]string{
+		"p1/intact.bin":     "hello",
+		"p1/unrecorded.bin": "world",
+		"p1/damaged.bin":    "hellO",
+		"p1/truncated.bin":  "hel",
+		"p1/orphan.bin":     "left behind",
+		"p1/uploading.bin":  "not committed yet",
+	})
+	backdate(t, store, "p1/orphan.bin")
+	helloSum := hashHex([]byte("hello"))
+	blobs := []Blob{
+		{Key: "p1/intact.bin", Size: 5, Checksum: helloSum, File: true},
+		{Key: "p1/unrecorded.bin", Size: 5, File: true},
+		{Key: "p1/damaged.bin", Size: 5, Checksum: helloSum, File: true},
+		{Key: "p1/truncated.bin", Size: 5, File: true},
+		{Key: "p1/gone.bin", Size: 5, File: true},
+	}
+	recorded := map[string]string{}
+	opts := ReconcileOptions{
+		VerifyChecksums: true,
+		OrphanGrace:     DefaultOrphanGrace,
+		RecordChecksum: func(_ context.Context, blob Blob, checksum string) error {
+			recorded[blob.Key] = checksum
+			return nil
+		},
+	}
+
+	report, err := Reconcile(context.Background(), store, blobs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ReconcileReport{Referenced: 5, Missing: 1, SizeMismatches: 1, ChecksumMismatches: 1, ChecksumsAdded: 1, Orphans: 1, OrphanBytes: 11}
+	if report != want {
+		t.Fatalf("report = %+v, want %+v", report, want)
+	}
+	if report.Healthy() {
+		t.Fatal("Healthy() = true with missing and damaged blobs")
+	}
+	if len(recorded) != 1 || recorded["p1/unrecorded.bin"] != hashHex([]byte("world")) {
+		t.Fatalf("recorded checksums = %v", recorded)
+	}
+	if _, err := os.Stat(filepath.Join(store.root, "p1/orphan.bin")); err != nil {
+		t.Fatalf("orphan removed without DeleteOrphans: %v", err)
+	}
+
+	opts.DeleteOrphans = true
+	report, err = Reconcile(context.Background(), store, blobs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OrphansDeleted != 1 {
+		t.Fatalf("OrphansDeleted = %d, want 1", report.OrphansDeleted)
+	}
+	if _, err := os.Stat(filepath.Join(store.root, "p1/orphan.bin")); !os.IsNotExist(err) {
+		t.Fatalf("orphan still present: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.root, "p1/uploading.bin")); err != nil {
+		t.Fatalf("recent unreferenced blob deleted inside the grace period: %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	src := newTestDiskStore(t, map[string]string{
+		"p1/a.bin":                  "alpha",
+		"p1/b.bin":                  "bravo",
+		"p1/corrupt.bin":            "charliE",
+		"branding/k/logo-32.png":    "png",
+		"p1/unreferenced-extra.bin": "x",
+	})
+	dst := newTestDiskStore(t, map[string]string{"p1/b.bin": "bravo"})
+	blobs := []Blob{
+		{Key: "p1/a.bin", Size: 5, File: true},
+		{Key: "p1/b.bin", Size: 5, File: true},
+		{Key: "p1/corrupt.bin", Size: 7, Checksum: hashHex([]byte("charlie")), File: true},
+		{Key: "p1/missing.bin", Size: 1, File: true},
+		{Key: "branding/k/logo-32.png", Size: -1},
+	}
+	recorded := map[string]string{}
+	report, err := Migrate(context.Background(), src, dst, blobs, func(_ context.Context, blob Blob, checksum string) error {
+		recorded[blob.Key] = checksum
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MigrateReport{Copied: 2, Skipped: 1, Missing: 1, Failed: 1, Bytes: 8}
+	if report != want {
+		t.Fatalf("report = %+v, want %+v", report, want)
+	}
+	if recorded["p1/a.bin"] != hashHex([]byte("alpha")) || len(recorded) != 1 {
+		t.Fatalf("recorded checksums = %v", recorded)
+	}
+	for key, wantPresent := range map[string]bool{"p1/a.bin": true, "branding/k/logo-32.png": true, "p1/corrupt.bin": false, "p1/unreferenced-extra.bin": false} {
+		_, err := os.Stat(filepath.Join(dst.root, key))
+		if present := err == nil; present != wantPresent {
+			t.Fatalf("%s present in destination = %v, want %v", key, present, wantPresent)
+		}
+	}
+}
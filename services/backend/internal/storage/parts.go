@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// OpenParts reads the blobs stored under keys back to back as one stream. Each
+// blob is opened only once the previous one is exhausted, so assembling a
+// large upload keeps a single read open at a time.
+func OpenParts(ctx context.Context, store FileStore, keys []string) io.ReadCloser {
+	return &partsReader{ctx: ctx, store: store, keys: keys}
+}
+
+type partsReader struct {
+	ctx     context.Context
+	store   FileStore
+	keys    []string
+	current io.ReadCloser
+}
+
+func (p *partsReader) Read(buf []byte) (int, error) {
+	for {
+		if p.current == nil {
+			if len(p.keys) == 0 {
+				return 0, io.EOF
+			}
+			reader, err := p.store.Open(p.ctx, p.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("open part %s: %w", p.keys[0], err)
+			}
+			p.current, p.keys = reader, p.keys[1:]
+		}
+		n, err := p.current.Read(buf)
+		if err == io.EOF {
+			p.current.Close()
+			p.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (p *partsReader) Close() error {
+	if p.current == nil {
+		return nil
+	}
+	err := p.current.Close()
+	p.current = nil
+	return err
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestOpenParts(t *testing.T) {
+	store := newTestDiskStore(t, map[string]string{
+		"uploads/s1/0.part":  "hello ",
+		"uploads/s1/6.part":  "resumable ",
+		"uploads/s1/16.part": "world",
+	})
+	reader := OpenParts(context.Background(), store, []string{"uploads/s1/0.part", "uploads/s1/6.part", "uploads/s1/16.part"})
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello resumable world" {
+		t.Fatalf("data = %q", data)
+	}
+
+	reader = OpenParts(context.Background(), store, []string{"uploads/s1/0.part", "uploads/s1/gone.part"})
+	defer reader.Close()
+	if _, err := io.ReadAll(reader); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("err = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RangeOpener is implemented by stores that can read part of a blob without
+// transferring the bytes before it.
+type RangeOpener interface {
+	OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// OpenRange reads length bytes of key starting at offset. Stores without
+// ranged reads fall back to skipping the first offset bytes.
+func OpenRange(ctx context.Context, store FileStore, key string, offset, length int64) (io.ReadCloser, error) {
+	if opener, ok := store.(RangeOpener); ok {
+		return opener.OpenRange(ctx, key, offset, length)
+	}
+	reader, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("skip to storage offset: %w", err)
+	}
+	return readCloser{Reader: io.LimitReader(reader, length), Closer: reader}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// BlobReadSeeker exposes a blob of known size as an io.ReadSeeker, which is
+// what http.ServeContent needs for Range requests. A read after a seek opens a
+// ranged read at the new offset, so only the requested bytes are transferred.
+type BlobReadSeeker struct {
+	ctx    context.Context
+	store  FileStore
+	key    string
+	size   int64
+	offset int64
+	reader io.ReadCloser
+	// readerOffset is where reader continues. Seeks only move offset, so
+	// http.ServeContent probing the size does not discard the open reader.
+	readerOffset int64
+}
+
+// OpenReadSeeker opens key for reading from the start. The first read is
+// opened eagerly so a missing blob is reported before any response is sent.
+func OpenReadSeeker(ctx context.Context, store FileStore, key string, size int64) (*BlobReadSeeker, error) {
+	reader, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &BlobReadSeeker{ctx: ctx, store: store, key: key, size: size, reader: reader}, nil
+}
+
+func (b *BlobReadSeeker) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.reader != nil && b.readerOffset != b.offset {
+		b.reader.Close()
+		b.reader = nil
+	}
+	if b.reader == nil {
+		reader, err := OpenRange(b.ctx, b.store, b.key, b.offset, b.size-b.offset)
+		if err != nil {
+			return 0, err
+		}
+		b.reader, b.readerOffset = reader, b.offset
+	}
+	n, err := b.reader.Read(p)
+	b.offset += int64(n)
+	b.readerOffset = b.offset
+	return n, err
+}
+
+func (b *BlobReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return b.offset, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return b.offset, errors.New("negative position")
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *BlobReadSeeker) Close() error {
+	if b.reader == nil {
+		return nil
+	}
+	err := b.reader.Close()
+	b.reader = nil
+	return err
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// plainStore hides the RangeOpener implementation of the wrapped store.
+type plainStore struct{ FileStore }
+
+func TestBlobReadSeekerServesRanges(t *testing.T) {
+	const data = "0123456789abcdefghijklmnopqrstuvwxyz"
+	fake := newFakeS3()
+	fake.objects["p1/file.bin"] = []byte(data)
+	stores := map[string]FileStore{
+		"disk":           newTestDiskStore(t, map[string]string{"p1/file.bin": data}),
+		"s3":             newTestS3Store(t, fake),
+		"without ranges": plainStore{newTestDiskStore(t, map[string]string{"p1/file.bin": data})},
+	}
+	tests := []struct {
+		name       string
+		rangeValue string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "whole file", wantStatus: http.StatusOK, wantBody: data},
+		{name: "middle", rangeValue: "bytes=10-19", wantStatus: http.StatusPartialContent, wantBody: "abcdefghij"},
+		{name: "open ended", rangeValue: "bytes=30-", wantStatus: http.StatusPartialContent, wantBody: "uvwxyz"},
+		{name: "suffix", rangeValue: "bytes=-4", wantStatus: http.StatusPartialContent, wantBody: "wxyz"},
+		{name: "unsatisfiable", rangeValue: "bytes=100-", wantStatus: http.StatusRequestedRangeNotSatisfiable},
+	}
+	for storeName, store := range stores {
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				reader, err := OpenReadSeeker(context.Background(), store, "p1/file.bin", int64(len(data)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer reader.Close()
+				request := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tt.rangeValue != "" {
+					request.Header.Set("Range", tt.rangeValue)
+				}
+				response := httptest.NewRecorder()
+				http.ServeContent(response, request, "", time.Now(), reader)
+				if response.Code != tt.wantStatus {
+					t.Fatalf("status = %d, want %d", response.Code, tt.wantStatus)
+				}
+				if tt.wantBody != "" && response.Body.String() != tt.wantBody {
+					t.Fatalf("body = %q, want %q", response.Body.String(), tt.wantBody)
+				}
+			})
+		}
+	}
+	if fake.rangeReads == 0 {
+		t.Fatal("S3 ranges were served from full object reads")
+	}
+}
+
+func TestOpenReadSeekerReportsMissingBlob(t *testing.T) {
+	store := newTestDiskStore(t, nil)
+	if _, err := OpenReadSeeker(context.Background(), store, "missing.bin", 10); err == nil {
+		t.Fatal("OpenReadSeeker() succeeded for a missing blob")
+	}
+}
+
+func TestOpenRangeFallback(t *testing.T) {
+	store := plainStore{newTestDiskStore(t, map[string]string{"k": "hello world"})}
+	reader, err := OpenRange(context.Background(), store, "k", 6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	got, _ := io.ReadAll(reader)
+	if string(got) != "wor" {
+		t.Fatalf("OpenRange() = %q, want wor", got)
+	}
+}
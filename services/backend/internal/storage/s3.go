@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPartSize is above the 5 MiB minimum S3 accepts for all but the
+	// last part. Uploads smaller than one part use a single PUT.
+	defaultPartSize = 8 << 20
+	abortTimeout    = 30 * time.Second
+)
+
+type S3Options struct {
+	// Endpoint is the service URL, e.g. https://s3.eu-central-1.amazonaws.com
+	// or http://minio:9000. Empty means AWS for Region.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// ForcePathStyle addresses objects as <endpoint>/<bucket>/<key>, which
+	// MinIO and most self-hosted S3 implementations expect.
+	ForcePathStyle bool
+	// PresignedDownloads lets clients download directly from the bucket. The
+	// bucket then needs a CORS rule for the web app origin.
+	PresignedDownloads bool
+}
+
+// S3Store keeps blobs in an S3-compatible bucket so backend replicas can share
+// storage without a shared volume.
+type S3Store struct {
+	endpoint           *url.URL
+	bucket             string
+	pathStyle          bool
+	presignedDownloads bool
+	signer             signer
+	client             *http.Client
+	partSize           int
+}
+
+func NewS3Store(opts S3Options, client *http.Client) (*S3Store, error) {
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = "https://s3." + opts.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(opts.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if opts.Bucket == "" || opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 storage requires a bucket and credentials")
+	}
+	return &S3Store{
+		endpoint:           endpoint,
+		bucket:             opts.Bucket,
+		pathStyle:          opts.ForcePathStyle,
+		presignedDownloads: opts.PresignedDownloads,
+		signer:             signer{accessKeyID: opts.AccessKeyID, secretAccessKey: opts.SecretAccessKey, region: opts.Region},
+		client:             client,
+		partSize:           defaultPartSize,
+	}, nil
+}
+
+type s3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3 status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3 status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *s3Error) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return fs.ErrNotExist
+	}
+	return nil
+}
+
+func (s *S3Store) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := "/" + strings.TrimLeft(key, "/")
+	if s.pathStyle {
+		path = "/" + s.bucket + path
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimRight(s.endpoint.Path, "/") + path
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// do sends a signed request and returns the response for 2xx statuses. Other
+// statuses are returned as *s3Error with the response body closed.
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		req.Body = http.NoBody
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.signer.sign(req, hashHex(body), time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &s3Error{StatusCode: resp.StatusCode}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = xml.Unmarshal(raw, apiErr)
+	return nil, apiErr
+}
+
+func (s *S3Store) Save(ctx context.Context, key string, src io.Reader) error {
+	buf := make([]byte, s.partSize)
+	n, err := io.ReadFull(src, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		resp, err := s.do(ctx, http.MethodPut, key, nil, buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("put storage object: %w", err)
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read upload: %w", err)
+	}
+	return s.saveMultipart(ctx, key, src, buf)
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// saveMultipart uploads buf, which already holds the first full part, and the
+// rest of src part by part. Failed uploads are aborted so the bucket does not
+// keep billing for orphaned parts.
+func (s *S3Store) saveMultipart(ctx context.Context, key string, src io.Reader, buf []byte) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("create multipart upload: %w", err)
+	}
+	var created struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil || created.UploadID == "" {
+		return fmt.Errorf("create multipart upload: invalid response")
+	}
+	uploadID := created.UploadID
+
+	parts := []completedPart{}
+	n := len(buf)
+	for number := 1; n > 0; number++ {
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(ctx, http.MethodPut, key, query, buf[:n], nil)
+		if err != nil {
+			s.abortMultipart(key, uploadID)
+			return fmt.Errorf("upload part %d: %w", number, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err = io.ReadFull(src, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			s.abortMultipart(key, uploadID)
+			return fmt.Errorf("read upload: %w", err)
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		s.abortMultipart(key, uploadID)
+		return err
+	}
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, http.Header{"Content-Type": {"application/xml"}})
+	if err != nil {
+		s.abortMultipart(key, uploadID)
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+	// CompleteMultipartUpload can fail after the 200 status line was sent; the
+	// error is then reported in the body.
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var result struct {
+		XMLName xml.Name
+		s3Error
+	}
+	if xml.Unmarshal(raw, &result) == nil && result.XMLName.Local == "Error" {
+		s.abortMultipart(key, uploadID)
+		result.StatusCode = resp.StatusCode
+		return fmt.Errorf("complete multipart upload: %w", &result.s3Error)
+	}
+	return nil
+}
+
+func (s *S3Store) abortMultipart(key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+	if resp, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open storage object: %w", err)
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if length <= 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, header)
+	if err != nil {
+		return nil, fmt.Errorf("open storage object range: %w", err)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		// The server ignored the range and sent the whole object.
+		resp.Body.Close()
+		return nil, fmt.Errorf("open storage object range: status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		if apiErr, ok := err.(*s3Error); ok && apiErr.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("delete storage object: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List walks the bucket with ListObjectsV2, one page of up to 1000 keys at a
+// time.
+func (s *S3Store) List(ctx context.Context, fn func(ObjectInfo) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return fmt.Errorf("list storage objects: %w", err)
+		}
+		var page struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("list storage objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			if err := fn(ObjectInfo{Key: object.Key, Size: object.Size, ModTime: object.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// PresignGet returns a GET link that serves the object with contentType and,
+// if set, contentDisposition. It returns an empty URL unless presigned
+// downloads are enabled.
+func (s *S3Store) PresignGet(key, contentType, contentDisposition string, expires time.Duration) (string, error) {
+	if !s.presignedDownloads {
+		return "", nil
+	}
+	query := url.Values{}
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+	if contentDisposition != "" {
+		query.Set("response-content-disposition", contentDisposition)
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(key, query), expires, time.Now()), nil
+}
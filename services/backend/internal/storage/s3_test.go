@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal MinIO-style stand-in with path-style addressing. It
+// checks that every request is signed and that the payload hash matches.
+type fakeS3 struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	uploads    map[string]map[int][]byte
+	aborted    int
+	nextID     int
+	rangeReads int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") || r.Header.Get("X-Amz-Content-Sha256") != hashHex(body) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error>`)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	query := r.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := "upload-" + strconv.Itoa(f.nextID)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		var complete struct {
+			Parts []completedPart `xml:"Part"`
+		}
+		_ = xml.Unmarshal(body, &complete)
+		parts := f.uploads[query.Get("uploadId")]
+		numbers := make([]int, 0, len(parts))
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var assembled []byte
+		for _, number := range numbers {
+			assembled = append(assembled, parts[number]...)
+		}
+		if len(complete.Parts) != len(parts) {
+			fmt.Fprint(w, `<Error><Code>InvalidPart</Code><Message>part list mismatch</Message></Error>`)
+			return
+		}
+		f.objects[key] = assembled
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, `<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && query.Get("uploadId") != "":
+		f.aborted++
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		// Two keys per page so listings exercise continuation tokens.
+		keys := make([]string, 0, len(f.objects))
+		for k := range f.objects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		start, _ := strconv.Atoi(query.Get("continuation-token"))
+		end := min(start+2, len(keys))
+		fmt.Fprint(w, `<ListBucketResult>`)
+		for _, k := range keys[start:end] {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>2026-01-02T03:04:05.000Z</LastModified><Size>%d</Size></Contents>`, k, len(f.objects[k]))
+		}
+		if end < len(keys) {
+			fmt.Fprintf(w, `<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>`, end)
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`)
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err == nil {
+			f.rangeReads++
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start:min(end+1, len(data))])
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T, fake http.Handler) *S3Store {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	store, err := NewS3Store(S3Options{
+		Endpoint: server.URL, Bucket: "bucket", AccessKeyID: "test-key", SecretAccessKey: "test-secret", ForcePathStyle: true,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.partSize = 1024
+	return store
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "empty object", size: 0},
+		{name: "single put", size: 700},
+		{name: "multipart with exact parts", size: 2048},
+		{name: "multipart with short last part", size: 2500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3()
+			store := newTestS3Store(t, fake)
+			data := make([]byte, tt.size)
+			rand.Read(data)
+			ctx := context.Background()
+			if err := store.Save(ctx, "project-1/file.bin", bytes.NewReader(data)); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			reader, err := store.Open(ctx, "project-1/file.bin")
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			got, _ := io.ReadAll(reader)
+			reader.Close()
+			if !bytes.Equal(got, data) {
+				t.Fatalf("read %d bytes, want the %d bytes written", len(got), len(data))
+			}
+			if len(fake.uploads) != 0 {
+				t.Fatalf("%d multipart uploads left open", len(fake.uploads))
+			}
+			if err := store.Delete(ctx, "project-1/file.bin"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := store.Open(ctx, "project-1/file.bin"); !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("Open() after delete error = %v, want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestS3StoreList(t *testing.T) {
+	fake := newFakeS3()
+	for _, key := range []string{"a/1.bin", "a/2.bin", "b/3.bin", "branding/k/logo-32.png", "c/4.bin"} {
+		fake.objects[key] = []byte(key)
+	}
+	store := newTestS3Store(t, fake)
+	var listed []string
+	err := store.List(context.Background(), func(object ObjectInfo) error {
+		if object.Size != int64(len(object.Key)) || object.ModTime.IsZero() {
+			t.Errorf("object %+v has unexpected size or time", object)
+		}
+		listed = append(listed, object.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(listed, ",") != "a/1.bin,a/2.bin,b/3.bin,branding/k/logo-32.png,c/4.bin" {
+		t.Fatalf("listed %v", listed)
+	}
+}
+
+type failingReader struct{ remaining int }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := min(len(p), r.remaining)
+	r.remaining -= n
+	return n, nil
+}
+
+func TestS3StoreAbortsFailedMultipartUpload(t *testing.T) {
+	fake := newFakeS3()
+	store := newTestS3Store(t, fake)
+	if err := store.Save(context.Background(), "project-1/file.bin", &failingReader{remaining: 1500}); err == nil {
+		t.Fatal("Save() succeeded with a failing reader")
+	}
+	if fake.aborted != 1 || len(fake.uploads) != 0 || len(fake.objects) != 0 {
+		t.Fatalf("aborted=%d uploads=%d objects=%d, want 1/0/0", fake.aborted, len(fake.uploads), len(fake.objects))
+	}
+}
+
+func TestS3StoreReportsAPIErrors(t *testing.T) {
+	store := newTestS3Store(t, newFakeS3())
+	store.signer.accessKeyID = "other-key"
+	err := store.Save(context.Background(), "k", strings.NewReader("data"))
+	var apiErr *s3Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "SignatureDoesNotMatch" {
+		t.Fatalf("Save() error = %v, want SignatureDoesNotMatch", err)
+	}
+}
+
+func TestS3StorePresignGet(t *testing.T) {
+	store := newTestS3Store(t, newFakeS3())
+	if link, _ := store.PresignGet("project-1/file.bin", "image/png", "", time.Minute); link != "" {
+		t.Fatalf("PresignGet() = %q while presigned downloads are disabled", link)
+	}
+	store.presignedDownloads = true
+	link, err := store.PresignGet("project-1/file.bin", "image/png", "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"/bucket/project-1/file.bin?", "X-Amz-Expires=60", "response-content-type=image%2Fpng", "X-Amz-Signature="} {
+		if !strings.Contains(link, part) {
+			t.Fatalf("PresignGet() = %s, missing %s", link, part)
+		}
+	}
+}
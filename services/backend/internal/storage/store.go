@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/config"
+)
+
+// FileStore persists opaque blobs under slash-separated keys such as
+// "<projectId>/<timestamp>-<id>.bin". Open returns an error wrapping
+// fs.ErrNotExist for missing keys, and Delete ignores them.
+type FileStore interface {
+	Save(ctx context.Context, key string, src io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by stores that can hand out short-lived direct
+// download links so large files do not have to be proxied by the backend.
+// An empty URL means direct downloads are disabled.
+type Presigner interface {
+	PresignGet(key, contentType, contentDisposition string, expires time.Duration) (string, error)
+}
+
+// ObjectInfo describes a stored blob as reported by Lister.
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Lister is implemented by stores that can enumerate their blobs. It is only
+// used by the STORAGE_MODE maintenance commands.
+type Lister interface {
+	List(ctx context.Context, fn func(ObjectInfo) error) error
+}
+
+// New returns the store selected by STORAGE_BACKEND.
+func New(cfg *config.Config) (FileStore, error) {
+	return NewBackend(cfg, cfg.StorageBackend)
+}
+
+// NewBackend returns the named store configured from cfg, so a migration can
+// open the backend it copies from alongside the configured one.
+func NewBackend(cfg *config.Config, backend string) (FileStore, error) {
+	switch backend {
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:           cfg.S3Endpoint,
+			Region:             cfg.S3Region,
+			Bucket:             cfg.S3Bucket,
+			AccessKeyID:        cfg.S3AccessKeyID,
+			SecretAccessKey:    cfg.S3SecretAccessKey,
+			ForcePathStyle:     cfg.S3ForcePathStyle,
+			PresignedDownloads: cfg.S3PresignedDownloads,
+		}, http.DefaultClient)
+	case "disk":
+		return NewDiskStore(cfg.FileStorageRoot)
+	}
+	return nil, fmt.Errorf("unsupported storage backend %q", backend)
+}
+
+// SaveWithChecksum saves src under key and returns the hex SHA-256 and size of
+// the bytes written, computed while they stream through.
+func SaveWithChecksum(ctx context.Context, store FileStore, key string, src io.Reader) (string, int64, error) {
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(src, hash)}
+	if err := store.Save(ctx, key, counter); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), counter.n, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	default:
+		return r.r.Read(p)
+	}
+}
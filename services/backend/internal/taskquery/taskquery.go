@@ -0,0 +1,403 @@
+// Package taskquery parses the filter language of the task list endpoints,
+// for example:
+//
+//	status:in-progress priority>=high assignee:me tag:backend due<7d -blocked
+//
+// A query is a list of terms that must all match. A term is field:value,
+// a comparison such as due<7d, or a bare flag such as blocked. A leading -
+// negates a term, commas list alternatives (status:todo,review), and values
+// containing spaces are quoted (tag:"needs review").
+//
+// Parsing only validates and normalizes terms; the repository turns them into
+// parameterized SQL.
+package taskquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxQueryLength = 1000
+	maxTerms       = 20
+)
+
+// Fields that take values. Flags are represented by Field "is".
+const (
+	FieldStatus   = "status"
+	FieldPriority = "priority"
+	FieldAssignee = "assignee"
+	FieldTag      = "tag"
+	FieldKey      = "key"
+	FieldText     = "text"
+	FieldDue      = "due"
+	FieldCreated  = "created"
+	FieldUpdated  = "updated"
+	FieldIs       = "is"
+)
+
+// Flags usable as is:<flag> or on their own.
+var flags = map[string]bool{
+	"open":      true,
+	"completed": true,
+	"blocked":   true,
+	"overdue":   true,
+	"subtask":   true,
+	"recurring": true,
+}
+
+// Priorities in ascending order; comparisons use the index.
+var Priorities = []string{"low", "medium", "high", "urgent"}
+
+var (
+	relativePattern = regexp.MustCompile(`^([+-]?\d{1,4})([hdw])$`)
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Query is a parsed filter. The zero value matches every task.
+type Query struct {
+	Terms []Term
+}
+
+// Term is one condition of a query.
+type Term struct {
+	Field  string
+	Negate bool
+	// Op is ":" for matches and "<", "<=", ">", or ">=" for priority
+	// comparisons. Date terms are resolved into Range instead.
+	Op string
+	// Values are normalized: lower case except for task keys, which are
+	// upper case, and tag and text values, which are kept as written.
+	Values []string
+	// Range bounds due, created, and updated terms. None matches tasks
+	// without a due date.
+	Range Range
+}
+
+// Range is a half-open time interval; nil bounds are unbounded.
+type Range struct {
+	From  *time.Time
+	Until *time.Time
+	None  bool
+}
+
+// Error is a parse error at a byte offset of the query.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos+1)
+}
+
+// Parse parses input. Relative dates such as 7d and today are resolved
+// against now; calendar dates and today are UTC days.
+func Parse(input string, now time.Time) (*Query, error) {
+	if len(input) > maxQueryLength {
+		return nil, &Error{Pos: maxQueryLength, Msg: fmt.Sprintf("query is longer than %d characters", maxQueryLength)}
+	}
+	p := &parser{input: input, now: now.UTC()}
+	query := &Query{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			return query, nil
+		}
+		if len(query.Terms) == maxTerms {
+			return nil, p.errorf(p.pos, "query has more than %d terms", maxTerms)
+		}
+		term, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		query.Terms = append(query.Terms, term)
+	}
+}
+
+type parser struct {
+	input string
+	pos   int
+	now   time.Time
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && isSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (p *parser) term() (Term, error) {
+	start := p.pos
+	term := Term{}
+	if p.input[p.pos] == '-' {
+		term.Negate = true
+		p.pos++
+	}
+	nameStart := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	name := strings.ToLower(p.input[nameStart:p.pos])
+
+	op := p.operator()
+	if op == "" {
+		// A bare word: only flags are allowed.
+		for p.pos < len(p.input) && !isSpace(p.input[p.pos]) {
+			p.pos++
+		}
+		word := p.input[nameStart:p.pos]
+		if word == "" {
+			return term, p.errorf(start, "expected a filter after -")
+		}
+		if !flags[strings.ToLower(word)] {
+			return term, p.errorf(nameStart, "unknown filter %q; use text:%q to search titles and descriptions", word, word)
+		}
+		term.Field, term.Op, term.Values = FieldIs, ":", []string{strings.ToLower(word)}
+		return term, nil
+	}
+	if name == "" {
+		return term, p.errorf(nameStart, "expected a field name before %q", op)
+	}
+
+	valuesStart := p.pos
+	values, err := p.values()
+	if err != nil {
+		return term, err
+	}
+	term.Field, term.Op = name, op
+	if err := p.resolve(&term, values, nameStart, valuesStart); err != nil {
+		return term, err
+	}
+	return term, nil
+}
+
+func (p *parser) operator() string {
+	for _, op := range []string{"<=", ">=", ":", "=", "<", ">"} {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			p.pos += len(op)
+			if op == "=" {
+				return ":"
+			}
+			return op
+		}
+	}
+	return ""
+}
+
+// values reads a comma-separated list of plain or double-quoted values.
+func (p *parser) values() ([]string, error) {
+	var values []string
+	for {
+		valueStart := p.pos
+		var value string
+		if p.pos < len(p.input) && p.input[p.pos] == '"' {
+			var b strings.Builder
+			p.pos++
+			closed := false
+			for p.pos < len(p.input) {
+				c := p.input[p.pos]
+				if c == '\\' && p.pos+1 < len(p.input) {
+					b.WriteByte(p.input[p.pos+1])
+					p.pos += 2
+					continue
+				}
+				p.pos++
+				if c == '"' {
+					closed = true
+					break
+				}
+				b.WriteByte(c)
+			}
+			if !closed {
+				return nil, p.errorf(valueStart, "unterminated quoted value")
+			}
+			value = b.String()
+		} else {
+			for p.pos < len(p.input) && !isSpace(p.input[p.pos]) && p.input[p.pos] != ',' {
+				if p.input[p.pos] == '"' {
+					return nil, p.errorf(p.pos, "unexpected quote")
+				}
+				p.pos++
+			}
+			value = p.input[valueStart:p.pos]
+		}
+		if strings.TrimSpace(value) == "" {
+			return nil, p.errorf(valueStart, "expected a value")
+		}
+		values = append(values, value)
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.input) && !isSpace(p.input[p.pos]) {
+			return nil, p.errorf(p.pos, "expected a space between terms")
+		}
+		return values, nil
+	}
+}
+
+func (p *parser) resolve(term *Term, values []string, fieldPos, valuesPos int) error {
+	single := func() error {
+		if len(values) > 1 {
+			return p.errorf(valuesPos, "%s takes a single value", term.Field)
+		}
+		return nil
+	}
+	matchOnly := func() error {
+		if term.Op != ":" {
+			return p.errorf(valuesPos-len(term.Op), "%s does not support %s", term.Field, term.Op)
+		}
+		return nil
+	}
+
+	switch term.Field {
+	case FieldStatus:
+		if err := matchOnly(); err != nil {
+			return err
+		}
+		for _, value := range values {
+			term.Values = append(term.Values, strings.ToLower(value))
+		}
+	case FieldTag:
+		if err := matchOnly(); err != nil {
+			return err
+		}
+		term.Values = values
+	case FieldKey:
+		if err := matchOnly(); err != nil {
+			return err
+		}
+		for _, value := range values {
+			term.Values = append(term.Values, strings.ToUpper(value))
+		}
+	case FieldText:
+		if err := matchOnly(); err != nil {
+			return err
+		}
+		if err := single(); err != nil {
+			return err
+		}
+		term.Values = values
+	case FieldIs:
+		if err := matchOnly(); err != nil {
+			return err
+		}
+		for _, value := range values {
+			value = strings.ToLower(value)
+			if !flags[value] {
+				return p.errorf(valuesPos, "unknown flag %q; use open, completed, blocked, overdue, subtask, or recurring", value)
+			}
+			term.Values = append(term.Values, value)
+		}
+	case FieldAssignee:
+		if err := matchOnly(); err != nil {
+			return err
+		}
+		for _, value := range values {
+			value = strings.ToLower(value)
+			if value != "me" && value != "none" && !strings.Contains(value, "@") && !uuidPattern.MatchString(value) {
+				return p.errorf(valuesPos, "assignee must be me, none, an email address, or a user ID")
+			}
+			term.Values = append(term.Values, value)
+		}
+	case FieldPriority:
+		if term.Op != ":" {
+			if err := single(); err != nil {
+				return err
+			}
+		}
+		for _, value := range values {
+			value = strings.ToLower(value)
+			if PriorityRank(value) < 0 {
+				return p.errorf(valuesPos, "priority must be low, medium, high, or urgent")
+			}
+			term.Values = append(term.Values, value)
+		}
+	case FieldDue, FieldCreated, FieldUpdated:
+		if err := single(); err != nil {
+			return err
+		}
+		value := strings.ToLower(values[0])
+		term.Values = []string{value}
+		if value == "none" {
+			if term.Field != FieldDue || term.Op != ":" {
+				return p.errorf(valuesPos, "only due:none is supported")
+			}
+			term.Range.None = true
+			return nil
+		}
+		return p.resolveRange(term, value, valuesPos)
+	default:
+		return p.errorf(fieldPos, "unknown field %q; use status, priority, assignee, tag, key, text, due, created, updated, or is", term.Field)
+	}
+	return nil
+}
+
+// resolveRange turns a date comparison into a half-open range. Calendar days
+// cover the whole day, so due<=2026-10-20 includes that day. Relative values
+// are instants, for which < and <= coincide.
+func (p *parser) resolveRange(term *Term, value string, pos int) error {
+	var start time.Time
+	day := true
+	switch {
+	case value == "today":
+		start = p.now.Truncate(24 * time.Hour)
+	case value == "now":
+		start, day = p.now, false
+	case relativePattern.MatchString(value):
+		match := relativePattern.FindStringSubmatch(value)
+		amount, _ := strconv.Atoi(match[1])
+		unit := map[string]time.Duration{"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[match[2]]
+		start, day = p.now.Add(time.Duration(amount)*unit), false
+	default:
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return p.errorf(pos, "%s must be a date (2026-10-20), today, now, none, or relative time such as 7d, -2w, or 12h", term.Field)
+		}
+		start = parsed
+	}
+	end := start
+	if day {
+		end = start.Add(24 * time.Hour)
+	}
+
+	switch term.Op {
+	case ":":
+		if !day {
+			return p.errorf(pos, "use <, <=, >, or >= with relative times")
+		}
+		term.Range.From, term.Range.Until = &start, &end
+	case "<":
+		term.Range.Until = &start
+	case "<=":
+		term.Range.Until = &end
+	case ">":
+		term.Range.From = &end
+	case ">=":
+		term.Range.From = &start
+	}
+	return nil
+}
+
+// PriorityRank is the position of priority in Priorities, or -1.
+func PriorityRank(priority string) int {
+	for i, candidate := range Priorities {
+		if candidate == priority {
+			return i
+		}
+	}
+	return -1
+}
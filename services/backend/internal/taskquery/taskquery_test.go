@@ -0,0 +1,97 @@
+package taskquery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	in7d := testNow.Add(7 * 24 * time.Hour)
+	ago2w := testNow.Add(-14 * 24 * time.Hour)
+	query, err := Parse(`status:in_progress,Review priority>=high assignee:me tag:"needs review" due<7d -blocked created>-2w key:test-14 text:"login page"`, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Term{
+		{Field: FieldStatus, Op: ":", Values: []string{"in_progress", "review"}},
+		{Field: FieldPriority, Op: ">=", Values: []string{"high"}},
+		{Field: FieldAssignee, Op: ":", Values: []string{"me"}},
+		{Field: FieldTag, Op: ":", Values: []string{"needs review"}},
+		{Field: FieldDue, Op: "<", Values: []string{"7d"}, Range: Range{Until: &in7d}},
+		{Field: FieldIs, Op: ":", Values: []string{"blocked"}, Negate: true},
+		{Field: FieldCreated, Op: ">", Values: []string{"-2w"}, Range: Range{From: &ago2w}},
+		{Field: FieldKey, Op: ":", Values: []string{"TEST-14"}},
+		{Field: FieldText, Op: ":", Values: []string{"login page"}},
+	}
+	if !reflect.DeepEqual(query.Terms, want) {
+		t.Fatalf("terms = %+v\nwant    %+v", query.Terms, want)
+	}
+}
+
+func TestParseDateRanges(t *testing.T) {
+	for input, want := range map[string]Range{
+		"due:2026-10-20":  {From: date(2026, 10, 20), Until: date(2026, 10, 21)},
+		"due<2026-10-20":  {Until: date(2026, 10, 20)},
+		"due<=2026-10-20": {Until: date(2026, 10, 21)},
+		"due>2026-10-20":  {From: date(2026, 10, 21)},
+		"due>=today":      {From: date(2026, 10, 16)},
+		"due:none":        {None: true},
+	} {
+		query, err := Parse(input, testNow)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", input, err)
+			continue
+		}
+		if got := query.Terms[0].Range; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse(%q) range = %+v, want %+v", input, got, want)
+		}
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	query, err := Parse("   ", testNow)
+	if err != nil || len(query.Terms) != 0 {
+		t.Fatalf("Parse(blank) = %+v, %v", query, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for input, wantPos := range map[string]int{
+		"login":               1,
+		"status:todo foo:bar": 13,
+		"priority:critical":   10,
+		"priority>low,high":   10,
+		"tag<backend":         4,
+		`tag:"unterminated`:   5,
+		"due<tomorrow":        5,
+		"due:7d":              5,
+		"created:none":        9,
+		"assignee:someone":    10,
+		"status:":             8,
+		"status:todo,":        13,
+		"-":                   1,
+		":todo":               1,
+		`status:todo"x"`:      12,
+		"is:archived":         4,
+		"text:one,two":        6,
+	} {
+		_, err := Parse(input, testNow)
+		var parseErr *Error
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) error = %v, want *Error", input, err)
+			continue
+		}
+		if parseErr.Pos+1 != wantPos {
+			t.Errorf("Parse(%q) error %q at position %d, want %d", input, parseErr.Msg, parseErr.Pos+1, wantPos)
+		}
+	}
+}
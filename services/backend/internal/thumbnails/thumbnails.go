@@ -0,0 +1,166 @@
+// Package thumbnails renders preview images for uploaded image attachments.
+// Thumbnails belong to a stored blob and live next to it in the file store, so
+// deduplicated files share them.
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/justlabv1/justspace/backend/internal/storage"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Sizes are the bounding boxes, in pixels, of the rendered thumbnails.
+var Sizes = []int{128, 512, 1024}
+
+// DefaultSize is served when a client does not ask for a size.
+const DefaultSize = 512
+
+const (
+	// MaxSourceBytes bounds the images read into memory for rendering.
+	MaxSourceBytes = 32 << 20
+	// maxSourcePixels rejects images whose decoded form would be far larger
+	// than their encoded size suggests.
+	maxSourcePixels = 50_000_000
+	jpegQuality     = 85
+)
+
+// ErrUnsupported reports an image that cannot be thumbnailed. Retrying will
+// not help, unlike storage errors.
+var ErrUnsupported = errors.New("image cannot be thumbnailed")
+
+// Supported reports whether files of contentType get thumbnails.
+func Supported(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(mediaType) {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Path is the store key of the thumbnail of storagePath at size.
+func Path(storagePath string, size int) string {
+	return fmt.Sprintf("thumbnails/%s/%d", storagePath, size)
+}
+
+// Paths returns the store keys of every thumbnail of storagePath.
+func Paths(storagePath string) []string {
+	paths := make([]string, 0, len(Sizes))
+	for _, size := range Sizes {
+		paths = append(paths, Path(storagePath, size))
+	}
+	return paths
+}
+
+// Size picks the smallest rendered size covering requested, or the largest
+// one when none does. Zero or negative requests get DefaultSize.
+func Size(requested int) int {
+	if requested <= 0 {
+		return DefaultSize
+	}
+	for _, size := range Sizes {
+		if size >= requested {
+			return size
+		}
+	}
+	return Sizes[len(Sizes)-1]
+}
+
+// Generate renders every size of the image stored at storagePath and saves
+// them to store. It returns the content type of the thumbnails: JPEG sources
+// stay JPEG, everything else becomes PNG to keep transparency.
+func Generate(ctx context.Context, store storage.FileStore, storagePath string, sizeBytes int64) (string, error) {
+	if sizeBytes > MaxSourceBytes {
+		return "", fmt.Errorf("%w: source is larger than %d bytes", ErrUnsupported, MaxSourceBytes)
+	}
+	reader, err := store.Open(ctx, storagePath)
+	if err != nil {
+		return "", fmt.Errorf("open image: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, MaxSourceBytes+1))
+	reader.Close()
+	if err != nil {
+		return "", fmt.Errorf("read image: %w", err)
+	}
+	if len(data) > MaxSourceBytes {
+		return "", fmt.Errorf("%w: source is larger than %d bytes", ErrUnsupported, MaxSourceBytes)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxSourcePixels {
+		return "", fmt.Errorf("%w: %dx%d pixels", ErrUnsupported, config.Width, config.Height)
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	contentType := "image/png"
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+	}
+	saved := make([]string, 0, len(Sizes))
+	for _, size := range Sizes {
+		width, height := fit(source.Bounds().Dx(), source.Bounds().Dy(), size)
+		canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(canvas, canvas.Bounds(), source, source.Bounds(), draw.Over, nil)
+		var encoded bytes.Buffer
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(&encoded, canvas, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&encoded, canvas)
+		}
+		if err == nil {
+			err = store.Save(ctx, Path(storagePath, size), bytes.NewReader(encoded.Bytes()))
+		}
+		if err != nil {
+			for _, path := range saved {
+				_ = store.Delete(context.WithoutCancel(ctx), path)
+			}
+			return "", fmt.Errorf("store %dpx thumbnail: %w", size, err)
+		}
+		saved = append(saved, Path(storagePath, size))
+	}
+	return contentType, nil
+}
+
+// Delete removes every thumbnail of storagePath. Missing thumbnails are not an
+// error.
+func Delete(ctx context.Context, store storage.FileStore, storagePath string) error {
+	var errs []error
+	for _, path := range Paths(storagePath) {
+		if err := store.Delete(ctx, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fit scales width x height to fit within a box x box square, keeping the
+// aspect ratio. Images are never enlarged.
+func fit(width, height, box int) (int, int) {
+	if width <= box && height <= box {
+		return width, height
+	}
+	if width >= height {
+		return box, max(1, height*box/width)
+	}
+	return max(1, width*box/height), box
+}
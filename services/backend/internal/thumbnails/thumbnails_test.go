@@ -0,0 +1,120 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/justlabv1/justspace/backend/internal/storage"
+)
+
+func TestFit(t *testing.T) {
+	for _, tc := range []struct {
+		width, height, box int
+		wantW, wantH       int
+	}{
+		{2000, 1000, 512, 512, 256},
+		{1000, 2000, 512, 256, 512},
+		{300, 150, 512, 300, 150},
+		{4000, 1, 128, 128, 1},
+	} {
+		if w, h := fit(tc.width, tc.height, tc.box); w != tc.wantW || h != tc.wantH {
+			t.Errorf("fit(%d, %d, %d) = %dx%d, want %dx%d", tc.width, tc.height, tc.box, w, h, tc.wantW, tc.wantH)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	for requested, want := range map[int]int{0: DefaultSize, 64: 128, 128: 128, 300: 512, 4096: 1024} {
+		if got := Size(requested); got != want {
+			t.Errorf("Size(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"image/png":                 true,
+		"image/JPEG":                true,
+		"image/webp; charset=utf-8": true,
+		"image/svg+xml":             false,
+		"application/pdf":           false,
+		"":                          false,
+	} {
+		if got := Supported(contentType); got != want {
+			t.Errorf("Supported(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for y := range 300 {
+		for x := range 600 {
+			source.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 128})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, source); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, "p1/image.bin", bytes.NewReader(encoded.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	contentType, err := Generate(ctx, store, "p1/image.bin", int64(encoded.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/png" {
+		t.Fatalf("content type = %q, want image/png", contentType)
+	}
+	for size, want := range map[int]image.Point{128: {128, 64}, 512: {512, 256}, 1024: {600, 300}} {
+		reader, err := store.Open(ctx, Path("p1/image.bin", size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := png.DecodeConfig(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Width != want.X || config.Height != want.Y {
+			t.Errorf("%dpx thumbnail is %dx%d, want %dx%d", size, config.Width, config.Height, want.X, want.Y)
+		}
+	}
+
+	if err := Delete(ctx, store, "p1/image.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(ctx, Path("p1/image.bin", 128)); err == nil {
+		t.Fatal("thumbnail still stored after Delete")
+	}
+}
+
+func TestGenerateRejectsUndecodableImages(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, "p1/broken.bin", strings.NewReader("not an image")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(ctx, store, "p1/broken.bin", 12); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("err = %v, want ErrUnsupported", err)
+	}
+	if _, err := Generate(ctx, store, "p1/huge.bin", MaxSourceBytes+1); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("err = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,242 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+)
+
+const (
+	SignatureHeader = "X-Justspace-Signature"
+	TimestampHeader = "X-Justspace-Timestamp"
+	EventHeader     = "X-Justspace-Event"
+	DeliveryHeader  = "X-Justspace-Delivery"
+
+	maxAttempts    = 8
+	claimBatch     = 20
+	pollInterval   = 5 * time.Second
+	baseBackoff    = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+	requestTimeout = 10 * time.Second
+)
+
+// Collections forwarded to webhooks. Presence, awareness, and activity feeds are
+// UI plumbing and intentionally excluded.
+var Collections = map[string]bool{
+	"projects":              true,
+	"project_members":       true,
+	"project_files":         true,
+	"project_task_statuses": true,
+	"tasks":                 true,
+	"task_assignees":        true,
+	"task_comments":         true,
+}
+
+// Fields kept in documents of encrypted projects. Everything else may contain
+// client-side encrypted envelopes and is dropped before the event is queued.
+var encryptedDocumentFields = []string{"id", "projectId", "taskId", "parentId", "userId", "workspaceId"}
+
+type Dispatcher struct {
+	repo   *repository.Repo
+	client *http.Client
+}
+
+func NewDispatcher(repo *repository.Repo) *Dispatcher {
+	return &Dispatcher{repo: repo, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Emit queues event for the webhooks of the project's workspace. It is safe to
+// call on a nil Dispatcher so handlers can be constructed without webhooks.
+func (d *Dispatcher) Emit(projectID string, event models.WSEvent) {
+	if d == nil || projectID == "" || !Collections[event.Collection] {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	workspaceID, encrypted, err := d.repo.GetWebhookProjectScope(ctx, projectID)
+	if err != nil {
+		log.Printf("webhook scope error: %v", err)
+		return
+	}
+	if workspaceID == "" {
+		return
+	}
+	d.EmitWorkspace(ctx, workspaceID, projectID, encrypted, event)
+}
+
+// EmitWorkspace queues event when the project scope is already known, e.g. for
+// a project that is about to be deleted.
+func (d *Dispatcher) EmitWorkspace(ctx context.Context, workspaceID, projectID string, encrypted bool, event models.WSEvent) {
+	if d == nil || !Collections[event.Collection] {
+		return
+	}
+	payload, name, err := buildPayload(workspaceID, projectID, encrypted, event, time.Now().UTC())
+	if err != nil {
+		log.Printf("webhook payload error: %v", err)
+		return
+	}
+	if _, err := d.repo.EnqueueWebhookDeliveries(ctx, workspaceID, event.Collection, event.Type, name, payload); err != nil {
+		log.Printf("webhook enqueue error: %v", err)
+	}
+}
+
+func buildPayload(workspaceID, projectID string, encrypted bool, event models.WSEvent, now time.Time) ([]byte, string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	document := event.Document
+	if encrypted {
+		redacted, err := redactDocument(document)
+		if err != nil {
+			return nil, "", err
+		}
+		document = redacted
+	}
+	name := event.Collection + "." + event.Type
+	payload, err := json.Marshal(models.WebhookEvent{
+		ID:          hex.EncodeToString(id),
+		Event:       name,
+		Type:        event.Type,
+		Collection:  event.Collection,
+		WorkspaceID: workspaceID,
+		ProjectID:   projectID,
+		ActorUserID: event.UserID,
+		IsEncrypted: encrypted,
+		OccurredAt:  now,
+		Document:    document,
+	})
+	return payload, name, err
+}
+
+// redactDocument reduces a document, or a list of documents, to its identifier
+// fields.
+func redactDocument(document interface{}) (interface{}, error) {
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]interface{}
+	if json.Unmarshal(raw, &list) == nil {
+		out := make([]map[string]interface{}, 0, len(list))
+		for _, item := range list {
+			out = append(out, keepFields(item))
+		}
+		return out, nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return map[string]interface{}{}, nil
+	}
+	return keepFields(object), nil
+}
+
+func keepFields(object map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, field := range encryptedDocumentFields {
+		if value, ok := object[field]; ok {
+			if _, isString := value.(string); isString {
+				out[field] = value
+			}
+		}
+	}
+	return out
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers recompute
+// it from the timestamp header and the raw request body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post sends one signed delivery and returns the receiver's status code. Any
+// 2xx response counts as success.
+func Post(ctx context.Context, client *http.Client, dispatch models.WebhookDispatch, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(dispatch.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "justspace-webhooks/1")
+	req.Header.Set(EventHeader, dispatch.Event)
+	req.Header.Set(DeliveryHeader, dispatch.DeliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(dispatch.Secret, timestamp, dispatch.Payload))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the retry delay after every failed attempt: 30s, 1m, 2m, …
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+func (d *Dispatcher) Run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		d.dispatch(context.Background())
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	dispatches, err := d.repo.ClaimWebhookDeliveries(ctx, claimBatch)
+	if err != nil {
+		log.Printf("webhook claim error: %v", err)
+		return
+	}
+	for _, dispatch := range dispatches {
+		attempts := dispatch.Attempts + 1
+		statusCode, postErr := Post(ctx, d.client, dispatch, time.Now())
+		var code *int
+		if statusCode != 0 {
+			code = &statusCode
+		}
+		status, next := "succeeded", time.Now()
+		var lastError *string
+		if postErr != nil {
+			message := postErr.Error()
+			lastError = &message
+			status, next = "pending", time.Now().Add(backoff(attempts))
+			if attempts >= maxAttempts {
+				status = "failed"
+			}
+		}
+		if err := d.repo.RecordWebhookAttempt(ctx, dispatch.DeliveryID, status, attempts, code, lastError, next); err != nil {
+			log.Printf("webhook record error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/justlabv1/justspace/backend/internal/models"
+)
+
+func TestPostSignsPayload(t *testing.T) {
+	const secret = "receiver-secret-0123456789"
+	payload := []byte(`{"event":"tasks.create"}`)
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		expected := "sha256=" + Sign(secret, timestamp, body)
+		verified = hmac.Equal([]byte(expected), []byte(r.Header.Get(SignatureHeader))) &&
+			r.Header.Get(EventHeader) == "tasks.create" && r.Header.Get(DeliveryHeader) == "delivery-1"
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatch := models.WebhookDispatch{DeliveryID: "delivery-1", Event: "tasks.create", Payload: payload, URL: server.URL, Secret: secret}
+	status, err := Post(context.Background(), server.Client(), dispatch, time.Unix(1700000000, 0))
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Post() = (%d, %v), want (204, nil)", status, err)
+	}
+	if !verified {
+		t.Fatal("receiver could not verify the signature")
+	}
+}
+
+func TestPostFailsOnNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	status, err := Post(context.Background(), server.Client(), models.WebhookDispatch{URL: server.URL, Secret: "s"}, time.Now())
+	if err == nil || status != http.StatusBadGateway {
+		t.Fatalf("Post() = (%d, %v), want (502, error)", status, err)
+	}
+}
+
+func TestBuildPayloadRedactsEncryptedProjects(t *testing.T) {
+	document := map[string]interface{}{"id": "task-1", "projectId": "project-1", "title": "envelope", "description": "envelope"}
+	tests := []struct {
+		name      string
+		encrypted bool
+		wantTitle bool
+	}{
+		{name: "plain project keeps fields", encrypted: false, wantTitle: true},
+		{name: "encrypted project keeps ids only", encrypted: true, wantTitle: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := models.WSEvent{Type: "update", Collection: "tasks", Document: document, UserID: "user-1"}
+			raw, name, err := buildPayload("ws-1", "project-1", tt.encrypted, event, time.Now())
+			if err != nil || name != "tasks.update" {
+				t.Fatalf("buildPayload() = (%q, %v)", name, err)
+			}
+			var decoded struct {
+				Document map[string]interface{} `json:"document"`
+			}
+			if err := json.Unmarshal(raw, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := decoded.Document["title"]; ok != tt.wantTitle {
+				t.Fatalf("title present = %v, want %v", ok, tt.wantTitle)
+			}
+			if decoded.Document["id"] != "task-1" {
+				t.Fatalf("id = %v, want task-1", decoded.Document["id"])
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 4, want: 4 * time.Minute},
+		{attempts: 20, want: maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Fatalf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
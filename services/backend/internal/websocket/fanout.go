@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Every replica delivers events to its own sockets immediately and relays them
+// through PostgreSQL NOTIFY so the other replicas can deliver to theirs.
+const fanoutChannel = "justspace_ws"
+
+// NOTIFY rejects payloads of 8000 bytes or more; anything larger is stored in
+// realtime_payloads and only the row id is sent.
+const maxNotifyPayloadBytes = 7900
+
+const realtimePayloadTTL = 5 * time.Minute
+
+// relay is the PostgreSQL side of the fan-out, implemented by
+// *repository.Repo.
+type relay interface {
+	PublishRealtime(ctx context.Context, channel, payload string) error
+	ListenRealtime(ctx context.Context, channel string, handle func(payload string)) error
+	CreateRealtimePayload(ctx context.Context, payload string) (int64, error)
+	GetRealtimePayload(ctx context.Context, id int64) (string, error)
+	DeleteExpiredRealtimePayloads(ctx context.Context, before time.Time) error
+}
+
+type fanoutMessage struct {
+	Origin     string          `json:"o"`
+	UserIDs    []string        `json:"u,omitempty"`
+	Data       json.RawMessage `json:"d,omitempty"`
+	Disconnect string          `json:"x,omitempty"`
+	Ref        int64           `json:"r,omitempty"`
+}
+
+func newReplicaID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+func (h *Hub) publish(msg fanoutMessage) {
+	if h.relay == nil {
+		return
+	}
+	msg.Origin = h.replicaID
+	select {
+	case h.outbound <- msg:
+	default:
+		log.Printf("WS fan-out queue full, dropping event for other replicas")
+	}
+}
+
+func (h *Hub) runPublisher() {
+	cleanup := time.NewTicker(time.Minute)
+	defer cleanup.Stop()
+	for {
+		select {
+		case msg := <-h.outbound:
+			if err := h.send(msg); err != nil {
+				log.Printf("WS fan-out publish error: %v", err)
+			}
+		case <-cleanup.C:
+			if err := h.relay.DeleteExpiredRealtimePayloads(context.Background(), time.Now().Add(-realtimePayloadTTL)); err != nil {
+				log.Printf("WS fan-out cleanup error: %v", err)
+			}
+		}
+	}
+}
+
+func (h *Hub) send(msg fanoutMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayloadBytes {
+		id, err := h.relay.CreateRealtimePayload(ctx, string(payload))
+		if err != nil {
+			return err
+		}
+		payload, err = json.Marshal(fanoutMessage{Origin: msg.Origin, Ref: id})
+		if err != nil {
+			return err
+		}
+	}
+	return h.relay.PublishRealtime(ctx, fanoutChannel, string(payload))
+}
+
+func (h *Hub) runListener() {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := h.relay.ListenRealtime(context.Background(), fanoutChannel, h.receive)
+		log.Printf("WS fan-out listener stopped: %v", err)
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (h *Hub) receive(payload string) {
+	var msg fanoutMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("WS fan-out decode error: %v", err)
+		return
+	}
+	if msg.Origin == h.replicaID {
+		return
+	}
+	if msg.Ref != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		stored, err := h.relay.GetRealtimePayload(ctx, msg.Ref)
+		cancel()
+		if err != nil || stored == "" {
+			log.Printf("WS fan-out payload %d unavailable: %v", msg.Ref, err)
+			return
+		}
+		if err := json.Unmarshal([]byte(stored), &msg); err != nil {
+			log.Printf("WS fan-out decode error: %v", err)
+			return
+		}
+	}
+	if msg.Disconnect != "" {
+		h.disconnect <- msg.Disconnect
+		return
+	}
+	for _, userID := range msg.UserIDs {
+		h.broadcast <- broadcastMsg{userID: userID, data: msg.Data}
+	}
+}
@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRelay stands in for PostgreSQL: it records NOTIFY payloads and keeps
+// stored payloads in memory.
+type fakeRelay struct {
+	published []string
+	stored    map[int64]string
+	nextID    int64
+}
+
+func newFakeRelay() *fakeRelay {
+	return &fakeRelay{stored: map[int64]string{}}
+}
+
+func (f *fakeRelay) PublishRealtime(_ context.Context, _, payload string) error {
+	f.published = append(f.published, payload)
+	return nil
+}
+
+func (f *fakeRelay) ListenRealtime(ctx context.Context, _ string, _ func(string)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fakeRelay) CreateRealtimePayload(_ context.Context, payload string) (int64, error) {
+	f.nextID++
+	f.stored[f.nextID] = payload
+	return f.nextID, nil
+}
+
+func (f *fakeRelay) GetRealtimePayload(_ context.Context, id int64) (string, error) {
+	return f.stored[id], nil
+}
+
+func (f *fakeRelay) DeleteExpiredRealtimePayloads(context.Context, time.Time) error {
+	return nil
+}
+
+func newTestHub(relay *fakeRelay) *Hub {
+	hub := NewHub("secret", "", nil)
+	hub.relay = relay
+	return hub
+}
+
+// drain returns the messages the hub queued for its own sockets.
+func drain(hub *Hub) []broadcastMsg {
+	messages := []broadcastMsg{}
+	for {
+		select {
+		case msg := <-hub.broadcast:
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestPublishStampsOrigin(t *testing.T) {
+	hub := newTestHub(newFakeRelay())
+	hub.publish(fanoutMessage{UserIDs: []string{"u1"}, Data: json.RawMessage(`{}`)})
+	select {
+	case msg := <-hub.outbound:
+		if msg.Origin != hub.replicaID {
+			t.Fatalf("origin = %q, want %q", msg.Origin, hub.replicaID)
+		}
+	default:
+		t.Fatal("publish did not queue the message")
+	}
+
+	local := NewHub("secret", "", nil)
+	local.publish(fanoutMessage{Disconnect: "u1"})
+	if len(local.outbound) != 0 {
+		t.Fatal("hub without a relay queued a message")
+	}
+}
+
+func TestFanoutDeliversToOtherReplicas(t *testing.T) {
+	relay := newFakeRelay()
+	sender, receiver := newTestHub(relay), newTestHub(relay)
+	data := json.RawMessage(`{"type":"update","collection":"tasks"}`)
+	if err := sender.send(fanoutMessage{Origin: sender.replicaID, UserIDs: []string{"u1", "u2"}, Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if len(relay.published) != 1 || len(relay.stored) != 0 {
+		t.Fatalf("published %d, stored %d, want 1 and 0", len(relay.published), len(relay.stored))
+	}
+
+	sender.receive(relay.published[0])
+	if got := drain(sender); len(got) != 0 {
+		t.Fatalf("sender redelivered its own event: %v", got)
+	}
+	receiver.receive(relay.published[0])
+	got := drain(receiver)
+	if len(got) != 2 || got[0].userID != "u1" || got[1].userID != "u2" || string(got[0].data) != string(data) {
+		t.Fatalf("delivered %+v", got)
+	}
+}
+
+func TestFanoutStoresLargePayloads(t *testing.T) {
+	relay := newFakeRelay()
+	sender, receiver := newTestHub(relay), newTestHub(relay)
+	data, _ := json.Marshal(map[string]string{"description": strings.Repeat("x", maxNotifyPayloadBytes)})
+	if err := sender.send(fanoutMessage{Origin: sender.replicaID, UserIDs: []string{"u1"}, Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if len(relay.published) != 1 || len(relay.stored) != 1 {
+		t.Fatalf("published %d, stored %d, want 1 and 1", len(relay.published), len(relay.stored))
+	}
+	if len(relay.published[0]) > maxNotifyPayloadBytes {
+		t.Fatalf("NOTIFY payload is %d bytes", len(relay.published[0]))
+	}
+	var reference fanoutMessage
+	if err := json.Unmarshal([]byte(relay.published[0]), &reference); err != nil {
+		t.Fatal(err)
+	}
+	if reference.Ref == 0 || reference.Origin != sender.replicaID || reference.Data != nil {
+		t.Fatalf("reference = %+v", reference)
+	}
+
+	receiver.receive(relay.published[0])
+	got := drain(receiver)
+	if len(got) != 1 || got[0].userID != "u1" || string(got[0].data) != string(data) {
+		t.Fatalf("delivered %d messages", len(got))
+	}
+}
+
+func TestFanoutReceive(t *testing.T) {
+	hub := newTestHub(newFakeRelay())
+	tests := []struct {
+		name           string
+		payload        string
+		wantDisconnect string
+		wantDelivered  int
+	}{
+		{name: "own replica", payload: `{"o":"` + hub.replicaID + `","u":["u1"],"d":{}}`},
+		{name: "malformed", payload: `{"o":`},
+		{name: "expired reference", payload: `{"o":"other","r":42}`},
+		{name: "disconnect", payload: `{"o":"other","x":"u1"}`, wantDisconnect: "u1"},
+		{name: "broadcast", payload: `{"o":"other","u":["u1"],"d":{"a":1}}`, wantDelivered: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub.receive(tt.payload)
+			if got := drain(hub); len(got) != tt.wantDelivered {
+				t.Fatalf("delivered %d messages, want %d", len(got), tt.wantDelivered)
+			}
+			select {
+			case userID := <-hub.disconnect:
+				if userID != tt.wantDisconnect {
+					t.Fatalf("disconnected %q, want %q", userID, tt.wantDisconnect)
+				}
+			default:
+				if tt.wantDisconnect != "" {
+					t.Fatalf("did not disconnect %q", tt.wantDisconnect)
+				}
+			}
+		})
+	}
+}
@@ -33,6 +33,9 @@ type Hub struct {
 	jwtSecret      string
 	allowedOrigins map[string]struct{}
 	repo           *repository.Repo
+	relay          relay
+	replicaID      string
+	outbound       chan fanoutMessage
 }
 
 type broadcastMsg struct {
@@ -47,7 +50,7 @@ func NewHub(jwtSecret, corsOrigins string, repo *repository.Repo) *Hub {
 			allowedOrigins[origin] = struct{}{}
 		}
 	}
-	return &Hub{
+	hub := &Hub{
 		clients:        make(map[*Client]bool),
 		broadcast:      make(chan broadcastMsg, 256),
 		register:       make(chan *Client),
@@ -56,10 +59,20 @@ func NewHub(jwtSecret, corsOrigins string, repo *repository.Repo) *Hub {
 		jwtSecret:      jwtSecret,
 		allowedOrigins: allowedOrigins,
 		repo:           repo,
+		replicaID:      newReplicaID(),
+		outbound:       make(chan fanoutMessage, 1024),
 	}
+	if repo != nil {
+		hub.relay = repo
+	}
+	return hub
 }
 
 func (h *Hub) Run() {
+	if h.relay != nil {
+		go h.runPublisher()
+		go h.runListener()
+	}
 	for {
 		select {
 		case client := <-h.register:
@@ -109,6 +122,7 @@ func (h *Hub) Run() {
 func (h *Hub) DisconnectUser(userID string) {
 	if userID != "" {
 		h.disconnect <- userID
+		h.publish(fanoutMessage{Disconnect: userID})
 	}
 }
 
@@ -119,6 +133,7 @@ func (h *Hub) Broadcast(userID string, event interface{}) {
 		return
 	}
 	h.broadcast <- broadcastMsg{userID: userID, data: data}
+	h.publish(fanoutMessage{UserIDs: []string{userID}, Data: data})
 }
 
 func (h *Hub) BroadcastUsers(userIDs []string, event interface{}) {
@@ -130,6 +145,9 @@ func (h *Hub) BroadcastUsers(userIDs []string, event interface{}) {
 	for _, userID := range userIDs {
 		h.broadcast <- broadcastMsg{userID: userID, data: data}
 	}
+	if len(userIDs) > 0 {
+		h.publish(fanoutMessage{UserIDs: userIDs, Data: data})
+	}
 }
 
 func (h *Hub) HandleWS(w http.ResponseWriter, r *http.Request) {
@@ -181,6 +199,17 @@ func (h *Hub) HandleWS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "session expired", http.StatusUnauthorized)
 		return
 	}
+	// Revoking a session calls DisconnectUser; the user's other sessions
+	// reconnect while the revoked one is refused here.
+	sessionID, _ := claims["jti"].(string)
+	if sessionID == "" {
+		http.Error(w, "session expired", http.StatusUnauthorized)
+		return
+	}
+	if valid, err := h.repo.ValidateUserSession(r.Context(), sessionID, userID, ""); err != nil || !valid {
+		http.Error(w, "session revoked", http.StatusUnauthorized)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WS upgrade error: %v", err)
@@ -15,10 +15,12 @@ import (
 	"github.com/justlabv1/justspace/backend/internal/config"
 	"github.com/justlabv1/justspace/backend/internal/database"
 	"github.com/justlabv1/justspace/backend/internal/handlers"
+	"github.com/justlabv1/justspace/backend/internal/mailer"
 	"github.com/justlabv1/justspace/backend/internal/middleware"
 	"github.com/justlabv1/justspace/backend/internal/reminders"
 	"github.com/justlabv1/justspace/backend/internal/repository"
 	"github.com/justlabv1/justspace/backend/internal/storage"
+	"github.com/justlabv1/justspace/backend/internal/webhooks"
 	"github.com/justlabv1/justspace/backend/internal/websocket"
 )
 
@@ -54,30 +56,58 @@ func main() {
 	}
 
 	repo := repository.New(pool)
-	fileStore, err := storage.NewFileStore(cfg.FileStorageRoot)
+	fileStore, err := storage.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize file storage: %v", err)
 	}
+	if cfg.StorageMode != "serve" {
+		if err := runStorageMode(cfg, repo, fileStore); err != nil {
+			log.Fatalf("Storage %s failed: %v", cfg.StorageMode, err)
+		}
+		return
+	}
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure email: %v", err)
+	}
 	hub := websocket.NewHub(cfg.JWTSecret, cfg.CORSOrigin, repo)
 	go hub.Run()
 	go reminders.NewDeadlineService(repo, hub).Run()
+	go reminders.NewBudgetAlertService(repo, hub, cfg.BudgetAlertThresholds).Run()
 	go reminders.RunAdminAuditRetention(repo)
+	go reminders.RunSessionRetention(repo)
+	go reminders.RunFileBlobCleanup(repo, fileStore)
+	go reminders.RunUploadSessionCleanup(repo, fileStore)
+	go reminders.NewThumbnailService(repo, fileStore, hub).Run()
+	go reminders.NewEmailService(repo, mail).Run()
+	webhookDispatcher := webhooks.NewDispatcher(repo)
+	go webhookDispatcher.Run()
 
-	authH := handlers.NewAuthHandler(repo, cfg.JWTSecret, cfg.OIDCEncryptionKey, cfg.CORSOrigin, hub, fileStore)
-	projectH := handlers.NewProjectHandler(repo, hub)
-	taskH := handlers.NewTaskHandler(repo, hub)
+	authH := handlers.NewAuthHandler(repo, cfg.JWTSecret, cfg.OIDCEncryptionKey, cfg.CORSOrigin, hub, fileStore, mail)
+	projectH := handlers.NewProjectHandler(repo, hub, webhookDispatcher)
+	taskH := handlers.NewTaskHandler(repo, hub, webhookDispatcher)
 	wikiH := handlers.NewWikiHandler(repo, hub)
 	installH := handlers.NewInstallationHandler(repo, hub)
 	snippetH := handlers.NewSnippetHandler(repo, hub)
 	activityH := handlers.NewActivityHandler(repo)
+	searchH := handlers.NewSearchHandler(repo)
 	notificationH := handlers.NewNotificationHandler(repo, hub)
 	vaultH := handlers.NewVaultHandler(repo)
 	accessH := handlers.NewAccessHandler(repo, hub)
 	versionH := handlers.NewVersionHandler(repo)
-	collabH := handlers.NewCollaborationHandler(repo, hub, fileStore, cfg.MaxUploadBytes)
-	workspaceH := handlers.NewWorkspaceHandler(repo, hub)
-	customerH := handlers.NewCustomerHandler(repo)
+	collabH := handlers.NewCollaborationHandler(repo, hub, fileStore, handlers.UploadLimits{
+		MaxUploadBytes:          cfg.MaxUploadBytes,
+		MaxResumableUploadBytes: cfg.MaxResumableUploadBytes,
+		ProjectQuotaBytes:       cfg.ProjectStorageQuotaBytes,
+	}, webhookDispatcher, mail)
+	workspaceH := handlers.NewWorkspaceHandler(repo, hub, mail)
+	customerH := handlers.NewCustomerHandler(repo, hub)
 	milestoneH := handlers.NewMilestoneHandler(repo, hub)
+	savedViewH := handlers.NewSavedViewHandler(repo, hub)
+	sprintH := handlers.NewSprintHandler(repo, hub)
+	analyticsH := handlers.NewAnalyticsHandler(repo)
+	timeEntryH := handlers.NewTimeEntryHandler(repo, hub)
+	webhookH := handlers.NewWebhookHandler(repo)
 
 	r := chi.NewRouter()
 	r.Use(chimw.Logger)
@@ -97,6 +127,11 @@ func main() {
 	r.Post("/api/auth/signup", authH.Signup)
 	r.Post("/api/auth/login", authH.Login)
 	r.Post("/api/auth/logout", authH.Logout)
+	r.Post("/api/auth/password/forgot", authH.ForgotPassword)
+	r.Post("/api/auth/password/reset", authH.ResetPassword)
+	r.Post("/api/auth/verify", authH.VerifyEmail)
+	r.Post("/api/auth/verify/resend", authH.ResendVerification)
+	r.Post("/api/auth/mfa/verify", authH.VerifyMFA)
 	r.Get("/api/auth/config", authH.AuthConfig)
 	r.Get("/api/platform/branding", authH.PublicBranding)
 	r.Get("/api/platform/branding/logo/{size}", authH.PublicBrandLogo)
@@ -108,15 +143,27 @@ func main() {
 		r.Use(middleware.Auth(cfg.JWTSecret, repo))
 
 		r.Get("/api/auth/me", authH.Me)
-		r.Put("/api/auth/profile", authH.UpdateProfile)
-		r.Get("/api/auth/oidc/identities", authH.ListOIDCIdentities)
-		r.Delete("/api/auth/oidc/identities/{identityId}", authH.DeleteOIDCIdentity)
-		r.Get("/api/auth/oidc/{provider}/link", authH.OIDCStartLink)
+		r.With(middleware.RequireSession).Put("/api/auth/profile", authH.UpdateProfile)
+		r.With(middleware.RequireSession).Get("/api/auth/oidc/identities", authH.ListOIDCIdentities)
+		r.With(middleware.RequireSession).Delete("/api/auth/oidc/identities/{identityId}", authH.DeleteOIDCIdentity)
+		r.With(middleware.RequireSession).Get("/api/auth/oidc/{provider}/link", authH.OIDCStartLink)
+		r.With(middleware.RequireSession).Get("/api/auth/tokens", authH.ListTokens)
+		r.With(middleware.RequireSession).Post("/api/auth/tokens", authH.CreateToken)
+		r.With(middleware.RequireSession).Delete("/api/auth/tokens/{tokenId}", authH.RevokeToken)
+		r.With(middleware.RequireSession).Get("/api/auth/sessions", authH.ListSessions)
+		r.With(middleware.RequireSession).Delete("/api/auth/sessions", authH.RevokeOtherSessions)
+		r.With(middleware.RequireSession).Delete("/api/auth/sessions/{sessionId}", authH.RevokeSession)
+		r.With(middleware.RequireSession).Get("/api/auth/mfa", authH.MFAStatus)
+		r.With(middleware.RequireSession).Post("/api/auth/mfa/setup", authH.SetupMFA)
+		r.With(middleware.RequireSession).Post("/api/auth/mfa/enable", authH.EnableMFA)
+		r.With(middleware.RequireSession).Post("/api/auth/mfa/disable", authH.DisableMFA)
+		r.With(middleware.RequireSession).Post("/api/auth/mfa/recovery-codes", authH.RegenerateRecoveryCodes)
 
 		r.Get("/api/workspaces", workspaceH.List)
-		r.Post("/api/workspaces", workspaceH.Create)
+		r.With(middleware.RequireUnscopedToken).Post("/api/workspaces", workspaceH.Create)
 		r.Put("/api/workspaces/{id}", workspaceH.Update)
 		r.Get("/api/workspaces/{workspaceId}/members", workspaceH.ListMembers)
+		r.Get("/api/workspaces/{workspaceId}/storage", workspaceH.StorageUsage)
 		r.Post("/api/workspaces/{workspaceId}/members", workspaceH.AddMember)
 		r.Put("/api/workspaces/{workspaceId}/members/{userId}", workspaceH.UpdateMemberRole)
 		r.Delete("/api/workspaces/{workspaceId}/members/{userId}", workspaceH.RemoveMember)
@@ -126,20 +173,37 @@ func main() {
 		r.Get("/api/workspaces/{workspaceId}/customers", customerH.List)
 		r.Post("/api/workspaces/{workspaceId}/customers", customerH.Create)
 		r.Put("/api/workspaces/{workspaceId}/customers/{customerId}", customerH.Update)
+		r.Get("/api/workspaces/{workspaceId}/reports/timesheet", customerH.Timesheet)
+		r.Get("/api/workspaces/{workspaceId}/reports/budgets", customerH.BudgetReport)
+		r.Get("/api/workspaces/{workspaceId}/capacity", customerH.Capacity)
+		r.Get("/api/workspaces/{workspaceId}/absences", customerH.ListAbsences)
+		r.Post("/api/workspaces/{workspaceId}/absences", customerH.CreateAbsence)
+		r.Delete("/api/workspaces/{workspaceId}/absences/{absenceId}", customerH.DeleteAbsence)
+		r.Get("/api/workspaces/{workspaceId}/views", savedViewH.ListByWorkspace)
+		r.Post("/api/workspaces/{workspaceId}/views", savedViewH.CreateForWorkspace)
+		r.Get("/api/workspaces/{workspaceId}/webhooks", webhookH.List)
+		r.Post("/api/workspaces/{workspaceId}/webhooks", webhookH.Create)
+		r.Put("/api/workspaces/{workspaceId}/webhooks/{webhookId}", webhookH.Update)
+		r.Delete("/api/workspaces/{workspaceId}/webhooks/{webhookId}", webhookH.Delete)
+		r.Get("/api/workspaces/{workspaceId}/webhooks/{webhookId}/deliveries", webhookH.ListDeliveries)
+		r.Post("/api/workspaces/{workspaceId}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", webhookH.Redeliver)
 
-		r.Get("/api/admin/settings", authH.AdminSettings)
-		r.Put("/api/admin/settings", authH.UpdateAdminSettings)
-		r.Get("/api/admin/branding", authH.AdminBranding)
-		r.Put("/api/admin/branding", authH.UpdateAdminBranding)
-		r.Post("/api/admin/branding/logo", authH.UploadBrandLogo)
-		r.Delete("/api/admin/branding/logo", authH.DeleteBrandLogo)
-		r.Get("/api/admin/overview", authH.AdminOverview)
-		r.Get("/api/admin/audit", authH.AdminAudit)
-		r.Get("/api/admin/users", authH.AdminUsers)
-		r.Patch("/api/admin/users/{userId}", authH.UpdateAdminUser)
-		r.Post("/api/admin/oidc/providers", authH.AdminCreateOIDCProvider)
-		r.Put("/api/admin/oidc/providers/{providerId}", authH.AdminUpdateOIDCProvider)
-		r.Delete("/api/admin/oidc/providers/{providerId}", authH.AdminDeleteOIDCProvider)
+		r.With(middleware.RequireUnscopedToken).Get("/api/admin/settings", authH.AdminSettings)
+		r.With(middleware.RequireUnscopedToken).Put("/api/admin/settings", authH.UpdateAdminSettings)
+		r.With(middleware.RequireUnscopedToken).Get("/api/admin/branding", authH.AdminBranding)
+		r.With(middleware.RequireUnscopedToken).Put("/api/admin/branding", authH.UpdateAdminBranding)
+		r.With(middleware.RequireUnscopedToken).Post("/api/admin/branding/logo", authH.UploadBrandLogo)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/admin/branding/logo", authH.DeleteBrandLogo)
+		r.With(middleware.RequireUnscopedToken).Get("/api/admin/overview", authH.AdminOverview)
+		r.With(middleware.RequireUnscopedToken).Put("/api/admin/workspaces/{workspaceId}/storage-quota", authH.AdminUpdateWorkspaceStorageQuota)
+		r.With(middleware.RequireUnscopedToken).Get("/api/admin/audit", authH.AdminAudit)
+		r.With(middleware.RequireUnscopedToken).Get("/api/admin/users", authH.AdminUsers)
+		r.With(middleware.RequireUnscopedToken).Patch("/api/admin/users/{userId}", authH.UpdateAdminUser)
+		r.With(middleware.RequireUnscopedToken).Get("/api/admin/users/{userId}/sessions", authH.AdminUserSessions)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/admin/users/{userId}/mfa", authH.AdminResetUserMFA)
+		r.With(middleware.RequireUnscopedToken).Post("/api/admin/oidc/providers", authH.AdminCreateOIDCProvider)
+		r.With(middleware.RequireUnscopedToken).Put("/api/admin/oidc/providers/{providerId}", authH.AdminUpdateOIDCProvider)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/admin/oidc/providers/{providerId}", authH.AdminDeleteOIDCProvider)
 
 		r.Get("/api/projects", projectH.List)
 		r.Post("/api/projects", projectH.Create)
@@ -155,6 +219,26 @@ func main() {
 		r.Post("/api/projects/{projectId}/milestones", milestoneH.Create)
 		r.Put("/api/milestones/{id}", milestoneH.Update)
 		r.Delete("/api/milestones/{id}", milestoneH.Delete)
+		r.Get("/api/projects/{projectId}/sprints", sprintH.List)
+		r.Post("/api/projects/{projectId}/sprints", sprintH.Create)
+		r.Get("/api/sprints/{id}", sprintH.Get)
+		r.Put("/api/sprints/{id}", sprintH.Update)
+		r.Delete("/api/sprints/{id}", sprintH.Delete)
+		r.Post("/api/sprints/{id}/start", sprintH.Start)
+		r.Post("/api/sprints/{id}/close", sprintH.Close)
+		r.Get("/api/sprints/{id}/report", sprintH.Report)
+		r.Get("/api/sprints/{id}/tasks", sprintH.ListTasks)
+		r.Post("/api/sprints/{id}/tasks", sprintH.AddTasks)
+		r.Delete("/api/sprints/{id}/tasks/{taskId}", sprintH.RemoveTask)
+		r.Get("/api/projects/{projectId}/analytics/burndown", analyticsH.Burndown)
+		r.Get("/api/projects/{projectId}/analytics/throughput", analyticsH.Throughput)
+		r.Get("/api/projects/{projectId}/analytics/cycle-time", analyticsH.CycleTime)
+		r.Get("/api/projects/{projectId}/analytics/time-in-status", analyticsH.TimeInStatus)
+		r.Get("/api/projects/{projectId}/views", savedViewH.ListByProject)
+		r.Post("/api/projects/{projectId}/views", savedViewH.CreateForProject)
+		r.Get("/api/views/{id}", savedViewH.Get)
+		r.Put("/api/views/{id}", savedViewH.Update)
+		r.Delete("/api/views/{id}", savedViewH.Delete)
 		r.Post("/api/projects/{projectId}/task-statuses", projectH.CreateTaskStatus)
 		r.Put("/api/projects/{projectId}/task-statuses/reorder", projectH.ReorderTaskStatuses)
 		r.Put("/api/projects/{projectId}/task-statuses/{statusId}", projectH.UpdateTaskStatus)
@@ -169,6 +253,16 @@ func main() {
 		r.Put("/api/projects/{projectId}/tasks/reorder", taskH.Reorder)
 		r.Put("/api/tasks/{id}", taskH.Update)
 		r.Delete("/api/tasks/{id}", taskH.Delete)
+		r.Get("/api/tasks/{id}/recurrence", taskH.Recurrence)
+		r.Post("/api/tasks/{id}/recurrence/skip", taskH.SkipOccurrence)
+		r.Get("/api/tasks/{taskId}/time-entries", timeEntryH.ListByTask)
+		r.Post("/api/tasks/{taskId}/time-entries", timeEntryH.CreateForTask)
+		r.Get("/api/time-entries", timeEntryH.List)
+		r.Get("/api/time-entries/running", timeEntryH.Running)
+		r.Post("/api/time-entries/start", timeEntryH.Start)
+		r.Post("/api/time-entries/stop", timeEntryH.Stop)
+		r.Put("/api/time-entries/{id}", timeEntryH.Update)
+		r.Delete("/api/time-entries/{id}", timeEntryH.Delete)
 		r.Get("/api/tasks/{taskId}/assignees", collabH.ListTaskAssignees)
 		r.Post("/api/tasks/{taskId}/assignees", collabH.AddTaskAssignee)
 		r.Delete("/api/tasks/{taskId}/assignees/{userId}", collabH.RemoveTaskAssignee)
@@ -183,38 +277,39 @@ func main() {
 		r.Post("/api/collaboration/documents/{documentId}/updates", collabH.CreateCollaborationUpdate)
 		r.Post("/api/collaboration/documents/{documentId}/awareness", collabH.BroadcastCollaborationAwareness)
 
-		r.Get("/api/wiki", wikiH.List)
-		r.Post("/api/wiki", wikiH.Create)
-		r.Get("/api/wiki/{id}", wikiH.Get)
-		r.Put("/api/wiki/{id}", wikiH.Update)
-		r.Delete("/api/wiki/{id}", wikiH.Delete)
+		r.With(middleware.RequireUnscopedToken).Get("/api/wiki", wikiH.List)
+		r.With(middleware.RequireUnscopedToken).Post("/api/wiki", wikiH.Create)
+		r.With(middleware.RequireUnscopedToken).Get("/api/wiki/{id}", wikiH.Get)
+		r.With(middleware.RequireUnscopedToken).Put("/api/wiki/{id}", wikiH.Update)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/wiki/{id}", wikiH.Delete)
 
-		r.Post("/api/installations", installH.Create)
-		r.Put("/api/installations/{id}", installH.Update)
-		r.Delete("/api/installations/{id}", installH.Delete)
+		r.With(middleware.RequireUnscopedToken).Post("/api/installations", installH.Create)
+		r.With(middleware.RequireUnscopedToken).Put("/api/installations/{id}", installH.Update)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/installations/{id}", installH.Delete)
 
-		r.Get("/api/snippets", snippetH.List)
-		r.Post("/api/snippets", snippetH.Create)
-		r.Put("/api/snippets/{id}", snippetH.Update)
-		r.Delete("/api/snippets/{id}", snippetH.Delete)
+		r.With(middleware.RequireUnscopedToken).Get("/api/snippets", snippetH.List)
+		r.With(middleware.RequireUnscopedToken).Post("/api/snippets", snippetH.Create)
+		r.With(middleware.RequireUnscopedToken).Put("/api/snippets/{id}", snippetH.Update)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/snippets/{id}", snippetH.Delete)
 
-		r.Get("/api/activity", activityH.List)
-		r.Get("/api/notifications", notificationH.List)
-		r.Get("/api/notifications/unread-count", notificationH.UnreadCount)
-		r.Post("/api/notifications/{id}/read", notificationH.MarkRead)
-		r.Delete("/api/notifications/{id}", notificationH.Delete)
+		r.With(middleware.RequireUnscopedToken).Get("/api/activity", activityH.List)
+		r.With(middleware.RequireUnscopedToken).Get("/api/search", searchH.Search)
+		r.With(middleware.RequireUnscopedToken).Get("/api/notifications", notificationH.List)
+		r.With(middleware.RequireUnscopedToken).Get("/api/notifications/unread-count", notificationH.UnreadCount)
+		r.With(middleware.RequireUnscopedToken).Post("/api/notifications/{id}/read", notificationH.MarkRead)
+		r.With(middleware.RequireUnscopedToken).Delete("/api/notifications/{id}", notificationH.Delete)
 
-		r.Get("/api/vault/keys", vaultH.GetKeys)
-		r.Post("/api/vault/keys", vaultH.CreateKeys)
-		r.Put("/api/vault/keys/{id}", vaultH.UpdateKeys)
+		r.With(middleware.RequireUnscopedToken).Get("/api/vault/keys", vaultH.GetKeys)
+		r.With(middleware.RequireUnscopedToken).Post("/api/vault/keys", vaultH.CreateKeys)
+		r.With(middleware.RequireUnscopedToken).Put("/api/vault/keys/{id}", vaultH.UpdateKeys)
 
-		r.Get("/api/access/{resourceId}", accessH.GetKey)
-		r.Post("/api/access", accessH.Grant)
+		r.With(middleware.RequireUnscopedToken).Get("/api/access/{resourceId}", accessH.GetKey)
+		r.With(middleware.RequireUnscopedToken).Post("/api/access", accessH.Grant)
 
-		r.Get("/api/versions/{resourceId}", versionH.List)
-		r.Post("/api/versions", versionH.Create)
+		r.With(middleware.RequireUnscopedToken).Get("/api/versions/{resourceId}", versionH.List)
+		r.With(middleware.RequireUnscopedToken).Post("/api/versions", versionH.Create)
 
-		r.Get("/api/users/search", collabH.SearchUsers)
+		r.With(middleware.RequireUnscopedToken).Get("/api/users/search", collabH.SearchUsers)
 		r.Get("/api/projects/{projectId}/members", collabH.ListMembers)
 		r.Post("/api/projects/{projectId}/members", collabH.AddMember)
 		r.Put("/api/projects/{projectId}/members/{userId}", collabH.UpdateMemberRole)
@@ -222,12 +317,19 @@ func main() {
 		r.Get("/api/projects/{projectId}/invitations", collabH.ListInvitations)
 		r.Post("/api/projects/{projectId}/invitations", collabH.CreateInvitation)
 		r.Delete("/api/projects/{projectId}/invitations/{invitationId}", collabH.CancelInvitation)
-		r.Post("/api/invitations/accept", collabH.AcceptInvitation)
+		r.With(middleware.RequireUnscopedToken).Post("/api/invitations/accept", collabH.AcceptInvitation)
 		r.Get("/api/projects/{projectId}/files", collabH.ListProjectFiles)
 		r.Post("/api/projects/{projectId}/files", collabH.UploadProjectFile)
 		r.Get("/api/tasks/{taskId}/files", collabH.ListTaskFiles)
 		r.Post("/api/tasks/{taskId}/files", collabH.UploadTaskFile)
+		r.Post("/api/projects/{projectId}/uploads", collabH.CreateProjectUpload)
+		r.Post("/api/tasks/{taskId}/uploads", collabH.CreateTaskUpload)
+		r.Head("/api/uploads/{uploadId}", collabH.UploadOffset)
+		r.Patch("/api/uploads/{uploadId}", collabH.UploadChunk)
+		r.Post("/api/uploads/{uploadId}/finalize", collabH.FinalizeUpload)
+		r.Delete("/api/uploads/{uploadId}", collabH.CancelUpload)
 		r.Get("/api/files/{fileId}", collabH.DownloadProjectFile)
+		r.Get("/api/files/{fileId}/thumbnail", collabH.ProjectFileThumbnail)
 		r.Delete("/api/files/{fileId}", collabH.DeleteProjectFile)
 		r.Get("/api/projects/{projectId}/activity", collabH.ListProjectActivity)
 	})
@@ -273,8 +375,9 @@ func corsMiddleware(origin string) func(http.Handler) http.Handler {
 					break
 				}
 			}
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset")
+			w.Header().Set("Access-Control-Expose-Headers", "Location, Tus-Resumable, Upload-Expires, Upload-Length, Upload-Offset")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Max-Age", "86400")
 			if r.Method == "OPTIONS" {
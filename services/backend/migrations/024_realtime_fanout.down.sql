@@ -0,0 +1 @@
+DROP TABLE IF EXISTS realtime_payloads;
@@ -0,0 +1,9 @@
+-- NOTIFY payloads are limited to 8000 bytes. Larger realtime events are parked
+-- here and only their id is sent over the channel; rows expire after a few minutes.
+CREATE TABLE realtime_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_realtime_payloads_created_at ON realtime_payloads (created_at);
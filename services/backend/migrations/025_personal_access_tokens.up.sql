@@ -0,0 +1,17 @@
+-- User-managed API tokens for scripts and CI. Only the SHA-256 hash is stored;
+-- the plaintext token is returned once at creation.
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(120) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    access VARCHAR(8) NOT NULL DEFAULT 'read' CHECK (access IN ('read', 'write')),
+    workspace_ids UUID[] NOT NULL DEFAULT '{}',
+    session_version BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
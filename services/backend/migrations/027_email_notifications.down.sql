@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS last_email_digest_at;
+DROP INDEX IF EXISTS idx_notifications_email_pending;
+ALTER TABLE notifications DROP COLUMN IF EXISTS emailed_at;
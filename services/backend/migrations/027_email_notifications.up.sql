@@ -0,0 +1,9 @@
+-- Tracks which notifications were emailed and when each user last received a digest.
+ALTER TABLE notifications ADD COLUMN emailed_at TIMESTAMPTZ;
+
+-- Existing notifications predate email delivery and must not be sent retroactively.
+UPDATE notifications SET emailed_at = created_at;
+
+CREATE INDEX idx_notifications_email_pending ON notifications(recipient_user_id, created_at) WHERE emailed_at IS NULL;
+
+ALTER TABLE users ADD COLUMN last_email_digest_at TIMESTAMPTZ;
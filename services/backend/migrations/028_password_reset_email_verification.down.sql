@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_auth_tokens;
+ALTER TABLE platform_settings DROP COLUMN IF EXISTS require_verified_email;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,22 @@
+-- Email verification state, the platform switch that enforces it, and the
+-- single-use tokens behind password reset and verification links.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Existing accounts were created before verification existed and stay usable
+-- when an administrator later requires verified emails.
+UPDATE users SET email_verified_at = created_at;
+
+ALTER TABLE platform_settings ADD COLUMN require_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE user_auth_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_auth_tokens_user_purpose ON user_auth_tokens(user_id, purpose);
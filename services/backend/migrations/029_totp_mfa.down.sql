@@ -0,0 +1,4 @@
+ALTER TABLE platform_settings DROP COLUMN IF EXISTS require_admin_mfa;
+DROP TABLE IF EXISTS user_mfa_recovery_codes;
+DROP TRIGGER IF EXISTS update_user_mfa_updated_at ON user_mfa;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,24 @@
+-- TOTP two-factor authentication. Secrets are encrypted with OIDC_ENCRYPTION_KEY;
+-- recovery codes are stored as SHA-256 hashes.
+CREATE TABLE user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_user_mfa_updated_at BEFORE UPDATE ON user_mfa
+FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE user_mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+ALTER TABLE platform_settings ADD COLUMN require_admin_mfa BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,18 @@
+-- Server-side registry of browser sessions. The row id is the JWT "jti" claim,
+-- so a single device can be signed out without bumping session_version.
+CREATE TABLE user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_version BIGINT NOT NULL,
+    device VARCHAR(120) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id, last_seen_at DESC);
+CREATE INDEX idx_user_sessions_expires_at ON user_sessions(expires_at);
+
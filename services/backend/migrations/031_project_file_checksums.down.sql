@@ -0,0 +1,2 @@
+ALTER TABLE project_files
+    DROP COLUMN IF EXISTS checksum_sha256;
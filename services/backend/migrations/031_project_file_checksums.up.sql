@@ -0,0 +1,2 @@
+ALTER TABLE project_files
+    ADD COLUMN IF NOT EXISTS checksum_sha256 CHAR(64);
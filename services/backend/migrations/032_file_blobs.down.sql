@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS track_project_file_blob_refs ON project_files;
+DROP FUNCTION IF EXISTS track_file_blob_refs();
+DROP INDEX IF EXISTS idx_project_files_storage_path;
+ALTER TABLE project_files DROP CONSTRAINT IF EXISTS project_files_storage_path_fkey;
+DROP TABLE IF EXISTS file_blobs;
+-- project_files.storage_path stays non-unique: deduplicated files share paths.
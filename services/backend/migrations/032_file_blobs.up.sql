@@ -0,0 +1,39 @@
+-- Stored blobs are reference-counted so identical plaintext uploads can share
+-- one blob. Each existing file keeps its own blob.
+CREATE TABLE IF NOT EXISTS file_blobs (
+    storage_path VARCHAR(1024) PRIMARY KEY,
+    checksum_sha256 CHAR(64),
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    ref_count INTEGER NOT NULL DEFAULT 0 CHECK (ref_count >= 0),
+    deduplicated BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_file_blobs_checksum ON file_blobs(checksum_sha256) WHERE deduplicated;
+CREATE INDEX IF NOT EXISTS idx_file_blobs_unreferenced ON file_blobs(created_at) WHERE ref_count = 0;
+
+INSERT INTO file_blobs (storage_path, checksum_sha256, size_bytes, ref_count)
+SELECT storage_path, checksum_sha256, size_bytes, 1 FROM project_files
+ON CONFLICT (storage_path) DO NOTHING;
+
+ALTER TABLE project_files DROP CONSTRAINT IF EXISTS project_files_storage_path_key;
+ALTER TABLE project_files
+    ADD CONSTRAINT project_files_storage_path_fkey FOREIGN KEY (storage_path) REFERENCES file_blobs(storage_path);
+CREATE INDEX IF NOT EXISTS idx_project_files_storage_path ON project_files(storage_path);
+
+-- Triggers keep ref_count right when files disappear through ON DELETE CASCADE
+-- from projects, tasks, or users. Blobs at zero are removed by the backend.
+CREATE OR REPLACE FUNCTION track_file_blob_refs()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE file_blobs SET ref_count = ref_count + 1 WHERE storage_path = NEW.storage_path;
+        RETURN NEW;
+    END IF;
+    UPDATE file_blobs SET ref_count = ref_count - 1 WHERE storage_path = OLD.storage_path;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS track_project_file_blob_refs ON project_files;
+CREATE TRIGGER track_project_file_blob_refs AFTER INSERT OR DELETE ON project_files FOR EACH ROW EXECUTE FUNCTION track_file_blob_refs();
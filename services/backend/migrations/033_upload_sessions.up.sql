@@ -0,0 +1,36 @@
+-- Resumable uploads: a session collects chunks until the declared size is
+-- reached and is then finalized into a project_files row.
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    uploader_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    encrypted_name TEXT NOT NULL,
+    content_type VARCHAR(255) NOT NULL DEFAULT 'application/octet-stream',
+    iv VARCHAR(128),
+    is_encrypted BOOLEAN NOT NULL DEFAULT TRUE,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    offset_bytes BIGINT NOT NULL DEFAULT 0 CHECK (offset_bytes >= 0 AND offset_bytes <= size_bytes),
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'finalizing')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_project_id ON upload_sessions(project_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions(expires_at);
+
+DROP TRIGGER IF EXISTS update_upload_sessions_updated_at ON upload_sessions;
+CREATE TRIGGER update_upload_sessions_updated_at BEFORE UPDATE ON upload_sessions
+FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Parts have no foreign key on purpose: when a session disappears through a
+-- cascade, its parts stay behind so the cleanup job can delete their blobs.
+CREATE TABLE IF NOT EXISTS upload_session_parts (
+    session_id UUID NOT NULL,
+    offset_bytes BIGINT NOT NULL CHECK (offset_bytes >= 0),
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    storage_path VARCHAR(1024) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, offset_bytes)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE workspaces DROP COLUMN IF EXISTS storage_quota_bytes;
+ALTER TABLE platform_settings DROP COLUMN IF EXISTS default_workspace_storage_quota_bytes;
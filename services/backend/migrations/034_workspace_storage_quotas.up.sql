@@ -0,0 +1,9 @@
+-- Workspace storage quotas in bytes. Zero means unlimited; a workspace without
+-- an override uses the platform default.
+ALTER TABLE platform_settings
+    ADD COLUMN IF NOT EXISTS default_workspace_storage_quota_bytes BIGINT NOT NULL DEFAULT 0
+    CHECK (default_workspace_storage_quota_bytes >= 0);
+
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS storage_quota_bytes BIGINT
+    CHECK (storage_quota_bytes >= 0);
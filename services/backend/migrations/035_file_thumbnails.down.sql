@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_file_blobs_thumbnail_queue;
+ALTER TABLE file_blobs
+    DROP COLUMN IF EXISTS thumbnail_claimed_at,
+    DROP COLUMN IF EXISTS thumbnail_content_type,
+    DROP COLUMN IF EXISTS thumbnail_status;
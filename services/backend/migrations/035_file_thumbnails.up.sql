@@ -0,0 +1,20 @@
+-- Thumbnails belong to the blob, so deduplicated files share them.
+ALTER TABLE file_blobs
+    ADD COLUMN IF NOT EXISTS thumbnail_status VARCHAR(16) NOT NULL DEFAULT 'none'
+        CHECK (thumbnail_status IN ('none', 'pending', 'processing', 'ready', 'failed')),
+    ADD COLUMN IF NOT EXISTS thumbnail_content_type VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS thumbnail_claimed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_file_blobs_thumbnail_queue ON file_blobs(created_at)
+    WHERE thumbnail_status IN ('pending', 'processing');
+
+-- Queue existing plaintext images in non-encrypted projects.
+UPDATE file_blobs fb SET thumbnail_status = 'pending'
+WHERE fb.thumbnail_status = 'none' AND EXISTS (
+    SELECT 1 FROM project_files pf
+    JOIN projects p ON p.id = pf.project_id
+    WHERE pf.storage_path = fb.storage_path
+      AND NOT pf.is_encrypted
+      AND NOT p.is_encrypted
+      AND lower(split_part(pf.content_type, ';', 1)) IN ('image/png', 'image/jpeg', 'image/gif', 'image/webp')
+);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_task_comments_search;
+DROP INDEX IF EXISTS idx_snippets_search;
+DROP INDEX IF EXISTS idx_installations_search;
+DROP INDEX IF EXISTS idx_wiki_guides_search;
+DROP INDEX IF EXISTS idx_tasks_search;
+
+ALTER TABLE task_comments DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE snippets DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE installations DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE wiki_guides DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
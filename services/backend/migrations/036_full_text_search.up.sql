@@ -0,0 +1,44 @@
+-- Full-text search vectors. Encrypted rows get no vector so ciphertext never
+-- reaches the index. Long bodies are cut off to stay below the tsvector size
+-- limit; the 'simple' configuration avoids language-specific stemming.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    CASE WHEN is_encrypted THEN NULL ELSE
+        setweight(to_tsvector('simple', coalesce(task_key, '') || ' ' || coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', left(coalesce(description, ''), 100000)), 'B')
+    END
+) STORED;
+
+ALTER TABLE wiki_guides ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    CASE WHEN is_encrypted THEN NULL ELSE
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', left(coalesce(description, ''), 100000)), 'B')
+    END
+) STORED;
+
+ALTER TABLE installations ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    CASE WHEN is_encrypted THEN NULL ELSE
+        setweight(to_tsvector('simple', coalesce(target, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(git_repo, '') || ' ' || coalesce(documentation, '')), 'C') ||
+        setweight(to_tsvector('simple', left(coalesce(notes, ''), 100000)), 'B')
+    END
+) STORED;
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    CASE WHEN is_encrypted THEN NULL ELSE
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+        setweight(to_tsvector('simple', left(coalesce(content, ''), 100000)), 'C')
+    END
+) STORED;
+
+ALTER TABLE task_comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    CASE WHEN is_encrypted THEN NULL ELSE
+        to_tsvector('simple', left(coalesce(body, ''), 100000))
+    END
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_wiki_guides_search ON wiki_guides USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_installations_search ON installations USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_snippets_search ON snippets USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_task_comments_search ON task_comments USING GIN (search_vector);
@@ -0,0 +1,22 @@
+-- Personal views go back into preferences.savedViews; shared views have no
+-- per-user equivalent and are dropped.
+UPDATE users u SET preferences = u.preferences || jsonb_build_object('savedViews', views.entries)
+FROM (
+    SELECT sv.owner_id, jsonb_agg(jsonb_build_object(
+        'id', sv.id::text,
+        'projectId', sv.project_id::text,
+        'name', sv.name,
+        'viewMode', sv.view_mode,
+        'searchQuery', COALESCE(sv.settings->>'searchQuery', ''),
+        'selectedTags', CASE WHEN jsonb_typeof(sv.settings->'selectedTags') = 'array' THEN sv.settings->'selectedTags' ELSE '[]'::jsonb END,
+        'hideCompleted', COALESCE(sv.settings->'hideCompleted' = 'true'::jsonb, FALSE),
+        'createdAt', to_char(sv.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"')
+    ) ORDER BY sv.created_at DESC) AS entries
+    FROM saved_views sv
+    WHERE sv.scope = 'user' AND sv.project_id IS NOT NULL
+    GROUP BY sv.owner_id
+) views
+WHERE u.id = views.owner_id;
+
+DROP TRIGGER IF EXISTS update_saved_views_updated_at ON saved_views;
+DROP TABLE IF EXISTS saved_views;
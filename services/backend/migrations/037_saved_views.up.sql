@@ -0,0 +1,64 @@
+-- Saved task views. Every view belongs to one project or one workspace; scope
+-- decides who sees it: only its owner ('user'), all project members
+-- ('project'), or all workspace members ('workspace').
+CREATE TABLE IF NOT EXISTS saved_views (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('user', 'project', 'workspace')),
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE,
+    name VARCHAR(120) NOT NULL,
+    view_mode VARCHAR(16) NOT NULL DEFAULT 'list'
+        CHECK (view_mode IN ('list', 'kanban', 'timeline', 'calendar')),
+    filter TEXT NOT NULL DEFAULT '',
+    sort VARCHAR(16) NOT NULL DEFAULT '',
+    group_by VARCHAR(16) NOT NULL DEFAULT '',
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (num_nonnulls(project_id, workspace_id) = 1),
+    CHECK (scope <> 'project' OR project_id IS NOT NULL),
+    CHECK (scope <> 'workspace' OR workspace_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_project_id ON saved_views(project_id) WHERE project_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_saved_views_workspace_id ON saved_views(workspace_id) WHERE workspace_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_saved_views_owner_id ON saved_views(owner_id);
+
+CREATE TRIGGER update_saved_views_updated_at BEFORE UPDATE ON saved_views
+FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Lift the per-user views stored in preferences.savedViews into personal
+-- views. Entries for projects that no longer exist or that the user has left
+-- are dropped. The client-side search, tag and completion toggles are kept in
+-- settings; the list is newest first, which created_at preserves.
+INSERT INTO saved_views (id, owner_id, scope, project_id, name, view_mode, settings, created_at, updated_at)
+SELECT
+    CASE WHEN v.view->>'id' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+        THEN (v.view->>'id')::uuid ELSE gen_random_uuid() END,
+    u.id,
+    'user',
+    p.id,
+    left(COALESCE(NULLIF(btrim(v.view->>'name'), ''), 'Saved view'), 120),
+    CASE
+        WHEN v.view->>'viewMode' IN ('list', 'kanban', 'timeline', 'calendar') THEN v.view->>'viewMode'
+        WHEN v.view->>'viewMode' = 'table' THEN 'list'
+        ELSE 'kanban'
+    END,
+    jsonb_build_object(
+        'searchQuery', COALESCE(v.view->>'searchQuery', ''),
+        'selectedTags', CASE WHEN jsonb_typeof(v.view->'selectedTags') = 'array' THEN v.view->'selectedTags' ELSE '[]'::jsonb END,
+        'hideCompleted', COALESCE(v.view->'hideCompleted' = 'true'::jsonb, FALSE)
+    ),
+    NOW() - v.position * INTERVAL '1 second',
+    NOW() - v.position * INTERVAL '1 second'
+FROM users u
+CROSS JOIN LATERAL jsonb_array_elements(
+    CASE WHEN jsonb_typeof(u.preferences->'savedViews') = 'array' THEN u.preferences->'savedViews' ELSE '[]'::jsonb END
+) WITH ORDINALITY AS v(view, position)
+JOIN projects p ON p.id::text = lower(v.view->>'projectId')
+JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = u.id
+WHERE jsonb_typeof(v.view) = 'object'
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE users SET preferences = preferences - 'savedViews' WHERE preferences ? 'savedViews';
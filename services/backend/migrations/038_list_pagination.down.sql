@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_snippets_user_created_at_id;
+DROP INDEX IF EXISTS idx_wiki_guides_user_created_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_created_at ON notifications(recipient_user_id, created_at DESC);
+DROP INDEX IF EXISTS idx_notifications_recipient_created_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_activity_created_at ON activity(created_at DESC);
+DROP INDEX IF EXISTS idx_activity_created_at_id;
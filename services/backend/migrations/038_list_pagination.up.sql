@@ -0,0 +1,10 @@
+-- Keyset pagination walks lists newest first by (created_at, id). These
+-- indexes replace the created_at-only ones so every page is an index range.
+CREATE INDEX IF NOT EXISTS idx_activity_created_at_id ON activity(created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_activity_created_at;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_created_at_id ON notifications(recipient_user_id, created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_notifications_recipient_created_at;
+
+CREATE INDEX IF NOT EXISTS idx_wiki_guides_user_created_at_id ON wiki_guides(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_snippets_user_created_at_id ON snippets(user_id, created_at DESC, id DESC);
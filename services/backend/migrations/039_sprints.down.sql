@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sprint_tasks;
+DROP TRIGGER IF EXISTS update_sprints_updated_at ON sprints;
+DROP TABLE IF EXISTS sprints;
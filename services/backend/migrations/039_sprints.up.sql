@@ -0,0 +1,39 @@
+-- Time-boxed project iterations. A project has at most one active sprint; a
+-- task is in at most one open sprint of its project, which the repository
+-- enforces when tasks are added.
+CREATE TABLE IF NOT EXISTS sprints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    goal TEXT NOT NULL DEFAULT '',
+    state VARCHAR(16) NOT NULL DEFAULT 'planned' CHECK (state IN ('planned', 'active', 'closed')),
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    started_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    report JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sprints_project_id ON sprints(project_id, start_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sprints_one_active ON sprints(project_id) WHERE state = 'active';
+
+DROP TRIGGER IF EXISTS update_sprints_updated_at ON sprints;
+CREATE TRIGGER update_sprints_updated_at BEFORE UPDATE ON sprints
+FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Sprint membership. Rows are kept when a task leaves a sprint so the closing
+-- report can tell committed work from tasks added or removed mid-sprint.
+CREATE TABLE IF NOT EXISTS sprint_tasks (
+    sprint_id UUID NOT NULL REFERENCES sprints(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    committed BOOLEAN NOT NULL DEFAULT FALSE,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    removed_at TIMESTAMPTZ,
+    PRIMARY KEY (sprint_id, task_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sprint_tasks_task_id ON sprint_tasks(task_id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS record_task_status_update ON tasks;
+DROP TRIGGER IF EXISTS record_task_status_insert ON tasks;
+DROP FUNCTION IF EXISTS record_task_status_event();
+DROP TABLE IF EXISTS task_status_events;
@@ -0,0 +1,58 @@
+-- Structured task status history for project analytics. A trigger records
+-- every status or completion change, whichever code path makes it, so the
+-- history does not depend on the activity summaries.
+CREATE TABLE IF NOT EXISTS task_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    from_status VARCHAR(64),
+    to_status VARCHAR(64) NOT NULL,
+    from_completed BOOLEAN,
+    completed BOOLEAN NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_status_events_task ON task_status_events(task_id, changed_at, id);
+CREATE INDEX IF NOT EXISTS idx_task_status_events_project ON task_status_events(project_id, changed_at);
+
+-- clock_timestamp() keeps several changes made in one transaction apart.
+CREATE OR REPLACE FUNCTION record_task_status_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO task_status_events (task_id, project_id, to_status, completed, changed_at)
+        VALUES (NEW.id, NEW.project_id, NEW.kanban_status, NEW.completed, clock_timestamp());
+    ELSE
+        INSERT INTO task_status_events (task_id, project_id, from_status, to_status, from_completed, completed, changed_at)
+        VALUES (NEW.id, NEW.project_id, OLD.kanban_status, NEW.kanban_status, OLD.completed, NEW.completed, clock_timestamp());
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS record_task_status_insert ON tasks;
+CREATE TRIGGER record_task_status_insert AFTER INSERT ON tasks
+FOR EACH ROW EXECUTE FUNCTION record_task_status_event();
+
+DROP TRIGGER IF EXISTS record_task_status_update ON tasks;
+CREATE TRIGGER record_task_status_update AFTER UPDATE OF kanban_status, completed ON tasks
+FOR EACH ROW
+WHEN (OLD.kanban_status IS DISTINCT FROM NEW.kanban_status OR OLD.completed IS DISTINCT FROM NEW.completed)
+EXECUTE FUNCTION record_task_status_event();
+
+-- Existing tasks have no history. Open tasks get a creation event with their
+-- current status. Completed tasks get a completion event dated by their last
+-- "complete" activity entry, or their last update, so lead times stay
+-- available; their cycle times and earlier statuses are unknown.
+INSERT INTO task_status_events (task_id, project_id, to_status, completed, changed_at)
+SELECT t.id, t.project_id, t.kanban_status, FALSE, t.created_at
+FROM tasks t
+WHERE NOT t.completed;
+
+INSERT INTO task_status_events (task_id, project_id, to_status, from_completed, completed, changed_at)
+SELECT t.id, t.project_id, t.kanban_status, FALSE, TRUE, GREATEST(t.created_at, COALESCE(
+    (SELECT MAX(a.created_at) FROM activity a WHERE a.task_id = t.id AND a.type = 'complete'),
+    t.updated_at
+))
+FROM tasks t
+WHERE t.completed;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS sync_task_time_totals ON time_entries;
+DROP FUNCTION IF EXISTS sync_task_time_totals();
+DROP FUNCTION IF EXISTS refresh_task_time_totals(UUID);
+DROP TRIGGER IF EXISTS update_time_entries_updated_at ON time_entries;
+DROP TABLE IF EXISTS time_entries;
@@ -0,0 +1,121 @@
+-- Itemized time tracking. Entries without an end are running timers; a user
+-- has at most one. The task columns time_spent, is_timer_running,
+-- timer_started_at, and time_entries become totals derived from this table.
+CREATE TABLE IF NOT EXISTS time_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    note TEXT NOT NULL DEFAULT '',
+    billable BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT time_entries_duration_check CHECK (
+        (ended_at IS NULL AND duration_seconds = 0)
+        OR (ended_at >= started_at AND duration_seconds = EXTRACT(EPOCH FROM ended_at - started_at)::integer)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_task ON time_entries(task_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_time_entries_user ON time_entries(user_id, started_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_one_running ON time_entries(user_id) WHERE ended_at IS NULL;
+
+DROP TRIGGER IF EXISTS update_time_entries_updated_at ON time_entries;
+CREATE TRIGGER update_time_entries_updated_at BEFORE UPDATE ON time_entries
+FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Legacy entries are JSON strings or objects such as
+-- {"date":"2026-10-16","seconds":5400}; anything else is skipped.
+CREATE FUNCTION pg_temp.legacy_time_entry(value JSONB)
+RETURNS JSONB AS $$
+BEGIN
+    IF jsonb_typeof(value) = 'string' THEN
+        value := (value #>> '{}')::jsonb;
+    END IF;
+    IF jsonb_typeof(value) IS DISTINCT FROM 'object' THEN
+        RETURN NULL;
+    END IF;
+    RETURN jsonb_build_object('day', (value->>'date')::date, 'seconds', round((value->>'seconds')::numeric)::integer);
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Legacy entries only know their day and do not record who worked, so each
+-- becomes an entry by the task's creator starting at midnight UTC.
+INSERT INTO time_entries (task_id, user_id, started_at, ended_at, duration_seconds)
+SELECT legacy.task_id, legacy.user_id,
+       (legacy.entry->>'day')::date::timestamp AT TIME ZONE 'UTC',
+       (legacy.entry->>'day')::date::timestamp AT TIME ZONE 'UTC' + (legacy.entry->>'seconds')::integer * INTERVAL '1 second',
+       (legacy.entry->>'seconds')::integer
+FROM (
+    SELECT t.id AS task_id, t.user_id, pg_temp.legacy_time_entry(item.value) AS entry
+    FROM tasks t
+    CROSS JOIN LATERAL jsonb_array_elements(CASE WHEN jsonb_typeof(t.time_entries) = 'array' THEN t.time_entries ELSE '[]'::jsonb END) AS item(value)
+) legacy
+WHERE legacy.entry IS NOT NULL AND (legacy.entry->>'seconds')::integer > 0;
+
+-- Time spent beyond the itemized entries is kept as one entry from the
+-- task's creation so no tracked total shrinks.
+INSERT INTO time_entries (task_id, user_id, started_at, ended_at, duration_seconds, note)
+SELECT t.id, t.user_id, t.created_at, t.created_at + (t.time_spent - imported.seconds) * INTERVAL '1 second',
+       t.time_spent - imported.seconds, 'Tracked before time entries were itemized'
+FROM tasks t
+CROSS JOIN LATERAL (
+    SELECT COALESCE(SUM(e.duration_seconds), 0) AS seconds FROM time_entries e WHERE e.task_id = t.id
+) imported
+WHERE t.time_spent > imported.seconds;
+
+-- Only the most recently started timer of each user keeps running; older
+-- ones never counted towards time_spent and are dropped.
+INSERT INTO time_entries (task_id, user_id, started_at)
+SELECT DISTINCT ON (t.user_id) t.id, t.user_id, t.timer_started_at
+FROM tasks t
+WHERE t.is_timer_running AND t.timer_started_at IS NOT NULL
+ORDER BY t.user_id, t.timer_started_at DESC;
+
+CREATE OR REPLACE FUNCTION refresh_task_time_totals(target_task_id UUID)
+RETURNS VOID AS $$
+    UPDATE tasks t SET
+        time_spent = totals.seconds,
+        is_timer_running = totals.running_since IS NOT NULL,
+        timer_started_at = totals.running_since,
+        time_entries = totals.days
+    FROM (
+        SELECT COALESCE(SUM(e.duration_seconds), 0) AS seconds,
+               MIN(e.started_at) FILTER (WHERE e.ended_at IS NULL) AS running_since,
+               COALESCE((
+                   SELECT jsonb_agg(jsonb_build_object('date', d.day, 'seconds', d.seconds)::text ORDER BY d.day)
+                   FROM (
+                       SELECT to_char(de.started_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, SUM(de.duration_seconds) AS seconds
+                       FROM time_entries de
+                       WHERE de.task_id = target_task_id AND de.ended_at IS NOT NULL
+                       GROUP BY 1
+                   ) d
+               ), '[]'::jsonb) AS days
+        FROM time_entries e
+        WHERE e.task_id = target_task_id
+    ) totals
+    WHERE t.id = target_task_id;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION sync_task_time_totals()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM refresh_task_time_totals(OLD.task_id);
+    END IF;
+    IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.task_id <> OLD.task_id) THEN
+        PERFORM refresh_task_time_totals(NEW.task_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sync_task_time_totals ON time_entries;
+CREATE TRIGGER sync_task_time_totals AFTER INSERT OR UPDATE OR DELETE ON time_entries
+FOR EACH ROW EXECUTE FUNCTION sync_task_time_totals();
+
+SELECT refresh_task_time_totals(t.id) FROM tasks t;
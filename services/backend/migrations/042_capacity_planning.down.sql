@@ -0,0 +1,14 @@
+DELETE FROM notifications WHERE type = 'over_allocation' OR task_id IS NULL;
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('mention', 'task_assigned', 'deadline_24h', 'deadline_4h', 'deadline_due'));
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS metadata;
+ALTER TABLE notifications ALTER COLUMN task_id SET NOT NULL;
+
+DROP TABLE IF EXISTS member_absences;
+
+ALTER TABLE project_member_allocations DROP CONSTRAINT IF EXISTS project_member_allocations_dates_check;
+ALTER TABLE project_member_allocations DROP COLUMN IF EXISTS end_date;
+ALTER TABLE project_member_allocations DROP COLUMN IF EXISTS start_date;
@@ -0,0 +1,30 @@
+-- Allocations may be limited to a date range; a missing bound leaves that side open.
+ALTER TABLE project_member_allocations ADD COLUMN start_date DATE;
+ALTER TABLE project_member_allocations ADD COLUMN end_date DATE;
+ALTER TABLE project_member_allocations ADD CONSTRAINT project_member_allocations_dates_check
+    CHECK (start_date IS NULL OR end_date IS NULL OR end_date >= start_date);
+
+-- Planned absences reduce a workspace member's capacity; both days are included.
+CREATE TABLE IF NOT EXISTS member_absences (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_member_absences_workspace_dates ON member_absences(workspace_id, start_date, end_date);
+CREATE INDEX IF NOT EXISTS idx_member_absences_user_id ON member_absences(user_id);
+
+-- Over-allocation alerts concern a project and a member rather than a task;
+-- metadata carries the details the inbox needs to describe them.
+ALTER TABLE notifications ALTER COLUMN task_id DROP NOT NULL;
+ALTER TABLE notifications ADD COLUMN metadata JSONB;
+
+ALTER TABLE notifications DROP CONSTRAINT notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('mention', 'task_assigned', 'deadline_24h', 'deadline_4h', 'deadline_due', 'over_allocation'));
@@ -0,0 +1,7 @@
+DELETE FROM notifications WHERE type = 'budget_alert';
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('mention', 'task_assigned', 'deadline_24h', 'deadline_4h', 'deadline_due', 'over_allocation'));
+
+DROP TABLE IF EXISTS project_budget_alerts;
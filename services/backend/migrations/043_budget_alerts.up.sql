@@ -0,0 +1,15 @@
+-- Records which thresholds of a project's hour budget were alerted. Keying on
+-- the budget re-arms the thresholds when the budget changes; keeping delivery
+-- apart from notifications keeps deleted notifications from being re-sent.
+-- The budget is stored as NUMERIC so the key compares exactly.
+CREATE TABLE IF NOT EXISTS project_budget_alerts (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    threshold INTEGER NOT NULL CHECK (threshold > 0),
+    hour_budget NUMERIC NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, threshold, hour_budget)
+);
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('mention', 'task_assigned', 'deadline_24h', 'deadline_4h', 'deadline_due', 'over_allocation', 'budget_alert'));
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/justlabv1/justspace/backend/internal/config"
+	"github.com/justlabv1/justspace/backend/internal/handlers"
+	"github.com/justlabv1/justspace/backend/internal/repository"
+	"github.com/justlabv1/justspace/backend/internal/storage"
+	"github.com/justlabv1/justspace/backend/internal/thumbnails"
+)
+
+// runStorageMode runs the STORAGE_MODE maintenance command against the
+// configured store. It returns an error when the command could not finish or
+// found referenced blobs that are missing or damaged, so a Job or script sees a
+// non-zero exit status.
+func runStorageMode(cfg *config.Config, repo *repository.Repo, store storage.FileStore) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	blobs, err := storedBlobs(ctx, repo)
+	if err != nil {
+		return err
+	}
+	recordChecksum := func(ctx context.Context, blob storage.Blob, checksum string) error {
+		return repo.SetFileBlobChecksum(ctx, blob.Key, checksum)
+	}
+
+	switch cfg.StorageMode {
+	case "reconcile":
+		log.Printf("Reconciling %d stored blobs against %s storage", len(blobs), cfg.StorageBackend)
+		report, err := storage.Reconcile(ctx, store, blobs, storage.ReconcileOptions{
+			VerifyChecksums: cfg.StorageVerifyChecksums,
+			DeleteOrphans:   cfg.StorageDeleteOrphans,
+			OrphanGrace:     storage.DefaultOrphanGrace,
+			RecordChecksum:  recordChecksum,
+		})
+		if err != nil {
+			return fmt.Errorf("reconcile storage: %w", err)
+		}
+		log.Printf("Reconcile complete: %d referenced, %d missing, %d size mismatches, %d checksum mismatches, %d read errors, %d checksums recorded, %d orphans (%d bytes), %d orphans deleted",
+			report.Referenced, report.Missing, report.SizeMismatches, report.ChecksumMismatches, report.ReadErrors, report.ChecksumsAdded, report.Orphans, report.OrphanBytes, report.OrphansDeleted)
+		if !report.Healthy() {
+			return fmt.Errorf("storage is inconsistent with the database")
+		}
+	case "migrate":
+		source, err := storage.NewBackend(cfg, cfg.StorageMigrateFrom)
+		if err != nil {
+			return fmt.Errorf("open %s storage: %w", cfg.StorageMigrateFrom, err)
+		}
+		log.Printf("Migrating %d stored blobs from %s to %s storage", len(blobs), cfg.StorageMigrateFrom, cfg.StorageBackend)
+		report, err := storage.Migrate(ctx, source, store, blobs, recordChecksum)
+		if err != nil {
+			return fmt.Errorf("migrate storage: %w", err)
+		}
+		log.Printf("Migration complete: %d copied (%d bytes, %d verified against recorded checksums), %d already present, %d missing in source, %d failed",
+			report.Copied, report.Bytes, report.Verified, report.Skipped, report.Missing, report.Failed)
+		if !report.Healthy() {
+			return fmt.Errorf("some blobs were not migrated")
+		}
+	}
+	return nil
+}
+
+// storedBlobs lists every store key the database refers to: project files and
+// their thumbnails, chunks of unfinished uploads, and the renditions of the
+// current brand logo.
+func storedBlobs(ctx context.Context, repo *repository.Repo) ([]storage.Blob, error) {
+	files, err := repo.ListStoredFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]storage.Blob, 0, len(files)+4)
+	for _, file := range files {
+		blob := storage.Blob{Key: file.StoragePath, Size: file.SizeBytes, File: true}
+		if file.ChecksumSHA256 != nil {
+			blob.Checksum = *file.ChecksumSHA256
+		}
+		blobs = append(blobs, blob)
+		if file.HasThumbnails {
+			for _, path := range thumbnails.Paths(file.StoragePath) {
+				blobs = append(blobs, storage.Blob{Key: path, Size: -1})
+			}
+		}
+	}
+	parts, err := repo.ListStoredUploadParts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		blobs = append(blobs, storage.Blob{Key: part.StoragePath, Size: part.SizeBytes})
+	}
+	settings, err := repo.GetPlatformSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if settings.BrandLogoKey != nil {
+		for _, path := range handlers.BrandingLogoPaths(*settings.BrandLogoKey) {
+			blobs = append(blobs, storage.Blob{Key: path, Size: -1})
+		}
+	}
+	return blobs, nil
+}